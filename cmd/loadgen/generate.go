@@ -0,0 +1,250 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	uc "github.com/nikolaev/service-order/internal/usecase/order"
+)
+
+type op int
+
+const (
+	opCreate op = iota
+	opUpdate
+	opCancel
+	opCount
+)
+
+func (o op) String() string {
+	return [...]string{"create", "update", "cancel"}[o]
+}
+
+// generate applies the load until -duration or -orders runs out or ctx is
+// canceled, then waits for the requests in flight. Arrivals are scheduled
+// on absolute times, so a slow target makes them late (reported as lag)
+// instead of lowering the offered rate.
+func generate(ctx context.Context, o options, t target) *report {
+	seed := cmp.Or(o.seed, rand.Uint64())
+	w := newWorld(o, seed)
+	rec := newRecorder()
+	// In-flight requests complete after an interrupt; only arrivals stop.
+	opCtx := context.WithoutCancel(ctx)
+	sem := make(chan struct{}, o.inFlight)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	next := start
+	for n := 0; o.orders == 0 || n < o.orders; n++ {
+		next = next.Add(w.gap())
+		if o.duration > 0 && next.Sub(start) >= o.duration {
+			break
+		}
+		if !sleepUntil(ctx, next) {
+			break
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		rec.lag(time.Since(next))
+		a := w.arrival()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			a.do(opCtx, t, w.placed, rec)
+		}()
+	}
+	wg.Wait()
+	return rec.report(o, seed, time.Since(start))
+}
+
+func sleepUntil(ctx context.Context, at time.Time) bool {
+	d := time.Until(at)
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// world draws the stream: arrival gaps, users, restaurants and menus. Only
+// the generating goroutine draws, so a seed repeats the arrival times and
+// the orders placed; which order an update or a cancellation hits still
+// depends on what has completed by then.
+type world struct {
+	o      options
+	r      *rand.Rand
+	zipf   *rand.Zipf
+	menus  [][]entity.Item
+	placed *placedSet
+	seq    int
+}
+
+var (
+	dishes  = []string{"Борщ", "Пицца", "Роллы", "Бургер", "Плов", "Салат", "Пельмени", "Лагман", "Суп том ям", "Шаурма"}
+	streets = []string{"Ленина", "Мира", "Садовая", "Гагарина", "Пушкина", "Тверская"}
+)
+
+const dishesPerMenu = 6
+
+func newWorld(o options, seed uint64) *world {
+	r := rand.New(rand.NewPCG(seed, seed^0x10ad))
+	w := &world{
+		o: o,
+		r: r,
+		// A few restaurants get most of the orders, like on a real evening.
+		zipf:   rand.NewZipf(r, 1.2, 1, uint64(o.restaurants-1)),
+		menus:  make([][]entity.Item, o.restaurants),
+		placed: newPlacedSet(),
+	}
+	for i := range w.menus {
+		for j := range dishesPerMenu {
+			w.menus[i] = append(w.menus[i], entity.Item{
+				FoodID: fmt.Sprintf("food-%03d-%d", i, j),
+				Name:   dishes[r.IntN(len(dishes))],
+				Price:  150 + 10*r.IntN(136),
+			})
+		}
+	}
+	return w
+}
+
+// gap is the exponential inter-arrival time of a Poisson process.
+func (w *world) gap() time.Duration {
+	return time.Duration(w.r.ExpFloat64() / w.o.rate * float64(time.Second))
+}
+
+// arrival draws the next operation; updates and cancellations fall back to
+// a create while nothing has been placed yet.
+func (w *world) arrival() arrival {
+	x := w.r.Float64() * 100
+	switch {
+	case x < w.o.cancelPct:
+		if p, ok := w.placed.take(w.r); ok {
+			return arrival{op: opCancel, order: p}
+		}
+	case x < w.o.cancelPct+w.o.updatePct:
+		if p, ok := w.placed.pick(w.r); ok {
+			items, total := w.items(p.restaurant)
+			return arrival{op: opUpdate, order: p, items: items, total: total}
+		}
+	}
+	w.seq++
+	rest := int(w.zipf.Uint64())
+	user := w.r.IntN(w.o.users)
+	items, total := w.items(rest)
+	return arrival{
+		op:    opCreate,
+		order: placed{user: fmt.Sprintf("user-%04d", user), restaurant: rest},
+		in: uc.CreateInput{
+			OrderNumber:  fmt.Sprintf("%06d", w.seq),
+			FIO:          fmt.Sprintf("Клиент %d", user),
+			RestaurantID: fmt.Sprintf("rest-%03d", rest),
+			Items:        items,
+			TotalPrice:   total,
+			Address: entity.DeliveryAddress{
+				Street: streets[user%len(streets)],
+				House:  fmt.Sprint(1 + user%97),
+			},
+		},
+	}
+}
+
+func (w *world) items(restaurant int) ([]entity.Item, int64) {
+	menu := w.menus[restaurant]
+	var (
+		items []entity.Item
+		total int64
+	)
+	for _, k := range w.r.Perm(len(menu))[:1+w.r.IntN(3)] {
+		it := menu[k]
+		it.Quantity = 1 + w.r.IntN(2)
+		items = append(items, it)
+		total += int64(it.Price * it.Quantity)
+	}
+	return items, total
+}
+
+type arrival struct {
+	op    op
+	order placed
+	in    uc.CreateInput
+	items []entity.Item
+	total int64
+}
+
+func (a arrival) do(ctx context.Context, t target, set *placedSet, rec *recorder) {
+	start := time.Now()
+	var err error
+	switch a.op {
+	case opCreate:
+		var id string
+		if id, err = t.Create(ctx, a.order.user, a.in); err == nil {
+			a.order.id = id
+			set.add(a.order)
+		}
+	case opUpdate:
+		err = t.Update(ctx, a.order.user, a.order.id, a.items, a.total)
+	case opCancel:
+		err = t.Cancel(ctx, a.order.user, a.order.id)
+	}
+	rec.record(a.op, time.Since(start), err)
+}
+
+// placed is an order the load may update or cancel later.
+type placed struct {
+	user, id   string
+	restaurant int
+}
+
+type placedSet struct {
+	mu   sync.Mutex
+	list []placed
+}
+
+func newPlacedSet() *placedSet { return &placedSet{} }
+
+func (s *placedSet) add(p placed) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = append(s.list, p)
+}
+
+func (s *placedSet) pick(r *rand.Rand) (placed, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.list) == 0 {
+		return placed{}, false
+	}
+	return s.list[r.IntN(len(s.list))], true
+}
+
+// take picks an order and forgets it, so it is canceled once.
+func (s *placedSet) take(r *rand.Rand) (placed, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.list) == 0 {
+		return placed{}, false
+	}
+	i := r.IntN(len(s.list))
+	p := s.list[i]
+	s.list[i] = s.list[len(s.list)-1]
+	s.list = s.list[:len(s.list)-1]
+	return p, true
+}
@@ -0,0 +1,171 @@
+// Command loadgen drives service-order with a sustained stream of orders and
+// reports throughput and latency percentiles at the end.
+//
+//	loadgen [flags]
+//
+// Orders arrive as a Poisson process at -rate per second from a mix of users
+// and restaurants; some arrivals update or cancel an order placed earlier.
+// By default the whole service runs in-process: the in-memory repository,
+// the outbox, the status worker on a simulation profile and the in-memory
+// Kafka broker. -via http puts the HTTP API in between; with -url the load
+// goes to a running service instead.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+type options struct {
+	rate        float64
+	duration    time.Duration
+	orders      int
+	users       int
+	restaurants int
+	updatePct   float64
+	cancelPct   float64
+	seed        uint64
+	inFlight    int
+	via         string
+	url         string
+	profile     string
+	timeScale   float64
+	tick        time.Duration
+	json        bool
+}
+
+const (
+	viaUsecase = "usecase"
+	viaHTTP    = "http"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// The status worker logs every change; the report is the output here.
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	os.Exit(run(ctx, os.Stdout, os.Stderr, os.Args[1:]))
+}
+
+func run(ctx context.Context, stdout, stderr io.Writer, args []string) int {
+	o, err := parse(stderr, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "loadgen: %v\n", err)
+		return 2
+	}
+	rep, err := load(ctx, o)
+	if err != nil {
+		fmt.Fprintf(stderr, "loadgen: %v\n", err)
+		return 1
+	}
+	if o.json {
+		err = rep.writeJSON(stdout)
+	} else {
+		err = rep.writeText(stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "loadgen: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func parse(stderr io.Writer, args []string) (options, error) {
+	var o options
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Float64Var(&o.rate, "rate", 50, "mean arrivals per second")
+	fs.DurationVar(&o.duration, "duration", 30*time.Second, "how long to generate load")
+	fs.IntVar(&o.orders, "orders", 0, "stop after this many arrivals (0: only -duration)")
+	fs.IntVar(&o.users, "users", 200, "number of distinct users")
+	fs.IntVar(&o.restaurants, "restaurants", 20, "number of restaurants; popularity follows a Zipf law")
+	fs.Float64Var(&o.updatePct, "update-percent", 10, "share of arrivals that update a placed order")
+	fs.Float64Var(&o.cancelPct, "cancel-percent", 5, "share of arrivals that cancel a placed order; updated or confirmed ones answer conflict")
+	fs.Uint64Var(&o.seed, "seed", 0, "random seed for a repeatable stream (0: random)")
+	fs.IntVar(&o.inFlight, "in-flight", 256, "maximum requests in flight; arrivals wait beyond it")
+	fs.StringVar(&o.via, "via", viaUsecase, "usecase calls the use case directly, http goes through the API")
+	fs.StringVar(&o.url, "url", "", "base URL of a running service (implies -via http)")
+	fs.StringVar(&o.profile, "profile", "fast", "simulation profile of the in-process status worker")
+	fs.Float64Var(&o.timeScale, "time-scale", 1, "multiplier of the profile's status timers")
+	fs.DurationVar(&o.tick, "tick", 100*time.Millisecond, "tick of the in-process status worker")
+	fs.BoolVar(&o.json, "json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return o, err
+	}
+	if fs.NArg() > 0 {
+		return o, fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+	if o.url != "" {
+		o.via = viaHTTP
+	}
+	return o, o.validate()
+}
+
+func (o options) validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	check(o.rate > 0, "-rate must be positive, got %g", o.rate)
+	check(o.duration > 0 || o.orders > 0, "-duration or -orders must be positive")
+	check(o.duration >= 0, "-duration must not be negative, got %s", o.duration)
+	check(o.orders >= 0, "-orders must not be negative, got %d", o.orders)
+	check(o.users > 0, "-users must be positive, got %d", o.users)
+	check(o.restaurants > 0, "-restaurants must be positive, got %d", o.restaurants)
+	check(o.updatePct >= 0 && o.cancelPct >= 0 && o.updatePct+o.cancelPct <= 100,
+		"-update-percent and -cancel-percent must be non-negative and add up to at most 100")
+	check(o.inFlight > 0, "-in-flight must be positive, got %d", o.inFlight)
+	check(o.via == viaUsecase || o.via == viaHTTP, "-via must be %s or %s, got %q", viaUsecase, viaHTTP, o.via)
+	check(o.timeScale > 0, "-time-scale must be positive, got %g", o.timeScale)
+	check(o.tick > 0, "-tick must be positive, got %s", o.tick)
+	return errors.Join(errs...)
+}
+
+// load runs the whole session: it starts the service unless -url points at
+// one, generates the load and drains the service before reporting.
+func load(ctx context.Context, o options) (*report, error) {
+	if o.url != "" {
+		t, err := newHTTPTarget(o.url, o.inFlight)
+		if err != nil {
+			return nil, err
+		}
+		return generate(ctx, o, t), nil
+	}
+
+	st, err := newStack(o)
+	if err != nil {
+		return nil, err
+	}
+	if err := st.start(ctx); err != nil {
+		return nil, err
+	}
+	var t target = usecaseTarget{st.svc}
+	if o.via == viaHTTP {
+		if t, err = st.serve(o.inFlight); err != nil {
+			_ = st.stop(context.Background())
+			return nil, err
+		}
+	}
+	rep := generate(ctx, o, t)
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := st.stop(stopCtx); err != nil {
+		return nil, err
+	}
+	rep.Events = st.events()
+	return rep, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runJSON(t *testing.T, args ...string) report {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), &stdout, &stderr, append(args, "-json"))
+	require.Equal(t, 0, code, stderr.String())
+	var rep report
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &rep))
+	return rep
+}
+
+func byOp(rep report) map[string]opReport {
+	out := map[string]opReport{}
+	for _, or := range rep.Ops {
+		out[or.Op] = or
+	}
+	return out
+}
+
+func TestRun_InProcess(t *testing.T) {
+	for _, via := range []string{viaUsecase, viaHTTP} {
+		t.Run(via, func(t *testing.T) {
+			rep := runJSON(t, "-via", via, "-orders", "300", "-rate", "3000", "-seed", "5",
+				"-update-percent", "20", "-cancel-percent", "10", "-users", "20", "-restaurants", "5")
+
+			ops := byOp(rep)
+			assert.Equal(t, via, rep.Via)
+			assert.Equal(t, uint64(5), rep.Seed)
+			assert.Equal(t, 300, ops["total"].OK+ops["total"].Errors, "one operation per arrival")
+			assert.Positive(t, ops["create"].OK)
+			assert.Positive(t, ops["update"].OK)
+			assert.Positive(t, ops["cancel"].OK)
+			assert.Zero(t, ops["create"].Errors)
+			assert.Positive(t, rep.Throughput)
+			total := ops["total"]
+			assert.True(t, total.P50 <= total.P90 && total.P90 <= total.P99 && total.P99 <= total.Max)
+
+			assert.Equal(t, int64(ops["create"].OK), rep.Events["order.event.created"], "every order is announced")
+			assert.Equal(t, int64(ops["cancel"].OK), rep.Events["order.event.canceled"])
+			assert.Zero(t, rep.Events["order.event.deleted"])
+		})
+	}
+}
+
+func TestRun_SeedRepeatsTheStream(t *testing.T) {
+	args := []string{"-orders", "100", "-rate", "5000", "-seed", "11", "-update-percent", "0", "-cancel-percent", "0"}
+	a, b := runJSON(t, args...), runJSON(t, args...)
+	assert.Equal(t, 100, byOp(a)["create"].OK)
+	assert.Equal(t, a.Events["order.event.created"], b.Events["order.event.created"])
+}
+
+func TestRun_RejectsBadFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"-rate", "0"},
+		{"-update-percent", "80", "-cancel-percent", "30"},
+		{"-via", "grpc"},
+		{"-duration", "0"},
+		{"extra"},
+	} {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, run(context.Background(), &stdout, &stderr, args), args)
+		assert.Contains(t, stderr.String(), "loadgen:", args)
+	}
+}
+
+func TestPercentiles(t *testing.T) {
+	lat := make([]time.Duration, 100)
+	for i := range lat {
+		lat[i] = time.Duration(i+1) * time.Millisecond
+	}
+	p50, p90, p99, maxLat := percentiles(lat)
+	assert.Equal(t, []time.Duration{50 * time.Millisecond, 90 * time.Millisecond, 99 * time.Millisecond, 100 * time.Millisecond},
+		[]time.Duration{p50, p90, p99, maxLat})
+	p50, _, _, _ = percentiles(nil)
+	assert.Zero(t, p50)
+}
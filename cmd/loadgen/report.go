@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// recorder collects the latency of every operation.
+type recorder struct {
+	mu       sync.Mutex
+	lat      [opCount][]time.Duration
+	errs     [opCount]int
+	firstErr [opCount]error
+	maxLag   time.Duration
+}
+
+func newRecorder() *recorder { return &recorder{} }
+
+func (r *recorder) record(o op, d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.errs[o]++
+		if r.firstErr[o] == nil {
+			r.firstErr[o] = err
+		}
+		return
+	}
+	r.lat[o] = append(r.lat[o], d)
+}
+
+func (r *recorder) lag(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxLag = max(r.maxLag, d)
+}
+
+// report is what loadgen prints at the end. Latencies are of successful
+// operations only.
+type report struct {
+	Via        string           `json:"via"`
+	Seed       uint64           `json:"seed"`
+	Elapsed    time.Duration    `json:"elapsed_ns"`
+	Rate       float64          `json:"offered_rate"`
+	Throughput float64          `json:"throughput"`
+	MaxLag     time.Duration    `json:"max_lag_ns"`
+	Ops        []opReport       `json:"ops"`
+	Events     map[string]int64 `json:"events,omitempty"`
+}
+
+type opReport struct {
+	Op         string        `json:"op"`
+	OK         int           `json:"ok"`
+	Errors     int           `json:"errors"`
+	FirstError string        `json:"first_error,omitempty"`
+	Throughput float64       `json:"throughput"`
+	P50        time.Duration `json:"p50_ns"`
+	P90        time.Duration `json:"p90_ns"`
+	P99        time.Duration `json:"p99_ns"`
+	Max        time.Duration `json:"max_ns"`
+}
+
+func (r *recorder) report(o options, seed uint64, elapsed time.Duration) *report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rep := &report{Via: o.via, Seed: seed, Elapsed: elapsed, Rate: o.rate, MaxLag: r.maxLag}
+	var all []time.Duration
+	for k := range opCount {
+		lat := slices.Clone(r.lat[k])
+		slices.Sort(lat)
+		all = append(all, lat...)
+		or := opReport{Op: k.String(), OK: len(lat), Errors: r.errs[k], Throughput: perSecond(len(lat), elapsed)}
+		if r.firstErr[k] != nil {
+			or.FirstError = r.firstErr[k].Error()
+		}
+		or.P50, or.P90, or.P99, or.Max = percentiles(lat)
+		rep.Ops = append(rep.Ops, or)
+	}
+	slices.Sort(all)
+	total := opReport{Op: "total", OK: len(all), Throughput: perSecond(len(all), elapsed)}
+	for _, or := range rep.Ops {
+		total.Errors += or.Errors
+	}
+	total.P50, total.P90, total.P99, total.Max = percentiles(all)
+	rep.Ops = append(rep.Ops, total)
+	rep.Throughput = total.Throughput
+	return rep
+}
+
+func perSecond(n int, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(n) / elapsed.Seconds()
+}
+
+// percentiles of sorted latencies by the nearest rank.
+func percentiles(sorted []time.Duration) (p50, p90, p99, maxLat time.Duration) {
+	if len(sorted) == 0 {
+		return 0, 0, 0, 0
+	}
+	rank := func(p float64) time.Duration {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return sorted[max(i, 0)]
+	}
+	return rank(0.5), rank(0.9), rank(0.99), sorted[len(sorted)-1]
+}
+
+func (rep *report) writeText(w io.Writer) error {
+	fmt.Fprintf(w, "via %s, seed %d: %s, offered %.1f/s, achieved %.1f/s, max arrival lag %s\n\n",
+		rep.Via, rep.Seed, rep.Elapsed.Round(time.Millisecond), rep.Rate, rep.Throughput, rep.MaxLag.Round(time.Microsecond))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "op\tok\terrors\tops/s\tp50\tp90\tp99\tmax\t")
+	for _, or := range rep.Ops {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t\n", or.Op, or.OK, or.Errors, or.Throughput,
+			latency(or.P50), latency(or.P90), latency(or.P99), latency(or.Max))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	blank := true
+	for _, or := range rep.Ops {
+		if or.FirstError == "" {
+			continue
+		}
+		if blank {
+			fmt.Fprintln(w)
+			blank = false
+		}
+		fmt.Fprintf(w, "first %s error: %s\n", or.Op, or.FirstError)
+	}
+	if len(rep.Events) > 0 {
+		topics := make([]string, 0, len(rep.Events))
+		for name := range rep.Events {
+			topics = append(topics, name)
+		}
+		sort.Strings(topics)
+		fmt.Fprintln(w, "\nevents:")
+		for _, name := range topics {
+			fmt.Fprintf(w, "  %-28s %d\n", name, rep.Events[name])
+		}
+	}
+	return nil
+}
+
+func latency(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
+
+func (rep *report) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/nikolaev/service-order/internal/config"
+	"github.com/nikolaev/service-order/internal/gateway/kafka"
+	"github.com/nikolaev/service-order/internal/gateway/kafka/memkafka"
+	"github.com/nikolaev/service-order/internal/gateway/outbox"
+	"github.com/nikolaev/service-order/internal/handlers"
+	repo "github.com/nikolaev/service-order/internal/repository/order"
+	"github.com/nikolaev/service-order/internal/simulation"
+	uc "github.com/nikolaev/service-order/internal/usecase/order"
+	"github.com/nikolaev/service-order/internal/worker"
+	"github.com/nikolaev/service-order/pkg/client"
+)
+
+// stack is the service assembled in-process the way cmd/service does with
+// kafka.memory: events go through the outbox to the in-memory broker.
+type stack struct {
+	broker   *memkafka.Broker
+	producer *kafka.SaramaProducer
+	outbox   *outbox.Outbox
+	worker   *worker.StatusWorker
+	svc      uc.Service
+	server   *http.Server
+}
+
+func newStack(o options) (*stack, error) {
+	cfg := config.Default()
+	sim, err := simulation.New(cfg.Profiles(), o.profile, o.timeScale)
+	if err != nil {
+		return nil, err
+	}
+	store := repo.NewInMemoryWithSchedule(sim)
+
+	kc := kafka.Config{
+		Topic:   cfg.Kafka.Topic,
+		// The report counts events by their typed topics.
+		Routing: kafka.Routing{Mode: kafka.RoutingBoth, Topics: cfg.Kafka.Routing.Topics()},
+	}
+	broker := memkafka.NewBroker(int32(cfg.Kafka.Topics.Partitions))
+	for _, name := range kc.RoutedTopics() {
+		_ = broker.CreateTopic(name, int32(cfg.Kafka.Topics.Partitions))
+	}
+	p := kafka.NewSaramaProducerFromSync(broker.SyncProducer(), kc)
+	ob := outbox.New(p, cfg.Outbox.Buffer)
+	return &stack{
+		broker:   broker,
+		producer: p,
+		outbox:   ob,
+		worker:   worker.NewStatusWorker(o.tick, store, ob),
+		svc:      uc.New(store, ob),
+	}, nil
+}
+
+func (s *stack) start(ctx context.Context) error {
+	if err := s.outbox.Start(ctx); err != nil {
+		return err
+	}
+	return s.worker.Start(ctx)
+}
+
+// serve puts the HTTP API in front of the use case on a loopback port.
+func (s *stack) serve(inFlight int) (target, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+	r := chi.NewRouter()
+	r.Mount(client.APIPrefix, handlers.NewOrderHandler(s.svc).Routes())
+	s.server = &http.Server{Handler: r}
+	go func() { _ = s.server.Serve(ln) }()
+	return newHTTPTarget("http://"+ln.Addr().String(), inFlight)
+}
+
+// stop shuts down in dependency order, so that the outbox delivers every
+// event the worker and the requests produced.
+func (s *stack) stop(ctx context.Context) error {
+	var errs []error
+	if s.server != nil {
+		errs = append(errs, s.server.Shutdown(ctx))
+	}
+	errs = append(errs, s.worker.Stop(ctx), s.outbox.Stop(ctx), s.producer.Close())
+	return errors.Join(errs...)
+}
+
+// events counts the messages per topic.
+func (s *stack) events() map[string]int64 {
+	out := map[string]int64{}
+	for _, t := range s.broker.Topics() {
+		out[t.Name] = t.Messages
+	}
+	return out
+}
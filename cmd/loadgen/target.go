@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	uc "github.com/nikolaev/service-order/internal/usecase/order"
+	"github.com/nikolaev/service-order/pkg/api/openapi"
+	"github.com/nikolaev/service-order/pkg/client"
+)
+
+// target is what the load is applied to; every call is one measured
+// operation.
+type target interface {
+	Create(ctx context.Context, userID string, in uc.CreateInput) (string, error)
+	Update(ctx context.Context, userID, id string, items []entity.Item, total int64) error
+	Cancel(ctx context.Context, userID, id string) error
+}
+
+// usecaseTarget calls the use case directly.
+type usecaseTarget struct{ svc uc.Service }
+
+func (t usecaseTarget) Create(ctx context.Context, userID string, in uc.CreateInput) (string, error) {
+	o, err := t.svc.Create(ctx, userID, in)
+	if err != nil {
+		return "", err
+	}
+	return o.ID, nil
+}
+
+func (t usecaseTarget) Update(ctx context.Context, userID, id string, items []entity.Item, total int64) error {
+	_, err := t.svc.Update(ctx, userID, id, uc.UpdateInput{Items: &items, TotalPrice: &total})
+	return err
+}
+
+func (t usecaseTarget) Cancel(ctx context.Context, userID, id string) error {
+	_, err := t.svc.Cancel(ctx, userID, id)
+	return err
+}
+
+// httpTarget goes through the API with one client per user; the clients
+// share a transport that keeps a connection per request in flight.
+type httpTarget struct {
+	url  string
+	doer *http.Client
+
+	mu      sync.Mutex
+	clients map[string]*client.Client
+}
+
+func newHTTPTarget(url string, inFlight int) (*httpTarget, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.MaxIdleConns = inFlight
+	tr.MaxIdleConnsPerHost = inFlight
+	t := &httpTarget{url: url, doer: &http.Client{Transport: tr}, clients: map[string]*client.Client{}}
+	// Fail fast on a malformed URL instead of on every request.
+	if _, err := t.client(""); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *httpTarget) client(userID string) (*client.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if c, ok := t.clients[userID]; ok {
+		return c, nil
+	}
+	c, err := client.New(t.url, client.WithUserID(userID), client.WithHTTPClient(t.doer), client.WithRetry(client.NoRetry))
+	if err != nil {
+		return nil, err
+	}
+	t.clients[userID] = c
+	return c, nil
+}
+
+func (t *httpTarget) Create(ctx context.Context, userID string, in uc.CreateInput) (string, error) {
+	c, err := t.client(userID)
+	if err != nil {
+		return "", err
+	}
+	o, err := c.CreateOrder(ctx, openapi.CreateOrderRequest{
+		OrderNumber:  in.OrderNumber,
+		FIO:          in.FIO,
+		RestaurantID: in.RestaurantID,
+		Items:        toItems(in.Items),
+		TotalPrice:   in.TotalPrice,
+		Address: openapi.DeliveryAddress{
+			Street:    in.Address.Street,
+			House:     in.Address.House,
+			Apartment: in.Address.Apartment,
+			Floor:     in.Address.Floor,
+			Comment:   in.Address.Comment,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return o.ID, nil
+}
+
+func (t *httpTarget) Update(ctx context.Context, userID, id string, items []entity.Item, total int64) error {
+	c, err := t.client(userID)
+	if err != nil {
+		return err
+	}
+	its := toItems(items)
+	_, err = c.UpdateOrder(ctx, id, openapi.UpdateOrderRequest{Items: &its, TotalPrice: &total})
+	return err
+}
+
+func (t *httpTarget) Cancel(ctx context.Context, userID, id string) error {
+	c, err := t.client(userID)
+	if err != nil {
+		return err
+	}
+	_, err = c.CancelOrder(ctx, id)
+	return err
+}
+
+func toItems(items []entity.Item) []openapi.Item {
+	out := make([]openapi.Item, 0, len(items))
+	for _, it := range items {
+		out = append(out, openapi.Item{FoodID: it.FoodID, Name: it.Name, Price: it.Price, Quantity: it.Quantity})
+	}
+	return out
+}
@@ -0,0 +1,555 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nikolaev/service-order/pkg/api/openapi"
+	"github.com/nikolaev/service-order/pkg/client"
+)
+
+func newFlagSet(e *env, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("orderctl "+name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	return fs
+}
+
+// itemsFlag parses repeated --item food_id:name:quantity:price values.
+type itemsFlag []openapi.Item
+
+func (f *itemsFlag) String() string { return fmt.Sprint(len(*f)) }
+
+func (f *itemsFlag) Set(v string) error {
+	parts := strings.Split(v, ":")
+	if len(parts) != 4 {
+		return errors.New("want food_id:name:quantity:price")
+	}
+	qty, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return fmt.Errorf("quantity: %w", err)
+	}
+	price, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return fmt.Errorf("price: %w", err)
+	}
+	*f = append(*f, openapi.Item{FoodID: parts[0], Name: parts[1], Quantity: qty, Price: price})
+	return nil
+}
+
+// pointFlag parses a --location lat,lon value.
+type pointFlag struct{ p *openapi.GeoPoint }
+
+func (f *pointFlag) String() string {
+	if f.p == nil {
+		return ""
+	}
+	return fmt.Sprintf("%g,%g", f.p.Lat, f.p.Lon)
+}
+
+func (f *pointFlag) Set(v string) error {
+	lat, lon, ok := strings.Cut(v, ",")
+	if !ok {
+		return errors.New("want lat,lon")
+	}
+	p := &openapi.GeoPoint{}
+	var err error
+	if p.Lat, err = strconv.ParseFloat(strings.TrimSpace(lat), 64); err != nil {
+		return fmt.Errorf("lat: %w", err)
+	}
+	if p.Lon, err = strconv.ParseFloat(strings.TrimSpace(lon), 64); err != nil {
+		return fmt.Errorf("lon: %w", err)
+	}
+	f.p = p
+	return nil
+}
+
+// timeFlag parses an RFC3339 time; unset stays nil.
+type timeFlag struct{ t *time.Time }
+
+func (f *timeFlag) String() string {
+	if f.t == nil {
+		return ""
+	}
+	return f.t.Format(time.RFC3339)
+}
+
+func (f *timeFlag) Set(v string) error {
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return err
+	}
+	f.t = &t
+	return nil
+}
+
+// scheduleFlags schedule an order for later.
+type scheduleFlags struct{ deliverAt, prepareFrom timeFlag }
+
+func (s *scheduleFlags) register(fs *flag.FlagSet) {
+	fs.Var(&s.deliverAt, "deliver-at", "schedule delivery at this RFC3339 time")
+	fs.Var(&s.prepareFrom, "prepare-from", "schedule cooking from this RFC3339 time")
+}
+
+type addressFlags struct {
+	street, house, apartment, floor, comment string
+	location                                 pointFlag
+}
+
+func (a *addressFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&a.street, "street", "", "delivery street")
+	fs.StringVar(&a.house, "house", "", "delivery house")
+	fs.StringVar(&a.apartment, "apartment", "", "delivery apartment")
+	fs.StringVar(&a.floor, "floor", "", "delivery floor")
+	fs.StringVar(&a.comment, "comment", "", "delivery comment")
+	fs.Var(&a.location, "location", "delivery location as lat,lon (default: geocoded by the service)")
+}
+
+func (a *addressFlags) value() openapi.DeliveryAddress {
+	return openapi.DeliveryAddress{Street: a.street, House: a.house, Apartment: a.apartment, Floor: a.floor, Comment: a.comment, Location: a.location.p}
+}
+
+func readJSON(e *env, path string, v any) error {
+	var r io.Reader = e.stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	return json.NewDecoder(r).Decode(v)
+}
+
+func runCreate(ctx context.Context, e *env, args []string) error {
+	var (
+		conn   connFlags
+		addr   addressFlags
+		sched  scheduleFlags
+		items  itemsFlag
+		file   string
+		out    string
+		req    openapi.CreateOrderRequest
+		totalV int64
+	)
+	fs := newFlagSet(e, "create")
+	conn.register(fs)
+	addr.register(fs)
+	sched.register(fs)
+	fs.Var(&items, "item", "order item food_id:name:quantity:price (repeatable)")
+	fs.StringVar(&file, "file", "", "read CreateOrderRequest JSON from file ('-' for stdin)")
+	fs.StringVar(&req.RestaurantID, "restaurant", "", "restaurant ID")
+	fs.StringVar(&req.FIO, "fio", "", "customer name")
+	fs.StringVar(&req.OrderNumber, "number", "", "order number")
+	fs.Int64Var(&totalV, "total", -1, "total price (default: sum of items)")
+	fs.StringVar(&out, "o", formatTable, "output format: table, json, csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if file != "" {
+		if err := readJSON(e, file, &req); err != nil {
+			return err
+		}
+	} else {
+		req.Items = items
+		req.Address = addr.value()
+		req.DeliverAt, req.PrepareFrom = sched.deliverAt.t, sched.prepareFrom.t
+		req.TotalPrice = totalV
+		if totalV < 0 {
+			req.TotalPrice = 0
+			for _, it := range items {
+				req.TotalPrice += int64(it.Price * it.Quantity)
+			}
+		}
+	}
+
+	c, err := conn.client()
+	if err != nil {
+		return err
+	}
+	o, err := c.CreateOrder(ctx, req)
+	if err != nil {
+		return err
+	}
+	return printOrders(e.stdout, out, []openapi.OrderResponse{*o})
+}
+
+// parseIDCommand parses flags and requires exactly one positional ID.
+func parseIDCommand(e *env, name string, args []string, register func(fs *flag.FlagSet)) (string, error) {
+	fs := newFlagSet(e, name)
+	register(fs)
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	// Allow flags after the ID: orderctl get ID -o json.
+	if fs.NArg() > 1 {
+		id := fs.Arg(0)
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return "", err
+		}
+		if fs.NArg() != 0 {
+			return "", errors.New("expected exactly one ID")
+		}
+		return id, nil
+	}
+	if fs.NArg() != 1 {
+		return "", errors.New("expected exactly one ID")
+	}
+	return fs.Arg(0), nil
+}
+
+func runGet(ctx context.Context, e *env, args []string) error {
+	var (
+		conn connFlags
+		out  string
+	)
+	id, err := parseIDCommand(e, "get", args, func(fs *flag.FlagSet) {
+		conn.register(fs)
+		fs.StringVar(&out, "o", formatTable, "output format: table, json, csv")
+	})
+	if err != nil {
+		return err
+	}
+	c, err := conn.client()
+	if err != nil {
+		return err
+	}
+	o, err := c.GetOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	return printOrders(e.stdout, out, []openapi.OrderResponse{*o})
+}
+
+func runStatus(ctx context.Context, e *env, args []string) error {
+	var conn connFlags
+	id, err := parseIDCommand(e, "status", args, conn.register)
+	if err != nil {
+		return err
+	}
+	c, err := conn.client()
+	if err != nil {
+		return err
+	}
+	st, err := c.GetOrderStatus(ctx, id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(e.stdout, st.Status)
+	return nil
+}
+
+func runList(ctx context.Context, e *env, args []string) error {
+	var (
+		conn       connFlags
+		from       string
+		since      time.Duration
+		status     string
+		restaurant string
+		user       string
+		out        string
+	)
+	fs := newFlagSet(e, "list")
+	conn.register(fs)
+	fs.StringVar(&from, "from", "", "only orders created at or after this RFC3339 time")
+	fs.DurationVar(&since, "since", 0, "only orders created within this duration, e.g. 1h")
+	fs.StringVar(&status, "status", "", "filter by status (comma-separated)")
+	fs.StringVar(&restaurant, "restaurant", "", "filter by restaurant ID")
+	fs.StringVar(&user, "owner", "", "filter by owner user ID")
+	fs.StringVar(&out, "o", formatTable, "output format: table, json, csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var fromT time.Time
+	switch {
+	case from != "" && since != 0:
+		return errors.New("use either --from or --since")
+	case from != "":
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return fmt.Errorf("--from: %w", err)
+		}
+		fromT = t
+	case since != 0:
+		fromT = time.Now().Add(-since)
+	}
+
+	c, err := conn.client()
+	if err != nil {
+		return err
+	}
+	orders, err := c.ListOrders(ctx, fromT)
+	if err != nil {
+		return err
+	}
+
+	statuses := map[string]bool{}
+	for _, s := range strings.Split(status, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			statuses[s] = true
+		}
+	}
+	filtered := orders[:0]
+	for _, o := range orders {
+		if len(statuses) > 0 && !statuses[string(o.Status)] {
+			continue
+		}
+		if restaurant != "" && o.RestaurantID != restaurant {
+			continue
+		}
+		if user != "" && o.UserID != user {
+			continue
+		}
+		filtered = append(filtered, o)
+	}
+	sort.Slice(filtered, func(i, j int) bool { return filtered[i].CreatedAt.Before(filtered[j].CreatedAt) })
+	return printOrders(e.stdout, out, filtered)
+}
+
+func runUpdate(ctx context.Context, e *env, args []string) error {
+	var (
+		conn  connFlags
+		addr  addressFlags
+		sched scheduleFlags
+		items itemsFlag
+		file  string
+		fio   string
+		num   string
+		total int64
+		out   string
+	)
+	var fs *flag.FlagSet
+	id, err := parseIDCommand(e, "update", args, func(f *flag.FlagSet) {
+		fs = f
+		conn.register(f)
+		addr.register(f)
+		sched.register(f)
+		f.Var(&items, "item", "replace items: food_id:name:quantity:price (repeatable)")
+		f.StringVar(&file, "file", "", "read UpdateOrderRequest JSON from file ('-' for stdin)")
+		f.StringVar(&fio, "fio", "", "customer name")
+		f.StringVar(&num, "number", "", "order number")
+		f.Int64Var(&total, "total", 0, "total price")
+		f.StringVar(&out, "o", formatTable, "output format: table, json, csv")
+	})
+	if err != nil {
+		return err
+	}
+
+	var req openapi.UpdateOrderRequest
+	if file != "" {
+		if err := readJSON(e, file, &req); err != nil {
+			return err
+		}
+	} else {
+		set := map[string]bool{}
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if set["fio"] {
+			req.FIO = &fio
+		}
+		if set["number"] {
+			req.OrderNumber = &num
+		}
+		if set["total"] {
+			req.TotalPrice = &total
+		}
+		if set["item"] {
+			v := []openapi.Item(items)
+			req.Items = &v
+		}
+		if set["street"] || set["house"] || set["apartment"] || set["floor"] || set["comment"] || set["location"] {
+			v := addr.value()
+			req.Address = &v
+		}
+		req.DeliverAt, req.PrepareFrom = sched.deliverAt.t, sched.prepareFrom.t
+	}
+
+	c, err := conn.client()
+	if err != nil {
+		return err
+	}
+	o, err := c.UpdateOrder(ctx, id, req)
+	if err != nil {
+		return err
+	}
+	return printOrders(e.stdout, out, []openapi.OrderResponse{*o})
+}
+
+func runDelete(ctx context.Context, e *env, args []string) error {
+	var conn connFlags
+	id, err := parseIDCommand(e, "delete", args, conn.register)
+	if err != nil {
+		return err
+	}
+	c, err := conn.client()
+	if err != nil {
+		return err
+	}
+	res, err := c.DeleteOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "%s %s\n", res.ID, res.Status)
+	return nil
+}
+
+func runCancel(ctx context.Context, e *env, args []string) error {
+	var (
+		conn connFlags
+		out  string
+	)
+	id, err := parseIDCommand(e, "cancel", args, func(f *flag.FlagSet) {
+		conn.register(f)
+		f.StringVar(&out, "o", formatTable, "output format: table, json, csv")
+	})
+	if err != nil {
+		return err
+	}
+	c, err := conn.client()
+	if err != nil {
+		return err
+	}
+	o, err := c.CancelOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	return printOrders(e.stdout, out, []openapi.OrderResponse{*o})
+}
+
+func runSeed(ctx context.Context, e *env, args []string) error {
+	var (
+		conn     connFlags
+		out      string
+		scenario string
+		file     string
+		count    int
+		seed     int64
+	)
+	fs := newFlagSet(e, "seed")
+	conn.register(fs)
+	fs.StringVar(&scenario, "scenario", "", "scenario name (default: the server's seed.scenario)")
+	fs.StringVar(&file, "file", "", "read an inline scenario JSON from file ('-' for stdin)")
+	fs.IntVar(&count, "count", 0, "orders to create (default: the scenario's count)")
+	fs.Int64Var(&seed, "seed", 0, "random seed, for a reproducible batch")
+	fs.StringVar(&out, "o", formatTable, "output format: table, json, csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var req openapi.SeedRequest
+	if scenario != "" {
+		req.Scenario = &scenario
+	}
+	if file != "" {
+		var inline map[string]any
+		if err := readJSON(e, file, &inline); err != nil {
+			return err
+		}
+		req.Inline = &inline
+	}
+	if count > 0 {
+		req.Count = &count
+	}
+	if seed > 0 {
+		req.Seed = &seed
+	}
+	c, err := conn.client()
+	if err != nil {
+		return err
+	}
+	orders, err := c.SeedDebugOrders(ctx, req)
+	if err != nil {
+		return err
+	}
+	return printOrders(e.stdout, out, orders)
+}
+
+func runWatch(ctx context.Context, e *env, args []string) error {
+	var (
+		conn     connFlags
+		interval time.Duration
+	)
+	id, err := parseIDCommand(e, "watch", args, func(fs *flag.FlagSet) {
+		conn.register(fs)
+		fs.DurationVar(&interval, "interval", time.Second, "poll interval")
+	})
+	if err != nil {
+		return err
+	}
+	c, err := conn.client(client.WithPollInterval(interval))
+	if err != nil {
+		return err
+	}
+	return c.WatchOrder(ctx, id, func(st openapi.OrderStatusResponse) {
+		fmt.Fprintf(e.stdout, "%s %s %s\n", time.Now().UTC().Format(time.RFC3339), st.OrderID, st.Status)
+	})
+}
+
+func runProfile(_ context.Context, e *env, args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected save, use or list")
+	}
+	var (
+		path string
+		p    Profile
+	)
+	fs := newFlagSet(e, "profile "+args[0])
+	fs.StringVar(&path, "config", defaultConfigPath(), "config file")
+	fs.StringVar(&p.BaseURL, "url", defaultBaseURL, "service base URL")
+	fs.StringVar(&p.UserID, "user", "", "X-User-ID")
+	fs.BoolVar(&p.Bypass, "bypass", false, "send X-Bypass-Auth=true")
+	fs.StringVar(&p.Token, "token", "", "bearer token")
+	fs.StringVar(&p.AdminToken, "admin-token", "", "X-Admin-Token of the /admin operations")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		names := make([]string, 0, len(cfg.Profiles))
+		for name := range cfg.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			mark := " "
+			if name == cfg.Current {
+				mark = "*"
+			}
+			fmt.Fprintf(e.stdout, "%s %s\t%s\n", mark, name, cfg.Profiles[name].BaseURL)
+		}
+		return nil
+	case "save", "use":
+		if fs.NArg() != 1 {
+			return errors.New("expected a profile name")
+		}
+		name := fs.Arg(0)
+		if args[0] == "save" {
+			cfg.Profiles[name] = p
+			if cfg.Current == "" {
+				cfg.Current = name
+			}
+		} else {
+			if _, ok := cfg.Profiles[name]; !ok {
+				return fmt.Errorf("profile %q not found", name)
+			}
+			cfg.Current = name
+		}
+		return saveConfig(path, cfg)
+	default:
+		return fmt.Errorf("unknown profile command %q", args[0])
+	}
+}
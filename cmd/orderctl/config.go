@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/nikolaev/service-order/pkg/client"
+)
+
+const defaultBaseURL = "http://localhost:8080"
+
+// Profile holds connection settings for one service-order deployment.
+type Profile struct {
+	BaseURL string `json:"base_url"`
+	UserID  string `json:"user_id,omitempty"`
+	Bypass  bool   `json:"bypass,omitempty"`
+	Token   string `json:"token,omitempty"`
+	// AdminToken is sent as X-Admin-Token by the replay and webhook commands.
+	AdminToken string `json:"admin_token,omitempty"`
+}
+
+type Config struct {
+	Current  string             `json:"current"`
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+func defaultConfigPath() string {
+	if p := os.Getenv("ORDERCTL_CONFIG"); p != "" {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "orderctl.json"
+	}
+	return filepath.Join(dir, "orderctl", "config.json")
+}
+
+func loadConfig(path string) (*Config, error) {
+	cfg := &Config{Profiles: map[string]Profile{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]Profile{}
+	}
+	return cfg, nil
+}
+
+func saveConfig(path string, cfg *Config) error {
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o600)
+}
+
+// connFlags are accepted by every command that talks to the service.
+type connFlags struct {
+	config  string
+	profile string
+	url     string
+	user    string
+	bypass  bool
+	token   string
+	admin   string
+}
+
+func (c *connFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.config, "config", defaultConfigPath(), "config file")
+	fs.StringVar(&c.profile, "profile", os.Getenv("ORDERCTL_PROFILE"), "profile name (default: current profile)")
+	fs.StringVar(&c.url, "url", "", "service base URL, overrides profile")
+	fs.StringVar(&c.user, "user", "", "X-User-ID, overrides profile")
+	fs.BoolVar(&c.bypass, "bypass", false, "send X-Bypass-Auth=true")
+	fs.StringVar(&c.token, "token", "", "bearer token, overrides profile")
+	fs.StringVar(&c.admin, "admin-token", "", "X-Admin-Token of the /admin operations, overrides profile")
+}
+
+// resolve merges the selected profile with command-line overrides.
+func (c *connFlags) resolve() (Profile, error) {
+	cfg, err := loadConfig(c.config)
+	if err != nil {
+		return Profile{}, err
+	}
+	name := c.profile
+	if name == "" {
+		name = cfg.Current
+	}
+	p := Profile{BaseURL: defaultBaseURL}
+	if name != "" {
+		found, ok := cfg.Profiles[name]
+		if !ok {
+			return Profile{}, fmt.Errorf("profile %q not found in %s", name, c.config)
+		}
+		p = found
+	}
+	if c.url != "" {
+		p.BaseURL = c.url
+	}
+	if c.user != "" {
+		p.UserID = c.user
+	}
+	if c.bypass {
+		p.Bypass = true
+	}
+	if c.token != "" {
+		p.Token = c.token
+	}
+	if c.admin != "" {
+		p.AdminToken = c.admin
+	}
+	return p, nil
+}
+
+func (c *connFlags) client(opts ...client.Option) (*client.Client, error) {
+	p, err := c.resolve()
+	if err != nil {
+		return nil, err
+	}
+	if p.UserID != "" {
+		opts = append(opts, client.WithUserID(p.UserID))
+	}
+	if p.Bypass {
+		opts = append(opts, client.WithBypassAuth())
+	}
+	if p.Token != "" {
+		opts = append(opts, client.WithBearerToken(p.Token))
+	}
+	if p.AdminToken != "" {
+		opts = append(opts, client.WithAdminToken(p.AdminToken))
+	}
+	return client.New(p.BaseURL, opts...)
+}
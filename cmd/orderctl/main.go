@@ -0,0 +1,84 @@
+// Command orderctl operates service-order over its HTTP API.
+//
+//	orderctl <command> [flags]
+//
+// Commands: create, get, status, list, update, delete, seed, watch, replay,
+// profile.
+// Connection settings come from the active profile in the config file
+// (~/.config/orderctl/config.json, override with --config or ORDERCTL_CONFIG)
+// and can be overridden per call with --url, --user, --bypass, --token and
+// --admin-token.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, env *env, args []string) error
+}
+
+var commands = map[string]command{
+	"create":  {"create order from flags or --file", runCreate},
+	"get":     {"get ID: show order", runGet},
+	"status":  {"status ID: show order status", runStatus},
+	"list":    {"list orders with filters", runList},
+	"update":  {"update ID: change order fields from flags or --file", runUpdate},
+	"delete":  {"delete ID: delete order", runDelete},
+	"cancel":  {"cancel ID: cancel an order not yet confirmed", runCancel},
+	"seed":    {"create demo orders via the debug route", runSeed},
+	"watch":   {"watch ID: print status changes until a terminal status", runWatch},
+	"replay":  {"replay start|list|get|cancel: republish order snapshots to Kafka", runReplay},
+	"profile": {"profile save|use|list: manage connection profiles", runProfile},
+}
+
+// env carries the process streams so commands can be tested in-process.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	os.Exit(run(ctx, &env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}, os.Args[1:]))
+}
+
+func run(ctx context.Context, e *env, args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(e.stderr)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(e.stderr, "orderctl: unknown command %q\n", args[0])
+		usage(e.stderr)
+		return 2
+	}
+	if err := cmd.run(ctx, e, args[1:]); err != nil {
+		fmt.Fprintf(e.stderr, "orderctl %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: orderctl <command> [flags]")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(w, "run 'orderctl <command> -h' for command flags")
+}
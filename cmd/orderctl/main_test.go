@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/clock"
+	"github.com/nikolaev/service-order/internal/gateway/kafka"
+	"github.com/nikolaev/service-order/internal/handlers"
+	repo "github.com/nikolaev/service-order/internal/repository/order"
+	seed "github.com/nikolaev/service-order/internal/usecase/debug/seed"
+	uc "github.com/nikolaev/service-order/internal/usecase/order"
+	"github.com/nikolaev/service-order/internal/usecase/replay"
+	"github.com/nikolaev/service-order/pkg/api/openapi"
+	"github.com/nikolaev/service-order/pkg/client"
+)
+
+type harness struct {
+	t      *testing.T
+	config string
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	mem := repo.NewInMemory()
+	rp := replay.NewWithLimits(mem, kafka.NoopProducer{}, clock.System{}, replay.Limits{Rate: 1000})
+	t.Cleanup(func() { _ = rp.Stop(context.Background()) })
+	svc := uc.New(mem, kafka.NoopProducer{})
+	sd, err := seed.New(svc, mem, kafka.NoopProducer{}, clock.System{}, seed.Options{})
+	require.NoError(t, err)
+	h := handlers.NewOrderHandler(svc, sd).WithReplay(rp).WithAdminToken("admin")
+	r := chi.NewRouter()
+	r.Mount(client.APIPrefix, h.Routes())
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	hs := &harness{t: t, config: filepath.Join(t.TempDir(), "config.json")}
+	hs.ok("profile", "save", "--config", hs.config, "--url", srv.URL, "--user", "u1", "--admin-token", "admin", "local")
+	return hs
+}
+
+func (h *harness) run(stdin string, args ...string) (string, string, int) {
+	var out, errOut bytes.Buffer
+	code := run(context.Background(), &env{stdin: strings.NewReader(stdin), stdout: &out, stderr: &errOut}, args)
+	return out.String(), errOut.String(), code
+}
+
+func (h *harness) ok(args ...string) string {
+	h.t.Helper()
+	out, errOut, code := h.run("", args...)
+	require.Equal(h.t, 0, code, errOut)
+	return out
+}
+
+func (h *harness) createJSON(args ...string) openapi.OrderResponse {
+	h.t.Helper()
+	out := h.ok(append([]string{"create", "--config", h.config, "-o", "json"}, args...)...)
+	var orders []openapi.OrderResponse
+	require.NoError(h.t, json.Unmarshal([]byte(out), &orders))
+	require.Len(h.t, orders, 1)
+	return orders[0]
+}
+
+func TestOrderctl_Lifecycle(t *testing.T) {
+	h := newHarness(t)
+
+	o := h.createJSON("--restaurant", "rest-1", "--item", "f1:Pizza:2:500", "--street", "Main", "--location", "55.76,37.62")
+	assert.Equal(t, "u1", o.UserID)
+	assert.Equal(t, int64(1000), o.TotalPrice)
+	assert.Equal(t, &openapi.GeoPoint{Lat: 55.76, Lon: 37.62}, o.Address.Location)
+
+	assert.Equal(t, "created\n", h.ok("status", "--config", h.config, o.ID))
+	assert.Contains(t, h.ok("get", "--config", h.config, o.ID), "rest-1")
+
+	out := h.ok("update", "--config", h.config, o.ID, "--fio", "Petrov", "-o", "json")
+	assert.Contains(t, out, `"fio": "Petrov"`)
+
+	assert.Equal(t, o.ID+" deleted\n", h.ok("delete", "--config", h.config, o.ID))
+
+	_, errOut, code := h.run("", "get", "--config", h.config, o.ID)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "not_found")
+}
+
+func TestOrderctl_RejectsSeveralIDs(t *testing.T) {
+	h := newHarness(t)
+	a := h.createJSON("--restaurant", "rest-1", "--item", "f1:Pizza:1:500")
+	b := h.createJSON("--restaurant", "rest-1", "--item", "f1:Pizza:1:500")
+
+	for _, args := range [][]string{
+		{"delete", "--config", h.config, a.ID, b.ID},
+		{"delete", a.ID, b.ID, "--config", h.config},
+		{"get", "--config", h.config, a.ID, "-o", "json", b.ID},
+	} {
+		_, errOut, code := h.run("", args...)
+		assert.Equal(t, 1, code, args)
+		assert.Contains(t, errOut, "expected exactly one ID", args)
+	}
+	assert.Equal(t, "created\n", h.ok("status", "--config", h.config, a.ID))
+	assert.Equal(t, "created\n", h.ok("status", "--config", h.config, b.ID))
+}
+
+func TestOrderctl_ScheduleAndCancel(t *testing.T) {
+	h := newHarness(t)
+
+	at := time.Now().Add(3 * time.Hour).UTC().Truncate(time.Second)
+	o := h.createJSON("--restaurant", "rest-1", "--item", "f1:Pizza:1:500", "--street", "Main",
+		"--deliver-at", at.Format(time.RFC3339))
+	assert.Equal(t, openapi.OrderStatusScheduled, o.Status)
+	require.NotNil(t, o.DeliverAt)
+	assert.True(t, at.Equal(*o.DeliverAt))
+
+	out := h.ok("cancel", "--config", h.config, o.ID, "-o", "json")
+	assert.Contains(t, out, `"status": "canceled"`)
+
+	_, errOut, code := h.run("", "cancel", "--config", h.config, o.ID)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "conflict")
+}
+
+func TestOrderctl_CreateFromFileAndStdin(t *testing.T) {
+	h := newHarness(t)
+	body := `{"restaurant_id":"rest-2","items":[{"food_id":"f1","name":"Sushi","quantity":1,"price":300}],"total_price":300,"address":{"street":"Arbat"}}`
+
+	path := filepath.Join(t.TempDir(), "order.json")
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+	o := h.createJSON("--file", path)
+	assert.Equal(t, "rest-2", o.RestaurantID)
+
+	out, errOut, code := h.run(body, "create", "--config", h.config, "--file", "-", "-o", "csv")
+	require.Equal(t, 0, code, errOut)
+	assert.True(t, strings.HasPrefix(out, "ID,USER,RESTAURANT"))
+}
+
+func TestOrderctl_ListFiltersAndFormats(t *testing.T) {
+	h := newHarness(t)
+	h.ok("seed", "--config", h.config, "--seed", "7")
+	h.createJSON("--restaurant", "rest-9", "--item", "f1:Pizza:1:500")
+
+	out := h.ok("list", "--config", h.config, "--restaurant", "rest-9", "-o", "json")
+	var orders []openapi.OrderResponse
+	require.NoError(t, json.Unmarshal([]byte(out), &orders))
+	assert.Len(t, orders, 1)
+
+	out = h.ok("list", "--config", h.config, "--status", "canceled,completed", "-o", "csv")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	assert.Len(t, lines, 4, "seed 7 of the demo draws three completed orders: %s", out)
+
+	out = h.ok("list", "--config", h.config)
+	assert.Len(t, strings.Split(strings.TrimSpace(out), "\n"), 12)
+
+	_, _, code := h.run("", "list", "--config", h.config, "-o", "yaml")
+	assert.Equal(t, 1, code)
+}
+
+func TestOrderctl_Watch(t *testing.T) {
+	h := newHarness(t)
+	o := h.createJSON("--restaurant", "rest-1", "--item", "f1:Pizza:1:500")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_, _, _ = h.run("", "delete", "--config", h.config, o.ID)
+	}()
+	out := h.ok("watch", "--config", h.config, "--interval", "10ms", o.ID)
+	assert.Contains(t, out, o.ID+" created")
+	assert.Contains(t, out, o.ID+" deleted")
+}
+
+func TestOrderctl_Profiles(t *testing.T) {
+	h := newHarness(t)
+	h.ok("profile", "save", "--config", h.config, "--url", "http://example.invalid", "--bypass", "prod")
+	h.ok("profile", "use", "--config", h.config, "prod")
+	out := h.ok("profile", "list", "--config", h.config)
+	assert.Contains(t, out, "* prod")
+
+	_, errOut, code := h.run("", "status", "--config", h.config, "--profile", "missing", "x")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, `profile "missing" not found`)
+}
+
+func TestOrderctl_Replay(t *testing.T) {
+	h := newHarness(t)
+	h.ok("seed", "--config", h.config)
+	h.createJSON("--restaurant", "rest-9", "--item", "f1:Pizza:1:500")
+
+	out, errOut, code := h.run("", "replay", "start", "--config", h.config, "--restaurant", "rest-9", "--wait", "--interval", "5ms", "-o", "json")
+	require.Equal(t, 0, code, errOut)
+	var jobs []openapi.ReplayJob
+	require.NoError(t, json.Unmarshal([]byte(out), &jobs))
+	require.Len(t, jobs, 1)
+	done := jobs[0]
+	assert.Equal(t, openapi.ReplayJobStateDone, done.State)
+	assert.Equal(t, 1, done.Total)
+	assert.Equal(t, 1, done.Published)
+	assert.Contains(t, errOut, done.ID+" done 1/1")
+
+	// A slow replay of every order is still running when canceled.
+	out = h.ok("replay", "start", "--config", h.config, "--rate", "1", "-o", "csv")
+	slow := strings.Split(strings.Split(out, "\n")[1], ",")[0]
+	_, errOut, code = h.run("", "replay", "start", "--config", h.config)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "conflict")
+	assert.Contains(t, h.ok("replay", "cancel", "--config", h.config, slow), "canceled")
+
+	out = h.ok("replay", "list", "--config", h.config)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[1], slow), "newest first")
+	assert.Contains(t, h.ok("replay", "get", "--config", h.config, done.ID), "done")
+
+	_, errOut, code = h.run("", "replay", "get", "--config", h.config, "missing")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "not_found")
+}
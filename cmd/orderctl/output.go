@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nikolaev/service-order/pkg/api/openapi"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+var orderColumns = []string{"ID", "USER", "RESTAURANT", "STATUS", "ITEMS", "TOTAL", "CREATED"}
+
+func orderRow(o openapi.OrderResponse) []string {
+	return []string{
+		o.ID,
+		o.UserID,
+		o.RestaurantID,
+		string(o.Status),
+		strconv.Itoa(len(o.Items)),
+		strconv.FormatInt(o.TotalPrice, 10),
+		o.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func printOrders(w io.Writer, format string, orders []openapi.OrderResponse) error {
+	return printRows(w, format, orders, orderColumns, orderRow)
+}
+
+// printRows writes rows as indented JSON, or as a table or CSV with the
+// given columns.
+func printRows[T any](w io.Writer, format string, rows []T, columns []string, row func(T) []string) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write(columns)
+		for _, r := range rows {
+			_ = cw.Write(row(r))
+		}
+		cw.Flush()
+		return cw.Error()
+	case formatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for i, c := range columns {
+			if i > 0 {
+				fmt.Fprint(tw, "\t")
+			}
+			fmt.Fprint(tw, c)
+		}
+		fmt.Fprintln(tw)
+		for _, r := range rows {
+			for i, c := range row(r) {
+				if i > 0 {
+					fmt.Fprint(tw, "\t")
+				}
+				fmt.Fprint(tw, c)
+			}
+			fmt.Fprintln(tw)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+var replayColumns = []string{"ID", "STATE", "PUBLISHED", "TOTAL", "RATE", "STARTED", "ERROR"}
+
+func replayRow(j openapi.ReplayJob) []string {
+	return []string{
+		j.ID,
+		string(j.State),
+		strconv.Itoa(j.Published),
+		strconv.Itoa(j.Total),
+		strconv.FormatFloat(j.Rate, 'g', -1, 64),
+		j.StartedAt.UTC().Format(time.RFC3339),
+		j.Error,
+	}
+}
+
+func printReplays(w io.Writer, format string, jobs []openapi.ReplayJob) error {
+	return printRows(w, format, jobs, replayColumns, replayRow)
+}
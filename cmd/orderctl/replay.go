@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nikolaev/service-order/pkg/api/openapi"
+	"github.com/nikolaev/service-order/pkg/client"
+)
+
+func runReplay(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected start, list, get or cancel")
+	}
+	switch args[0] {
+	case "start":
+		return runReplayStart(ctx, e, args[1:])
+	case "list":
+		return runReplayList(ctx, e, args[1:])
+	case "get", "cancel":
+		return runReplayJob(ctx, e, args[0], args[1:])
+	default:
+		return fmt.Errorf("unknown replay command %q", args[0])
+	}
+}
+
+func runReplayStart(ctx context.Context, e *env, args []string) error {
+	var (
+		conn       connFlags
+		from, to   string
+		status     string
+		restaurant string
+		rate       float64
+		wait       bool
+		interval   time.Duration
+		out        string
+	)
+	fs := newFlagSet(e, "replay start")
+	conn.register(fs)
+	fs.StringVar(&from, "from", "", "only orders created at or after this RFC3339 time")
+	fs.StringVar(&to, "to", "", "only orders created before this RFC3339 time")
+	fs.StringVar(&status, "status", "", "only orders in these statuses (comma-separated)")
+	fs.StringVar(&restaurant, "restaurant", "", "only orders of this restaurant")
+	fs.Float64Var(&rate, "rate", 0, "events per second, 0 uses the service default")
+	fs.BoolVar(&wait, "wait", false, "print progress until the replay finishes")
+	fs.DurationVar(&interval, "interval", time.Second, "progress poll interval with --wait")
+	fs.StringVar(&out, "o", formatTable, "output format: table, json, csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var req openapi.ReplayRequest
+	for name, v := range map[string]string{"from": from, "to": to} {
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fmt.Errorf("--%s: %w", name, err)
+		}
+		if name == "from" {
+			req.From = &t
+		} else {
+			req.To = &t
+		}
+	}
+	var statuses []openapi.OrderStatus
+	for _, s := range strings.Split(status, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			statuses = append(statuses, openapi.OrderStatus(s))
+		}
+	}
+	if len(statuses) > 0 {
+		req.Statuses = &statuses
+	}
+	if restaurant != "" {
+		req.RestaurantID = &restaurant
+	}
+	if rate > 0 {
+		req.Rate = &rate
+	}
+
+	c, err := conn.client()
+	if err != nil {
+		return err
+	}
+	job, err := c.StartReplay(ctx, req)
+	if err != nil {
+		return err
+	}
+	if wait {
+		if job, err = waitReplay(ctx, e, c, job, interval); err != nil {
+			return err
+		}
+	}
+	return printReplays(e.stdout, out, []openapi.ReplayJob{*job})
+}
+
+// waitReplay polls the job and reports progress on stderr, so that stdout
+// keeps only the final result.
+func waitReplay(ctx context.Context, e *env, c *client.Client, job *openapi.ReplayJob, interval time.Duration) (*openapi.ReplayJob, error) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	last := -1
+	for {
+		if job.Published != last {
+			fmt.Fprintf(e.stderr, "%s %s %d/%d\n", job.ID, job.State, job.Published, job.Total)
+			last = job.Published
+		}
+		if job.State != openapi.ReplayJobStateRunning {
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		var err error
+		if job, err = c.GetReplay(ctx, job.ID); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func runReplayList(ctx context.Context, e *env, args []string) error {
+	var (
+		conn connFlags
+		out  string
+	)
+	fs := newFlagSet(e, "replay list")
+	conn.register(fs)
+	fs.StringVar(&out, "o", formatTable, "output format: table, json, csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c, err := conn.client()
+	if err != nil {
+		return err
+	}
+	jobs, err := c.ListReplays(ctx)
+	if err != nil {
+		return err
+	}
+	return printReplays(e.stdout, out, jobs)
+}
+
+func runReplayJob(ctx context.Context, e *env, name string, args []string) error {
+	var (
+		conn connFlags
+		out  string
+	)
+	id, err := parseIDCommand(e, "replay "+name, args, func(fs *flag.FlagSet) {
+		conn.register(fs)
+		fs.StringVar(&out, "o", formatTable, "output format: table, json, csv")
+	})
+	if err != nil {
+		return err
+	}
+	c, err := conn.client()
+	if err != nil {
+		return err
+	}
+	get := c.GetReplay
+	if name == "cancel" {
+		get = c.CancelReplay
+	}
+	job, err := get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return printReplays(e.stdout, out, []openapi.ReplayJob{*job})
+}
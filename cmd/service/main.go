@@ -2,100 +2,560 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/dig"
+	"google.golang.org/grpc"
 
+	"github.com/nikolaev/service-order/internal/clock"
+	"github.com/nikolaev/service-order/internal/config"
+	"github.com/nikolaev/service-order/internal/eta"
+	"github.com/nikolaev/service-order/internal/gateway/broadcast"
 	"github.com/nikolaev/service-order/internal/gateway/kafka"
+	"github.com/nikolaev/service-order/internal/gateway/kafka/memkafka"
+	"github.com/nikolaev/service-order/internal/gateway/outbox"
+	"github.com/nikolaev/service-order/internal/gateway/webhook"
+	"github.com/nikolaev/service-order/internal/geo"
 	"github.com/nikolaev/service-order/internal/handlers"
+	"github.com/nikolaev/service-order/internal/handlers/grpcapi"
+	"github.com/nikolaev/service-order/internal/handlers/validation"
+	"github.com/nikolaev/service-order/internal/health"
+	"github.com/nikolaev/service-order/internal/hours"
+	"github.com/nikolaev/service-order/internal/lifecycle"
+	"github.com/nikolaev/service-order/internal/logging"
+	"github.com/nikolaev/service-order/internal/metrics"
 	repo "github.com/nikolaev/service-order/internal/repository/order"
+	"github.com/nikolaev/service-order/internal/simulation"
+	"github.com/nikolaev/service-order/internal/tracing"
 	seed "github.com/nikolaev/service-order/internal/usecase/debug/seed"
 	ucase "github.com/nikolaev/service-order/internal/usecase/order"
+	"github.com/nikolaev/service-order/internal/usecase/replay"
+	"github.com/nikolaev/service-order/internal/worker"
 )
 
-type sysClock struct{}
+func main() {
+	cfg, printOnly, err := config.Load(os.Args[1:], os.Getenv)
+	if err != nil {
+		slog.Error("load config", "error", err)
+		os.Exit(1)
+	}
+	if printOnly {
+		fmt.Print(cfg)
+		return
+	}
 
-func (sysClock) Now() time.Time { return time.Now().UTC() }
+	logger, err := newLogger(cfg.Log)
+	if err != nil {
+		slog.Error("init logger", "error", err)
+		os.Exit(1)
+	}
+	// Also routes the standard log package (http.Server, grpc) through slog.
+	slog.SetDefault(logger)
+	slog.Info("effective config", "config", cfg.String())
 
-func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, newContainer(*cfg)); err != nil {
+		// RootCause drops dig's chain of providers, e.g. leaves only the
+		// invalid Kafka setting that prevented the start.
+		slog.Error("service failed", "error", dig.RootCause(err))
+		os.Exit(1)
+	}
+	slog.Info("service stopped")
+}
+
+func newLogger(cfg config.Log) (*slog.Logger, error) {
+	level, err := logging.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	return logging.New(os.Stderr, cfg.Format, level)
+}
+
+// newContainer wires the dependency graph. Components with background work
+// register start/stop hooks in *lifecycle.Lifecycle and their probes in
+// *health.Health from their providers.
+func newContainer(cfg config.Config) *dig.Container {
 	c := dig.New()
 
-	_ = c.Provide(provideInMemory)
+	_ = c.Provide(func() config.Config { return cfg })
+	_ = c.Provide(lifecycle.New)
+	_ = c.Provide(provideHealth)
+	_ = c.Provide(metrics.New)
+	_ = c.Provide(provideTracerProvider)
+	_ = c.Provide(provideVirtualClock)
+	_ = c.Provide(provideClock)
+	_ = c.Provide(provideSimulator)
+	_ = c.Provide(provideEventSourced)
+	_ = c.Provide(provideStore)
 	_ = c.Provide(provideRepo)
+	_ = c.Provide(provideHub)
+	_ = c.Provide(provideMemoryBroker)
+	_ = c.Provide(provideWebhooks)
 	_ = c.Provide(provideProducer)
+	_ = c.Provide(provideOutbox)
+	_ = c.Provide(provideGeocoder)
+	_ = c.Provide(provideZones)
+	_ = c.Provide(provideHours)
+	_ = c.Provide(provideEstimator)
 	_ = c.Provide(provideService)
+	_ = c.Provide(provideWorker)
+	_ = c.Provide(provideETAWorker)
 	_ = c.Provide(provideSeeder)
+	_ = c.Provide(provideReplay)
 	_ = c.Provide(provideOrderHandler)
 	_ = c.Provide(provideRouter)
+	_ = c.Provide(provideHTTPServer)
+	_ = c.Provide(provideGRPCServer)
 
-	err := c.Invoke(func(r *chi.Mux, h *handlers.OrderHandler, mem *repo.InMemory, prod ucase.Producer) error {
-		ctx, cancel := context.WithCancel(context.Background())
-		go func() {
-			defer cancel()
-			runStatusWorker(ctx, mem, prod)
-		}()
-
-		r.Mount("/public/api/v1", h.Routes())
+	return c
+}
 
-		log.Println("service started on :8080")
+// run starts every component and blocks until ctx is done, then stops them
+// in reverse order: readiness flips to false first, then the servers, the
+// worker, the outbox and finally the producers, so that no stored change
+// loses its event.
+func run(ctx context.Context, c *dig.Container) error {
+	return c.Invoke(func(cfg config.Config, lc *lifecycle.Lifecycle, hc *health.Health, _ *worker.StatusWorker, _ *worker.ETAWorker, _ *grpc.Server, _ *http.Server) error {
+		lc.Append(lifecycle.Hook{Name: "readiness", OnStop: func(context.Context) error {
+			hc.SetShuttingDown()
+			return nil
+		}})
+		return lc.Run(ctx, cfg.Shutdown.Timeout)
+	})
+}
 
-		return http.ListenAndServe(":8080", r)
+// provideTracerProvider installs the otel globals; on stop it flushes spans.
+func provideTracerProvider(cfg config.Config, lc *lifecycle.Lifecycle) (trace.TracerProvider, error) {
+	tp, err := tracing.New(context.Background(), tracing.Config{
+		Exporter:     cfg.Tracing.Exporter,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	lc.Append(lifecycle.Hook{Name: "tracer provider", OnStop: tp.Shutdown})
+	return tp, nil
+}
+
+func provideHealth(cfg config.Config) *health.Health {
+	return health.New(cfg.Health.CheckTimeout)
+}
+
+// provideVirtualClock returns nil unless clock.virtual is set.
+func provideVirtualClock(cfg config.Config) *clock.Virtual {
+	if !cfg.Clock.Virtual {
+		return nil
+	}
+	return clock.NewVirtual(cfg.Clock.StartTime(), cfg.Clock.Frozen)
+}
+
+// provideClock is the time of every order, timer and event.
+func provideClock(vc *clock.Virtual) clock.Clock {
+	if vc == nil {
+		return clock.System{}
+	}
+	return vc
+}
+
+// provideSeeder creates the demo orders through the use case and publishes
+// their status moves through the outbox, like the worker does.
+func provideSeeder(cfg config.Config, svc ucase.Service, r ucase.Repository, ob *outbox.Outbox, clk clock.Clock) (seed.Service, error) {
+	scenarios, err := seed.LoadDir(cfg.Seed.ScenariosDir)
+	if err != nil {
+		return nil, fmt.Errorf("seed.scenarios_dir: %w", err)
 	}
+	return seed.New(svc, r, ob, clk, seed.Options{Scenarios: scenarios, Default: cfg.Seed.Scenario, Count: cfg.Seed.Count})
+}
+
+// provideReplay publishes snapshots through the outbox, so that they keep the
+// order of live events. Its hook comes after the outbox's and thus stops first.
+func provideReplay(cfg config.Config, lc *lifecycle.Lifecycle, r ucase.Repository, ob *outbox.Outbox, clk clock.Clock) replay.Service {
+	rp := replay.NewWithLimits(r, ob, clk, replay.Limits{
+		Rate:    float64(cfg.Replay.Rate),
+		MaxRate: float64(cfg.Replay.MaxRate),
+	})
+	lc.Append(lifecycle.Hook{Name: "replay", OnStop: rp.Stop})
+	return rp
+}
+
+func provideOrderHandler(cfg config.Config, svc ucase.Service, dbg seed.Service, rp replay.Service, wh *webhook.Dispatcher) *handlers.OrderHandler {
+	return handlers.NewOrderHandler(svc, dbg).WithReplay(rp).WithWebhooks(wh).WithAdminToken(cfg.HTTP.AdminToken)
 }
 
-func provideSeeder(mem *repo.InMemory) seed.Service {
-	return seed.New(mem, sysClock{})
+// provideWebhooks is fed by the producer, behind the outbox; its hook comes
+// first and thus stops after the outbox has drained. It keeps the wall
+// clock even when the virtual one is on: retries wait in real time.
+func provideWebhooks(cfg config.Config, lc *lifecycle.Lifecycle) *webhook.Dispatcher {
+	wh := webhook.NewDispatcher(clock.System{}, webhook.Options{
+		Timeout:     cfg.Webhook.Timeout,
+		MaxAttempts: cfg.Webhook.MaxAttempts,
+		Backoff:     cfg.Webhook.Backoff,
+		MaxBackoff:  cfg.Webhook.MaxBackoff,
+		Workers:     cfg.Webhook.Workers,
+		LogSize:     cfg.Webhook.LogSize,
+		MaxPending:  cfg.Webhook.MaxPending,
+
+		AllowedHosts: cfg.Webhook.AllowedHosts,
+		AllowPrivate: cfg.Webhook.AllowPrivate,
+	})
+	lc.Append(lifecycle.Hook{Name: "webhooks", OnStart: wh.Start, OnStop: wh.Stop})
+	return wh
 }
 
-func provideOrderHandler(svc ucase.Service, dbg seed.Service) *handlers.OrderHandler {
-	return handlers.NewOrderHandler(svc, dbg)
+func provideGRPCServer(cfg config.Config, lc *lifecycle.Lifecycle, svc ucase.Service, hub *broadcast.Hub) *grpc.Server {
+	gs := grpcapi.NewServer(grpcapi.NewOrderServer(svc, hub))
+	lc.Append(lifecycle.Hook{
+		Name: "grpc server",
+		OnStart: func(context.Context) error {
+			lis, err := net.Listen("tcp", cfg.GRPC.Addr)
+			if err != nil {
+				return err
+			}
+			slog.Info("grpc started", "addr", cfg.GRPC.Addr)
+			go func() {
+				if err := gs.Serve(lis); err != nil {
+					slog.Error("grpc server stopped", "error", err)
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			// WatchOrder streams may outlive the timeout; cut them off then.
+			done := make(chan struct{})
+			go func() {
+				gs.GracefulStop()
+				close(done)
+			}()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				gs.Stop()
+				return ctx.Err()
+			}
+		},
+	})
+	return gs
 }
 
-func provideRouter() *chi.Mux {
+// provideRouter builds the root router. http.openapi_validation selects spec
+// validation: off, request (default), log or strict.
+func provideRouter(cfg config.Config, m *metrics.Metrics, tp trace.TracerProvider) (*chi.Mux, error) {
+	mode, err := validation.ParseMode(cfg.HTTP.OpenAPIValidation)
+	if err != nil {
+		return nil, err
+	}
+	validate, err := validation.Middleware(mode)
+	if err != nil {
+		return nil, err
+	}
+
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
-	return r
-}
-
-func runStatusWorker(ctx context.Context, mem *repo.InMemory, prod ucase.Producer) {
-	ticker := time.NewTicker(500 * time.Millisecond)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case now := <-ticker.C:
-			changed := mem.AdvanceStatuses(now)
-			for _, o := range changed {
-				_ = prod.OrderUpdated(ctx, o)
+	r.Use(tracing.HTTPMiddleware(tp))
+	r.Use(m.HTTPMiddleware)
+	r.Use(logging.HTTPMiddleware)
+	r.Use(validate)
+	return r, nil
+}
+
+// provideHTTPServer serves the router, the probes and /metrics; on stop it
+// waits for in-flight requests via http.Server.Shutdown.
+func provideHTTPServer(cfg config.Config, lc *lifecycle.Lifecycle, hc *health.Health, m *metrics.Metrics, r *chi.Mux, h *handlers.OrderHandler, mb *memkafka.Broker, es *repo.EventSourced, sim *simulation.Simulator, vc *clock.Virtual) *http.Server {
+	r.Method(http.MethodGet, "/healthz", hc.LivenessHandler())
+	r.Method(http.MethodGet, "/readyz", hc.ReadinessHandler())
+	r.Method(http.MethodGet, "/metrics", m.Handler())
+	r.Mount("/public/api/v1", h.Routes())
+	if cfg.Simulation.Debug {
+		r.Mount("/debug/simulation", h.AdminOnly(sim.Handler()))
+	}
+	if mb != nil {
+		r.Mount("/debug/kafka", mb.Handler())
+	}
+	if es != nil {
+		r.Mount("/debug/history", h.AdminOnly(es.Handler()))
+	}
+	if vc != nil {
+		r.Mount("/debug/clock", vc.Handler())
+	}
+	srv := &http.Server{Addr: cfg.HTTP.Addr, Handler: r}
+	lc.Append(lifecycle.Hook{
+		Name: "http server",
+		OnStart: func(context.Context) error {
+			lis, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
 			}
-		}
+			slog.Info("service started", "addr", srv.Addr)
+			go func() {
+				if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					slog.Error("http server stopped", "error", err)
+				}
+			}()
+			return nil
+		},
+		OnStop: srv.Shutdown,
+	})
+	return srv
+}
+
+// provideWorker reads the statuses off clk. A move of the virtual clock
+// runs a tick before the move returns, so every change it made due is
+// already in the outbox by then.
+func provideWorker(cfg config.Config, lc *lifecycle.Lifecycle, hc *health.Health, m *metrics.Metrics, tp trace.TracerProvider, st repo.Store, ob *outbox.Outbox, clk clock.Clock, vc *clock.Virtual) *worker.StatusWorker {
+	w := worker.NewStatusWorkerWithDeps(cfg.Worker.Tick, st, ob, m, tp).WithClock(clk)
+	if vc != nil {
+		vc.OnMove(func(time.Time) { w.CatchUp(context.Background()) })
 	}
+	lc.Append(lifecycle.Hook{Name: "status worker", OnStart: w.Start, OnStop: w.Stop})
+	hc.AddLiveness("status_worker", w.CheckHeartbeat(cfg.Health.WorkerMaxAge))
+	return w
 }
 
-func provideInMemory() *repo.InMemory { return repo.NewInMemory() }
+// provideGeocoder returns nil with geo.geocoder none.
+func provideGeocoder(cfg config.Config) (ucase.Geocoder, error) {
+	switch {
+	case cfg.Geo.Geocoder != geo.GeocoderFixture:
+		return nil, nil
+	case cfg.Geo.Fixture == "":
+		return geo.DefaultFixture(), nil
+	}
+	f, err := geo.LoadFixture(cfg.Geo.Fixture)
+	if err != nil {
+		return nil, fmt.Errorf("geo.fixture: %w", err)
+	}
+	return f, nil
+}
+
+// provideZones returns nil unless geo.zones is set.
+func provideZones(cfg config.Config) (*geo.Zones, error) {
+	if cfg.Geo.Zones == "" {
+		return nil, nil
+	}
+	z, err := geo.LoadZones(cfg.Geo.Zones)
+	if err != nil {
+		return nil, fmt.Errorf("geo.zones: %w", err)
+	}
+	return z, nil
+}
+
+// provideHours returns nil when no restaurant has opening hours.
+func provideHours(cfg config.Config) (*hours.Hours, error) {
+	oh := cfg.OpeningHours
+	if oh.Default == "" && len(oh.Restaurants) == 0 {
+		return nil, nil
+	}
+	h, err := oh.Hours()
+	if err != nil {
+		return nil, fmt.Errorf("opening_hours: %w", err)
+	}
+	return h, nil
+}
+
+// provideEstimator returns nil unless eta.enabled. The restaurant points of
+// geo.zones give the distances of the rides.
+func provideEstimator(cfg config.Config, z *geo.Zones) (*eta.Estimator, error) {
+	if !cfg.ETA.Enabled {
+		return nil, nil
+	}
+	if z == nil {
+		return cfg.ETA.Estimator(nil)
+	}
+	return cfg.ETA.Estimator(z)
+}
+
+// provideETAWorker returns nil unless eta.enabled. Like the status worker
+// it refreshes the estimates before a move of the virtual clock returns.
+func provideETAWorker(cfg config.Config, lc *lifecycle.Lifecycle, st repo.Store, ob *outbox.Outbox, est *eta.Estimator, clk clock.Clock, vc *clock.Virtual) *worker.ETAWorker {
+	if est == nil {
+		return nil
+	}
+	w := worker.NewETAWorker(cfg.ETA.Tick, cfg.ETA.MinChange, st, ob, est).WithClock(clk)
+	if vc != nil {
+		vc.OnMove(func(time.Time) { w.Refresh(context.Background()) })
+	}
+	lc.Append(lifecycle.Hook{Name: "eta worker", OnStart: w.Start, OnStop: w.Stop})
+	return w
+}
+
+// provideSimulator schedules the status changes of either store with the
+// profile of simulation.profile.
+func provideSimulator(cfg config.Config) (*simulation.Simulator, error) {
+	return simulation.New(cfg.Profiles(), cfg.Simulation.Profile, cfg.Simulation.TimeScale)
+}
+
+// provideEventSourced returns nil unless repository.kind is eventsourced.
+func provideEventSourced(cfg config.Config, sim *simulation.Simulator) *repo.EventSourced {
+	if cfg.Repository.Kind != repo.KindEventSourced {
+		return nil
+	}
+	return repo.NewEventSourced(sim, cfg.Repository.SnapshotEvery)
+}
+
+// provideStore returns the event-sourced store when it is selected and the
+// in-memory one otherwise.
+func provideStore(hc *health.Health, es *repo.EventSourced, sim *simulation.Simulator) repo.Store {
+	var st repo.Store = es
+	if es == nil {
+		st = repo.NewInMemoryWithSchedule(sim)
+	}
+	hc.AddReadiness("repository", st.Ping)
+	return st
+}
+
+func provideRepo(st repo.Store, tp trace.TracerProvider) ucase.Repository {
+	return repo.NewTraced(st, tp)
+}
+
+func provideHub() *broadcast.Hub { return broadcast.NewHub() }
+
+// provideMemoryBroker returns the in-process broker of kafka.in_memory, nil
+// when Kafka is external or off.
+func provideMemoryBroker(cfg config.Config) *memkafka.Broker {
+	if !cfg.Kafka.InMemory {
+		return nil
+	}
+	return memkafka.NewBroker(int32(cfg.Kafka.Topics.Partitions))
+}
 
-func provideRepo(mem *repo.InMemory) ucase.Repository { return mem }
+// provideProducer publishes every event to Kafka (or noop), to the
+// in-process hub that feeds gRPC WatchOrder streams, to the status
+// metrics tracker and to the webhook subscriptions.
+func provideProducer(cfg config.Config, lc *lifecycle.Lifecycle, hc *health.Health, m *metrics.Metrics, tp trace.TracerProvider, hub *broadcast.Hub, mb *memkafka.Broker, wh *webhook.Dispatcher, clk clock.Clock) (ucase.Producer, error) {
+	kp, err := provideKafkaProducer(cfg.Kafka, lc, hc, m, tp, mb, clk)
+	if err != nil {
+		return nil, err
+	}
+	return broadcast.Multi{
+		m.InstrumentProducer("kafka", kp),
+		hub,
+		m.StatusTracker().WithClock(clk),
+		m.InstrumentProducer("webhook", wh),
+	}, nil
+}
+
+// provideKafkaProducer fails on invalid settings (TLS, SASL, topics), but
+// falls back to the noop producer when the brokers are unreachable. With
+// kafka.in_memory the sync producer writes to the in-process broker.
+func provideKafkaProducer(cfg config.Kafka, lc *lifecycle.Lifecycle, hc *health.Health, m *metrics.Metrics, tp trace.TracerProvider, mb *memkafka.Broker, clk clock.Clock) (ucase.Producer, error) {
+	if len(cfg.Brokers) == 0 && mb == nil {
+		return kafka.NoopProducer{}, nil
+	}
+	kc := kafka.Config{
+		Brokers:         cfg.Brokers,
+		Topic:           cfg.Topic,
+		Routing:         kafka.Routing{Mode: cfg.Routing.Mode, Topics: cfg.Routing.Topics()},
+		RetryMax:        cfg.RetryMax,
+		Compression:     cfg.Compression,
+		Buffer:          cfg.Buffer,
+		BatchSize:       cfg.BatchSize,
+		Linger:          cfg.Linger,
+		DeadLetterTopic: cfg.DeadLetterTopic,
+		TLS: kafka.TLS{
+			Enabled:            cfg.TLS.Enabled,
+			CAFile:             cfg.TLS.CAFile,
+			CertFile:           cfg.TLS.CertFile,
+			KeyFile:            cfg.TLS.KeyFile,
+			InsecureSkipVerify: cfg.TLS.InsecureSkipVerify,
+		},
+		SASL: kafka.SASL{Mechanism: cfg.SASL.Mechanism, Username: cfg.SASL.Username, Password: cfg.SASL.Password},
+		Topics: kafka.Topics{
+			Mode:              cfg.Topics.Mode,
+			Partitions:        int32(cfg.Topics.Partitions),
+			ReplicationFactor: int16(cfg.Topics.ReplicationFactor),
+			Retention:         cfg.Topics.Retention,
+		},
+		TracerProvider: tp,
+		Clock:          clk,
+	}
+	topics := kc.RoutedTopics()
+	if mb != nil {
+		// Created up front, so that /debug/kafka lists them before the first event.
+		for _, name := range topics {
+			_ = mb.CreateTopic(name, kc.Topics.Partitions)
+		}
+		p := kafka.NewSaramaProducerFromSync(mb.SyncProducer(), kc)
+		appendCloser(lc, "kafka producer", p)
+		return p, nil
+	}
+	if cfg.Mode == kafka.ModeAsync && cfg.DeadLetterTopic != "" {
+		topics = append(topics, cfg.DeadLetterTopic)
+	}
+	if err := kafka.SetupTopics(kc, topics...); err != nil {
+		return nil, fmt.Errorf("kafka topics (kafka.topics.mode=%s): %w", cfg.Topics.Mode, err)
+	}
 
-func provideProducer() ucase.Producer {
-	if os.Getenv("KAFKA_BROKERS") != "" {
-		p, err := kafka.NewSaramaProducer()
-		if err == nil {
-			return p
+	var (
+		p interface {
+			ucase.Producer
+			io.Closer
+			Ping(context.Context) error
 		}
-		log.Printf("failed to init sarama producer, fallback to noop: %v", err)
+		err error
+	)
+	if cfg.Mode == kafka.ModeAsync {
+		var ap *kafka.AsyncProducer
+		if ap, err = kafka.NewAsyncProducer(kc, m); err == nil {
+			m.Gauge("kafka_in_flight_messages", "Messages queued in the async Kafka producer or awaiting acknowledgement.",
+				func() float64 { return float64(ap.InFlight()) })
+			p = ap
+		}
+	} else {
+		p, err = kafka.NewSaramaProducer(kc)
+	}
+	switch {
+	case errors.Is(err, kafka.ErrInvalidConfig):
+		return nil, err
+	case err != nil:
+		slog.Error("failed to init sarama producer, fallback to noop", "mode", cfg.Mode, "error", err)
+		return kafka.NoopProducer{}, nil
 	}
-	return kafka.NoopProducer{}
+	appendCloser(lc, "kafka producer", p)
+	hc.AddReadiness("kafka", p.Ping)
+	return p, nil
+}
+
+// appendCloser registers c.Close as a stop hook; Close flushes buffered
+// messages for producers that have them.
+func appendCloser(lc *lifecycle.Lifecycle, name string, c io.Closer) {
+	lc.Append(lifecycle.Hook{Name: name, OnStop: func(context.Context) error { return c.Close() }})
+}
+
+// provideOutbox queues events between the usecase and the producers.
+func provideOutbox(cfg config.Config, lc *lifecycle.Lifecycle, hc *health.Health, m *metrics.Metrics, p ucase.Producer) *outbox.Outbox {
+	ob := outbox.New(p, cfg.Outbox.Buffer)
+	lc.Append(lifecycle.Hook{Name: "outbox", OnStart: ob.Start, OnStop: ob.Stop})
+	hc.AddReadiness("outbox", ob.CheckLag(cfg.Health.OutboxMaxLag))
+	m.Gauge("outbox_pending_events", "Events waiting in the outbox.", func() float64 { return float64(ob.Pending()) })
+	m.Gauge("outbox_lag_seconds", "Age of the oldest undelivered outbox event.", func() float64 { return ob.Lag().Seconds() })
+	return ob
 }
 
-func provideService(r ucase.Repository, p ucase.Producer) ucase.Service {
-	return ucase.New(r, p)
+func provideService(cfg config.Config, r ucase.Repository, ob *outbox.Outbox, m *metrics.Metrics, tp trace.TracerProvider, clk clock.Clock, est *eta.Estimator, gc ucase.Geocoder, z *geo.Zones, h *hours.Hours) ucase.Service {
+	opts := []ucase.Option{ucase.WithScheduling(ucase.Scheduling(cfg.Scheduling))}
+	if est != nil {
+		opts = append(opts, ucase.WithEstimator(est))
+	}
+	if gc != nil {
+		opts = append(opts, ucase.WithGeocoder(gc))
+	}
+	if z != nil {
+		opts = append(opts, ucase.WithZones(z))
+	}
+	if h != nil {
+		opts = append(opts, ucase.WithHours(h))
+	}
+	return ucase.NewTraced(ucase.NewWithDeps(r, ob, clk, logging.Logger{}, m, opts...), tp)
 }
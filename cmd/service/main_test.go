@@ -0,0 +1,902 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nikolaev/service-order/internal/clock"
+	"github.com/nikolaev/service-order/internal/config"
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/gateway/kafka"
+	"github.com/nikolaev/service-order/internal/gateway/kafka/memkafka"
+	"github.com/nikolaev/service-order/internal/gateway/webhook"
+	"github.com/nikolaev/service-order/internal/geo"
+	"github.com/nikolaev/service-order/internal/handlers"
+	"github.com/nikolaev/service-order/internal/health"
+	"github.com/nikolaev/service-order/internal/logging"
+	repo "github.com/nikolaev/service-order/internal/repository/order"
+	ucase "github.com/nikolaev/service-order/internal/usecase/order"
+	"github.com/nikolaev/service-order/pkg/api/openapi"
+	"github.com/nikolaev/service-order/pkg/client"
+)
+
+// recordingProducer stands in for Kafka: it is slow, so events pile up in
+// the outbox and have to be drained on shutdown.
+type recordingProducer struct {
+	mu       sync.Mutex
+	created  map[string]int
+	statuses map[string]map[entity.OrderStatus]bool
+	traceIDs map[string]string // by "id/status"
+}
+
+func newRecordingProducer() *recordingProducer {
+	return &recordingProducer{
+		created:  map[string]int{},
+		statuses: map[string]map[entity.OrderStatus]bool{},
+		traceIDs: map[string]string{},
+	}
+}
+
+func (p *recordingProducer) record(o *entity.Order) {
+	time.Sleep(200 * time.Microsecond)
+	if p.statuses[o.ID] == nil {
+		p.statuses[o.ID] = map[entity.OrderStatus]bool{}
+	}
+	p.statuses[o.ID][o.Status] = true
+}
+
+func (p *recordingProducer) OrderCreated(_ context.Context, o *entity.Order) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.created[o.ID]++
+	p.record(o)
+	return nil
+}
+
+func (p *recordingProducer) OrderUpdated(_ context.Context, o *entity.Order) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.record(o)
+	return nil
+}
+
+func (p *recordingProducer) OrderStatusChanged(ctx context.Context, o *entity.Order) error {
+	return p.OrderUpdated(ctx, o)
+}
+
+func (p *recordingProducer) OrderDeleted(context.Context, string, string) error { return nil }
+
+func (p *recordingProducer) OrderSnapshot(context.Context, *entity.Order, string) error { return nil }
+func (p *recordingProducer) OrderETAChanged(context.Context, *entity.Order) error       { return nil }
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	return lis.Addr().String()
+}
+
+func testConfig(t *testing.T) config.Config {
+	t.Helper()
+	cfg := config.Default()
+	cfg.HTTP.Addr = freeAddr(t)
+	cfg.GRPC.Addr = freeAddr(t)
+	return cfg
+}
+
+func getJSON(t *testing.T, url string, v any) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	return resp.StatusCode
+}
+
+// getAdminJSON is getJSON with the admin token set.
+func getAdminJSON(t *testing.T, url, token string, v any) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set(handlers.HeaderAdminToken, token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	return resp.StatusCode
+}
+
+func TestRun_Probes(t *testing.T) {
+	cfg := testConfig(t)
+	c := newContainer(cfg)
+	var hc *health.Health
+	require.NoError(t, c.Invoke(func(h *health.Health) { hc = h }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- run(ctx, c) }()
+
+	base := "http://" + cfg.HTTP.Addr
+	var ready health.Response
+	require.Eventually(t, func() bool {
+		_, err := http.Get(base + "/readyz")
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Equal(t, http.StatusOK, getJSON(t, base+"/readyz", &ready))
+	for _, name := range []string{"repository", "outbox", "status_worker", "shutdown"} {
+		assert.Equal(t, health.StatusOK, ready.Checks[name].Status, name)
+	}
+
+	var live health.Response
+	require.Equal(t, http.StatusOK, getJSON(t, base+"/healthz", &live))
+	assert.Contains(t, live.Checks, "status_worker")
+
+	resp, err := http.Get(base + "/metrics")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Contains(t, string(body), `service_order_http_request_duration_seconds_count{code="200",method="GET",route="/readyz"}`)
+	assert.Contains(t, string(body), "service_order_outbox_pending_events 0")
+
+	cancel()
+	require.NoError(t, <-stopped)
+	assert.Equal(t, health.StatusFail, hc.Readiness(context.Background()).Checks["shutdown"].Status)
+}
+
+// syncBuffer collects log output written from several goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Split(strings.TrimSpace(b.buf.String()), "\n")
+}
+
+func TestRun_OrderLifecycleIsGreppableInLogs(t *testing.T) {
+	var out syncBuffer
+	logger, err := logging.New(&out, logging.FormatJSON, slog.LevelInfo)
+	require.NoError(t, err)
+	prev := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	cfg := testConfig(t)
+	cfg.Worker.Tick = 5 * time.Millisecond
+	cfg.StatusTimers.Created = 10 * time.Millisecond
+	c := newContainer(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- run(ctx, c) }()
+
+	cl, err := client.New("http://"+cfg.HTTP.Addr, client.WithUserID("u1"), client.WithRetry(client.NoRetry),
+		client.WithHTTPClient(requestIDDoer{id: "req-1"}))
+	require.NoError(t, err)
+	var created *openapi.OrderResponse
+	require.Eventually(t, func() bool {
+		created, err = cl.CreateOrder(context.Background(), openapi.CreateOrderRequest{
+			RestaurantID: "r1",
+			Items:        []openapi.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 100}},
+			TotalPrice:   100,
+		})
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Wait until the worker moves the order to pending.
+	require.Eventually(t, func() bool {
+		return strings.Contains(strings.Join(out.lines(), "\n"), `"status":"pending"`)
+	}, 2*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-stopped)
+
+	var msgs []string
+	for _, line := range out.lines() {
+		var rec map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &rec), line)
+		if rec["order_id"] != created.ID {
+			continue
+		}
+		msgs = append(msgs, rec["msg"].(string))
+		if rec["msg"] == "order created" || rec["msg"] == "kafka noop: order created" {
+			assert.Equal(t, "req-1", rec["request_id"], line)
+			assert.Equal(t, "u1", rec["user_id"], line)
+		}
+	}
+	assert.Subset(t, msgs, []string{
+		"order created",             // usecase
+		"kafka noop: order created", // producer, via the outbox
+		"order status advanced",     // worker
+		"kafka noop: order status changed",
+	})
+}
+
+type requestIDDoer struct{ id string }
+
+func (d requestIDDoer) Do(r *http.Request) (*http.Response, error) {
+	r.Header.Set(logging.HeaderRequestID, d.id)
+	return http.DefaultClient.Do(r)
+}
+
+func TestRun_TracesRequestIntoEventsAndWorker(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+
+	cfg := testConfig(t)
+	cfg.Worker.Tick = 5 * time.Millisecond
+	cfg.StatusTimers.Created = 10 * time.Millisecond
+	c := newContainer(cfg)
+	require.NoError(t, c.Decorate(func(trace.TracerProvider) trace.TracerProvider { return tp }))
+	rec := newRecordingProducer()
+	require.NoError(t, c.Decorate(func(p ucase.Producer) ucase.Producer { return tracedProducer{rec} }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- run(ctx, c) }()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	cl, err := client.New("http://"+cfg.HTTP.Addr, client.WithUserID("u1"), client.WithRetry(client.NoRetry),
+		client.WithHTTPClient(traceparentDoer{"00-" + traceID + "-00f067aa0ba902b7-01"}))
+	require.NoError(t, err)
+	var created *openapi.OrderResponse
+	require.Eventually(t, func() bool {
+		created, err = cl.CreateOrder(context.Background(), openapi.CreateOrderRequest{
+			RestaurantID: "r1",
+			Items:        []openapi.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 100}},
+			TotalPrice:   100,
+		})
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		for _, s := range spans.Ended() {
+			if s.Name() == "StatusWorker/advance" {
+				return true
+			}
+		}
+		return false
+	}, 2*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-stopped)
+
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range spans.Ended() {
+		if _, seen := byName[s.Name()]; !seen {
+			byName[s.Name()] = s
+		}
+	}
+	httpSpan := byName["POST /public/api/v1/order"]
+	svcSpan := byName["order.Service/Create"]
+	repoSpan := byName["repo.InMemory/Create"]
+	require.NotNil(t, httpSpan)
+	require.NotNil(t, svcSpan)
+	require.NotNil(t, repoSpan)
+	for _, s := range []sdktrace.ReadOnlySpan{httpSpan, svcSpan, repoSpan} {
+		assert.Equal(t, traceID, s.SpanContext().TraceID().String(), s.Name())
+	}
+	assert.Equal(t, httpSpan.SpanContext().SpanID(), svcSpan.Parent().SpanID())
+	assert.Equal(t, svcSpan.SpanContext().SpanID(), repoSpan.Parent().SpanID())
+
+	// The event is published asynchronously through the outbox, still inside
+	// the request's trace; worker advances start traces of their own.
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	assert.Equal(t, traceID, rec.traceIDs[created.ID+"/created"])
+	worker := byName["StatusWorker/advance"]
+	assert.NotEqual(t, traceID, worker.SpanContext().TraceID().String())
+	assert.Equal(t, worker.SpanContext().TraceID().String(), rec.traceIDs[created.ID+"/pending"])
+}
+
+type traceparentDoer struct{ traceparent string }
+
+func (d traceparentDoer) Do(r *http.Request) (*http.Response, error) {
+	r.Header.Set("traceparent", d.traceparent)
+	return http.DefaultClient.Do(r)
+}
+
+// tracedProducer records the trace each event was published in.
+type tracedProducer struct{ *recordingProducer }
+
+func (p tracedProducer) note(ctx context.Context, o *entity.Order) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.traceIDs[o.ID+"/"+string(o.Status)] = trace.SpanContextFromContext(ctx).TraceID().String()
+}
+
+func (p tracedProducer) OrderCreated(ctx context.Context, o *entity.Order) error {
+	p.note(ctx, o)
+	return p.recordingProducer.OrderCreated(ctx, o)
+}
+
+func (p tracedProducer) OrderUpdated(ctx context.Context, o *entity.Order) error {
+	p.note(ctx, o)
+	return p.recordingProducer.OrderUpdated(ctx, o)
+}
+
+func (p tracedProducer) OrderStatusChanged(ctx context.Context, o *entity.Order) error {
+	p.note(ctx, o)
+	return p.recordingProducer.OrderStatusChanged(ctx, o)
+}
+
+func TestRun_ShutdownDropsNoEvents(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Worker.Tick = time.Millisecond
+	cfg.StatusTimers = config.StatusTimers{
+		Created: time.Millisecond, Pending: 2 * time.Millisecond, Confirmed: 3 * time.Millisecond,
+		Cooking: 5 * time.Millisecond, Delivering: time.Hour,
+	}
+	cfg.Outbox.Buffer = 8
+
+	c := newContainer(cfg)
+	rec := newRecordingProducer()
+	require.NoError(t, c.Decorate(func(ucase.Producer) ucase.Producer { return rec }))
+	var mem repo.Store
+	require.NoError(t, c.Invoke(func(m repo.Store) { mem = m }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- run(ctx, c) }()
+
+	cl, err := client.New("http://"+cfg.HTTP.Addr, client.WithUserID("u1"), client.WithRetry(client.NoRetry))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		_, err := cl.ListOrders(context.Background(), time.Time{})
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Keep creating orders while the shutdown starts.
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				_, err := cl.CreateOrder(context.Background(), openapi.CreateOrderRequest{
+					RestaurantID: "r1",
+					Items:        []openapi.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 100}},
+					TotalPrice:   100,
+				})
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	select {
+	case err := <-stopped:
+		require.NoError(t, err)
+	case <-time.After(cfg.Shutdown.Timeout):
+		t.Fatal("shutdown did not finish")
+	}
+
+	orders, err := mem.ListFrom(context.Background(), time.Time{})
+	require.NoError(t, err)
+	require.NotEmpty(t, orders)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	assert.Len(t, rec.created, len(orders))
+	for _, o := range orders {
+		assert.Equal(t, 1, rec.created[o.ID], "created events for %s", o.ID)
+		assert.True(t, rec.statuses[o.ID][o.Status], "order %s: last status %s was not published", o.ID, o.Status)
+	}
+}
+
+// courier is a sarama consumer group handler as a courier service would
+// write it; here it runs against the in-process broker.
+type courier struct{ created chan kafka.CreatedEvent }
+
+func (courier) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (courier) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (c courier) ConsumeClaim(s sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for m := range claim.Messages() {
+		var ev kafka.CreatedEvent
+		if err := json.Unmarshal(m.Value, &ev); err != nil {
+			return err
+		}
+		c.created <- ev
+		s.MarkMessage(m, "")
+	}
+	return nil
+}
+
+func TestRun_InMemoryKafkaFeedsConsumerInProcess(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Kafka.InMemory = true
+	cfg.Kafka.Routing.Mode = kafka.RoutingBoth
+	c := newContainer(cfg)
+	var mb *memkafka.Broker
+	require.NoError(t, c.Invoke(func(b *memkafka.Broker) { mb = b }))
+	require.NotNil(t, mb)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- run(ctx, c) }()
+
+	cg := mb.NewConsumerGroup("courier", sarama.OffsetOldest)
+	defer cg.Close()
+	cour := courier{created: make(chan kafka.CreatedEvent, 1)}
+	go func() {
+		for ctx.Err() == nil {
+			if err := cg.Consume(ctx, []string{cfg.Kafka.Routing.Created}, cour); err != nil {
+				return
+			}
+		}
+	}()
+
+	cl, err := client.New("http://"+cfg.HTTP.Addr, client.WithUserID("u1"), client.WithRetry(client.NoRetry))
+	require.NoError(t, err)
+	var created *openapi.OrderResponse
+	require.Eventually(t, func() bool {
+		created, err = cl.CreateOrder(context.Background(), openapi.CreateOrderRequest{
+			RestaurantID: "r1",
+			Items:        []openapi.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 100}},
+			TotalPrice:   100,
+		})
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	select {
+	case ev := <-cour.created:
+		assert.Equal(t, created.ID, ev.OrderID)
+		assert.Equal(t, "u1", ev.UserID)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the courier did not get the created event")
+	}
+
+	var msgs []memkafka.Message
+	require.Equal(t, http.StatusOK, getJSON(t, "http://"+cfg.HTTP.Addr+"/debug/kafka/topics/"+cfg.Kafka.Topic+"/messages", &msgs))
+	require.NotEmpty(t, msgs)
+	assert.Equal(t, created.ID, msgs[0].Key)
+
+	cancel()
+	require.NoError(t, <-stopped)
+}
+
+func TestRun_EventSourcedRepositoryKeepsHistory(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Repository.Kind = repo.KindEventSourced
+	cfg.Worker.Tick = 5 * time.Millisecond
+	cfg.HTTP.AdminToken = "admin"
+	cfg.StatusTimers.Created = time.Millisecond
+	c := newContainer(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- run(ctx, c) }()
+
+	cl, err := client.New("http://"+cfg.HTTP.Addr, client.WithUserID("u1"), client.WithRetry(client.NoRetry))
+	require.NoError(t, err)
+	var created *openapi.OrderResponse
+	require.Eventually(t, func() bool {
+		created, err = cl.CreateOrder(context.Background(), openapi.CreateOrderRequest{
+			RestaurantID: "r1",
+			Items:        []openapi.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 100}},
+			TotalPrice:   100,
+		})
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The worker advances the order through the event store.
+	require.Eventually(t, func() bool {
+		st, err := cl.GetOrderStatus(context.Background(), created.ID)
+		return err == nil && st.Status == openapi.OrderStatusPending
+	}, 2*time.Second, 10*time.Millisecond)
+
+	var denied map[string]any
+	require.Equal(t, http.StatusUnauthorized, getJSON(t, "http://"+cfg.HTTP.Addr+"/debug/history/orders/"+created.ID+"/events", &denied))
+
+	var evs []repo.EventView
+	require.Equal(t, http.StatusOK, getAdminJSON(t, "http://"+cfg.HTTP.Addr+"/debug/history/orders/"+created.ID+"/events", "admin", &evs))
+	require.Len(t, evs, 2)
+	assert.Equal(t, repo.EventCreated, evs[0].Kind)
+	assert.Equal(t, repo.EventStatusAdvanced, evs[1].Kind)
+
+	var past repo.OrderView
+	at := evs[0].At.Format(time.RFC3339Nano)
+	require.Equal(t, http.StatusOK, getAdminJSON(t, "http://"+cfg.HTTP.Addr+"/debug/history/orders/"+created.ID+"?at="+at, "admin", &past))
+	assert.Equal(t, entity.OrderStatusCreated, past.Status)
+
+	cancel()
+	require.NoError(t, <-stopped)
+}
+
+func TestRun_WebhooksReceiveSignedEvents(t *testing.T) {
+	got := make(chan webhook.Payload, 16)
+	partner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if webhook.Verify("s3cret", r.Header, body) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var p webhook.Payload
+		_ = json.Unmarshal(body, &p)
+		got <- p
+	}))
+	defer partner.Close()
+
+	cfg := testConfig(t)
+	cfg.HTTP.AdminToken = "admin"
+	cfg.Webhook.AllowPrivate = true
+	c := newContainer(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- run(ctx, c) }()
+
+	cl, err := client.New("http://"+cfg.HTTP.Addr, client.WithUserID("u1"), client.WithAdminToken("admin"), client.WithRetry(client.NoRetry))
+	require.NoError(t, err)
+	secret, restaurant := "s3cret", "r1"
+	events := []openapi.WebhookEvent{"created"}
+	sub := openapi.WebhookSubscriptionRequest{URL: partner.URL, Secret: &secret, Events: &events, RestaurantID: &restaurant}
+	require.Eventually(t, func() bool {
+		_, err := cl.CreateWebhook(context.Background(), sub)
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	for _, restaurant := range []string{"r2", "r1"} {
+		_, err := cl.CreateOrder(context.Background(), openapi.CreateOrderRequest{
+			RestaurantID: restaurant,
+			Items:        []openapi.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 100}},
+			TotalPrice:   100,
+		})
+		require.NoError(t, err)
+	}
+
+	select {
+	case p := <-got:
+		assert.Equal(t, webhook.EventCreated, p.Type)
+		assert.Equal(t, "r1", p.Order.RestaurantID)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no webhook delivered")
+	}
+
+	cancel()
+	require.NoError(t, <-stopped)
+	assert.Empty(t, got, "r2 is filtered out")
+}
+
+func TestRun_SimulationProfileSwitchesAtRuntime(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Worker.Tick = 5 * time.Millisecond
+	cfg.Simulation.Debug = true
+	cfg.HTTP.AdminToken = "admin"
+	c := newContainer(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- run(ctx, c) }()
+
+	cl, err := client.New("http://"+cfg.HTTP.Addr, client.WithUserID("u1"), client.WithRetry(client.NoRetry))
+	require.NoError(t, err)
+	var created *openapi.OrderResponse
+	require.Eventually(t, func() bool {
+		created, err = cl.CreateOrder(context.Background(), openapi.CreateOrderRequest{
+			RestaurantID: "r1",
+			Items:        []openapi.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 100}},
+			TotalPrice:   100,
+		})
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The default profile keeps it cooking for minutes; fast at a hundredth
+	// of its timers completes it in about a tenth of a second.
+	req, _ := http.NewRequest(http.MethodPut, "http://"+cfg.HTTP.Addr+"/debug/simulation", strings.NewReader(`{"profile":"fast","time_scale":0.01}`))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req, _ = http.NewRequest(http.MethodPut, "http://"+cfg.HTTP.Addr+"/debug/simulation", strings.NewReader(`{"profile":"fast","time_scale":0.01}`))
+	req.Header.Set(handlers.HeaderAdminToken, "admin")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.Eventually(t, func() bool {
+		st, err := cl.GetOrderStatus(context.Background(), created.ID)
+		return err == nil && st.Status == openapi.OrderStatusCompleted
+	}, 3*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-stopped)
+}
+
+func TestRun_VirtualClockFastForwardsOrder(t *testing.T) {
+	t0 := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	cfg := testConfig(t)
+	cfg.Kafka.InMemory = true
+	cfg.Kafka.Routing.Mode = kafka.RoutingBoth
+	cfg.Clock = config.Clock{Virtual: true, Frozen: true, Start: t0.Format(time.RFC3339)}
+	c := newContainer(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- run(ctx, c) }()
+
+	cl, err := client.New("http://"+cfg.HTTP.Addr, client.WithUserID("u1"), client.WithRetry(client.NoRetry))
+	require.NoError(t, err)
+	var created *openapi.OrderResponse
+	require.Eventually(t, func() bool {
+		created, err = cl.CreateOrder(context.Background(), openapi.CreateOrderRequest{
+			RestaurantID: "r1",
+			Items:        []openapi.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 100}},
+			TotalPrice:   100,
+		})
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, t0, created.CreatedAt.UTC(), "the frozen clock dates the order")
+
+	post := func(path, body string) clock.StateView {
+		resp, err := http.Post("http://"+cfg.HTTP.Addr+"/debug/clock"+path, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var v clock.StateView
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&v))
+		return v
+	}
+	v := post("/advance", `{"duration":"1h"}`)
+	assert.Equal(t, clock.StateView{Now: t0.Add(time.Hour), Frozen: true}, v)
+
+	// The move has run the worker: the order went through every status.
+	st, err := cl.GetOrderStatus(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, openapi.OrderStatusCompleted, st.Status)
+
+	var msgs []memkafka.Message
+	require.Eventually(t, func() bool {
+		getJSON(t, "http://"+cfg.HTTP.Addr+"/debug/kafka/topics/"+cfg.Kafka.Routing.StatusChanged+"/messages", &msgs)
+		return len(msgs) == 5
+	}, 2*time.Second, 10*time.Millisecond)
+	type step struct {
+		Status    string
+		ChangedAt time.Time
+	}
+	var got []step
+	for _, m := range msgs {
+		var ev kafka.StatusChangedEvent
+		require.NoError(t, json.Unmarshal(m.Value, &ev))
+		got = append(got, step{ev.Status, ev.ChangedAt})
+		assert.Equal(t, "2026-10-01T13:00:00.000Z", m.Timestamp, "published at the virtual now")
+	}
+	// Each status is dated when its timer ran out, not when the clock moved.
+	assert.Equal(t, []step{
+		{"pending", t0.Add(time.Second)},
+		{"confirmed", t0.Add(6 * time.Second)},
+		{"cooking", t0.Add(11 * time.Second)},
+		{"delivering", t0.Add(5*time.Minute + 11*time.Second)},
+		{"completed", t0.Add(15*time.Minute + 11*time.Second)},
+	}, got)
+
+	resp, err := http.Post("http://"+cfg.HTTP.Addr+"/debug/clock/advance", "application/json", strings.NewReader(`{"duration":"-1m"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	v = post("/set", `{"time":"2026-10-02T09:00:00Z"}`)
+	assert.Equal(t, time.Date(2026, 10, 2, 9, 0, 0, 0, time.UTC), v.Now)
+	assert.False(t, post("/resume", ``).Frozen)
+
+	cancel()
+	require.NoError(t, <-stopped)
+}
+
+func TestRun_EstimatesDeliveryAndPublishesChanges(t *testing.T) {
+	t0 := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	cfg := testConfig(t)
+	cfg.Kafka.InMemory = true
+	cfg.Kafka.Routing.Mode = kafka.RoutingBoth
+	cfg.Clock = config.Clock{Virtual: true, Frozen: true, Start: t0.Format(time.RFC3339)}
+	c := newContainer(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- run(ctx, c) }()
+
+	cl, err := client.New("http://"+cfg.HTTP.Addr, client.WithUserID("u1"), client.WithRetry(client.NoRetry))
+	require.NoError(t, err)
+	var created *openapi.OrderResponse
+	require.Eventually(t, func() bool {
+		created, err = cl.CreateOrder(context.Background(), openapi.CreateOrderRequest{
+			RestaurantID: "r1",
+			Items:        []openapi.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 100}},
+			TotalPrice:   100,
+		})
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	// accept 2m + cook 15m + pickup 5m + ride 15m of the default profile
+	assert.Equal(t, t0.Add(37*time.Minute), created.EstimatedDelivery.UTC())
+
+	advance := func(d string) {
+		resp, err := http.Post("http://"+cfg.HTTP.Addr+"/debug/clock/advance", "application/json", strings.NewReader(`{"duration":"`+d+`"}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	etas := func(n int) []time.Time {
+		var msgs []memkafka.Message
+		require.Eventually(t, func() bool {
+			getJSON(t, "http://"+cfg.HTTP.Addr+"/debug/kafka/topics/"+cfg.Kafka.Routing.ETAChanged+"/messages", &msgs)
+			return len(msgs) == n
+		}, 2*time.Second, 10*time.Millisecond)
+		var got []time.Time
+		for _, m := range msgs {
+			var ev kafka.ETAChangedEvent
+			require.NoError(t, json.Unmarshal(m.Value, &ev))
+			assert.Equal(t, created.ID, ev.OrderID)
+			got = append(got, ev.EstimatedDelivery)
+		}
+		return got
+	}
+
+	// Cooking since 12:00:11, so 15m - 2m49s of it is left.
+	advance("3m")
+	assert.Equal(t, []time.Time{t0.Add(35*time.Minute + 11*time.Second)}, etas(1))
+
+	// Completed at 12:15:11: the estimate becomes the delivery time.
+	advance("1h")
+	assert.Equal(t, t0.Add(15*time.Minute+11*time.Second), etas(2)[1])
+	got, err := cl.GetOrder(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, t0.Add(15*time.Minute+11*time.Second), got.EstimatedDelivery.UTC())
+
+	cancel()
+	require.NoError(t, <-stopped)
+}
+
+func TestRun_ScheduledOrdersWaitForPrepareFrom(t *testing.T) {
+	t0 := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	cfg := testConfig(t)
+	cfg.Kafka.InMemory = true
+	cfg.Kafka.Routing.Mode = kafka.RoutingBoth
+	cfg.Clock = config.Clock{Virtual: true, Frozen: true, Start: t0.Format(time.RFC3339)}
+	cfg.OpeningHours = config.OpeningHours{TimeZone: "UTC", Default: "10:00-22:00"}
+	c := newContainer(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- run(ctx, c) }()
+
+	cl, err := client.New("http://"+cfg.HTTP.Addr, client.WithUserID("u1"), client.WithRetry(client.NoRetry))
+	require.NoError(t, err)
+	create := func(deliverAt time.Time) (*openapi.OrderResponse, error) {
+		return cl.CreateOrder(context.Background(), openapi.CreateOrderRequest{
+			RestaurantID: "r1",
+			Items:        []openapi.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 100}},
+			TotalPrice:   100,
+			DeliverAt:    &deliverAt,
+		})
+	}
+	var scheduled *openapi.OrderResponse
+	require.Eventually(t, func() bool {
+		scheduled, err = create(t0.Add(3 * time.Hour))
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, openapi.OrderStatusScheduled, scheduled.Status)
+	// 37m ahead, the estimate of the default profile
+	assert.Equal(t, t0.Add(3*time.Hour-37*time.Minute), scheduled.PrepareFrom.UTC())
+	assert.Equal(t, t0.Add(3*time.Hour), scheduled.EstimatedDelivery.UTC())
+
+	_, err = create(t0.Add(11 * time.Hour))
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Contains(t, apiErr.Message, "restaurant r1 is closed at 22:23")
+
+	other, err := create(t0.Add(4 * time.Hour))
+	require.NoError(t, err)
+	canceled, err := cl.CancelOrder(context.Background(), other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, openapi.OrderStatusCanceled, canceled.Status)
+
+	advance := func(d string) {
+		resp, err := http.Post("http://"+cfg.HTTP.Addr+"/debug/clock/advance", "application/json", strings.NewReader(`{"duration":"`+d+`"}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	status := func() openapi.OrderStatus {
+		st, err := cl.GetOrderStatus(context.Background(), scheduled.ID)
+		require.NoError(t, err)
+		return st.Status
+	}
+	advance("2h")
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, openapi.OrderStatusScheduled, status(), "not yet time to cook")
+
+	advance("30m")
+	require.Eventually(t, func() bool { return status() != openapi.OrderStatusScheduled }, 2*time.Second, 10*time.Millisecond)
+	var msgs []memkafka.Message
+	require.Eventually(t, func() bool {
+		getJSON(t, "http://"+cfg.HTTP.Addr+"/debug/kafka/topics/"+cfg.Kafka.Routing.StatusChanged+"/messages", &msgs)
+		return len(msgs) > 0
+	}, 2*time.Second, 10*time.Millisecond)
+	var first kafka.StatusChangedEvent
+	require.NoError(t, json.Unmarshal(msgs[0].Value, &first))
+	assert.Equal(t, scheduled.ID, first.OrderID)
+	assert.Equal(t, "created", first.Status)
+	assert.Equal(t, t0.Add(3*time.Hour-37*time.Minute), first.ChangedAt.UTC())
+
+	getJSON(t, "http://"+cfg.HTTP.Addr+"/debug/kafka/topics/"+cfg.Kafka.Routing.Canceled+"/messages", &msgs)
+	require.Len(t, msgs, 1)
+	var ev kafka.CanceledEvent
+	require.NoError(t, json.Unmarshal(msgs[0].Value, &ev))
+	assert.Equal(t, other.ID, ev.OrderID)
+
+	cancel()
+	require.NoError(t, <-stopped)
+}
+
+func TestRun_DeliveryZonesRejectOutsideAddresses(t *testing.T) {
+	zones := filepath.Join(t.TempDir(), "zones.geojson")
+	require.NoError(t, os.WriteFile(zones, []byte(`{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "properties": {"restaurant_id": "r1"}, "geometry": {"type": "Point", "coordinates": [37.6173, 55.7558]}},
+		{"type": "Feature", "properties": {"restaurant_id": "r1"}, "geometry": {"type": "Polygon",
+			"coordinates": [[[37.55, 55.73], [37.69, 55.73], [37.69, 55.79], [37.55, 55.79], [37.55, 55.73]]]}}
+	]}`), 0o600))
+	cfg := testConfig(t)
+	cfg.Geo = config.Geo{Geocoder: geo.GeocoderFixture, Zones: zones}
+	c := newContainer(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- run(ctx, c) }()
+
+	cl, err := client.New("http://"+cfg.HTTP.Addr, client.WithUserID("u1"), client.WithRetry(client.NoRetry))
+	require.NoError(t, err)
+	create := func(a openapi.DeliveryAddress) (*openapi.OrderResponse, error) {
+		return cl.CreateOrder(context.Background(), openapi.CreateOrderRequest{
+			RestaurantID: "r1",
+			Items:        []openapi.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 100}},
+			TotalPrice:   100,
+			Address:      a,
+		})
+	}
+	var created *openapi.OrderResponse
+	require.Eventually(t, func() bool {
+		created, err = create(openapi.DeliveryAddress{Street: "Tverskaya", House: "11"})
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, &openapi.GeoPoint{Lat: 55.7617, Lon: 37.6086}, created.Address.Location, "geocoded by the built-in fixture")
+	// A ride of under a kilometre instead of the 15m of an unknown distance.
+	assert.Less(t, created.EstimatedDelivery.Sub(created.CreatedAt), 25*time.Minute)
+
+	_, err = create(openapi.DeliveryAddress{Street: "Nevsky", House: "12"})
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Contains(t, apiErr.Message, "outside the delivery zones of restaurant r1")
+
+	_, err = create(openapi.DeliveryAddress{Street: "Somewhere", Location: &openapi.GeoPoint{Lat: 55.76, Lon: 37.62}})
+	require.NoError(t, err, "the location sent by the client is enough")
+
+	cancel()
+	require.NoError(t, <-stopped)
+}
@@ -0,0 +1,126 @@
+// Package clock is the one source of time of the service: order timestamps,
+// status timers and event times all read a Clock. System is the wall clock;
+// Virtual is a debug clock that can be frozen, moved forward or set, so that
+// tests fast-forward an order through its whole lifecycle.
+package clock
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+}
+
+// System reads the wall clock in UTC.
+type System struct{}
+
+func (System) Now() time.Time { return time.Now().UTC() }
+
+var ErrInvalidMove = errors.New("invalid clock move")
+
+// Virtual runs at wall speed from a start time until frozen. Advance and
+// Set call the OnMove hooks before they return, so whatever became due by
+// the new time is already handled when the caller looks.
+type Virtual struct {
+	mu     sync.Mutex
+	at     time.Time // virtual time at anchor
+	anchor time.Time // wall time of the last move
+	frozen bool
+	hooks  []func(now time.Time)
+	wall   func() time.Time
+}
+
+var _ Clock = (*Virtual)(nil)
+
+// NewVirtual starts at start, or at the wall time when it is zero.
+func NewVirtual(start time.Time, frozen bool) *Virtual {
+	v := &Virtual{wall: System{}.Now, frozen: frozen}
+	v.anchor = v.wall()
+	v.at = v.anchor
+	if !start.IsZero() {
+		v.at = start.UTC()
+	}
+	return v
+}
+
+func (v *Virtual) Now() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.now()
+}
+
+func (v *Virtual) now() time.Time {
+	if v.frozen {
+		return v.at
+	}
+	return v.at.Add(v.wall().Sub(v.anchor))
+}
+
+// State is the virtual time and whether it stands still.
+type State struct {
+	Now    time.Time
+	Frozen bool
+}
+
+func (v *Virtual) State() State {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return State{Now: v.now(), Frozen: v.frozen}
+}
+
+// Advance moves the clock forward by d.
+func (v *Virtual) Advance(d time.Duration) (State, error) {
+	if d <= 0 {
+		return State{}, fmt.Errorf("%w: advance by %s, must be positive", ErrInvalidMove, d)
+	}
+	return v.move(func(now time.Time) time.Time { return now.Add(d) }), nil
+}
+
+// Set jumps to t. Going back is allowed; status timers then simply wait
+// until the clock passes them again.
+func (v *Virtual) Set(t time.Time) (State, error) {
+	if t.IsZero() {
+		return State{}, fmt.Errorf("%w: time is required", ErrInvalidMove)
+	}
+	return v.move(func(time.Time) time.Time { return t.UTC() }), nil
+}
+
+// Freeze stops the clock at the current time.
+func (v *Virtual) Freeze() State {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.at, v.anchor, v.frozen = v.now(), v.wall(), true
+	return State{Now: v.at, Frozen: true}
+}
+
+// Resume lets the clock run at wall speed again from where it stands.
+func (v *Virtual) Resume() State {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.at, v.anchor, v.frozen = v.now(), v.wall(), false
+	return State{Now: v.at}
+}
+
+// OnMove registers fn to run after every Advance and Set.
+func (v *Virtual) OnMove(fn func(now time.Time)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.hooks = append(v.hooks, fn)
+}
+
+func (v *Virtual) move(to func(now time.Time) time.Time) State {
+	v.mu.Lock()
+	v.at, v.anchor = to(v.now()), v.wall()
+	st := State{Now: v.at, Frozen: v.frozen}
+	hooks := append([]func(time.Time){}, v.hooks...)
+	v.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn(st.Now)
+	}
+	return st
+}
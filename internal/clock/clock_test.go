@@ -0,0 +1,77 @@
+package clock_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/clock"
+)
+
+var t0 = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func TestVirtual_MovesAndNotifies(t *testing.T) {
+	v := clock.NewVirtual(t0, true)
+	var moves []time.Time
+	v.OnMove(func(now time.Time) { moves = append(moves, now) })
+
+	assert.Equal(t, t0, v.Now())
+	st, err := v.Advance(90 * time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, clock.State{Now: t0.Add(90 * time.Minute), Frozen: true}, st)
+	assert.Equal(t, t0.Add(90*time.Minute), v.Now(), "still frozen")
+
+	_, err = v.Advance(0)
+	assert.ErrorIs(t, err, clock.ErrInvalidMove)
+	_, err = v.Set(time.Time{})
+	assert.ErrorIs(t, err, clock.ErrInvalidMove)
+
+	_, err = v.Set(t0)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{t0.Add(90 * time.Minute), t0}, moves)
+}
+
+func TestVirtual_RunsUntilFrozen(t *testing.T) {
+	v := clock.NewVirtual(t0, false)
+	time.Sleep(5 * time.Millisecond)
+	assert.True(t, v.Now().After(t0))
+
+	frozen := v.Freeze()
+	assert.True(t, frozen.Frozen)
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, frozen.Now, v.Now())
+
+	resumed := v.Resume()
+	assert.Equal(t, frozen.Now, resumed.Now, "resumes where it stood")
+	time.Sleep(5 * time.Millisecond)
+	assert.True(t, v.Now().After(frozen.Now))
+}
+
+func TestVirtual_Handler(t *testing.T) {
+	srv := httptest.NewServer(clock.NewVirtual(t0, true).Handler())
+	defer srv.Close()
+
+	post := func(path, body string) int {
+		resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusOK, post("/advance", `{"duration":"2h"}`))
+	assert.Equal(t, http.StatusBadRequest, post("/advance", `{"duration":"soon"}`))
+	assert.Equal(t, http.StatusBadRequest, post("/advance", `{"duration":"-1s"}`))
+	assert.Equal(t, http.StatusBadRequest, post("/set", `{"time":"tomorrow"}`))
+
+	resp, err := http.Get(srv.URL + "/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var v clock.StateView
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&v))
+	assert.Equal(t, clock.StateView{Now: t0.Add(2 * time.Hour), Frozen: true}, v)
+}
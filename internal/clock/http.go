@@ -0,0 +1,80 @@
+package clock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/nikolaev/service-order/internal/debughttp"
+)
+
+// Handler controls the clock, meant to be mounted under a debug prefix:
+//
+//	GET  /         the current virtual time
+//	POST /advance  move forward with {"duration": "90m"}
+//	POST /set      jump with {"time": "2026-10-01T12:00:00Z"}
+//	POST /freeze   stop the clock
+//	POST /resume   let it run again
+//
+// Every call answers with the resulting state.
+func (v *Virtual) Handler() http.Handler {
+	mux := chi.NewRouter()
+	mux.Get("/", func(w http.ResponseWriter, _ *http.Request) {
+		debughttp.JSON(w, http.StatusOK, stateView(v.State()))
+	})
+	mux.Post("/advance", func(w http.ResponseWriter, req *http.Request) {
+		var in AdvanceRequest
+		if err := json.NewDecoder(req.Body).Decode(&in); err != nil {
+			debughttp.Error(w, http.StatusBadRequest, err)
+			return
+		}
+		d, err := time.ParseDuration(in.Duration)
+		if err != nil {
+			debughttp.Error(w, http.StatusBadRequest, fmt.Errorf("%w: %w", ErrInvalidMove, err))
+			return
+		}
+		reply(w, func() (State, error) { return v.Advance(d) })
+	})
+	mux.Post("/set", func(w http.ResponseWriter, req *http.Request) {
+		var in SetRequest
+		if err := json.NewDecoder(req.Body).Decode(&in); err != nil {
+			debughttp.Error(w, http.StatusBadRequest, err)
+			return
+		}
+		reply(w, func() (State, error) { return v.Set(in.Time) })
+	})
+	mux.Post("/freeze", func(w http.ResponseWriter, _ *http.Request) {
+		debughttp.JSON(w, http.StatusOK, stateView(v.Freeze()))
+	})
+	mux.Post("/resume", func(w http.ResponseWriter, _ *http.Request) {
+		debughttp.JSON(w, http.StatusOK, stateView(v.Resume()))
+	})
+	return mux
+}
+
+type AdvanceRequest struct {
+	Duration string `json:"duration"`
+}
+
+type SetRequest struct {
+	Time time.Time `json:"time"`
+}
+
+type StateView struct {
+	Now    time.Time `json:"now"`
+	Frozen bool      `json:"frozen"`
+}
+
+func stateView(st State) StateView { return StateView(st) }
+
+func reply(w http.ResponseWriter, fn func() (State, error)) {
+	st, err := fn()
+	if err != nil {
+		debughttp.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	debughttp.JSON(w, http.StatusOK, stateView(st))
+}
@@ -0,0 +1,600 @@
+// Package config loads the service configuration. Values are applied in
+// order: defaults, YAML file (-config / CONFIG_FILE), environment, flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nikolaev/service-order/internal/eta"
+	"github.com/nikolaev/service-order/internal/gateway/kafka"
+	"github.com/nikolaev/service-order/internal/geo"
+	"github.com/nikolaev/service-order/internal/handlers/validation"
+	"github.com/nikolaev/service-order/internal/hours"
+	"github.com/nikolaev/service-order/internal/logging"
+	repo "github.com/nikolaev/service-order/internal/repository/order"
+	"github.com/nikolaev/service-order/internal/simulation"
+	"github.com/nikolaev/service-order/internal/tracing"
+	"github.com/nikolaev/service-order/internal/usecase/debug/seed"
+	ucase "github.com/nikolaev/service-order/internal/usecase/order"
+)
+
+type Config struct {
+	HTTP         HTTP         `yaml:"http"`
+	GRPC         GRPC         `yaml:"grpc"`
+	Worker       Worker       `yaml:"worker"`
+	StatusTimers StatusTimers `yaml:"status_timers"`
+	Simulation   Simulation   `yaml:"simulation"`
+	Clock        Clock        `yaml:"clock"`
+	ETA          ETA          `yaml:"eta"`
+	Geo          Geo          `yaml:"geo"`
+	Scheduling   Scheduling   `yaml:"scheduling"`
+	OpeningHours OpeningHours `yaml:"opening_hours"`
+	Repository   Repository   `yaml:"repository"`
+	Kafka        Kafka        `yaml:"kafka"`
+	Seed         Seed         `yaml:"seed"`
+	Outbox       Outbox       `yaml:"outbox"`
+	Replay       Replay       `yaml:"replay"`
+	Webhook      Webhook      `yaml:"webhook"`
+	Shutdown     Shutdown     `yaml:"shutdown"`
+	Health       Health       `yaml:"health"`
+	Log          Log          `yaml:"log"`
+	Tracing      Tracing      `yaml:"tracing"`
+}
+
+type HTTP struct {
+	Addr string `yaml:"addr"`
+	// OpenAPIValidation is one of off, request, log, strict.
+	OpenAPIValidation string `yaml:"openapi_validation"`
+	// AdminToken is the X-Admin-Token of the /admin operations; empty
+	// disables them. It is redacted when the config is printed.
+	AdminToken string `yaml:"admin_token"`
+}
+
+type GRPC struct {
+	Addr string `yaml:"addr"`
+}
+
+type Worker struct {
+	// Tick is how often the status worker advances orders.
+	Tick time.Duration `yaml:"tick"`
+}
+
+// StatusTimers is how long an order stays in a status before the worker
+// moves it to the next one.
+type StatusTimers struct {
+	Created    time.Duration `yaml:"created"`
+	Pending    time.Duration `yaml:"pending"`
+	Confirmed  time.Duration `yaml:"confirmed"`
+	Cooking    time.Duration `yaml:"cooking"`
+	Delivering time.Duration `yaml:"delivering"`
+}
+
+func (t StatusTimers) timers() repo.StatusTimers {
+	return repo.StatusTimers{
+		Created:    t.Created,
+		Pending:    t.Pending,
+		Confirmed:  t.Confirmed,
+		Cooking:    t.Cooking,
+		Delivering: t.Delivering,
+	}
+}
+
+// or fills the zero timers of t from def.
+func (t StatusTimers) or(def StatusTimers) StatusTimers {
+	pick := func(v, d time.Duration) time.Duration {
+		if v == 0 {
+			return d
+		}
+		return v
+	}
+	return StatusTimers{
+		Created:    pick(t.Created, def.Created),
+		Pending:    pick(t.Pending, def.Pending),
+		Confirmed:  pick(t.Confirmed, def.Confirmed),
+		Cooking:    pick(t.Cooking, def.Cooking),
+		Delivering: pick(t.Delivering, def.Delivering),
+	}
+}
+
+// Clock replaces the wall clock with a virtual one, controlled through
+// /debug/clock. Meant for tests and local runs only.
+type Clock struct {
+	Virtual bool `yaml:"virtual"`
+	// Frozen starts the virtual clock stopped; it then moves only on request.
+	Frozen bool `yaml:"frozen"`
+	// Start is the RFC 3339 time the virtual clock starts at; empty is now.
+	Start string `yaml:"start"`
+}
+
+// StartTime parses Start; Validate has checked it.
+func (c Clock) StartTime() time.Time {
+	t, _ := time.Parse(time.RFC3339, c.Start)
+	return t
+}
+
+// Simulation picks the profile that drives the status worker; with Debug it
+// can be switched at runtime through /debug/simulation.
+type Simulation struct {
+	// Profile is default (status_timers as they are), fast, realistic or
+	// one of Profiles.
+	Profile string `yaml:"profile"`
+	// TimeScale multiplies every timer; 0.1 runs the lifecycle ten times
+	// faster.
+	TimeScale float64 `yaml:"time_scale"`
+	// Profiles add named profiles or replace the built-in ones.
+	Profiles map[string]SimulationProfile `yaml:"profiles,omitempty"`
+	// Debug serves /debug/simulation behind http.admin_token.
+	Debug bool `yaml:"debug"`
+}
+
+type SimulationProfile struct {
+	// Timers left zero are taken from status_timers.
+	Timers StatusTimers `yaml:"timers"`
+	// Jitter is none, uniform, normal or exponential; JitterSpread is its
+	// share of each timer, from 0 to 1.
+	Jitter       string  `yaml:"jitter"`
+	JitterSpread float64 `yaml:"jitter_spread"`
+	// CancelPercent of orders are rejected by the restaurant while pending.
+	CancelPercent float64 `yaml:"cancel_percent"`
+	// Restaurants override timers by restaurant ID; zero fields keep the
+	// profile's.
+	Restaurants map[string]StatusTimers `yaml:"restaurants,omitempty"`
+}
+
+// Profiles returns the built-in profiles followed by the configured ones.
+func (c Config) Profiles() []simulation.Profile {
+	out := simulation.Builtin(c.StatusTimers.timers())
+	for name, p := range c.Simulation.Profiles {
+		t := p.Timers.or(c.StatusTimers).timers()
+		sp := simulation.Profile{
+			Name:          name,
+			Timers:        t,
+			Jitter:        simulation.Jitter{Distribution: simulation.Distribution(p.Jitter), Spread: p.JitterSpread},
+			CancelPercent: p.CancelPercent,
+		}
+		for id, rt := range p.Restaurants {
+			if sp.Restaurants == nil {
+				sp.Restaurants = make(map[string]repo.StatusTimers)
+			}
+			sp.Restaurants[id] = rt.timers()
+		}
+		out = append(out, sp)
+	}
+	return out
+}
+
+// ETA fills estimated_delivery of the orders and keeps it current.
+type ETA struct {
+	Enabled bool `yaml:"enabled"`
+	// Tick is how often every estimate is recomputed; only moves of at least
+	// MinChange are stored and published.
+	Tick      time.Duration `yaml:"tick"`
+	MinChange time.Duration `yaml:"min_change"`
+	// Profile is how long restaurants take; Restaurants override it by
+	// restaurant ID, zero fields keep Profile's.
+	Profile     ETAProfile            `yaml:"profile"`
+	Restaurants map[string]ETAProfile `yaml:"restaurants,omitempty"`
+}
+
+// ETAProfile mirrors eta.Profile.
+type ETAProfile struct {
+	Accept    time.Duration `yaml:"accept"`
+	Cook      time.Duration `yaml:"cook"`
+	PerItem   time.Duration `yaml:"per_item"`
+	PerQueued time.Duration `yaml:"per_queued"`
+	Pickup    time.Duration `yaml:"pickup"`
+	Ride      time.Duration `yaml:"ride"`
+	PerKm     time.Duration `yaml:"per_km"`
+}
+
+// Estimator builds the estimator of the configured profiles; distance may
+// be nil.
+func (c ETA) Estimator(distance eta.Distance) (*eta.Estimator, error) {
+	restaurants := make(map[string]eta.Profile, len(c.Restaurants))
+	for id, p := range c.Restaurants {
+		restaurants[id] = eta.Profile(p)
+	}
+	return eta.New(eta.Profile(c.Profile), restaurants, distance)
+}
+
+// Geo locates addresses and limits where restaurants deliver.
+type Geo struct {
+	// Geocoder is none or fixture, the offline geocoder of Fixture.
+	Geocoder string `yaml:"geocoder"`
+	// Fixture is the JSON table of the fixture geocoder; empty is the
+	// built-in one.
+	Fixture string `yaml:"fixture"`
+	// Zones is a GeoJSON file of delivery zones; empty lets every
+	// restaurant deliver anywhere.
+	Zones string `yaml:"zones"`
+}
+
+// Scheduling mirrors order.Scheduling: orders placed for later.
+type Scheduling struct {
+	// Lead is how long before deliver_at an order goes to the kitchen when
+	// the ETA component is off.
+	Lead time.Duration `yaml:"lead"`
+	// MaxAhead is how far ahead an order can be scheduled.
+	MaxAhead time.Duration `yaml:"max_ahead"`
+}
+
+// OpeningHours are when restaurants take orders, as daily ranges of local
+// time like "10:00-15:00,17:00-23:30"; empty is always open.
+type OpeningHours struct {
+	// TimeZone is the IANA zone of the ranges.
+	TimeZone string `yaml:"time_zone"`
+	Default  string `yaml:"default"`
+	// Restaurants override Default by restaurant ID.
+	Restaurants map[string]string `yaml:"restaurants,omitempty"`
+}
+
+// Hours parses the configured schedules.
+func (c OpeningHours) Hours() (*hours.Hours, error) {
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	return hours.New(loc, c.Default, c.Restaurants)
+}
+
+type Repository struct {
+	// Kind is memory (the current state only) or eventsourced (every change
+	// is kept as an event and the state rebuilt from them).
+	Kind string `yaml:"kind"`
+	// SnapshotEvery is how many events of an order an eventsourced store
+	// folds at most before it takes a snapshot.
+	SnapshotEvery int `yaml:"snapshot_every"`
+}
+
+type Kafka struct {
+	// Brokers is empty when Kafka is disabled; events then go to the noop producer.
+	Brokers []string `yaml:"brokers"`
+	// InMemory replaces the brokers with an in-process broker, see memkafka;
+	// the producer is then sync and TLS, SASL and topics are not used.
+	InMemory bool `yaml:"in_memory"`
+	// Topic receives every event in the legacy format unless routing.mode is typed.
+	Topic    string       `yaml:"topic"`
+	Routing  KafkaRouting `yaml:"routing"`
+	RetryMax int          `yaml:"retry_max"`
+	// Mode is sync (wait for the broker on every event) or async.
+	Mode        string `yaml:"mode"`
+	Compression string `yaml:"compression"`
+	// Buffer, BatchSize, Linger and DeadLetterTopic apply to the async mode.
+	Buffer          int           `yaml:"buffer"`
+	BatchSize       int           `yaml:"batch_size"`
+	Linger          time.Duration `yaml:"linger"`
+	DeadLetterTopic string        `yaml:"dead_letter_topic"`
+	TLS             KafkaTLS      `yaml:"tls"`
+	SASL            KafkaSASL     `yaml:"sasl"`
+	Topics          KafkaTopics   `yaml:"topics"`
+}
+
+// KafkaRouting maps event types to topics.
+type KafkaRouting struct {
+	// Mode is legacy (everything to kafka.topic), typed (each type to its
+	// topic) or both.
+	Mode          string `yaml:"mode"`
+	Created       string `yaml:"created"`
+	Updated       string `yaml:"updated"`
+	StatusChanged string `yaml:"status_changed"`
+	Canceled      string `yaml:"canceled"`
+	Deleted       string `yaml:"deleted"`
+	// Snapshot receives replayed states; empty means the created topic.
+	Snapshot string `yaml:"snapshot"`
+	// ETAChanged receives new delivery estimates; empty drops them.
+	ETAChanged string `yaml:"eta_changed"`
+}
+
+// Topics returns the routing as kafka.Routing topics.
+func (r KafkaRouting) Topics() map[kafka.EventType]string {
+	return map[kafka.EventType]string{
+		kafka.EventCreated:       r.Created,
+		kafka.EventUpdated:       r.Updated,
+		kafka.EventStatusChanged: r.StatusChanged,
+		kafka.EventCanceled:      r.Canceled,
+		kafka.EventDeleted:       r.Deleted,
+		kafka.EventSnapshot:      r.Snapshot,
+		kafka.EventETAChanged:    r.ETAChanged,
+	}
+}
+
+type KafkaTLS struct {
+	Enabled bool `yaml:"enabled"`
+	// CAFile is a PEM bundle of trusted CAs; empty uses the system pool.
+	CAFile string `yaml:"ca_file"`
+	// CertFile and KeyFile enable mutual TLS.
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+type KafkaSASL struct {
+	// Mechanism is PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512; empty disables SASL.
+	Mechanism string `yaml:"mechanism"`
+	Username  string `yaml:"username"`
+	// Password is redacted when the config is printed.
+	Password string `yaml:"password"`
+}
+
+// KafkaTopics is the admin step run before the producer starts.
+type KafkaTopics struct {
+	// Mode is off, check (fail on a missing or different topic) or create.
+	Mode              string        `yaml:"mode"`
+	Partitions        int           `yaml:"partitions"`
+	ReplicationFactor int           `yaml:"replication_factor"`
+	Retention         time.Duration `yaml:"retention"`
+}
+
+type Seed struct {
+	// Count is how many orders the debug seed route creates when neither
+	// the request nor the scenario says.
+	Count int `yaml:"count"`
+	// Scenario runs when the request names none: demo or one of
+	// ScenariosDir.
+	Scenario string `yaml:"scenario"`
+	// ScenariosDir holds scenario files, *.yaml, *.yml or *.json.
+	ScenariosDir string `yaml:"scenarios_dir"`
+}
+
+type Outbox struct {
+	// Buffer is how many events may wait for delivery before publishers block.
+	Buffer int `yaml:"buffer"`
+}
+
+// Replay limits the admin replay of order snapshots, in events per second.
+type Replay struct {
+	Rate    int `yaml:"rate"`
+	MaxRate int `yaml:"max_rate"`
+}
+
+// Webhook tunes the delivery of order events to partner endpoints.
+type Webhook struct {
+	// Timeout bounds one delivery attempt.
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxAttempts is how many attempts a delivery gets before it goes to
+	// the dead letter.
+	MaxAttempts int `yaml:"max_attempts"`
+	// Backoff is the delay after the first failed attempt; it doubles up to
+	// MaxBackoff.
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	Workers    int           `yaml:"workers"`
+	// LogSize is how many finished deliveries are kept for inspection.
+	LogSize int `yaml:"log_size"`
+	// MaxPending bounds the deliveries of one subscription waiting for an
+	// attempt; the oldest go dead beyond it.
+	MaxPending int `yaml:"max_pending"`
+	// AllowedHosts limits subscription URLs to these hosts and their
+	// subdomains; empty allows any host.
+	AllowedHosts []string `yaml:"allowed_hosts"`
+	// AllowPrivate permits loopback, private and link-local targets.
+	AllowPrivate bool `yaml:"allow_private"`
+}
+
+type Shutdown struct {
+	// Timeout bounds the whole graceful shutdown: draining servers, the
+	// worker, the outbox and flushing the producer.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type Health struct {
+	// CheckTimeout bounds each dependency check of /healthz and /readyz.
+	CheckTimeout time.Duration `yaml:"check_timeout"`
+	// WorkerMaxAge is the oldest acceptable status worker heartbeat.
+	WorkerMaxAge time.Duration `yaml:"worker_max_age"`
+	// OutboxMaxLag is how long an event may wait in the outbox before the
+	// service reports itself not ready.
+	OutboxMaxLag time.Duration `yaml:"outbox_max_lag"`
+}
+
+type Log struct {
+	// Level is one of debug, info, warn, error.
+	Level string `yaml:"level"`
+	// Format is json or text.
+	Format string `yaml:"format"`
+}
+
+type Tracing struct {
+	// Exporter is none, stdout or otlp.
+	Exporter string `yaml:"exporter"`
+	// OTLPEndpoint is the OTLP/HTTP collector URL.
+	OTLPEndpoint string `yaml:"otlp_endpoint"`
+}
+
+func Default() Config {
+	return Config{
+		HTTP:   HTTP{Addr: ":8080", OpenAPIValidation: string(validation.ModeRequest)},
+		GRPC:   GRPC{Addr: ":9090"},
+		Worker: Worker{Tick: 500 * time.Millisecond},
+		StatusTimers: StatusTimers{
+			Created:    1 * time.Second,
+			Pending:    5 * time.Second,
+			Confirmed:  5 * time.Second,
+			Cooking:    5 * time.Minute,
+			Delivering: 10 * time.Minute,
+		},
+		Kafka: Kafka{
+			Topic: "order.status.changed",
+			Routing: KafkaRouting{
+				Mode:          kafka.RoutingLegacy,
+				Created:       "order.event.created",
+				Updated:       "order.event.updated",
+				StatusChanged: "order.event.status-changed",
+				Canceled:      "order.event.canceled",
+				Deleted:       "order.event.deleted",
+				ETAChanged:    "order.event.eta-changed",
+			},
+			RetryMax:        5,
+			Mode:            kafka.ModeSync,
+			Compression:     "none",
+			Buffer:          256,
+			BatchSize:       100,
+			Linger:          10 * time.Millisecond,
+			DeadLetterTopic: "order.status.changed.dlq",
+			Topics: KafkaTopics{
+				Mode:              kafka.TopicsOff,
+				Partitions:        3,
+				ReplicationFactor: 1,
+				Retention:         7 * 24 * time.Hour,
+			},
+		},
+		Simulation: Simulation{Profile: simulation.DefaultProfile, TimeScale: 1},
+		ETA: ETA{
+			Enabled:   true,
+			Tick:      5 * time.Second,
+			MinChange: time.Minute,
+			Profile:   ETAProfile(eta.DefaultProfile),
+		},
+		Geo:          Geo{Geocoder: geo.GeocoderNone},
+		Scheduling:   Scheduling(ucase.DefaultScheduling),
+		OpeningHours: OpeningHours{TimeZone: "UTC"},
+		Repository:   Repository{Kind: repo.KindMemory, SnapshotEvery: repo.DefaultSnapshotEvery},
+		Seed:         Seed{Count: 10, Scenario: seed.DemoScenario},
+		Outbox:       Outbox{Buffer: 1024},
+		Replay:       Replay{Rate: 100, MaxRate: 1000},
+		Webhook: Webhook{
+			Timeout:     5 * time.Second,
+			MaxAttempts: 6,
+			Backoff:     time.Second,
+			MaxBackoff:  5 * time.Minute,
+			Workers:     4,
+			LogSize:     1000,
+			MaxPending:  1000,
+		},
+		Shutdown: Shutdown{Timeout: 15 * time.Second},
+		Health: Health{
+			CheckTimeout: 2 * time.Second,
+			WorkerMaxAge: 5 * time.Second,
+			OutboxMaxLag: 10 * time.Second,
+		},
+		Log:     Log{Level: "info", Format: logging.FormatJSON},
+		Tracing: Tracing{Exporter: tracing.ExporterNone, OTLPEndpoint: "http://localhost:4318"},
+	}
+}
+
+// Validate reports every invalid value at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.HTTP.Addr != "", "http.addr is required")
+	if _, err := validation.ParseMode(c.HTTP.OpenAPIValidation); err != nil {
+		errs = append(errs, fmt.Errorf("http.openapi_validation: %w", err))
+	}
+	check(c.GRPC.Addr != "", "grpc.addr is required")
+	check(c.HTTP.Addr != c.GRPC.Addr, "http.addr and grpc.addr must differ")
+	check(c.Worker.Tick > 0, "worker.tick must be positive, got %s", c.Worker.Tick)
+	for name, d := range map[string]time.Duration{
+		"created":    c.StatusTimers.Created,
+		"pending":    c.StatusTimers.Pending,
+		"confirmed":  c.StatusTimers.Confirmed,
+		"cooking":    c.StatusTimers.Cooking,
+		"delivering": c.StatusTimers.Delivering,
+	} {
+		check(d > 0, "status_timers.%s must be positive, got %s", name, d)
+	}
+	check(c.Simulation.TimeScale > 0, "simulation.time_scale must be positive, got %g", c.Simulation.TimeScale)
+	if _, err := simulation.New(c.Profiles(), c.Simulation.Profile, c.Simulation.TimeScale); err != nil {
+		errs = append(errs, fmt.Errorf("simulation: %w", err))
+	}
+	if c.Clock.Start != "" {
+		_, err := time.Parse(time.RFC3339, c.Clock.Start)
+		check(err == nil, "clock.start must be an RFC 3339 time, got %q", c.Clock.Start)
+	}
+	check(c.Clock.Virtual || !c.Clock.Frozen && c.Clock.Start == "", "clock.frozen and clock.start need clock.virtual")
+	if c.ETA.Enabled {
+		check(c.ETA.Tick > 0, "eta.tick must be positive, got %s", c.ETA.Tick)
+		check(c.ETA.MinChange >= 0, "eta.min_change must not be negative, got %s", c.ETA.MinChange)
+		if _, err := c.ETA.Estimator(nil); err != nil {
+			errs = append(errs, fmt.Errorf("eta: %w", err))
+		}
+	}
+	if _, err := geo.ParseGeocoder(c.Geo.Geocoder); err != nil {
+		errs = append(errs, fmt.Errorf("geo.geocoder: %w", err))
+	}
+	check(c.Geo.Fixture == "" || c.Geo.Geocoder == geo.GeocoderFixture, "geo.fixture needs geo.geocoder fixture")
+	check(c.Scheduling.Lead > 0, "scheduling.lead must be positive, got %s", c.Scheduling.Lead)
+	check(c.Scheduling.MaxAhead > 0, "scheduling.max_ahead must be positive, got %s", c.Scheduling.MaxAhead)
+	if _, err := c.OpeningHours.Hours(); err != nil {
+		errs = append(errs, fmt.Errorf("opening_hours: %w", err))
+	}
+	check(len(c.Kafka.Brokers) == 0 && !c.Kafka.InMemory || c.Kafka.Topic != "", "kafka.topic is required when kafka.brokers or kafka.in_memory is set")
+	check(len(c.Kafka.Brokers) == 0 || !c.Kafka.InMemory, "kafka.brokers and kafka.in_memory are mutually exclusive")
+	check(c.Kafka.RetryMax >= 0, "kafka.retry_max must not be negative")
+	if _, err := kafka.ParseRoutingMode(c.Kafka.Routing.Mode); err != nil {
+		errs = append(errs, fmt.Errorf("kafka.routing.mode: %w", err))
+	}
+	if c.Kafka.Routing.Mode != kafka.RoutingLegacy {
+		for typ, topic := range c.Kafka.Routing.Topics() {
+			check(topic != c.Kafka.Topic, "kafka.routing.%s must differ from kafka.topic, which keeps the legacy format", typ)
+			check(topic == "" || topic != c.Kafka.DeadLetterTopic, "kafka.routing.%s must differ from kafka.dead_letter_topic", typ)
+		}
+	}
+	if _, err := kafka.ParseMode(c.Kafka.Mode); err != nil {
+		errs = append(errs, fmt.Errorf("kafka.mode: %w", err))
+	}
+	if _, err := kafka.ParseCompression(c.Kafka.Compression); err != nil {
+		errs = append(errs, fmt.Errorf("kafka.compression: %w", err))
+	}
+	check(c.Kafka.Buffer > 0, "kafka.buffer must be positive, got %d", c.Kafka.Buffer)
+	check(c.Kafka.BatchSize >= 0, "kafka.batch_size must not be negative")
+	check(c.Kafka.Linger >= 0, "kafka.linger must not be negative")
+	check(c.Kafka.DeadLetterTopic != c.Kafka.Topic, "kafka.dead_letter_topic must differ from kafka.topic")
+	tls := c.Kafka.TLS
+	check(tls.Enabled || tls.CAFile == "" && tls.CertFile == "" && tls.KeyFile == "",
+		"kafka.tls.enabled must be true when kafka.tls certificate files are set")
+	check((tls.CertFile == "") == (tls.KeyFile == ""), "kafka.tls.cert_file and kafka.tls.key_file must be set together")
+	if _, err := kafka.ParseSASLMechanism(c.Kafka.SASL.Mechanism); err != nil {
+		errs = append(errs, fmt.Errorf("kafka.sasl.mechanism: %w", err))
+	}
+	check(c.Kafka.SASL.Mechanism == "" || c.Kafka.SASL.Username != "" && c.Kafka.SASL.Password != "",
+		"kafka.sasl.username and kafka.sasl.password are required for kafka.sasl.mechanism %s", c.Kafka.SASL.Mechanism)
+	if _, err := kafka.ParseTopicsMode(c.Kafka.Topics.Mode); err != nil {
+		errs = append(errs, fmt.Errorf("kafka.topics.mode: %w", err))
+	}
+	check(c.Kafka.Topics.Partitions > 0 && c.Kafka.Topics.Partitions <= math.MaxInt32,
+		"kafka.topics.partitions must be positive, got %d", c.Kafka.Topics.Partitions)
+	check(c.Kafka.Topics.ReplicationFactor > 0 && c.Kafka.Topics.ReplicationFactor <= math.MaxInt16,
+		"kafka.topics.replication_factor must be positive, got %d", c.Kafka.Topics.ReplicationFactor)
+	check(c.Kafka.Topics.Retention >= 0, "kafka.topics.retention must not be negative")
+	if _, err := repo.ParseKind(c.Repository.Kind); err != nil {
+		errs = append(errs, fmt.Errorf("repository.kind: %w", err))
+	}
+	check(c.Repository.SnapshotEvery > 0, "repository.snapshot_every must be positive, got %d", c.Repository.SnapshotEvery)
+	check(c.Seed.Count > 0, "seed.count must be positive, got %d", c.Seed.Count)
+	check(c.Seed.Scenario != "", "seed.scenario is required")
+	check(c.Outbox.Buffer > 0, "outbox.buffer must be positive, got %d", c.Outbox.Buffer)
+	check(c.Replay.Rate > 0, "replay.rate must be positive, got %d", c.Replay.Rate)
+	check(c.Replay.MaxRate >= c.Replay.Rate, "replay.max_rate (%d) must not be below replay.rate (%d)", c.Replay.MaxRate, c.Replay.Rate)
+	check(c.Webhook.Timeout > 0, "webhook.timeout must be positive, got %s", c.Webhook.Timeout)
+	check(c.Webhook.MaxAttempts > 0, "webhook.max_attempts must be positive, got %d", c.Webhook.MaxAttempts)
+	check(c.Webhook.Backoff > 0, "webhook.backoff must be positive, got %s", c.Webhook.Backoff)
+	check(c.Webhook.MaxBackoff >= c.Webhook.Backoff, "webhook.max_backoff (%s) must not be below webhook.backoff (%s)", c.Webhook.MaxBackoff, c.Webhook.Backoff)
+	check(c.Webhook.Workers > 0, "webhook.workers must be positive, got %d", c.Webhook.Workers)
+	check(c.Webhook.LogSize > 0, "webhook.log_size must be positive, got %d", c.Webhook.LogSize)
+	check(c.Webhook.MaxPending > 0, "webhook.max_pending must be positive, got %d", c.Webhook.MaxPending)
+	check(c.Shutdown.Timeout > 0, "shutdown.timeout must be positive, got %s", c.Shutdown.Timeout)
+	check(c.Health.CheckTimeout > 0, "health.check_timeout must be positive, got %s", c.Health.CheckTimeout)
+	check(c.Health.WorkerMaxAge > c.Worker.Tick, "health.worker_max_age (%s) must exceed worker.tick (%s)", c.Health.WorkerMaxAge, c.Worker.Tick)
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	if _, err := logging.ParseFormat(c.Log.Format); err != nil {
+		errs = append(errs, fmt.Errorf("log.format: %w", err))
+	}
+	if _, err := tracing.ParseExporter(c.Tracing.Exporter); err != nil {
+		errs = append(errs, fmt.Errorf("tracing.exporter: %w", err))
+	}
+	check(c.Tracing.Exporter != tracing.ExporterOTLP || c.Tracing.OTLPEndpoint != "",
+		"tracing.otlp_endpoint is required for the otlp exporter")
+	check(c.Health.OutboxMaxLag > 0, "health.outbox_max_lag must be positive, got %s", c.Health.OutboxMaxLag)
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,160 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/config"
+)
+
+func envOf(m map[string]string) func(string) string {
+	return func(k string) string { return m[k] }
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, printOnly, err := config.Load(nil, envOf(nil))
+	require.NoError(t, err)
+	assert.False(t, printOnly)
+	assert.Equal(t, config.Default(), *cfg)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+http:
+  addr: ":7000"
+worker:
+  tick: 1s
+status_timers:
+  cooking: 30s
+kafka:
+  brokers: [file:9092]
+`), 0o600))
+
+	cfg, _, err := config.Load(
+		[]string{"-config", path, "-worker.tick", "250ms"},
+		envOf(map[string]string{"PORT": "8081", "KAFKA_BROKERS": "a:9092, b:9092", "WORKER_TICK": "2s"}),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, ":8081", cfg.HTTP.Addr, "env PORT overrides file")
+	assert.Equal(t, 250*time.Millisecond, cfg.Worker.Tick, "flag overrides env")
+	assert.Equal(t, 30*time.Second, cfg.StatusTimers.Cooking, "file overrides default")
+	assert.Equal(t, 5*time.Second, cfg.StatusTimers.Pending, "default kept")
+	assert.Equal(t, []string{"a:9092", "b:9092"}, cfg.Kafka.Brokers)
+}
+
+func TestLoad_Validation(t *testing.T) {
+	_, _, err := config.Load(
+		[]string{"-worker.tick", "0s", "-seed.count", "0", "-kafka.mode", "fire-and-forget", "-kafka.in_memory", "true"},
+		envOf(map[string]string{"OPENAPI_VALIDATION": "loose", "KAFKA_COMPRESSION": "brotli", "KAFKA_BROKERS": "a:9092"}),
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "worker.tick")
+	assert.Contains(t, err.Error(), "seed.count")
+	assert.Contains(t, err.Error(), "openapi_validation")
+	assert.Contains(t, err.Error(), "kafka.mode")
+	assert.Contains(t, err.Error(), "kafka.compression")
+	assert.Contains(t, err.Error(), "kafka.brokers and kafka.in_memory are mutually exclusive")
+
+	_, _, err = config.Load([]string{"-clock.start", "noon"}, envOf(map[string]string{"CLOCK_FROZEN": "true"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "clock.start must be an RFC 3339 time")
+	assert.Contains(t, err.Error(), "need clock.virtual")
+
+	_, _, err = config.Load([]string{"-opening_hours.time_zone", "Mars/Olympus"}, envOf(map[string]string{"OPENING_HOURS": "10:00-25:00"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "opening_hours: unknown time zone Mars/Olympus")
+	_, _, err = config.Load(nil, envOf(map[string]string{"OPENING_HOURS": "10:00-25:00", "SCHEDULING_LEAD": "0s"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `opening_hours: default: range "10:00-25:00"`)
+	assert.Contains(t, err.Error(), "scheduling.lead must be positive")
+
+	_, _, err = config.Load([]string{"-worker.tick", "soon"}, envOf(nil))
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("htpp:\n  addr: x\n"), 0o600))
+	_, _, err = config.Load([]string{"-config", path}, envOf(nil))
+	assert.Error(t, err, "unknown keys are rejected")
+}
+
+func TestConfig_StringRoundTrips(t *testing.T) {
+	cfg := config.Default()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(cfg.String()), 0o600))
+
+	loaded, _, err := config.Load([]string{"-config", path}, envOf(nil))
+	require.NoError(t, err)
+	assert.Equal(t, cfg.String(), loaded.String())
+	assert.Contains(t, cfg.String(), "tick: 500ms")
+}
+
+func TestLoad_KafkaSecurity(t *testing.T) {
+	cfg, _, err := config.Load(
+		[]string{"-kafka.tls.enabled", "true", "-kafka.tls.ca_file", "/etc/kafka/ca.pem"},
+		envOf(map[string]string{
+			"KAFKA_SASL_MECHANISM": "SCRAM-SHA-512",
+			"KAFKA_SASL_USERNAME":  "service-order",
+			"KAFKA_SASL_PASSWORD":  "s3cret",
+		}),
+	)
+	require.NoError(t, err)
+	assert.True(t, cfg.Kafka.TLS.Enabled)
+	assert.Equal(t, "s3cret", cfg.Kafka.SASL.Password)
+	assert.NotContains(t, cfg.String(), "s3cret", "the password is not printed")
+
+	_, _, err = config.Load(
+		[]string{"-kafka.tls.cert_file", "client.pem", "-kafka.topics.mode", "ensure"},
+		envOf(map[string]string{"KAFKA_SASL_MECHANISM": "PLAIN"}),
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "kafka.tls.enabled must be true")
+	assert.Contains(t, err.Error(), "kafka.tls.cert_file and kafka.tls.key_file")
+	assert.Contains(t, err.Error(), "kafka.sasl.username and kafka.sasl.password are required")
+	assert.Contains(t, err.Error(), "kafka.topics.mode")
+}
+
+func TestLoad_SimulationProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+status_timers:
+  cooking: 30s
+simulation:
+  profile: slow-kitchen
+  profiles:
+    slow-kitchen:
+      timers:
+        delivering: 1m
+      jitter: uniform
+      jitter_spread: 0.2
+      cancel_percent: 5
+      restaurants:
+        r2:
+          cooking: 1h
+`), 0o600))
+
+	cfg, _, err := config.Load([]string{"-config", path}, envOf(map[string]string{"SIMULATION_TIME_SCALE": "0.5"}))
+	require.NoError(t, err)
+	assert.Equal(t, "slow-kitchen", cfg.Simulation.Profile)
+	assert.Equal(t, 0.5, cfg.Simulation.TimeScale)
+
+	profiles := cfg.Profiles()
+	require.Len(t, profiles, 4)
+	assert.Equal(t, 30*time.Second, profiles[0].Timers.Cooking, "default follows status_timers")
+	slow := profiles[3]
+	assert.Equal(t, time.Minute, slow.Timers.Delivering)
+	assert.Equal(t, 30*time.Second, slow.Timers.Cooking, "zero timers come from status_timers")
+	assert.Equal(t, time.Hour, slow.Restaurants["r2"].Cooking)
+	assert.Equal(t, 5.0, slow.CancelPercent)
+
+	_, _, err = config.Load([]string{"-simulation.profile", "turbo", "-simulation.time_scale", "0"}, envOf(nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "simulation.time_scale must be positive")
+	assert.Contains(t, err.Error(), `unknown simulation profile "turbo"`)
+}
@@ -0,0 +1,259 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// field binds one config value to its environment variable and flag.
+type field struct {
+	key   string // dotted YAML path, also the flag name
+	env   string
+	usage string
+	set   func(string) error
+}
+
+func (c *Config) fields() []field {
+	return []field{
+		str("http.addr", "HTTP_ADDR", "HTTP listen address", &c.HTTP.Addr),
+		str("http.openapi_validation", "OPENAPI_VALIDATION", "OpenAPI validation: off, request, log, strict", &c.HTTP.OpenAPIValidation),
+		str("http.admin_token", "ADMIN_TOKEN", "X-Admin-Token of the /admin operations, empty disables them", &c.HTTP.AdminToken),
+		str("grpc.addr", "GRPC_ADDR", "gRPC listen address", &c.GRPC.Addr),
+		dur("worker.tick", "WORKER_TICK", "status worker tick", &c.Worker.Tick),
+		dur("status_timers.created", "STATUS_TIMER_CREATED", "time in created before pending", &c.StatusTimers.Created),
+		dur("status_timers.pending", "STATUS_TIMER_PENDING", "time in pending before confirmed", &c.StatusTimers.Pending),
+		dur("status_timers.confirmed", "STATUS_TIMER_CONFIRMED", "time in confirmed before cooking", &c.StatusTimers.Confirmed),
+		dur("status_timers.cooking", "STATUS_TIMER_COOKING", "time in cooking before delivering", &c.StatusTimers.Cooking),
+		dur("status_timers.delivering", "STATUS_TIMER_DELIVERING", "time in delivering before completed", &c.StatusTimers.Delivering),
+		str("simulation.profile", "SIMULATION_PROFILE", "status worker profile: default, fast, realistic or one of simulation.profiles", &c.Simulation.Profile),
+		fnum("simulation.time_scale", "SIMULATION_TIME_SCALE", "multiplier of every status timer, 0.1 runs ten times faster", &c.Simulation.TimeScale),
+		boolean("simulation.debug", "SIMULATION_DEBUG", "serve /debug/simulation to switch the profile at runtime, needs http.admin_token", &c.Simulation.Debug),
+		boolean("clock.virtual", "CLOCK_VIRTUAL", "use a virtual clock controlled at /debug/clock instead of the wall clock", &c.Clock.Virtual),
+		boolean("clock.frozen", "CLOCK_FROZEN", "start the virtual clock stopped", &c.Clock.Frozen),
+		str("clock.start", "CLOCK_START", "RFC 3339 time the virtual clock starts at, empty is now", &c.Clock.Start),
+		boolean("eta.enabled", "ETA_ENABLED", "fill and refresh estimated_delivery of orders", &c.ETA.Enabled),
+		dur("eta.tick", "ETA_TICK", "how often delivery estimates are recomputed", &c.ETA.Tick),
+		dur("eta.min_change", "ETA_MIN_CHANGE", "smallest estimate move that is stored and published", &c.ETA.MinChange),
+		dur("eta.profile.accept", "ETA_ACCEPT", "time until the kitchen accepts an order", &c.ETA.Profile.Accept),
+		dur("eta.profile.cook", "ETA_COOK", "cooking time of one unit", &c.ETA.Profile.Cook),
+		dur("eta.profile.per_item", "ETA_PER_ITEM", "cooking time of every further unit", &c.ETA.Profile.PerItem),
+		dur("eta.profile.per_queued", "ETA_PER_QUEUED", "wait for every order already in the kitchen", &c.ETA.Profile.PerQueued),
+		dur("eta.profile.pickup", "ETA_PICKUP", "courier time at the restaurant and at the door", &c.ETA.Profile.Pickup),
+		dur("eta.profile.ride", "ETA_RIDE", "courier ride when the distance is unknown", &c.ETA.Profile.Ride),
+		dur("eta.profile.per_km", "ETA_PER_KM", "courier ride per kilometre when the distance is known", &c.ETA.Profile.PerKm),
+		str("geo.geocoder", "GEO_GEOCODER", "geocoder of addresses without a location: none or fixture", &c.Geo.Geocoder),
+		str("geo.fixture", "GEO_FIXTURE", "JSON table of the fixture geocoder (empty: built-in)", &c.Geo.Fixture),
+		str("geo.zones", "GEO_ZONES", "GeoJSON file of restaurant delivery zones (empty: no limits)", &c.Geo.Zones),
+		dur("scheduling.lead", "SCHEDULING_LEAD", "how long before deliver_at an order goes to the kitchen without ETA", &c.Scheduling.Lead),
+		dur("scheduling.max_ahead", "SCHEDULING_MAX_AHEAD", "how far ahead an order can be scheduled", &c.Scheduling.MaxAhead),
+		str("opening_hours.time_zone", "OPENING_HOURS_TIME_ZONE", "IANA time zone of opening hours", &c.OpeningHours.TimeZone),
+		str("opening_hours.default", "OPENING_HOURS", "opening hours of restaurants, e.g. 10:00-23:00 (empty: always open)", &c.OpeningHours.Default),
+		str("repository.kind", "REPOSITORY_KIND", "order store: memory or eventsourced", &c.Repository.Kind),
+		num("repository.snapshot_every", "REPOSITORY_SNAPSHOT_EVERY", "events of an order between snapshots (eventsourced)", &c.Repository.SnapshotEvery),
+		list("kafka.brokers", "KAFKA_BROKERS", "comma-separated Kafka brokers, empty disables Kafka", &c.Kafka.Brokers),
+		boolean("kafka.in_memory", "KAFKA_IN_MEMORY", "use an in-process broker instead of kafka.brokers, inspected at /debug/kafka", &c.Kafka.InMemory),
+		str("kafka.topic", "KAFKA_ORDER_TOPIC", "topic for order events", &c.Kafka.Topic),
+		str("kafka.routing.mode", "KAFKA_ROUTING_MODE", "event routing: legacy, typed, both", &c.Kafka.Routing.Mode),
+		str("kafka.routing.created", "KAFKA_TOPIC_CREATED", "topic of created events", &c.Kafka.Routing.Created),
+		str("kafka.routing.updated", "KAFKA_TOPIC_UPDATED", "topic of updated events (user edits)", &c.Kafka.Routing.Updated),
+		str("kafka.routing.status_changed", "KAFKA_TOPIC_STATUS_CHANGED", "topic of automatic status changes", &c.Kafka.Routing.StatusChanged),
+		str("kafka.routing.canceled", "KAFKA_TOPIC_CANCELED", "topic of canceled events", &c.Kafka.Routing.Canceled),
+		str("kafka.routing.deleted", "KAFKA_TOPIC_DELETED", "topic of deleted events", &c.Kafka.Routing.Deleted),
+		str("kafka.routing.snapshot", "KAFKA_TOPIC_SNAPSHOT", "topic of replayed snapshots, empty uses kafka.routing.created", &c.Kafka.Routing.Snapshot),
+		str("kafka.routing.eta_changed", "KAFKA_TOPIC_ETA_CHANGED", "topic of delivery estimate changes, empty drops them", &c.Kafka.Routing.ETAChanged),
+		num("kafka.retry_max", "KAFKA_RETRY_MAX", "producer retries", &c.Kafka.RetryMax),
+		str("kafka.mode", "KAFKA_PRODUCER_MODE", "producer mode: sync or async", &c.Kafka.Mode),
+		str("kafka.compression", "KAFKA_COMPRESSION", "compression: none, gzip, snappy, lz4, zstd", &c.Kafka.Compression),
+		num("kafka.buffer", "KAFKA_BUFFER", "async: messages buffered before publishers block", &c.Kafka.Buffer),
+		num("kafka.batch_size", "KAFKA_BATCH_SIZE", "async: messages per batch, 0 flushes by linger only", &c.Kafka.BatchSize),
+		dur("kafka.linger", "KAFKA_LINGER", "async: how long a batch may wait before it is sent", &c.Kafka.Linger),
+		str("kafka.dead_letter_topic", "KAFKA_DEAD_LETTER_TOPIC", "async: topic for messages that failed all retries, empty drops them", &c.Kafka.DeadLetterTopic),
+		boolean("kafka.tls.enabled", "KAFKA_TLS_ENABLED", "connect to brokers over TLS", &c.Kafka.TLS.Enabled),
+		str("kafka.tls.ca_file", "KAFKA_TLS_CA_FILE", "PEM file with trusted CAs, empty uses the system pool", &c.Kafka.TLS.CAFile),
+		str("kafka.tls.cert_file", "KAFKA_TLS_CERT_FILE", "client certificate for mutual TLS", &c.Kafka.TLS.CertFile),
+		str("kafka.tls.key_file", "KAFKA_TLS_KEY_FILE", "client certificate key for mutual TLS", &c.Kafka.TLS.KeyFile),
+		boolean("kafka.tls.insecure_skip_verify", "KAFKA_TLS_INSECURE_SKIP_VERIFY", "do not verify broker certificates", &c.Kafka.TLS.InsecureSkipVerify),
+		str("kafka.sasl.mechanism", "KAFKA_SASL_MECHANISM", "SASL mechanism: PLAIN, SCRAM-SHA-256, SCRAM-SHA-512, empty disables SASL", &c.Kafka.SASL.Mechanism),
+		str("kafka.sasl.username", "KAFKA_SASL_USERNAME", "SASL username", &c.Kafka.SASL.Username),
+		str("kafka.sasl.password", "KAFKA_SASL_PASSWORD", "SASL password", &c.Kafka.SASL.Password),
+		str("kafka.topics.mode", "KAFKA_TOPICS_MODE", "topic admin step: off, check, create", &c.Kafka.Topics.Mode),
+		num("kafka.topics.partitions", "KAFKA_TOPICS_PARTITIONS", "partitions of created topics, minimum for checked ones", &c.Kafka.Topics.Partitions),
+		num("kafka.topics.replication_factor", "KAFKA_TOPICS_REPLICATION_FACTOR", "replication factor of topics", &c.Kafka.Topics.ReplicationFactor),
+		dur("kafka.topics.retention", "KAFKA_TOPICS_RETENTION", "retention of topics, 0 keeps the broker default", &c.Kafka.Topics.Retention),
+		num("seed.count", "SEED_COUNT", "orders created by the debug seed route unless the scenario sets a count", &c.Seed.Count),
+		str("seed.scenario", "SEED_SCENARIO", "scenario of the debug seed route: demo or one of seed.scenarios_dir", &c.Seed.Scenario),
+		str("seed.scenarios_dir", "SEED_SCENARIOS_DIR", "directory of seed scenario files (YAML or JSON)", &c.Seed.ScenariosDir),
+		num("outbox.buffer", "OUTBOX_BUFFER", "events queued before publishers block", &c.Outbox.Buffer),
+		num("replay.rate", "REPLAY_RATE", "default replay rate, events per second", &c.Replay.Rate),
+		num("replay.max_rate", "REPLAY_MAX_RATE", "highest replay rate a caller may ask for", &c.Replay.MaxRate),
+		dur("webhook.timeout", "WEBHOOK_TIMEOUT", "timeout of one webhook delivery attempt", &c.Webhook.Timeout),
+		num("webhook.max_attempts", "WEBHOOK_MAX_ATTEMPTS", "webhook delivery attempts before the dead letter", &c.Webhook.MaxAttempts),
+		dur("webhook.backoff", "WEBHOOK_BACKOFF", "delay after the first failed webhook attempt, doubled after each next one", &c.Webhook.Backoff),
+		dur("webhook.max_backoff", "WEBHOOK_MAX_BACKOFF", "longest delay between webhook attempts", &c.Webhook.MaxBackoff),
+		num("webhook.workers", "WEBHOOK_WORKERS", "webhook deliveries sent at once", &c.Webhook.Workers),
+		num("webhook.log_size", "WEBHOOK_LOG_SIZE", "finished webhook deliveries kept for inspection", &c.Webhook.LogSize),
+		num("webhook.max_pending", "WEBHOOK_MAX_PENDING", "webhook deliveries of one subscription waiting for an attempt before the oldest go dead", &c.Webhook.MaxPending),
+		list("webhook.allowed_hosts", "WEBHOOK_ALLOWED_HOSTS", "comma-separated webhook target hosts, subdomains included; empty allows any", &c.Webhook.AllowedHosts),
+		boolean("webhook.allow_private", "WEBHOOK_ALLOW_PRIVATE", "allow webhook targets on loopback, private and link-local addresses", &c.Webhook.AllowPrivate),
+		dur("shutdown.timeout", "SHUTDOWN_TIMEOUT", "graceful shutdown timeout", &c.Shutdown.Timeout),
+		dur("health.check_timeout", "HEALTH_CHECK_TIMEOUT", "timeout of each health check", &c.Health.CheckTimeout),
+		dur("health.worker_max_age", "HEALTH_WORKER_MAX_AGE", "oldest acceptable status worker heartbeat", &c.Health.WorkerMaxAge),
+		str("log.level", "LOG_LEVEL", "log level: debug, info, warn, error", &c.Log.Level),
+		str("log.format", "LOG_FORMAT", "log format: json or text", &c.Log.Format),
+		str("tracing.exporter", "TRACING_EXPORTER", "trace exporter: none, stdout, otlp", &c.Tracing.Exporter),
+		str("tracing.otlp_endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTLP/HTTP collector URL", &c.Tracing.OTLPEndpoint),
+		dur("health.outbox_max_lag", "HEALTH_OUTBOX_MAX_LAG", "outbox lag after which the service is not ready", &c.Health.OutboxMaxLag),
+	}
+}
+
+// Load builds the config from defaults, the YAML file, env and flags.
+// With -print-config it returns printOnly=true so the caller can exit after
+// printing the effective config.
+func Load(args []string, getenv func(string) string) (cfg *Config, printOnly bool, err error) {
+	c := Default()
+	fields := c.fields()
+
+	fs := flag.NewFlagSet("service", flag.ContinueOnError)
+	path := fs.String("config", getenv("CONFIG_FILE"), "YAML config file")
+	printCfg := fs.Bool("print-config", false, "print the effective config and exit")
+	raw := make(map[string]*string, len(fields))
+	for _, f := range fields {
+		raw[f.key] = fs.String(f.key, "", f.usage+" (env "+f.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
+	}
+
+	if *path != "" {
+		b, err := os.ReadFile(*path)
+		if err != nil {
+			return nil, false, fmt.Errorf("read config: %w", err)
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(&c); err != nil {
+			return nil, false, fmt.Errorf("parse %s: %w", *path, err)
+		}
+	}
+
+	// PORT is what docker-compose and most PaaS set.
+	if port := getenv("PORT"); port != "" && getenv("HTTP_ADDR") == "" {
+		c.HTTP.Addr = ":" + port
+	}
+	for _, f := range fields {
+		if v := getenv(f.env); v != "" {
+			if err := f.set(v); err != nil {
+				return nil, false, fmt.Errorf("env %s: %w", f.env, err)
+			}
+		}
+	}
+
+	for _, f := range fields {
+		if isSet(fs, f.key) {
+			if err := f.set(*raw[f.key]); err != nil {
+				return nil, false, fmt.Errorf("flag -%s: %w", f.key, err)
+			}
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, false, fmt.Errorf("invalid config:\n%w", err)
+	}
+	return &c, *printCfg, nil
+}
+
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// String renders the effective config as YAML with secrets redacted.
+func (c Config) String() string {
+	if c.Kafka.SASL.Password != "" {
+		c.Kafka.SASL.Password = "<redacted>"
+	}
+	if c.HTTP.AdminToken != "" {
+		c.HTTP.AdminToken = "<redacted>"
+	}
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
+
+func str(key, env, usage string, p *string) field {
+	return field{key: key, env: env, usage: usage, set: func(v string) error {
+		*p = v
+		return nil
+	}}
+}
+
+func dur(key, env, usage string, p *time.Duration) field {
+	return field{key: key, env: env, usage: usage, set: func(v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*p = d
+		return nil
+	}}
+}
+
+func num(key, env, usage string, p *int) field {
+	return field{key: key, env: env, usage: usage, set: func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*p = n
+		return nil
+	}}
+}
+
+func fnum(key, env, usage string, p *float64) field {
+	return field{key: key, env: env, usage: usage, set: func(v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		*p = f
+		return nil
+	}}
+}
+
+func boolean(key, env, usage string, p *bool) field {
+	return field{key: key, env: env, usage: usage, set: func(v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*p = b
+		return nil
+	}}
+}
+
+func list(key, env, usage string, p *[]string) field {
+	return field{key: key, env: env, usage: usage, set: func(v string) error {
+		var out []string
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+		*p = out
+		return nil
+	}}
+}
@@ -0,0 +1,22 @@
+// Package debughttp holds the response helpers shared by the /debug
+// handlers.
+package debughttp
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// JSON writes v with the status; debug state changes all the time, so the
+// response is never cached.
+func JSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// Error writes {"error": err} with the status.
+func Error(w http.ResponseWriter, status int, err error) {
+	JSON(w, status, map[string]string{"error": err.Error()})
+}
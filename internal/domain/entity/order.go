@@ -15,6 +15,9 @@ const (
 	OrderStatusCreated    OrderStatus = "created"
 	OrderStatusUpdated    OrderStatus = "updated"
 	OrderStatusCompleted  OrderStatus = "completed"
+	// OrderStatusScheduled holds an order until PrepareFrom, when it enters
+	// the lifecycle as created.
+	OrderStatusScheduled OrderStatus = "scheduled"
 )
 
 type Item struct {
@@ -30,6 +33,26 @@ type DeliveryAddress struct {
 	Apartment string
 	Floor     string
 	Comment   string
+	// Location is nil until the address is geocoded or the client sends it.
+	Location *GeoPoint
+}
+
+// Equal compares the addresses by value, locations included.
+func (a DeliveryAddress) Equal(b DeliveryAddress) bool {
+	la, lb := a.Location, b.Location
+	a.Location, b.Location = nil, nil
+	return a == b && (la == lb || la != nil && lb != nil && *la == *lb)
+}
+
+// GeoPoint is a WGS 84 position in degrees.
+type GeoPoint struct {
+	Lat float64
+	Lon float64
+}
+
+// Valid reports whether the coordinates are in range.
+func (p GeoPoint) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lon >= -180 && p.Lon <= 180
 }
 
 type Order struct {
@@ -46,5 +69,10 @@ type Order struct {
 	UpdatedAt         time.Time
 	EstimatedDelivery time.Time
 	StatusChangedAt   time.Time
-	IsDeleted         bool
+	// DeliverAt is the delivery time the customer asked for, PrepareFrom
+	// when a scheduled order goes to the kitchen; both are zero for an
+	// order placed for now.
+	DeliverAt   time.Time
+	PrepareFrom time.Time
+	IsDeleted   bool
 }
@@ -0,0 +1,208 @@
+// Package eta estimates when an order is delivered. An estimate adds up
+// what is left of the current stage and the stages after it, using the
+// preparation profile of the restaurant, the time the order has already
+// spent in its status, the kitchen queue of the restaurant and, when known,
+// the distance to the address.
+package eta
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+// Profile is how long a restaurant takes for each stage of an order.
+type Profile struct {
+	// Accept is the time from placing the order until the kitchen takes it.
+	Accept time.Duration
+	// Cook is the cooking time of one unit; PerItem is added for every
+	// further unit of the order.
+	Cook    time.Duration
+	PerItem time.Duration
+	// PerQueued is the wait for each order already in the kitchen.
+	PerQueued time.Duration
+	// Pickup is the courier's time at both ends; Ride is the trip when the
+	// distance is unknown, PerKm when it is known.
+	Pickup time.Duration
+	Ride   time.Duration
+	PerKm  time.Duration
+}
+
+// DefaultProfile is an average city restaurant.
+var DefaultProfile = Profile{
+	Accept:    2 * time.Minute,
+	Cook:      15 * time.Minute,
+	PerItem:   time.Minute,
+	PerQueued: 2 * time.Minute,
+	Pickup:    5 * time.Minute,
+	Ride:      15 * time.Minute,
+	PerKm:     3 * time.Minute,
+}
+
+func (p Profile) validate() error {
+	if p.Accept < 0 || p.Cook <= 0 || p.PerItem < 0 || p.PerQueued < 0 || p.Pickup < 0 || p.Ride < 0 || p.PerKm < 0 {
+		return fmt.Errorf("cook must be positive and other durations not negative, got %+v", p)
+	}
+	return nil
+}
+
+// merge returns base with the non-zero fields of over.
+func merge(base, over Profile) Profile {
+	return Profile{
+		Accept:    cmp.Or(over.Accept, base.Accept),
+		Cook:      cmp.Or(over.Cook, base.Cook),
+		PerItem:   cmp.Or(over.PerItem, base.PerItem),
+		PerQueued: cmp.Or(over.PerQueued, base.PerQueued),
+		Pickup:    cmp.Or(over.Pickup, base.Pickup),
+		Ride:      cmp.Or(over.Ride, base.Ride),
+		PerKm:     cmp.Or(over.PerKm, base.PerKm),
+	}
+}
+
+// Distance tells how far the address is from the restaurant; ok is false
+// when that is not known.
+type Distance interface {
+	Km(ctx context.Context, restaurantID string, a entity.DeliveryAddress) (km float64, ok bool)
+}
+
+// Once an order has spent a whole stage in it, 1/overdueShare of the stage
+// is assumed to be left, so that a late kitchen moves the estimate forward
+// instead of into the past.
+const overdueShare = 5
+
+// Estimator is safe for concurrent use.
+type Estimator struct {
+	def         Profile
+	restaurants map[string]Profile
+	distance    Distance
+
+	mu     sync.RWMutex
+	queues map[string]int // orders in the kitchen per restaurant
+}
+
+// New validates the profiles; restaurant profiles override def field by
+// field. distance may be nil.
+func New(def Profile, restaurants map[string]Profile, distance Distance) (*Estimator, error) {
+	errs := []error{def.validate()}
+	merged := make(map[string]Profile, len(restaurants))
+	for id, p := range restaurants {
+		merged[id] = merge(def, p)
+		if err := merged[id].validate(); err != nil {
+			errs = append(errs, fmt.Errorf("restaurant %s: %w", id, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return &Estimator{def: def, restaurants: merged, distance: distance, queues: map[string]int{}}, nil
+}
+
+func (e *Estimator) profile(restaurantID string) Profile {
+	if p, ok := e.restaurants[restaurantID]; ok {
+		return p
+	}
+	return e.def
+}
+
+// inKitchen reports whether the kitchen has accepted o and not yet handed
+// it to a courier.
+func inKitchen(o *entity.Order) bool {
+	return !o.IsDeleted && (o.Status == entity.OrderStatusConfirmed || o.Status == entity.OrderStatusCooking)
+}
+
+// Observe recounts the kitchen queues from every live order; estimates use
+// the counts of the last call.
+func (e *Estimator) Observe(orders []*entity.Order) {
+	queues := map[string]int{}
+	for _, o := range orders {
+		if inKitchen(o) {
+			queues[o.RestaurantID]++
+		}
+	}
+	e.mu.Lock()
+	e.queues = queues
+	e.mu.Unlock()
+}
+
+// Queue returns how many orders the restaurant's kitchen has.
+func (e *Estimator) Queue(restaurantID string) int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.queues[restaurantID]
+}
+
+// Estimate returns the delivery time of o as of now, to the second. A
+// scheduled order starts at its PrepareFrom, a completed one was delivered
+// when it completed; canceled and deleted orders have no estimate.
+func (e *Estimator) Estimate(ctx context.Context, o *entity.Order, now time.Time) (time.Time, bool) {
+	p := e.profile(o.RestaurantID)
+	since := o.StatusChangedAt
+	if since.IsZero() {
+		since = o.CreatedAt
+	}
+	elapsed := max(now.Sub(since), 0)
+
+	ahead := e.Queue(o.RestaurantID)
+	if inKitchen(o) {
+		ahead--
+	}
+	wait := p.PerQueued * time.Duration(max(ahead, 0))
+	cook := p.Cook + p.PerItem*time.Duration(max(units(o.Items)-1, 0))
+	deliver := p.Pickup + e.ride(ctx, p, o)
+
+	var left time.Duration
+	switch o.Status {
+	case entity.OrderStatusCreated, entity.OrderStatusPending, entity.OrderStatusUpdated:
+		left = remaining(p.Accept, elapsed) + wait + cook + deliver
+	case entity.OrderStatusConfirmed:
+		left = remaining(wait, elapsed) + cook + deliver
+	case entity.OrderStatusCooking:
+		left = remaining(cook, elapsed) + deliver
+	case entity.OrderStatusDelivering:
+		left = remaining(deliver, elapsed)
+	case entity.OrderStatusScheduled:
+		// Today's queue says nothing about the kitchen at PrepareFrom; the
+		// time the customer asked for stands unless it cannot be made.
+		start := o.PrepareFrom
+		if start.Before(now) {
+			start = now
+		}
+		at := start.Add(p.Accept + cook + deliver)
+		if o.DeliverAt.After(at) {
+			at = o.DeliverAt
+		}
+		return at.UTC().Truncate(time.Second), true
+	case entity.OrderStatusCompleted, entity.OrderStatusDelivered:
+		return since.UTC().Truncate(time.Second), true
+	default:
+		return time.Time{}, false
+	}
+	return now.Add(left).UTC().Truncate(time.Second), true
+}
+
+func (e *Estimator) ride(ctx context.Context, p Profile, o *entity.Order) time.Duration {
+	if e.distance != nil {
+		if km, ok := e.distance.Km(ctx, o.RestaurantID, o.Address); ok {
+			return time.Duration(km * float64(p.PerKm))
+		}
+	}
+	return p.Ride
+}
+
+// remaining is what is left of a stage of length d after elapsed.
+func remaining(d, elapsed time.Duration) time.Duration {
+	return max(d-elapsed, d/overdueShare)
+}
+
+func units(items []entity.Item) int {
+	n := 0
+	for _, it := range items {
+		n += max(it.Quantity, 1)
+	}
+	return n
+}
@@ -0,0 +1,114 @@
+package eta_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/eta"
+)
+
+var t0 = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+var profile = eta.Profile{
+	Accept:    2 * time.Minute,
+	Cook:      10 * time.Minute,
+	PerItem:   time.Minute,
+	PerQueued: 3 * time.Minute,
+	Pickup:    5 * time.Minute,
+	Ride:      15 * time.Minute,
+	PerKm:     2 * time.Minute,
+}
+
+type fixedKm map[string]float64
+
+func (f fixedKm) Km(_ context.Context, _ string, a entity.DeliveryAddress) (float64, bool) {
+	km, ok := f[a.Street]
+	return km, ok
+}
+
+func order(id, restaurant string, status entity.OrderStatus, since time.Time, units int) *entity.Order {
+	return &entity.Order{
+		ID:              id,
+		RestaurantID:    restaurant,
+		Status:          status,
+		CreatedAt:       since,
+		StatusChangedAt: since,
+		Items:           []entity.Item{{FoodID: "f1", Quantity: units}},
+		Address:         entity.DeliveryAddress{Street: "Main"},
+	}
+}
+
+func TestEstimator_Stages(t *testing.T) {
+	e, err := eta.New(profile, nil, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+	estimate := func(o *entity.Order, now time.Time) time.Duration {
+		t.Helper()
+		at, ok := e.Estimate(ctx, o, now)
+		require.True(t, ok)
+		return at.Sub(t0)
+	}
+
+	// accept 2 + cook 10 + 2 more units 2 + pickup 5 + ride 15
+	assert.Equal(t, 34*time.Minute, estimate(order("o1", "r1", entity.OrderStatusPending, t0, 3), t0))
+	assert.Equal(t, 34*time.Minute, estimate(order("o1", "r1", entity.OrderStatusPending, t0, 3), t0.Add(time.Minute)),
+		"time spent in the stage is not counted twice")
+	assert.Equal(t, 40*time.Minute, estimate(order("o1", "r1", entity.OrderStatusCooking, t0, 1), t0.Add(18*time.Minute)),
+		"an overdue stage keeps a fifth of it")
+	assert.Equal(t, 20*time.Minute, estimate(order("o1", "r1", entity.OrderStatusDelivering, t0, 1), t0))
+
+	done := order("o1", "r1", entity.OrderStatusCompleted, t0.Add(40*time.Minute), 1)
+	assert.Equal(t, 40*time.Minute, estimate(done, t0.Add(time.Hour)), "a completed order was delivered when it completed")
+
+	later := order("o1", "r1", entity.OrderStatusScheduled, t0, 1)
+	later.PrepareFrom = t0.Add(2 * time.Hour)
+	assert.Equal(t, 2*time.Hour+32*time.Minute, estimate(later, t0), "the whole lifecycle from prepare_from")
+	later.DeliverAt = t0.Add(3 * time.Hour)
+	assert.Equal(t, 3*time.Hour, estimate(later, t0), "the asked time when the kitchen makes it")
+
+	_, ok := e.Estimate(ctx, order("o1", "r1", entity.OrderStatusCanceled, t0, 1), t0)
+	assert.False(t, ok)
+}
+
+func TestEstimator_QueueAndDistance(t *testing.T) {
+	e, err := eta.New(profile, map[string]eta.Profile{"slow": {Cook: 30 * time.Minute}}, fixedKm{"Main": 4})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	e.Observe([]*entity.Order{
+		order("a", "r1", entity.OrderStatusCooking, t0, 1),
+		order("b", "r1", entity.OrderStatusConfirmed, t0, 1),
+		order("c", "r1", entity.OrderStatusPending, t0, 1),
+		order("d", "r2", entity.OrderStatusCooking, t0, 1),
+	})
+	assert.Equal(t, 2, e.Queue("r1"))
+	assert.Equal(t, 1, e.Queue("r2"))
+
+	// accept 2 + 2 queued × 3 + cook 10 + pickup 5 + 4 km × 2
+	at, _ := e.Estimate(ctx, order("c", "r1", entity.OrderStatusPending, t0, 1), t0)
+	assert.Equal(t, 31*time.Minute, at.Sub(t0))
+	// b waits for a only
+	at, _ = e.Estimate(ctx, order("b", "r1", entity.OrderStatusConfirmed, t0, 1), t0)
+	assert.Equal(t, 26*time.Minute, at.Sub(t0))
+
+	// the restaurant's profile replaces cook only
+	at, _ = e.Estimate(ctx, order("x", "slow", entity.OrderStatusCooking, t0, 1), t0)
+	assert.Equal(t, 43*time.Minute, at.Sub(t0))
+
+	far := order("y", "r3", entity.OrderStatusDelivering, t0, 1)
+	far.Address.Street = "Unknown"
+	at, _ = e.Estimate(ctx, far, t0)
+	assert.Equal(t, 20*time.Minute, at.Sub(t0), "the ride without a distance")
+}
+
+func TestNew_Validates(t *testing.T) {
+	_, err := eta.New(eta.Profile{}, nil, nil)
+	assert.ErrorContains(t, err, "cook must be positive")
+	_, err = eta.New(eta.DefaultProfile, map[string]eta.Profile{"r1": {Pickup: -time.Minute}}, nil)
+	assert.ErrorContains(t, err, "restaurant r1")
+}
@@ -0,0 +1,100 @@
+package broadcast
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+// subscriberBuffer is how many pending changes a slow subscriber may hold
+// before the hub gives up on it.
+const subscriberBuffer = 16
+
+// Hub fans order changes out to in-process subscribers (e.g. gRPC WatchOrder
+// streams). It implements the producer contract so it can sit next to Kafka.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan *entity.Order]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[string]map[chan *entity.Order]struct{})}
+}
+
+// Subscribe returns a channel with changes of the given order and a function
+// that must be called to release the subscription. A subscriber that falls
+// subscriberBuffer changes behind is dropped: its channel is closed after the
+// pending changes, so it ends instead of silently missing one.
+func (h *Hub) Subscribe(orderID string) (<-chan *entity.Order, func()) {
+	ch := make(chan *entity.Order, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subs[orderID] == nil {
+		h.subs[orderID] = make(map[chan *entity.Order]struct{})
+	}
+	h.subs[orderID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if _, ok := h.subs[orderID][ch]; ok {
+				h.drop(orderID, ch)
+			}
+		})
+	}
+}
+
+func (h *Hub) OrderCreated(_ context.Context, o *entity.Order) error {
+	h.publish(o)
+	return nil
+}
+
+func (h *Hub) OrderUpdated(_ context.Context, o *entity.Order) error {
+	h.publish(o)
+	return nil
+}
+
+func (h *Hub) OrderStatusChanged(_ context.Context, o *entity.Order) error {
+	h.publish(o)
+	return nil
+}
+
+func (h *Hub) OrderDeleted(_ context.Context, id string, userID string) error {
+	h.publish(&entity.Order{ID: id, UserID: userID, Status: entity.OrderStatusDeleted, IsDeleted: true})
+	return nil
+}
+
+// OrderSnapshot is ignored: a replay does not change any order, so watchers
+// have nothing new to see.
+func (h *Hub) OrderSnapshot(context.Context, *entity.Order, string) error { return nil }
+
+func (h *Hub) OrderETAChanged(_ context.Context, o *entity.Order) error {
+	h.publish(o)
+	return nil
+}
+
+func (h *Hub) publish(o *entity.Order) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[o.ID] {
+		cp := *o
+		select {
+		case ch <- &cp:
+		default:
+			h.drop(o.ID, ch)
+		}
+	}
+}
+
+// drop removes a subscription and closes its channel; h.mu must be held.
+func (h *Hub) drop(orderID string, ch chan *entity.Order) {
+	delete(h.subs[orderID], ch)
+	if len(h.subs[orderID]) == 0 {
+		delete(h.subs, orderID)
+	}
+	close(ch)
+}
@@ -0,0 +1,39 @@
+package broadcast_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/gateway/broadcast"
+)
+
+func TestHub_DropsSlowSubscriber(t *testing.T) {
+	hub := broadcast.NewHub()
+	slow, cancelSlow := hub.Subscribe("o1")
+	defer cancelSlow()
+
+	for i := 0; i < 17; i++ {
+		require.NoError(t, hub.OrderUpdated(context.Background(), &entity.Order{ID: "o1", TotalPrice: int64(i)}))
+	}
+
+	// The buffered changes are still delivered, then the channel ends.
+	n := 0
+	for range slow {
+		n++
+	}
+	assert.Equal(t, 16, n)
+
+	// A new subscriber is not affected by the dropped one.
+	fresh, cancelFresh := hub.Subscribe("o1")
+	require.NoError(t, hub.OrderUpdated(context.Background(), &entity.Order{ID: "o1"}))
+	o, ok := <-fresh
+	require.True(t, ok)
+	assert.Equal(t, "o1", o.ID)
+	cancelFresh()
+	_, ok = <-fresh
+	assert.False(t, ok)
+}
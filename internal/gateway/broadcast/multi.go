@@ -0,0 +1,69 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+type Producer interface {
+	OrderCreated(ctx context.Context, o *entity.Order) error
+	OrderUpdated(ctx context.Context, o *entity.Order) error
+	OrderStatusChanged(ctx context.Context, o *entity.Order) error
+	OrderDeleted(ctx context.Context, id string, userID string) error
+	OrderSnapshot(ctx context.Context, o *entity.Order, replayID string) error
+	OrderETAChanged(ctx context.Context, o *entity.Order) error
+}
+
+// Multi calls every producer in order and joins their errors, so one failing
+// sink does not stop the others from receiving the event.
+type Multi []Producer
+
+func (m Multi) OrderCreated(ctx context.Context, o *entity.Order) error {
+	var errs []error
+	for _, p := range m {
+		errs = append(errs, p.OrderCreated(ctx, o))
+	}
+	return errors.Join(errs...)
+}
+
+func (m Multi) OrderUpdated(ctx context.Context, o *entity.Order) error {
+	var errs []error
+	for _, p := range m {
+		errs = append(errs, p.OrderUpdated(ctx, o))
+	}
+	return errors.Join(errs...)
+}
+
+func (m Multi) OrderStatusChanged(ctx context.Context, o *entity.Order) error {
+	var errs []error
+	for _, p := range m {
+		errs = append(errs, p.OrderStatusChanged(ctx, o))
+	}
+	return errors.Join(errs...)
+}
+
+func (m Multi) OrderDeleted(ctx context.Context, id string, userID string) error {
+	var errs []error
+	for _, p := range m {
+		errs = append(errs, p.OrderDeleted(ctx, id, userID))
+	}
+	return errors.Join(errs...)
+}
+
+func (m Multi) OrderSnapshot(ctx context.Context, o *entity.Order, replayID string) error {
+	var errs []error
+	for _, p := range m {
+		errs = append(errs, p.OrderSnapshot(ctx, o, replayID))
+	}
+	return errors.Join(errs...)
+}
+
+func (m Multi) OrderETAChanged(ctx context.Context, o *entity.Order) error {
+	var errs []error
+	for _, p := range m {
+		errs = append(errs, p.OrderETAChanged(ctx, o))
+	}
+	return errors.Join(errs...)
+}
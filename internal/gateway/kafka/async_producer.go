@@ -0,0 +1,266 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+// Headers added to messages routed to the dead-letter topic.
+const (
+	HeaderOriginalTopic = "x-original-topic"
+	HeaderError         = "x-error"
+)
+
+// ErrClosed is returned for events published after Close.
+var ErrClosed = errors.New("kafka producer is closed")
+
+// metric receives delivery outcomes of the async producer.
+type metric interface {
+	ObserveDelivery(topic string, d time.Duration, err error)
+	IncDeadLettered(topic string)
+}
+
+type noopMetric struct{}
+
+func (noopMetric) ObserveDelivery(string, time.Duration, error) {}
+func (noopMetric) IncDeadLettered(string)                       {}
+
+// AsyncProducer publishes order events without waiting for the broker: an
+// event is queued into a bounded buffer and sent in batches, its delivery is
+// reported later on the sarama success and error channels. Sarama retries
+// each message RetryMax times; a message that still fails is routed to the
+// dead-letter topic with the original topic and error in its headers.
+//
+// The producer span of an event stays open until the broker acknowledges it.
+type AsyncProducer struct {
+	client sarama.Client
+	p      sarama.AsyncProducer
+	cfg    Config
+	dlt    string
+	tracer trace.Tracer
+	metric metric
+
+	mu       sync.Mutex
+	closed   bool
+	pending  sync.WaitGroup // events not delivered or dropped yet
+	inFlight atomic.Int64
+	handlers sync.WaitGroup
+}
+
+// delivery travels with a message in ProducerMessage.Metadata.
+type delivery struct {
+	ctx        context.Context
+	span       trace.Span
+	start      time.Time
+	deadLetter bool
+}
+
+// NewAsyncProducer connects to the brokers. m may be nil.
+func NewAsyncProducer(c Config, m metric) (*AsyncProducer, error) {
+	cfg, err := c.sarama()
+	if err != nil {
+		return nil, err
+	}
+	if c.Buffer > 0 {
+		cfg.ChannelBufferSize = c.Buffer
+	}
+	cfg.Producer.Flush.Messages = c.BatchSize
+	cfg.Producer.Flush.Frequency = c.Linger
+	// One request per broker at a time keeps retried batches in order.
+	cfg.Net.MaxOpenRequests = 1
+
+	client, err := newClient(c.Brokers, cfg)
+	if err != nil {
+		return nil, err
+	}
+	prod, err := sarama.NewAsyncProducerFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	a := NewAsyncProducerFrom(prod, c, m)
+	a.client = client
+	return a, nil
+}
+
+// NewAsyncProducerFrom builds a producer on top of an existing async
+// producer, e.g. sarama/mocks in tests. p must return successes and errors.
+func NewAsyncProducerFrom(p sarama.AsyncProducer, c Config, m metric) *AsyncProducer {
+	if m == nil {
+		m = noopMetric{}
+	}
+	a := &AsyncProducer{p: p, cfg: c, dlt: c.DeadLetterTopic, tracer: c.tracer(), metric: m}
+	a.handlers.Add(2)
+	go a.handleSuccesses()
+	go a.handleErrors()
+	return a
+}
+
+// Ping refreshes the metadata of the routed topics, see SaramaProducer.Ping.
+func (a *AsyncProducer) Ping(context.Context) error {
+	return ping(a.client, a.cfg.RoutedTopics()...)
+}
+
+// InFlight returns how many events are queued or awaiting acknowledgement.
+func (a *AsyncProducer) InFlight() int {
+	return int(a.inFlight.Load())
+}
+
+// Close stops accepting events, waits until every queued event is delivered
+// or dead-lettered and then shuts the producer down.
+func (a *AsyncProducer) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.mu.Unlock()
+
+	a.pending.Wait()
+	a.p.AsyncClose()
+	a.handlers.Wait()
+	if a.client != nil {
+		return a.client.Close()
+	}
+	return nil
+}
+
+func (a *AsyncProducer) OrderCreated(ctx context.Context, o *entity.Order) error {
+	return a.send(ctx, eventOf(EventCreated, o))
+}
+
+func (a *AsyncProducer) OrderUpdated(ctx context.Context, o *entity.Order) error {
+	return a.send(ctx, eventOf(EventUpdated, o))
+}
+
+func (a *AsyncProducer) OrderStatusChanged(ctx context.Context, o *entity.Order) error {
+	return a.send(ctx, eventOf(EventStatusChanged, o))
+}
+
+func (a *AsyncProducer) OrderDeleted(ctx context.Context, id string, userID string) error {
+	return a.send(ctx, event{typ: EventDeleted, id: id, userID: userID})
+}
+
+func (a *AsyncProducer) OrderSnapshot(ctx context.Context, o *entity.Order, replayID string) error {
+	return a.send(ctx, snapshotOf(o, replayID))
+}
+
+func (a *AsyncProducer) OrderETAChanged(ctx context.Context, o *entity.Order) error {
+	return a.send(ctx, eventOf(EventETAChanged, o))
+}
+
+// send blocks only while the buffer is full.
+func (a *AsyncProducer) send(ctx context.Context, ev event) error {
+	for _, m := range a.cfg.route(ev) {
+		if err := a.enqueue(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *AsyncProducer) enqueue(ctx context.Context, m outMessage) error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return ErrClosed
+	}
+	a.add()
+	a.mu.Unlock()
+
+	msg, span := startPublish(ctx, a.tracer, m)
+	msg.Metadata = &delivery{ctx: ctx, span: span, start: time.Now()}
+	select {
+	case a.p.Input() <- msg:
+		return nil
+	case <-ctx.Done():
+		endPublish(span, 0, 0, ctx.Err())
+		a.done()
+		return ctx.Err()
+	}
+}
+
+func (a *AsyncProducer) add() {
+	a.pending.Add(1)
+	a.inFlight.Add(1)
+}
+
+func (a *AsyncProducer) done() {
+	a.inFlight.Add(-1)
+	a.pending.Done()
+}
+
+func (a *AsyncProducer) handleSuccesses() {
+	defer a.handlers.Done()
+	for msg := range a.p.Successes() {
+		d := msg.Metadata.(*delivery)
+		endPublish(d.span, msg.Partition, msg.Offset, nil)
+		a.metric.ObserveDelivery(msg.Topic, time.Since(d.start), nil)
+		a.done()
+	}
+}
+
+func (a *AsyncProducer) handleErrors() {
+	defer a.handlers.Done()
+	for pe := range a.p.Errors() {
+		msg := pe.Msg
+		d := msg.Metadata.(*delivery)
+		endPublish(d.span, 0, 0, pe.Err)
+		a.metric.ObserveDelivery(msg.Topic, time.Since(d.start), pe.Err)
+
+		if d.deadLetter || a.dlt == "" {
+			slog.ErrorContext(d.ctx, "kafka: event lost", "topic", msg.Topic, "error", pe.Err)
+			a.done()
+			continue
+		}
+		a.deadLetter(d, msg, pe.Err)
+		a.done()
+	}
+}
+
+// deadLetter resends a failed message to the dead-letter topic. It is called
+// while the original message is still pending, so Close waits for it too.
+func (a *AsyncProducer) deadLetter(d *delivery, failed *sarama.ProducerMessage, cause error) {
+	slog.WarnContext(d.ctx, "kafka: event dead-lettered", "topic", failed.Topic, "dead_letter_topic", a.dlt, "error", cause)
+	a.metric.IncDeadLettered(failed.Topic)
+
+	ctx, span := a.tracer.Start(d.ctx, a.dlt+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypePublish,
+			semconv.MessagingDestinationName(a.dlt),
+			attribute.String("kafka.original_topic", failed.Topic),
+		))
+	headers := make([]sarama.RecordHeader, 0, len(failed.Headers)+2)
+	headers = append(headers, failed.Headers...)
+	headers = append(headers,
+		sarama.RecordHeader{Key: []byte(HeaderOriginalTopic), Value: []byte(failed.Topic)},
+		sarama.RecordHeader{Key: []byte(HeaderError), Value: []byte(cause.Error())},
+	)
+	msg := &sarama.ProducerMessage{
+		Topic:    a.dlt,
+		Key:      failed.Key,
+		Value:    failed.Value,
+		Headers:  headers,
+		Metadata: &delivery{ctx: ctx, span: span, start: time.Now(), deadLetter: true},
+	}
+	InjectTraceContext(ctx, msg)
+	a.add()
+	// Not inline: sarama may be blocked delivering the next error to this
+	// handler while the input buffer is full.
+	go func() { a.p.Input() <- msg }()
+}
@@ -0,0 +1,174 @@
+package kafka_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/gateway/kafka"
+	"github.com/nikolaev/service-order/internal/tracing"
+)
+
+const deadLetterTopic = topic + ".dlq"
+
+type recordingMetric struct {
+	mu           sync.Mutex
+	delivered    map[string]int
+	failed       map[string]int
+	deadLettered map[string]int
+}
+
+func newRecordingMetric() *recordingMetric {
+	return &recordingMetric{delivered: map[string]int{}, failed: map[string]int{}, deadLettered: map[string]int{}}
+}
+
+func (m *recordingMetric) ObserveDelivery(topic string, _ time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.failed[topic]++
+		return
+	}
+	m.delivered[topic]++
+}
+
+func (m *recordingMetric) IncDeadLettered(topic string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deadLettered[topic]++
+}
+
+func mockAsync(t *testing.T) *mocks.AsyncProducer {
+	cfg := mocks.NewTestConfig()
+	cfg.Producer.Return.Successes = true
+	return mocks.NewAsyncProducer(t, cfg)
+}
+
+func TestAsyncProducer_DeliversInOrderPerOrder(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	m := newRecordingMetric()
+
+	ap := mockAsync(t)
+	var mu sync.Mutex
+	var statuses []string
+	for range 3 {
+		ap.ExpectInputWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+			key, _ := msg.Key.Encode()
+			if string(key) != "o1" {
+				return errors.New("message key must be the order id, got " + string(key))
+			}
+			value, _ := msg.Value.Encode()
+			var ev struct{ Status string }
+			if err := json.Unmarshal(value, &ev); err != nil {
+				return err
+			}
+			mu.Lock()
+			statuses = append(statuses, ev.Status)
+			mu.Unlock()
+			return nil
+		})
+	}
+	p := kafka.NewAsyncProducerFrom(ap, kafka.Config{Topic: topic, TracerProvider: tp}, m)
+
+	ctx := context.Background()
+	require.NoError(t, p.OrderCreated(ctx, &entity.Order{ID: "o1", Status: entity.OrderStatusCreated}))
+	require.NoError(t, p.OrderUpdated(ctx, &entity.Order{ID: "o1", Status: entity.OrderStatusPending}))
+	require.NoError(t, p.OrderDeleted(ctx, "o1", "u1"))
+	require.NoError(t, p.Close())
+
+	assert.Equal(t, []string{"created", "pending", "deleted"}, statuses)
+	assert.Equal(t, 0, p.InFlight())
+	assert.Equal(t, 3, m.delivered[topic])
+	// Spans end on acknowledgement and carry the offset.
+	require.Len(t, rec.Ended(), 3)
+	for _, s := range rec.Ended() {
+		assert.Equal(t, codes.Unset, s.Status().Code)
+		keys := map[string]bool{}
+		for _, kv := range s.Attributes() {
+			keys[string(kv.Key)] = true
+		}
+		assert.True(t, keys["messaging.kafka.message.offset"], s.Name())
+	}
+}
+
+func TestAsyncProducer_FailedMessageGoesToDeadLetterTopic(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	tracing.Install(tp)
+	m := newRecordingMetric()
+	ap := mockAsync(t)
+	ap.ExpectInputAndFail(sarama.ErrNotLeaderForPartition)
+	var dead *sarama.ProducerMessage
+	ap.ExpectInputWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		dead = msg
+		return nil
+	})
+	p := kafka.NewAsyncProducerFrom(ap, kafka.Config{Topic: topic, DeadLetterTopic: deadLetterTopic, TracerProvider: tp}, m)
+
+	require.NoError(t, p.OrderUpdated(context.Background(), &entity.Order{ID: "o1", Status: entity.OrderStatusCooking}))
+	require.NoError(t, p.Close())
+
+	require.NotNil(t, dead)
+	assert.Equal(t, deadLetterTopic, dead.Topic)
+	headers := map[string]string{}
+	for _, h := range dead.Headers {
+		headers[string(h.Key)] = string(h.Value)
+	}
+	assert.Equal(t, topic, headers[kafka.HeaderOriginalTopic])
+	assert.Equal(t, sarama.ErrNotLeaderForPartition.Error(), headers[kafka.HeaderError])
+	assert.Contains(t, headers, "traceparent")
+	value, _ := dead.Value.Encode()
+	assert.Contains(t, string(value), `"status":"cooking"`)
+
+	assert.Equal(t, 1, m.failed[topic])
+	assert.Equal(t, 1, m.deadLettered[topic])
+	assert.Equal(t, 1, m.delivered[deadLetterTopic])
+}
+
+func TestAsyncProducer_WithoutDeadLetterTopicDropsFailedMessage(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	m := newRecordingMetric()
+	ap := mockAsync(t)
+	ap.ExpectInputAndFail(sarama.ErrOutOfBrokers)
+	p := kafka.NewAsyncProducerFrom(ap, kafka.Config{Topic: topic, TracerProvider: tp}, m)
+
+	// The error is reported asynchronously, not to the caller.
+	require.NoError(t, p.OrderDeleted(context.Background(), "o1", "u1"))
+	require.NoError(t, p.Close())
+
+	assert.Equal(t, 1, m.failed[topic])
+	assert.Empty(t, m.deadLettered)
+	require.Len(t, rec.Ended(), 1)
+	assert.Equal(t, codes.Error, rec.Ended()[0].Status().Code)
+}
+
+func TestAsyncProducer_RejectsEventsAfterClose(t *testing.T) {
+	p := kafka.NewAsyncProducerFrom(mockAsync(t), kafka.Config{Topic: topic}, nil)
+	require.NoError(t, p.Close())
+	require.NoError(t, p.Close())
+
+	err := p.OrderUpdated(context.Background(), &entity.Order{ID: "o1", Status: entity.OrderStatusPending})
+	assert.ErrorIs(t, err, kafka.ErrClosed)
+}
+
+func TestParseCompression(t *testing.T) {
+	for _, name := range []string{"", "none", "gzip", "snappy", "lz4", "zstd"} {
+		_, err := kafka.ParseCompression(name)
+		assert.NoError(t, err, name)
+	}
+	_, err := kafka.ParseCompression("brotli")
+	assert.Error(t, err)
+}
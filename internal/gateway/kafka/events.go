@@ -0,0 +1,125 @@
+package kafka
+
+import "time"
+
+// LegacyEvent is the original payload of every event on Config.Topic. It is
+// kept for existing consumers; status "deleted" marks a deletion.
+type LegacyEvent struct {
+	OrderID   string `json:"order_id"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"created_at"` // publish time, RFC 3339
+}
+
+// Payloads of the typed topics, see Routing. Each starts with event_type and
+// order_id; the order id is also the message key.
+
+type CreatedEvent struct {
+	EventType    EventType    `json:"event_type"`
+	OrderID      string       `json:"order_id"`
+	UserID       string       `json:"user_id"`
+	OrderNumber  string       `json:"order_number"`
+	RestaurantID string       `json:"restaurant_id"`
+	Status       string       `json:"status"`
+	Items        []EventItem  `json:"items"`
+	TotalPrice   int64        `json:"total_price"`
+	Address      EventAddress `json:"address"`
+	CreatedAt    time.Time    `json:"created_at"`
+	// EstimatedDelivery is the first ETA, omitted without the ETA component.
+	EstimatedDelivery time.Time `json:"estimated_delivery,omitzero"`
+	// DeliverAt and PrepareFrom are set on scheduled orders.
+	DeliverAt   time.Time `json:"deliver_at,omitzero"`
+	PrepareFrom time.Time `json:"prepare_from,omitzero"`
+}
+
+// UpdatedEvent is a user edit; it carries the whole editable state.
+type UpdatedEvent struct {
+	EventType   EventType    `json:"event_type"`
+	OrderID     string       `json:"order_id"`
+	UserID      string       `json:"user_id"`
+	OrderNumber string       `json:"order_number"`
+	Status      string       `json:"status"`
+	Items       []EventItem  `json:"items"`
+	TotalPrice  int64        `json:"total_price"`
+	Address     EventAddress `json:"address"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	DeliverAt   time.Time    `json:"deliver_at,omitzero"`
+	PrepareFrom time.Time    `json:"prepare_from,omitzero"`
+}
+
+// StatusChangedEvent is an automatic status change.
+type StatusChangedEvent struct {
+	EventType EventType `json:"event_type"`
+	OrderID   string    `json:"order_id"`
+	UserID    string    `json:"user_id"`
+	Status    string    `json:"status"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// SnapshotEvent is the current state of an order republished by a replay,
+// so that a new or reset consumer can rebuild its state. Without a topic of
+// its own it goes to the created topic.
+type SnapshotEvent struct {
+	EventType         EventType    `json:"event_type"`
+	ReplayID          string       `json:"replay_id"`
+	OrderID           string       `json:"order_id"`
+	UserID            string       `json:"user_id"`
+	OrderNumber       string       `json:"order_number"`
+	RestaurantID      string       `json:"restaurant_id"`
+	Status            string       `json:"status"`
+	Items             []EventItem  `json:"items"`
+	TotalPrice        int64        `json:"total_price"`
+	Address           EventAddress `json:"address"`
+	CreatedAt         time.Time    `json:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at"`
+	StatusChangedAt   time.Time    `json:"status_changed_at"`
+	EstimatedDelivery time.Time    `json:"estimated_delivery,omitzero"`
+	DeliverAt         time.Time    `json:"deliver_at,omitzero"`
+	PrepareFrom       time.Time    `json:"prepare_from,omitzero"`
+}
+
+// ETAChangedEvent is a new delivery estimate of an order; ChangedAt is when
+// it was made.
+type ETAChangedEvent struct {
+	EventType         EventType `json:"event_type"`
+	OrderID           string    `json:"order_id"`
+	UserID            string    `json:"user_id"`
+	RestaurantID      string    `json:"restaurant_id"`
+	Status            string    `json:"status"`
+	EstimatedDelivery time.Time `json:"estimated_delivery"`
+	ChangedAt         time.Time `json:"changed_at"`
+}
+
+type CanceledEvent struct {
+	EventType  EventType `json:"event_type"`
+	OrderID    string    `json:"order_id"`
+	UserID     string    `json:"user_id"`
+	CanceledAt time.Time `json:"canceled_at"`
+}
+
+type DeletedEvent struct {
+	EventType EventType `json:"event_type"`
+	OrderID   string    `json:"order_id"`
+	UserID    string    `json:"user_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type EventItem struct {
+	FoodID   string `json:"food_id"`
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+	Price    int    `json:"price"`
+}
+
+type EventAddress struct {
+	Street    string         `json:"street"`
+	House     string         `json:"house"`
+	Apartment string         `json:"apartment,omitempty"`
+	Floor     string         `json:"floor,omitempty"`
+	Comment   string         `json:"comment,omitempty"`
+	Location  *EventGeoPoint `json:"location,omitempty"`
+}
+
+type EventGeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
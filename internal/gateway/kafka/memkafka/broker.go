@@ -0,0 +1,279 @@
+// Package memkafka is an in-process stand-in for a Kafka cluster: topics
+// with partitions and offsets, consumer groups and an HTTP inspection
+// endpoint. Broker implements sarama.SyncProducer and sarama.ConsumerGroup,
+// so kafka.SaramaProducer and any sarama consumer, e.g. a courier service,
+// run against it unchanged in one process or in tests, without Docker.
+//
+// Messages are kept in memory for the life of the process; there is no
+// retention, replication or transactions.
+package memkafka
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// ErrUnknownTopic is returned for reads of a topic or partition that does not
+// exist. It is the sarama error, so callers can match either.
+var ErrUnknownTopic = sarama.ErrUnknownTopicOrPartition
+
+// Broker holds the topics and consumer groups. Topics are created on first
+// use with the default number of partitions, as with auto.create.topics.
+type Broker struct {
+	mu         sync.Mutex
+	partitions int32
+	topics     map[string]*topic
+	groups     map[string]*group
+	seq        int64
+	// changed is closed and replaced whenever a message is appended or a
+	// consumer is paused or resumed; waiters select on it.
+	changed chan struct{}
+}
+
+type topic struct {
+	parts [][]record
+}
+
+type record struct {
+	seq       int64 // broker-wide append order, used to merge partitions
+	key       []byte
+	value     []byte
+	headers   []*sarama.RecordHeader
+	timestamp time.Time
+}
+
+// NewBroker returns an empty broker whose topics get partitions partitions
+// when created implicitly; values below one mean one.
+func NewBroker(partitions int32) *Broker {
+	if partitions < 1 {
+		partitions = 1
+	}
+	return &Broker{
+		partitions: partitions,
+		topics:     map[string]*topic{},
+		groups:     map[string]*group{},
+		changed:    make(chan struct{}),
+	}
+}
+
+// CreateTopic creates a topic; it fails with sarama.ErrTopicAlreadyExists
+// when the topic exists.
+func (b *Broker) CreateTopic(name string, partitions int32) error {
+	if name == "" || partitions < 1 {
+		return fmt.Errorf("memkafka: invalid topic %q with %d partitions", name, partitions)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.topics[name]; ok {
+		return sarama.ErrTopicAlreadyExists
+	}
+	b.topics[name] = &topic{parts: make([][]record, partitions)}
+	return nil
+}
+
+// topicLocked returns the topic, creating it when it is missing.
+func (b *Broker) topicLocked(name string) *topic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &topic{parts: make([][]record, b.partitions)}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// Produce appends msg to its topic and fills in the partition, offset and
+// timestamp, like a sync producer does. The partition is chosen by the
+// sarama hash partitioner, so a key lands where it would on a real cluster
+// with the same number of partitions.
+func (b *Broker) Produce(msg *sarama.ProducerMessage) (int32, int64, error) {
+	if msg.Topic == "" {
+		return -1, -1, errors.New("memkafka: message without topic")
+	}
+	key, err := encode(msg.Key)
+	if err != nil {
+		return -1, -1, fmt.Errorf("memkafka: encode key: %w", err)
+	}
+	value, err := encode(msg.Value)
+	if err != nil {
+		return -1, -1, fmt.Errorf("memkafka: encode value: %w", err)
+	}
+	headers := make([]*sarama.RecordHeader, 0, len(msg.Headers))
+	for _, h := range msg.Headers {
+		headers = append(headers, &sarama.RecordHeader{Key: clone(h.Key), Value: clone(h.Value)})
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.topicLocked(msg.Topic)
+	partition, err := sarama.NewHashPartitioner(msg.Topic).Partition(msg, int32(len(t.parts)))
+	if err != nil {
+		return -1, -1, err
+	}
+	ts := msg.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	b.seq++
+	t.parts[partition] = append(t.parts[partition], record{seq: b.seq, key: key, value: value, headers: headers, timestamp: ts})
+	offset := int64(len(t.parts[partition]) - 1)
+	msg.Partition, msg.Offset, msg.Timestamp = partition, offset, ts
+	b.wakeLocked()
+	return partition, offset, nil
+}
+
+func encode(e sarama.Encoder) ([]byte, error) {
+	if e == nil {
+		return nil, nil
+	}
+	return e.Encode()
+}
+
+func clone(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
+
+func (b *Broker) wakeLocked() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// wait returns a channel closed on the next change of the broker.
+func (b *Broker) wait() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.changed
+}
+
+// Fetch returns up to limit messages of a partition starting at offset; a
+// non-positive limit means all of them.
+func (b *Broker) Fetch(topicName string, partition int32, offset int64, limit int) ([]*sarama.ConsumerMessage, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.topics[topicName]
+	if !ok || partition < 0 || int(partition) >= len(t.parts) {
+		return nil, ErrUnknownTopic
+	}
+	log := t.parts[partition]
+	if offset < 0 {
+		offset = 0
+	}
+	var out []*sarama.ConsumerMessage
+	for o := offset; o < int64(len(log)) && (limit <= 0 || len(out) < limit); o++ {
+		out = append(out, log[o].message(topicName, partition, o))
+	}
+	return out, nil
+}
+
+// Tail returns the last limit messages of a topic across its partitions in
+// the order they were produced.
+func (b *Broker) Tail(topicName string, limit int) ([]*sarama.ConsumerMessage, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.topics[topicName]
+	if !ok {
+		return nil, ErrUnknownTopic
+	}
+	type ref struct {
+		partition int32
+		offset    int64
+		seq       int64
+	}
+	var refs []ref
+	for p, log := range t.parts {
+		from := 0
+		if limit > 0 && len(log) > limit {
+			from = len(log) - limit
+		}
+		for o := from; o < len(log); o++ {
+			refs = append(refs, ref{int32(p), int64(o), log[o].seq})
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].seq < refs[j].seq })
+	if limit > 0 && len(refs) > limit {
+		refs = refs[len(refs)-limit:]
+	}
+	out := make([]*sarama.ConsumerMessage, 0, len(refs))
+	for _, r := range refs {
+		out = append(out, t.parts[r.partition][r.offset].message(topicName, r.partition, r.offset))
+	}
+	return out, nil
+}
+
+func (r record) message(topic string, partition int32, offset int64) *sarama.ConsumerMessage {
+	headers := make([]*sarama.RecordHeader, 0, len(r.headers))
+	for _, h := range r.headers {
+		headers = append(headers, &sarama.RecordHeader{Key: clone(h.Key), Value: clone(h.Value)})
+	}
+	return &sarama.ConsumerMessage{
+		Topic:     topic,
+		Partition: partition,
+		Offset:    offset,
+		Key:       clone(r.key),
+		Value:     clone(r.value),
+		Headers:   headers,
+		Timestamp: r.timestamp,
+	}
+}
+
+// HighWaterMark is the offset the next message of the partition gets.
+func (b *Broker) HighWaterMark(topicName string, partition int32) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.topics[topicName]
+	if !ok || partition < 0 || int(partition) >= len(t.parts) {
+		return 0, ErrUnknownTopic
+	}
+	return int64(len(t.parts[partition])), nil
+}
+
+// TopicInfo describes a topic for inspection.
+type TopicInfo struct {
+	Name       string          `json:"name"`
+	Partitions []PartitionInfo `json:"partitions"`
+	Messages   int64           `json:"messages"`
+}
+
+type PartitionInfo struct {
+	ID            int32 `json:"id"`
+	HighWaterMark int64 `json:"high_water_mark"`
+}
+
+// Topics lists the topics sorted by name.
+func (b *Broker) Topics() []TopicInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]TopicInfo, 0, len(b.topics))
+	for name := range b.topics {
+		out = append(out, b.topicInfoLocked(name))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Topic describes one topic; ok is false when it does not exist.
+func (b *Broker) Topic(name string) (TopicInfo, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.topics[name]; !ok {
+		return TopicInfo{}, false
+	}
+	return b.topicInfoLocked(name), true
+}
+
+func (b *Broker) topicInfoLocked(name string) TopicInfo {
+	t := b.topics[name]
+	info := TopicInfo{Name: name, Partitions: make([]PartitionInfo, 0, len(t.parts))}
+	for p, log := range t.parts {
+		info.Partitions = append(info.Partitions, PartitionInfo{ID: int32(p), HighWaterMark: int64(len(log))})
+		info.Messages += int64(len(log))
+	}
+	return info
+}
@@ -0,0 +1,113 @@
+package memkafka_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/gateway/kafka"
+	"github.com/nikolaev/service-order/internal/gateway/kafka/memkafka"
+)
+
+const topic = "order.status.changed"
+
+func TestBroker_SaramaProducerRoutesEvents(t *testing.T) {
+	b := memkafka.NewBroker(3)
+	p := kafka.NewSaramaProducerFromSync(b.SyncProducer(), kafka.Config{
+		Topic:   topic,
+		Routing: kafka.Routing{Mode: kafka.RoutingBoth, Topics: map[kafka.EventType]string{kafka.EventCreated: "order.event.created"}},
+	})
+	defer p.Close()
+
+	ctx := context.Background()
+	o := &entity.Order{ID: "o-1", UserID: "u-1", Status: entity.OrderStatusCreated}
+	require.NoError(t, p.OrderCreated(ctx, o))
+	o.Status = entity.OrderStatusPending
+	require.NoError(t, p.OrderStatusChanged(ctx, o))
+
+	var names []string
+	for _, info := range b.Topics() {
+		names = append(names, info.Name)
+		assert.Len(t, info.Partitions, 3)
+	}
+	assert.Equal(t, []string{"order.event.created", topic}, names)
+
+	legacy, err := b.Tail(topic, 0)
+	require.NoError(t, err)
+	require.Len(t, legacy, 2)
+	// One key, one partition: the events of an order keep their order.
+	assert.Equal(t, legacy[0].Partition, legacy[1].Partition)
+	assert.Equal(t, []int64{0, 1}, []int64{legacy[0].Offset, legacy[1].Offset})
+	assert.Equal(t, "o-1", string(legacy[1].Key))
+	var ev kafka.LegacyEvent
+	require.NoError(t, json.Unmarshal(legacy[1].Value, &ev))
+	assert.Equal(t, string(entity.OrderStatusPending), ev.Status)
+
+	created, err := b.Tail("order.event.created", 0)
+	require.NoError(t, err)
+	require.Len(t, created, 1)
+	require.NotEmpty(t, created[0].Headers)
+	assert.Equal(t, kafka.HeaderEventType, string(created[0].Headers[0].Key))
+	assert.Equal(t, string(kafka.EventCreated), string(created[0].Headers[0].Value))
+}
+
+func TestBroker_TailMergesPartitionsInProduceOrder(t *testing.T) {
+	b := memkafka.NewBroker(4)
+	for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		_, _, err := b.Produce(&sarama.ProducerMessage{Topic: topic, Key: sarama.StringEncoder(key), Value: sarama.StringEncoder(key)})
+		require.NoError(t, err)
+	}
+
+	msgs, err := b.Tail(topic, 3)
+	require.NoError(t, err)
+	var values []string
+	for _, m := range msgs {
+		values = append(values, string(m.Value))
+	}
+	assert.Equal(t, []string{"d", "e", "f"}, values)
+
+	_, err = b.Tail("missing", 3)
+	assert.ErrorIs(t, err, memkafka.ErrUnknownTopic)
+}
+
+func TestBroker_FetchAndCreateTopic(t *testing.T) {
+	b := memkafka.NewBroker(1)
+	require.NoError(t, b.CreateTopic("two", 2))
+	assert.ErrorIs(t, b.CreateTopic("two", 2), sarama.ErrTopicAlreadyExists)
+
+	for range 5 {
+		_, _, err := b.Produce(&sarama.ProducerMessage{Topic: "two", Partition: 0, Key: sarama.StringEncoder("k"), Value: sarama.StringEncoder("v")})
+		require.NoError(t, err)
+	}
+	msg := &sarama.ProducerMessage{Topic: "two", Key: sarama.StringEncoder("k")}
+	partition, offset, err := b.Produce(msg)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), offset)
+	assert.Equal(t, partition, msg.Partition)
+	assert.False(t, msg.Timestamp.IsZero())
+
+	msgs, err := b.Fetch("two", partition, 2, 2)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, int64(2), msgs[0].Offset)
+
+	hwm, err := b.HighWaterMark("two", partition)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), hwm)
+
+	_, err = b.Fetch("two", 7, 0, 0)
+	assert.ErrorIs(t, err, memkafka.ErrUnknownTopic)
+}
+
+func TestSyncProducer_Closed(t *testing.T) {
+	p := memkafka.NewBroker(1).SyncProducer()
+	require.NoError(t, p.Close())
+	_, _, err := p.SendMessage(&sarama.ProducerMessage{Topic: topic})
+	assert.ErrorIs(t, err, sarama.ErrClosedClient)
+	assert.ErrorIs(t, p.BeginTxn(), sarama.ErrNonTransactedProducer)
+}
@@ -0,0 +1,425 @@
+package memkafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/IBM/sarama"
+)
+
+// group is the broker side of a consumer group. Partitions of every topic
+// are spread round-robin over the members subscribed to it, ordered by
+// member id; a join, a leave or a subscription change starts a new
+// generation and ends the sessions of the old one.
+type group struct {
+	generation int32
+	members    map[string][]string // member id -> topics
+	committed  map[string]map[int32]int64
+	rebalance  chan struct{} // closed when the generation ends
+}
+
+func (b *Broker) groupLocked(id string) *group {
+	g, ok := b.groups[id]
+	if !ok {
+		g = &group{
+			members:   map[string][]string{},
+			committed: map[string]map[int32]int64{},
+			rebalance: make(chan struct{}),
+		}
+		b.groups[id] = g
+	}
+	return g
+}
+
+func (g *group) bumpLocked() {
+	g.generation++
+	close(g.rebalance)
+	g.rebalance = make(chan struct{})
+}
+
+// assignmentLocked returns the partitions of member in the current generation.
+func (b *Broker) assignmentLocked(g *group, member string) map[string][]int32 {
+	out := map[string][]int32{}
+	for _, name := range g.members[member] {
+		var subs []string
+		for id, topics := range g.members {
+			if slices.Contains(topics, name) {
+				subs = append(subs, id)
+			}
+		}
+		sort.Strings(subs)
+		idx := slices.Index(subs, member)
+		for p := range b.topicLocked(name).parts {
+			if p%len(subs) == idx {
+				out[name] = append(out[name], int32(p))
+			}
+		}
+	}
+	return out
+}
+
+func (b *Broker) commit(groupID, topic string, partition int32, offset int64, reset bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	g := b.groupLocked(groupID)
+	offsets, ok := g.committed[topic]
+	if !ok {
+		offsets = map[int32]int64{}
+		g.committed[topic] = offsets
+	}
+	if cur, ok := offsets[partition]; !ok || reset || offset > cur {
+		offsets[partition] = offset
+	}
+}
+
+func (b *Broker) leave(groupID, member string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	g := b.groupLocked(groupID)
+	if _, ok := g.members[member]; ok {
+		delete(g.members, member)
+		g.bumpLocked()
+	}
+}
+
+var memberSeq atomic.Int64
+
+// ConsumerGroup is one member of a consumer group; it implements
+// sarama.ConsumerGroup. Offsets are committed as soon as they are marked.
+type ConsumerGroup struct {
+	b       *Broker
+	group   string
+	member  string
+	initial int64
+	errs    chan error
+	done    chan struct{}
+
+	mu     sync.Mutex
+	paused map[string]map[int32]bool
+	closed bool
+}
+
+var _ sarama.ConsumerGroup = (*ConsumerGroup)(nil)
+
+// NewConsumerGroup returns a new member of groupID; it joins on the first
+// Consume. initial is sarama.OffsetOldest or sarama.OffsetNewest and applies
+// to partitions the group has not committed yet.
+func (b *Broker) NewConsumerGroup(groupID string, initial int64) *ConsumerGroup {
+	return &ConsumerGroup{
+		b:       b,
+		group:   groupID,
+		member:  fmt.Sprintf("%s-%d", groupID, memberSeq.Add(1)),
+		initial: initial,
+		errs:    make(chan error, 16),
+		done:    make(chan struct{}),
+		paused:  map[string]map[int32]bool{},
+	}
+}
+
+// Consume joins the group, runs a session like sarama does and returns when
+// ctx is done, the group rebalances, a ConsumeClaim returns or the consumer
+// group is closed. Call it in a loop to follow rebalances.
+func (c *ConsumerGroup) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) error {
+	if len(topics) == 0 {
+		return errors.New("memkafka: no topics to consume")
+	}
+	select {
+	case <-c.done:
+		return sarama.ErrClosedConsumerGroup
+	default:
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sess, claims, rebalance := c.join(ctx, topics)
+	go func() {
+		select {
+		case <-rebalance:
+		case <-c.done:
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+
+	if err := handler.Setup(sess); err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	for _, cl := range claims {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.feed(ctx, cl)
+		}()
+		go func() {
+			defer wg.Done()
+			// As in sarama, the first claim to return ends the session.
+			defer cancel()
+			if err := handler.ConsumeClaim(sess, cl); err != nil {
+				c.sendError(err)
+			}
+		}()
+	}
+	<-ctx.Done()
+	wg.Wait()
+	return handler.Cleanup(sess)
+}
+
+func (c *ConsumerGroup) join(ctx context.Context, topics []string) (*session, []*claim, <-chan struct{}) {
+	topics = slices.Clone(topics)
+	sort.Strings(topics)
+	topics = slices.Compact(topics)
+
+	b := c.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	g := b.groupLocked(c.group)
+	if cur, ok := g.members[c.member]; !ok || !slices.Equal(cur, topics) {
+		g.members[c.member] = topics
+		g.bumpLocked()
+	}
+	assigned := b.assignmentLocked(g, c.member)
+
+	var claims []*claim
+	for _, name := range topics {
+		for _, p := range assigned[name] {
+			offset, ok := g.committed[name][p]
+			if !ok {
+				offset = 0
+				if c.initial == sarama.OffsetNewest {
+					offset = int64(len(b.topics[name].parts[p]))
+				}
+			}
+			claims = append(claims, &claim{b: b, topic: name, partition: p, initial: offset, msgs: make(chan *sarama.ConsumerMessage)})
+		}
+	}
+	sess := &session{c: c, ctx: ctx, claims: assigned, generation: g.generation}
+	return sess, claims, g.rebalance
+}
+
+// feed delivers the messages of a claim until ctx is done.
+func (c *ConsumerGroup) feed(ctx context.Context, cl *claim) {
+	defer close(cl.msgs)
+	offset := cl.initial
+	for {
+		// Take the wait channel first, so that an append between the fetch
+		// and the select is not missed.
+		wait := c.b.wait()
+		var msgs []*sarama.ConsumerMessage
+		if !c.isPaused(cl.topic, cl.partition) {
+			msgs, _ = c.b.Fetch(cl.topic, cl.partition, offset, 0)
+		}
+		for _, m := range msgs {
+			select {
+			case cl.msgs <- m:
+				offset = m.Offset + 1
+			case <-ctx.Done():
+				return
+			}
+		}
+		if len(msgs) > 0 {
+			continue
+		}
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *ConsumerGroup) sendError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.errs <- err:
+	default: // nobody reads the errors
+	}
+}
+
+// Errors returns the errors of ConsumeClaim; it is closed by Close.
+func (c *ConsumerGroup) Errors() <-chan error { return c.errs }
+
+// Close ends the running session and leaves the group.
+func (c *ConsumerGroup) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+	close(c.errs)
+	c.mu.Unlock()
+	c.b.leave(c.group, c.member)
+	return nil
+}
+
+func (c *ConsumerGroup) isPaused(topic string, partition int32) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused[topic][partition]
+}
+
+// setPaused updates the paused partitions; all applies to every claimed
+// partition, and resuming all also clears partitions of older generations.
+func (c *ConsumerGroup) setPaused(partitions map[string][]int32, all, paused bool) {
+	if all {
+		partitions = c.Claims()
+	}
+	c.mu.Lock()
+	if all && !paused {
+		c.paused = map[string]map[int32]bool{}
+	}
+	c.markPausedLocked(partitions, paused)
+	c.mu.Unlock()
+
+	c.b.mu.Lock()
+	c.b.wakeLocked()
+	c.b.mu.Unlock()
+}
+
+func (c *ConsumerGroup) markPausedLocked(partitions map[string][]int32, paused bool) {
+	for topic, parts := range partitions {
+		if c.paused[topic] == nil {
+			c.paused[topic] = map[int32]bool{}
+		}
+		for _, p := range parts {
+			c.paused[topic][p] = paused
+		}
+	}
+}
+
+// Claims returns the partitions of the member in the current generation.
+func (c *ConsumerGroup) Claims() map[string][]int32 {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	g := c.b.groupLocked(c.group)
+	if _, ok := g.members[c.member]; !ok {
+		return map[string][]int32{}
+	}
+	return c.b.assignmentLocked(g, c.member)
+}
+
+func (c *ConsumerGroup) Pause(partitions map[string][]int32)  { c.setPaused(partitions, false, true) }
+func (c *ConsumerGroup) Resume(partitions map[string][]int32) { c.setPaused(partitions, false, false) }
+func (c *ConsumerGroup) PauseAll()                            { c.setPaused(nil, true, true) }
+func (c *ConsumerGroup) ResumeAll()                           { c.setPaused(nil, true, false) }
+
+type session struct {
+	c          *ConsumerGroup
+	ctx        context.Context
+	claims     map[string][]int32
+	generation int32
+}
+
+func (s *session) Claims() map[string][]int32 { return s.claims }
+func (s *session) MemberID() string           { return s.c.member }
+func (s *session) GenerationID() int32        { return s.generation }
+func (s *session) Context() context.Context   { return s.ctx }
+
+// Commit is a no-op: marked offsets are committed right away.
+func (s *session) Commit() {}
+
+func (s *session) MarkOffset(topic string, partition int32, offset int64, _ string) {
+	s.c.b.commit(s.c.group, topic, partition, offset, false)
+}
+
+func (s *session) ResetOffset(topic string, partition int32, offset int64, _ string) {
+	s.c.b.commit(s.c.group, topic, partition, offset, true)
+}
+
+func (s *session) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1, metadata)
+}
+
+type claim struct {
+	b         *Broker
+	topic     string
+	partition int32
+	initial   int64
+	msgs      chan *sarama.ConsumerMessage
+}
+
+func (c *claim) Topic() string                            { return c.topic }
+func (c *claim) Partition() int32                         { return c.partition }
+func (c *claim) InitialOffset() int64                     { return c.initial }
+func (c *claim) Messages() <-chan *sarama.ConsumerMessage { return c.msgs }
+
+func (c *claim) HighWaterMarkOffset() int64 {
+	hwm, _ := c.b.HighWaterMark(c.topic, c.partition)
+	return hwm
+}
+
+// GroupInfo describes a consumer group for inspection.
+type GroupInfo struct {
+	ID         string        `json:"id"`
+	Generation int32         `json:"generation"`
+	Members    []MemberInfo  `json:"members"`
+	Offsets    []GroupOffset `json:"offsets"`
+}
+
+type MemberInfo struct {
+	ID     string             `json:"id"`
+	Topics []string           `json:"topics"`
+	Claims map[string][]int32 `json:"claims"`
+}
+
+// GroupOffset is the committed offset of a partition, -1 when there is none,
+// and how many messages the group has not committed yet.
+type GroupOffset struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Committed int64  `json:"committed"`
+	Lag       int64  `json:"lag"`
+}
+
+// Groups lists the consumer groups sorted by id.
+func (b *Broker) Groups() []GroupInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]GroupInfo, 0, len(b.groups))
+	for id, g := range b.groups {
+		info := GroupInfo{ID: id, Generation: g.generation, Members: []MemberInfo{}, Offsets: []GroupOffset{}}
+		topics := map[string]bool{}
+		for member, subs := range g.members {
+			info.Members = append(info.Members, MemberInfo{ID: member, Topics: subs, Claims: b.assignmentLocked(g, member)})
+			for _, t := range subs {
+				topics[t] = true
+			}
+		}
+		for t := range g.committed {
+			topics[t] = true
+		}
+		for t := range topics {
+			for p, log := range b.topicLocked(t).parts {
+				committed, ok := g.committed[t][int32(p)]
+				if !ok {
+					committed = -1
+				}
+				info.Offsets = append(info.Offsets, GroupOffset{
+					Topic:     t,
+					Partition: int32(p),
+					Committed: committed,
+					Lag:       int64(len(log)) - max(committed, 0),
+				})
+			}
+		}
+		sort.Slice(info.Members, func(i, j int) bool { return info.Members[i].ID < info.Members[j].ID })
+		sort.Slice(info.Offsets, func(i, j int) bool {
+			a, b := info.Offsets[i], info.Offsets[j]
+			return a.Topic < b.Topic || a.Topic == b.Topic && a.Partition < b.Partition
+		})
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
@@ -0,0 +1,189 @@
+package memkafka_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/gateway/kafka/memkafka"
+)
+
+// collector records and marks every message it consumes.
+type collector struct {
+	mu     sync.Mutex
+	values []string
+	claims map[string][]int32
+}
+
+func (c *collector) Setup(s sarama.ConsumerGroupSession) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.claims = s.Claims()
+	return nil
+}
+
+func (c *collector) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (c *collector) ConsumeClaim(s sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for m := range claim.Messages() {
+		c.mu.Lock()
+		c.values = append(c.values, string(m.Value))
+		c.mu.Unlock()
+		s.MarkMessage(m, "")
+	}
+	return nil
+}
+
+func (c *collector) got() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.values...)
+}
+
+func (c *collector) claimed() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.claims[topic])
+}
+
+// consume runs the usual sarama loop until ctx is done or the group closes.
+func consume(ctx context.Context, cg sarama.ConsumerGroup, h sarama.ConsumerGroupHandler) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ctx.Err() == nil {
+			if err := cg.Consume(ctx, []string{topic}, h); err != nil {
+				return
+			}
+		}
+	}()
+	return done
+}
+
+func produce(t *testing.T, b *memkafka.Broker, n int) {
+	t.Helper()
+	for i := range n {
+		_, _, err := b.Produce(&sarama.ProducerMessage{Topic: topic, Key: sarama.StringEncoder(fmt.Sprint(i)), Value: sarama.StringEncoder(fmt.Sprint(i))})
+		require.NoError(t, err)
+	}
+}
+
+func lag(b *memkafka.Broker, group string) int64 {
+	var total int64
+	for _, g := range b.Groups() {
+		if g.ID == group {
+			for _, o := range g.Offsets {
+				total += o.Lag
+			}
+		}
+	}
+	return total
+}
+
+func TestConsumerGroup_ConsumesAndCommits(t *testing.T) {
+	b := memkafka.NewBroker(3)
+	produce(t, b, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cg := b.NewConsumerGroup("courier", sarama.OffsetOldest)
+	h := &collector{}
+	done := consume(ctx, cg, h)
+	produce(t, b, 5)
+
+	require.Eventually(t, func() bool { return len(h.got()) == 10 }, time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool { return lag(b, "courier") == 0 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 3, h.claimed())
+
+	require.NoError(t, cg.Close())
+	<-done
+
+	// A new member of the group resumes from the committed offsets.
+	produce(t, b, 2)
+	again := &collector{}
+	cg = b.NewConsumerGroup("courier", sarama.OffsetOldest)
+	defer cg.Close()
+	consume(ctx, cg, again)
+	require.Eventually(t, func() bool { return len(again.got()) == 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestConsumerGroup_OffsetNewestSkipsHistory(t *testing.T) {
+	b := memkafka.NewBroker(1)
+	produce(t, b, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cg := b.NewConsumerGroup("fresh", sarama.OffsetNewest)
+	defer cg.Close()
+	h := &collector{}
+	consume(ctx, cg, h)
+	require.Eventually(t, func() bool { return h.claimed() == 1 }, time.Second, 5*time.Millisecond)
+
+	_, _, err := b.Produce(&sarama.ProducerMessage{Topic: topic, Value: sarama.StringEncoder("new")})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(h.got()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"new"}, h.got())
+}
+
+func TestConsumerGroup_RebalancesPartitions(t *testing.T) {
+	b := memkafka.NewBroker(4)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first, second := &collector{}, &collector{}
+	a := b.NewConsumerGroup("courier", sarama.OffsetOldest)
+	defer a.Close()
+	consume(ctx, a, first)
+	require.Eventually(t, func() bool { return first.claimed() == 4 }, time.Second, 5*time.Millisecond)
+
+	c := b.NewConsumerGroup("courier", sarama.OffsetOldest)
+	consume(ctx, c, second)
+	require.Eventually(t, func() bool { return first.claimed() == 2 && second.claimed() == 2 }, time.Second, 5*time.Millisecond)
+
+	// Every message goes to exactly one member.
+	produce(t, b, 20)
+	require.Eventually(t, func() bool { return len(first.got())+len(second.got()) == 20 }, time.Second, 5*time.Millisecond)
+	assert.NotEmpty(t, first.got())
+	assert.NotEmpty(t, second.got())
+
+	// When a member leaves, the rest takes over its partitions.
+	require.NoError(t, c.Close())
+	require.Eventually(t, func() bool { return first.claimed() == 4 }, time.Second, 5*time.Millisecond)
+	produce(t, b, 4)
+	require.Eventually(t, func() bool { return len(first.got())+len(second.got()) == 24 }, time.Second, 5*time.Millisecond)
+	assert.Zero(t, lag(b, "courier"))
+}
+
+func TestConsumerGroup_PauseAndResume(t *testing.T) {
+	b := memkafka.NewBroker(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cg := b.NewConsumerGroup("courier", sarama.OffsetOldest)
+	defer cg.Close()
+	h := &collector{}
+	consume(ctx, cg, h)
+	require.Eventually(t, func() bool { return h.claimed() == 1 }, time.Second, 5*time.Millisecond)
+
+	cg.PauseAll()
+	produce(t, b, 2)
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, h.got())
+
+	cg.ResumeAll()
+	require.Eventually(t, func() bool { return len(h.got()) == 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestConsumerGroup_Closed(t *testing.T) {
+	cg := memkafka.NewBroker(1).NewConsumerGroup("courier", sarama.OffsetOldest)
+	require.NoError(t, cg.Close())
+	assert.ErrorIs(t, cg.Consume(context.Background(), []string{topic}, &collector{}), sarama.ErrClosedConsumerGroup)
+	_, open := <-cg.Errors()
+	assert.False(t, open)
+}
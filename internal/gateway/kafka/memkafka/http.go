@@ -0,0 +1,194 @@
+package memkafka
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/IBM/sarama"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/nikolaev/service-order/internal/debughttp"
+)
+
+// defaultLimit is how many messages a listing returns without ?limit.
+const defaultLimit = 20
+
+// Handler serves the inspection API, meant to be mounted under a debug
+// prefix:
+//
+//	GET /topics                   topics with partitions and high water marks
+//	GET /topics/{topic}           one topic
+//	GET /topics/{topic}/messages  the last ?limit messages of the topic or of
+//	                              ?partition, or those from ?offset on;
+//	                              limit=0 means all
+//	GET /groups                   consumer groups with members, offsets and lag
+//
+// With ?follow=true the messages listing keeps the response open and streams
+// every new message as a line of JSON until the client goes away.
+func (b *Broker) Handler() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/topics", func(w http.ResponseWriter, _ *http.Request) {
+		debughttp.JSON(w, http.StatusOK, b.Topics())
+	})
+	r.Get("/topics/{topic}", func(w http.ResponseWriter, r *http.Request) {
+		info, ok := b.Topic(chi.URLParam(r, "topic"))
+		if !ok {
+			debughttp.Error(w, http.StatusNotFound, ErrUnknownTopic)
+			return
+		}
+		debughttp.JSON(w, http.StatusOK, info)
+	})
+	r.Get("/topics/{topic}/messages", b.messages)
+	r.Get("/groups", func(w http.ResponseWriter, _ *http.Request) {
+		debughttp.JSON(w, http.StatusOK, b.Groups())
+	})
+	return r
+}
+
+// Message is a stored message as the inspection API shows it. A value that
+// is valid JSON is embedded as is, anything else as a string.
+type Message struct {
+	Topic     string            `json:"topic"`
+	Partition int32             `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Key       string            `json:"key,omitempty"`
+	Value     json.RawMessage   `json:"value"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Timestamp string            `json:"timestamp"`
+}
+
+func messageOf(m *sarama.ConsumerMessage) Message {
+	out := Message{
+		Topic:     m.Topic,
+		Partition: m.Partition,
+		Offset:    m.Offset,
+		Key:       string(m.Key),
+		Value:     m.Value,
+		Timestamp: m.Timestamp.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+	}
+	if !json.Valid(m.Value) {
+		out.Value, _ = json.Marshal(string(m.Value))
+	}
+	if len(m.Headers) > 0 {
+		out.Headers = make(map[string]string, len(m.Headers))
+		for _, h := range m.Headers {
+			out.Headers[string(h.Key)] = string(h.Value)
+		}
+	}
+	return out
+}
+
+func (b *Broker) messages(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "topic")
+	q := r.URL.Query()
+	limit, err := intParam(q.Get("limit"), defaultLimit)
+	if err != nil || limit < 0 {
+		debughttp.Error(w, http.StatusBadRequest, errors.New("limit must be a non-negative integer"))
+		return
+	}
+	partition, err := intParam(q.Get("partition"), -1)
+	if err != nil {
+		debughttp.Error(w, http.StatusBadRequest, errors.New("partition must be an integer"))
+		return
+	}
+	offset, err := intParam(q.Get("offset"), -1)
+	if err != nil {
+		debughttp.Error(w, http.StatusBadRequest, errors.New("offset must be an integer"))
+		return
+	}
+	follow := q.Get("follow") == "true"
+	if offset >= 0 && partition < 0 {
+		debughttp.Error(w, http.StatusBadRequest, errors.New("offset needs a partition"))
+		return
+	}
+
+	// Snapshot before reading, so that following misses nothing.
+	wait := b.wait()
+	info, _ := b.Topic(name)
+	var msgs []*sarama.ConsumerMessage
+	switch {
+	case partition >= 0 && offset >= 0:
+		msgs, err = b.Fetch(name, int32(partition), int64(offset), limit)
+	case partition >= 0:
+		var from int64
+		if from, err = b.HighWaterMark(name, int32(partition)); err == nil {
+			if from -= int64(limit); limit == 0 {
+				from = 0
+			}
+			msgs, err = b.Fetch(name, int32(partition), from, limit)
+		}
+	default:
+		msgs, err = b.Tail(name, limit)
+	}
+	if err != nil {
+		debughttp.Error(w, http.StatusNotFound, err)
+		return
+	}
+
+	if !follow {
+		out := make([]Message, 0, len(msgs))
+		for _, m := range msgs {
+			out = append(out, messageOf(m))
+		}
+		debughttp.JSON(w, http.StatusOK, out)
+		return
+	}
+	b.follow(w, r, info, int32(partition), msgs, wait)
+}
+
+// follow writes msgs and then every message produced to the topic (or to
+// one partition when partition is not negative) after the info snapshot, as
+// JSON lines.
+func (b *Broker) follow(w http.ResponseWriter, r *http.Request, info TopicInfo, partition int32, msgs []*sarama.ConsumerMessage, wait <-chan struct{}) {
+	name := info.Name
+	next := make([]int64, len(info.Partitions))
+	for _, p := range info.Partitions {
+		next[p.ID] = p.HighWaterMark
+	}
+	for _, m := range msgs {
+		next[m.Partition] = max(next[m.Partition], m.Offset+1)
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	for {
+		for _, m := range msgs {
+			if err := enc.Encode(messageOf(m)); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		select {
+		case <-wait:
+		case <-r.Context().Done():
+			return
+		}
+		wait = b.wait()
+		msgs = msgs[:0]
+		for p := range next {
+			if partition >= 0 && int32(p) != partition {
+				continue
+			}
+			fetched, _ := b.Fetch(name, int32(p), next[p], 0)
+			if len(fetched) > 0 {
+				next[p] = fetched[len(fetched)-1].Offset + 1
+			}
+			msgs = append(msgs, fetched...)
+		}
+	}
+}
+
+func intParam(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
+}
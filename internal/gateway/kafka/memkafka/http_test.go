@@ -0,0 +1,113 @@
+package memkafka_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/gateway/kafka/memkafka"
+)
+
+func getJSON(t *testing.T, url string, v any) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if v != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	return resp.StatusCode
+}
+
+func TestHandler_TopicsMessagesAndGroups(t *testing.T) {
+	b := memkafka.NewBroker(2)
+	_, _, err := b.Produce(&sarama.ProducerMessage{
+		Topic:   topic,
+		Key:     sarama.StringEncoder("o-1"),
+		Value:   sarama.StringEncoder(`{"order_id":"o-1"}`),
+		Headers: []sarama.RecordHeader{{Key: []byte("event-type"), Value: []byte("created")}},
+	})
+	require.NoError(t, err)
+	_, _, err = b.Produce(&sarama.ProducerMessage{Topic: topic, Key: sarama.StringEncoder("o-2"), Value: sarama.StringEncoder("not json")})
+	require.NoError(t, err)
+	srv := httptest.NewServer(b.Handler())
+	defer srv.Close()
+
+	var topics []memkafka.TopicInfo
+	require.Equal(t, http.StatusOK, getJSON(t, srv.URL+"/topics", &topics))
+	require.Len(t, topics, 1)
+	assert.Equal(t, topic, topics[0].Name)
+	assert.Equal(t, int64(2), topics[0].Messages)
+
+	var msgs []memkafka.Message
+	require.Equal(t, http.StatusOK, getJSON(t, srv.URL+"/topics/"+topic+"/messages?limit=5", &msgs))
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "o-1", msgs[0].Key)
+	assert.JSONEq(t, `{"order_id":"o-1"}`, string(msgs[0].Value))
+	assert.Equal(t, "created", msgs[0].Headers["event-type"])
+	assert.JSONEq(t, `"not json"`, string(msgs[1].Value))
+
+	require.Equal(t, http.StatusOK, getJSON(t, srv.URL+"/topics/"+topic+"/messages?limit=1", &msgs))
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "o-2", msgs[0].Key)
+
+	assert.Equal(t, http.StatusNotFound, getJSON(t, srv.URL+"/topics/missing", nil))
+	assert.Equal(t, http.StatusNotFound, getJSON(t, srv.URL+"/topics/missing/messages", nil))
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, srv.URL+"/topics/"+topic+"/messages?offset=1", nil))
+
+	cg := b.NewConsumerGroup("courier", sarama.OffsetOldest)
+	defer cg.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h := &collector{}
+	consume(ctx, cg, h)
+	require.Eventually(t, func() bool { return len(h.got()) == 2 }, time.Second, 5*time.Millisecond)
+
+	var groups []memkafka.GroupInfo
+	require.Eventually(t, func() bool {
+		getJSON(t, srv.URL+"/groups", &groups)
+		return len(groups) == 1 && len(groups[0].Members) == 1 && lag(b, "courier") == 0
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "courier", groups[0].ID)
+	assert.Len(t, groups[0].Offsets, 2)
+}
+
+func TestHandler_FollowStreamsNewMessages(t *testing.T) {
+	b := memkafka.NewBroker(1)
+	produce(t, b, 1)
+	srv := httptest.NewServer(b.Handler())
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/topics/"+topic+"/messages?follow=true", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	lines := bufio.NewScanner(resp.Body)
+	next := func() memkafka.Message {
+		t.Helper()
+		require.True(t, lines.Scan())
+		var m memkafka.Message
+		require.NoError(t, json.Unmarshal(lines.Bytes(), &m))
+		return m
+	}
+	assert.Equal(t, int64(0), next().Offset)
+
+	_, _, err = b.Produce(&sarama.ProducerMessage{Topic: topic, Value: sarama.StringEncoder(`"later"`)})
+	require.NoError(t, err)
+	m := next()
+	assert.Equal(t, int64(1), m.Offset)
+	assert.JSONEq(t, `"later"`, string(m.Value))
+}
@@ -0,0 +1,57 @@
+package memkafka
+
+import (
+	"sync/atomic"
+
+	"github.com/IBM/sarama"
+)
+
+// SyncProducer returns a sarama.SyncProducer writing to the broker, e.g. for
+// kafka.NewSaramaProducerFromSync. Transactions are not supported.
+func (b *Broker) SyncProducer() sarama.SyncProducer {
+	return &syncProducer{b: b}
+}
+
+type syncProducer struct {
+	b      *Broker
+	closed atomic.Bool
+}
+
+func (p *syncProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	if p.closed.Load() {
+		return -1, -1, sarama.ErrClosedClient
+	}
+	return p.b.Produce(msg)
+}
+
+func (p *syncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	var errs sarama.ProducerErrors
+	for _, msg := range msgs {
+		if _, _, err := p.SendMessage(msg); err != nil {
+			errs = append(errs, &sarama.ProducerError{Msg: msg, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (p *syncProducer) Close() error {
+	p.closed.Store(true)
+	return nil
+}
+
+func (p *syncProducer) TxnStatus() sarama.ProducerTxnStatusFlag { return sarama.ProducerTxnFlagReady }
+func (p *syncProducer) IsTransactional() bool                   { return false }
+func (p *syncProducer) BeginTxn() error                         { return sarama.ErrNonTransactedProducer }
+func (p *syncProducer) CommitTxn() error                        { return sarama.ErrNonTransactedProducer }
+func (p *syncProducer) AbortTxn() error                         { return sarama.ErrNonTransactedProducer }
+
+func (p *syncProducer) AddOffsetsToTxn(map[string][]*sarama.PartitionOffsetMetadata, string) error {
+	return sarama.ErrNonTransactedProducer
+}
+
+func (p *syncProducer) AddMessageToTxn(*sarama.ConsumerMessage, string, *string) error {
+	return sarama.ErrNonTransactedProducer
+}
@@ -2,7 +2,7 @@ package kafka
 
 import (
 	"context"
-	"log"
+	"log/slog"
 
 	"github.com/nikolaev/service-order/internal/domain/entity"
 )
@@ -10,22 +10,41 @@ import (
 type Producer interface {
 	OrderCreated(ctx context.Context, o *entity.Order) error
 	OrderUpdated(ctx context.Context, o *entity.Order) error
+	OrderStatusChanged(ctx context.Context, o *entity.Order) error
 	OrderDeleted(ctx context.Context, id string, userID string) error
+	OrderSnapshot(ctx context.Context, o *entity.Order, replayID string) error
+	OrderETAChanged(ctx context.Context, o *entity.Order) error
 }
 
+// NoopProducer only logs events; it is used when Kafka is not configured.
 type NoopProducer struct{}
 
-func (NoopProducer) OrderCreated(_ context.Context, o *entity.Order) error {
-	log.Printf("kafka noop: order created %s", o.ID)
+func (NoopProducer) OrderCreated(ctx context.Context, o *entity.Order) error {
+	slog.InfoContext(ctx, "kafka noop: order created", "order_id", o.ID, "status", o.Status)
 	return nil
 }
 
-func (NoopProducer) OrderUpdated(_ context.Context, o *entity.Order) error {
-	log.Printf("kafka noop: order updated %s", o.ID)
+func (NoopProducer) OrderUpdated(ctx context.Context, o *entity.Order) error {
+	slog.InfoContext(ctx, "kafka noop: order updated", "order_id", o.ID, "status", o.Status)
 	return nil
 }
 
-func (NoopProducer) OrderDeleted(_ context.Context, id string, userID string) error {
-	log.Printf("kafka noop: order deleted %s", id)
+func (NoopProducer) OrderStatusChanged(ctx context.Context, o *entity.Order) error {
+	slog.InfoContext(ctx, "kafka noop: order status changed", "order_id", o.ID, "status", o.Status)
+	return nil
+}
+
+func (NoopProducer) OrderDeleted(ctx context.Context, id string, userID string) error {
+	slog.InfoContext(ctx, "kafka noop: order deleted", "order_id", id)
+	return nil
+}
+
+func (NoopProducer) OrderSnapshot(ctx context.Context, o *entity.Order, replayID string) error {
+	slog.InfoContext(ctx, "kafka noop: order snapshot", "order_id", o.ID, "status", o.Status, "replay_id", replayID)
+	return nil
+}
+
+func (NoopProducer) OrderETAChanged(ctx context.Context, o *entity.Order) error {
+	slog.InfoContext(ctx, "kafka noop: order eta changed", "order_id", o.ID, "estimated_delivery", o.EstimatedDelivery)
 	return nil
 }
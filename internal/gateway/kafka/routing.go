@@ -0,0 +1,273 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+// EventType is the kind of an order event; Routing maps each to a topic.
+type EventType string
+
+const (
+	EventCreated       EventType = "created"
+	EventUpdated       EventType = "updated" // a user edit
+	EventStatusChanged EventType = "status_changed"
+	EventCanceled      EventType = "canceled"
+	EventDeleted       EventType = "deleted"
+	// EventSnapshot is the current state of an order republished by a replay.
+	EventSnapshot EventType = "snapshot"
+	// EventETAChanged is a new delivery estimate. It has no legacy message:
+	// the legacy topic carries statuses only.
+	EventETAChanged EventType = "eta_changed"
+)
+
+// EventTypes lists every event type.
+var EventTypes = []EventType{EventCreated, EventUpdated, EventStatusChanged, EventCanceled, EventDeleted, EventSnapshot, EventETAChanged}
+
+const (
+	// HeaderEventType carries the EventType of every message.
+	HeaderEventType = "event-type"
+	// HeaderReplay marks snapshots with the id of the replay job, so that
+	// consumers can tell a backfill from live events.
+	HeaderReplay = "x-replay"
+)
+
+// Routing modes.
+const (
+	// RoutingLegacy publishes every event to Config.Topic as LegacyEvent.
+	RoutingLegacy = "legacy"
+	// RoutingTyped publishes every event to the topic of its type with the
+	// payload of its type.
+	RoutingTyped = "typed"
+	// RoutingBoth is RoutingTyped plus RoutingLegacy, so that consumers of
+	// the old topic keep working during migration.
+	RoutingBoth = "both"
+)
+
+// Routing maps event types to topics. The zero value is RoutingLegacy.
+type Routing struct {
+	Mode string
+	// Topics of the typed events; a type without a topic gets no typed
+	// message, except snapshots, which then go to the created topic.
+	Topics map[EventType]string
+}
+
+func (r Routing) topic(typ EventType) string {
+	if t := r.Topics[typ]; t != "" || typ != EventSnapshot {
+		return t
+	}
+	return r.Topics[EventCreated]
+}
+
+// ParseRoutingMode validates a routing mode.
+func ParseRoutingMode(s string) (string, error) {
+	switch s {
+	case RoutingLegacy, RoutingTyped, RoutingBoth:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown routing mode %q, want legacy, typed or both", s)
+}
+
+func (r Routing) typed() bool  { return r.Mode == RoutingTyped || r.Mode == RoutingBoth }
+func (r Routing) legacy() bool { return r.Mode != RoutingTyped }
+
+// RoutedTopics returns every topic the producer publishes order events to.
+func (c Config) RoutedTopics() []string {
+	var out []string
+	seen := map[string]bool{}
+	add := func(t string) {
+		if t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	if c.Routing.legacy() {
+		add(c.Topic)
+	}
+	if c.Routing.typed() {
+		for _, typ := range EventTypes {
+			add(c.Routing.topic(typ))
+		}
+	}
+	return out
+}
+
+// event is an order event before routing; order is nil for deletions.
+type event struct {
+	typ    EventType
+	order  *entity.Order
+	id     string
+	userID string
+	replay string
+}
+
+func eventOf(typ EventType, o *entity.Order) event {
+	// Any change that leaves the order canceled is reported as a cancellation.
+	if (typ == EventUpdated || typ == EventStatusChanged) && o.Status == entity.OrderStatusCanceled {
+		typ = EventCanceled
+	}
+	return event{typ: typ, order: o, id: o.ID, userID: o.UserID}
+}
+
+func snapshotOf(o *entity.Order, replayID string) event {
+	return event{typ: EventSnapshot, order: o, id: o.ID, userID: o.UserID, replay: replayID}
+}
+
+// outMessage is an event encoded for one topic.
+type outMessage struct {
+	topic  string
+	typ    EventType
+	key    string
+	status string
+	replay string
+	value  []byte
+	at     time.Time
+}
+
+// route encodes ev for every topic the routing sends it to.
+func (c Config) route(ev event) []outMessage {
+	status, now := ev.status(), c.now()
+	var out []outMessage
+	if topic := c.Routing.topic(ev.typ); c.Routing.typed() && topic != "" {
+		b, _ := json.Marshal(ev.payload(now))
+		out = append(out, outMessage{topic: topic, typ: ev.typ, key: ev.id, status: status, replay: ev.replay, value: b, at: now})
+	}
+	if c.Routing.legacy() && ev.typ != EventETAChanged {
+		b, _ := json.Marshal(LegacyEvent{
+			OrderID:   ev.id,
+			Status:    status,
+			CreatedAt: now.Format(time.RFC3339),
+		})
+		out = append(out, outMessage{topic: c.Topic, typ: ev.typ, key: ev.id, status: status, replay: ev.replay, value: b, at: now})
+	}
+	return out
+}
+
+func (ev event) status() string {
+	switch ev.typ {
+	case EventCreated:
+		return string(entity.OrderStatusCreated)
+	case EventDeleted:
+		return string(entity.OrderStatusDeleted)
+	default:
+		return string(ev.order.Status)
+	}
+}
+
+// payload is the typed event; now fills the times the order lacks.
+func (ev event) payload(now time.Time) any {
+	switch ev.typ {
+	case EventCreated:
+		o := ev.order
+		return CreatedEvent{
+			EventType:    ev.typ,
+			OrderID:      o.ID,
+			UserID:       o.UserID,
+			OrderNumber:  o.OrderNumber,
+			RestaurantID: o.RestaurantID,
+			Status:       string(o.Status),
+			Items:        eventItems(o.Items),
+			TotalPrice:   o.TotalPrice,
+			Address:      eventAddress(o.Address),
+			CreatedAt:    orNow(o.CreatedAt, now),
+			// Zero unless the ETA component is on.
+			EstimatedDelivery: o.EstimatedDelivery.UTC(),
+			DeliverAt:         o.DeliverAt.UTC(),
+			PrepareFrom:       o.PrepareFrom.UTC(),
+		}
+	case EventUpdated:
+		o := ev.order
+		return UpdatedEvent{
+			EventType:   ev.typ,
+			OrderID:     o.ID,
+			UserID:      o.UserID,
+			OrderNumber: o.OrderNumber,
+			Status:      string(o.Status),
+			Items:       eventItems(o.Items),
+			TotalPrice:  o.TotalPrice,
+			Address:     eventAddress(o.Address),
+			UpdatedAt:   orNow(o.UpdatedAt, now),
+			DeliverAt:   o.DeliverAt.UTC(),
+			PrepareFrom: o.PrepareFrom.UTC(),
+		}
+	case EventStatusChanged:
+		o := ev.order
+		return StatusChangedEvent{
+			EventType: ev.typ,
+			OrderID:   o.ID,
+			UserID:    o.UserID,
+			Status:    string(o.Status),
+			ChangedAt: orNow(o.StatusChangedAt, now),
+		}
+	case EventSnapshot:
+		o := ev.order
+		return SnapshotEvent{
+			EventType:         ev.typ,
+			ReplayID:          ev.replay,
+			OrderID:           o.ID,
+			UserID:            o.UserID,
+			OrderNumber:       o.OrderNumber,
+			RestaurantID:      o.RestaurantID,
+			Status:            string(o.Status),
+			Items:             eventItems(o.Items),
+			TotalPrice:        o.TotalPrice,
+			Address:           eventAddress(o.Address),
+			CreatedAt:         orNow(o.CreatedAt, now),
+			UpdatedAt:         orNow(o.UpdatedAt, now),
+			StatusChangedAt:   orNow(o.StatusChangedAt, now),
+			EstimatedDelivery: o.EstimatedDelivery.UTC(),
+			DeliverAt:         o.DeliverAt.UTC(),
+			PrepareFrom:       o.PrepareFrom.UTC(),
+		}
+	case EventETAChanged:
+		o := ev.order
+		return ETAChangedEvent{
+			EventType:         ev.typ,
+			OrderID:           o.ID,
+			UserID:            o.UserID,
+			RestaurantID:      o.RestaurantID,
+			Status:            string(o.Status),
+			EstimatedDelivery: o.EstimatedDelivery.UTC(),
+			ChangedAt:         now,
+		}
+	case EventCanceled:
+		o := ev.order
+		return CanceledEvent{
+			EventType:  ev.typ,
+			OrderID:    o.ID,
+			UserID:     o.UserID,
+			CanceledAt: orNow(o.StatusChangedAt, now),
+		}
+	default:
+		return DeletedEvent{EventType: EventDeleted, OrderID: ev.id, UserID: ev.userID, DeletedAt: now}
+	}
+}
+
+func orNow(t, now time.Time) time.Time {
+	if t.IsZero() {
+		return now
+	}
+	return t.UTC()
+}
+
+func eventItems(items []entity.Item) []EventItem {
+	out := make([]EventItem, 0, len(items))
+	for _, it := range items {
+		out = append(out, EventItem{FoodID: it.FoodID, Name: it.Name, Quantity: it.Quantity, Price: it.Price})
+	}
+	return out
+}
+
+func eventAddress(a entity.DeliveryAddress) EventAddress {
+	return EventAddress{
+		Street:    a.Street,
+		House:     a.House,
+		Apartment: a.Apartment,
+		Floor:     a.Floor,
+		Comment:   a.Comment,
+		Location:  (*EventGeoPoint)(a.Location),
+	}
+}
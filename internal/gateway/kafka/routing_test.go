@@ -0,0 +1,192 @@
+package kafka_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/gateway/kafka"
+)
+
+var typedTopics = map[kafka.EventType]string{
+	kafka.EventCreated:       "order.event.created",
+	kafka.EventUpdated:       "order.event.updated",
+	kafka.EventStatusChanged: "order.event.status-changed",
+	kafka.EventCanceled:      "order.event.canceled",
+	kafka.EventDeleted:       "order.event.deleted",
+}
+
+// captureSync expects n messages and returns them once sent.
+func captureSync(t *testing.T, n int) (*mocks.SyncProducer, *[]*sarama.ProducerMessage) {
+	sp := mocks.NewSyncProducer(t, nil)
+	var sent []*sarama.ProducerMessage
+	for range n {
+		sp.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+			sent = append(sent, msg)
+			return nil
+		})
+	}
+	return sp, &sent
+}
+
+func header(msg *sarama.ProducerMessage, key string) string {
+	for _, h := range msg.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func decode(t *testing.T, msg *sarama.ProducerMessage, v any) {
+	t.Helper()
+	b, err := msg.Value.Encode()
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(b, v))
+}
+
+func TestRouting_BothPublishesTypedAndLegacy(t *testing.T) {
+	sp, sent := captureSync(t, 2)
+	p := kafka.NewSaramaProducerFromSync(sp, kafka.Config{
+		Topic:   topic,
+		Routing: kafka.Routing{Mode: kafka.RoutingBoth, Topics: typedTopics},
+	})
+	defer p.Close()
+
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, p.OrderCreated(context.Background(), &entity.Order{
+		ID: "o1", UserID: "u1", RestaurantID: "r1", Status: entity.OrderStatusCreated,
+		Items:      []entity.Item{{FoodID: "f1", Name: "Pizza", Quantity: 2, Price: 100}},
+		TotalPrice: 200, CreatedAt: createdAt,
+	}))
+
+	require.Len(t, *sent, 2)
+	typed, legacy := (*sent)[0], (*sent)[1]
+
+	assert.Equal(t, "order.event.created", typed.Topic)
+	assert.Equal(t, "created", header(typed, kafka.HeaderEventType))
+	var ce kafka.CreatedEvent
+	decode(t, typed, &ce)
+	assert.Equal(t, kafka.EventCreated, ce.EventType)
+	assert.Equal(t, "u1", ce.UserID)
+	assert.Equal(t, "r1", ce.RestaurantID)
+	assert.Equal(t, int64(200), ce.TotalPrice)
+	assert.Equal(t, []kafka.EventItem{{FoodID: "f1", Name: "Pizza", Quantity: 2, Price: 100}}, ce.Items)
+	assert.Equal(t, createdAt, ce.CreatedAt)
+
+	// Existing consumers see exactly the old payload on the old topic.
+	assert.Equal(t, topic, legacy.Topic)
+	var le map[string]any
+	decode(t, legacy, &le)
+	assert.Equal(t, "o1", le["order_id"])
+	assert.Equal(t, "created", le["status"])
+	assert.Len(t, le, 3)
+}
+
+func TestRouting_TypedPayloadPerEventType(t *testing.T) {
+	sp, sent := captureSync(t, 4)
+	p := kafka.NewSaramaProducerFromSync(sp, kafka.Config{
+		Topic:   topic,
+		Routing: kafka.Routing{Mode: kafka.RoutingTyped, Topics: typedTopics},
+	})
+	defer p.Close()
+
+	ctx := context.Background()
+	changedAt := time.Date(2026, 1, 2, 3, 10, 0, 0, time.UTC)
+	require.NoError(t, p.OrderUpdated(ctx, &entity.Order{ID: "o1", UserID: "u1", Status: entity.OrderStatusUpdated, TotalPrice: 300}))
+	require.NoError(t, p.OrderStatusChanged(ctx, &entity.Order{ID: "o1", UserID: "u1", Status: entity.OrderStatusCooking, StatusChangedAt: changedAt}))
+	require.NoError(t, p.OrderStatusChanged(ctx, &entity.Order{ID: "o1", UserID: "u1", Status: entity.OrderStatusCanceled, StatusChangedAt: changedAt}))
+	require.NoError(t, p.OrderDeleted(ctx, "o1", "u1"))
+
+	require.Len(t, *sent, 4)
+	var topics []string
+	for _, msg := range *sent {
+		topics = append(topics, msg.Topic)
+		key, _ := msg.Key.Encode()
+		assert.Equal(t, "o1", string(key))
+	}
+	assert.Equal(t, []string{"order.event.updated", "order.event.status-changed", "order.event.canceled", "order.event.deleted"}, topics)
+
+	var ue kafka.UpdatedEvent
+	decode(t, (*sent)[0], &ue)
+	assert.Equal(t, kafka.EventUpdated, ue.EventType)
+	assert.Equal(t, int64(300), ue.TotalPrice)
+
+	var se kafka.StatusChangedEvent
+	decode(t, (*sent)[1], &se)
+	assert.Equal(t, "cooking", se.Status)
+	assert.Equal(t, changedAt, se.ChangedAt)
+
+	var cancel kafka.CanceledEvent
+	decode(t, (*sent)[2], &cancel)
+	assert.Equal(t, kafka.EventCanceled, cancel.EventType)
+	assert.Equal(t, changedAt, cancel.CanceledAt)
+
+	var de kafka.DeletedEvent
+	decode(t, (*sent)[3], &de)
+	assert.Equal(t, kafka.EventDeleted, de.EventType)
+	assert.Equal(t, "u1", de.UserID)
+}
+
+func TestRouting_TypeWithoutTopicIsSkipped(t *testing.T) {
+	topics := map[kafka.EventType]string{kafka.EventCreated: "order.event.created"}
+	// No expectations: nothing may be sent.
+	sp := mocks.NewSyncProducer(t, nil)
+	p := kafka.NewSaramaProducerFromSync(sp, kafka.Config{Topic: topic, Routing: kafka.Routing{Mode: kafka.RoutingTyped, Topics: topics}})
+	defer p.Close()
+
+	require.NoError(t, p.OrderDeleted(context.Background(), "o1", "u1"))
+}
+
+func TestConfig_RoutedTopics(t *testing.T) {
+	c := kafka.Config{Topic: topic}
+	assert.Equal(t, []string{topic}, c.RoutedTopics(), "zero routing is legacy")
+
+	c.Routing = kafka.Routing{Mode: kafka.RoutingTyped, Topics: map[kafka.EventType]string{
+		kafka.EventCreated:       "order.event.created",
+		kafka.EventStatusChanged: "order.event.status",
+		kafka.EventCanceled:      "order.event.status",
+	}}
+	assert.Equal(t, []string{"order.event.created", "order.event.status"}, c.RoutedTopics())
+
+	c.Routing.Mode = kafka.RoutingBoth
+	assert.Equal(t, []string{topic, "order.event.created", "order.event.status"}, c.RoutedTopics())
+}
+
+func TestRouting_SnapshotFallsBackToCreatedTopic(t *testing.T) {
+	sp, sent := captureSync(t, 2)
+	p := kafka.NewSaramaProducerFromSync(sp, kafka.Config{
+		Topic:   topic,
+		Routing: kafka.Routing{Mode: kafka.RoutingBoth, Topics: typedTopics},
+	})
+	defer p.Close()
+
+	require.NoError(t, p.OrderSnapshot(context.Background(), &entity.Order{
+		ID: "o1", UserID: "u1", RestaurantID: "r1", Status: entity.OrderStatusCooking, TotalPrice: 200,
+	}, "job-1"))
+
+	require.Len(t, *sent, 2)
+	typed, legacy := (*sent)[0], (*sent)[1]
+	assert.Equal(t, "order.event.created", typed.Topic)
+	assert.Equal(t, "snapshot", header(typed, kafka.HeaderEventType))
+	assert.Equal(t, "job-1", header(typed, kafka.HeaderReplay))
+	var se kafka.SnapshotEvent
+	decode(t, typed, &se)
+	assert.Equal(t, kafka.EventSnapshot, se.EventType)
+	assert.Equal(t, "job-1", se.ReplayID)
+	assert.Equal(t, "cooking", se.Status)
+	assert.Equal(t, int64(200), se.TotalPrice)
+
+	assert.Equal(t, topic, legacy.Topic)
+	assert.Equal(t, "job-1", header(legacy, kafka.HeaderReplay))
+	var le map[string]any
+	decode(t, legacy, &le)
+	assert.Equal(t, "cooking", le["status"])
+}
@@ -2,106 +2,258 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
-	"os"
-	"strings"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/nikolaev/service-order/internal/clock"
 	"github.com/nikolaev/service-order/internal/domain/entity"
 )
 
+const instrumentation = "github.com/nikolaev/service-order/internal/gateway/kafka"
+
 // SaramaProducer implements producing order events to Kafka using sarama.
-// Env:
-//   - KAFKA_BROKERS: comma-separated list of brokers (default: localhost:9092)
-//   - KAFKA_ORDER_TOPIC: topic for order status changes (default: order.status.changed)
+// Events go to the topics chosen by Config.Routing. Every message gets a
+// producer span and carries the trace context in its headers (W3C
+// traceparent), see ExtractTraceContext for the consumer side.
 type SaramaProducer struct {
-	p     sarama.SyncProducer
-	topic string
+	client sarama.Client
+	p      sarama.SyncProducer
+	cfg    Config
+	tracer trace.Tracer
 }
 
-type createdEvent struct {
-	OrderID   string `json:"order_id"`
-	Status    string `json:"status"`
-	CreatedAt string `json:"created_at"`
+// Config configures SaramaProducer and AsyncProducer.
+type Config struct {
+	Brokers  []string
+	Topic    string // legacy topic of every event, see Routing
+	RetryMax int
+	Routing  Routing
+	// Compression is one of none, gzip, snappy, lz4, zstd; empty means none.
+	Compression string
+	// Buffer, BatchSize, Linger and DeadLetterTopic are used by AsyncProducer
+	// only. Zero values keep the sarama defaults; an empty DeadLetterTopic
+	// drops messages that failed all retries.
+	Buffer          int
+	BatchSize       int
+	Linger          time.Duration
+	DeadLetterTopic string
+	TLS             TLS
+	SASL            SASL
+	// Topics is applied by SetupTopics.
+	Topics Topics
+	// TracerProvider defaults to the otel global.
+	TracerProvider trace.TracerProvider
+	// Clock dates the events and the messages; defaults to the wall clock.
+	Clock interface{ Now() time.Time }
 }
 
-func NewSaramaProducer() (*SaramaProducer, error) {
-	brokers := os.Getenv("KAFKA_BROKERS")
-	if brokers == "" {
-		brokers = "localhost:9092"
+// Producer modes: sync waits for the broker on every event, async is
+// AsyncProducer.
+const (
+	ModeSync  = "sync"
+	ModeAsync = "async"
+)
+
+// ParseMode validates a producer mode.
+func ParseMode(s string) (string, error) {
+	switch s {
+	case ModeSync, ModeAsync:
+		return s, nil
 	}
+	return "", fmt.Errorf("unknown producer mode %q, want sync or async", s)
+}
 
-	topic := os.Getenv("KAFKA_ORDER_TOPIC")
-	if topic == "" {
-		topic = "order.status.changed"
+// ParseCompression validates a compression codec name.
+func ParseCompression(s string) (sarama.CompressionCodec, error) {
+	var codec sarama.CompressionCodec
+	if s == "" {
+		return sarama.CompressionNone, nil
 	}
+	if err := codec.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown compression %q, want none, gzip, snappy, lz4 or zstd", s)
+	}
+	return codec, nil
+}
 
+// sarama builds the client config; every error wraps ErrInvalidConfig.
+func (c Config) sarama() (*sarama.Config, error) {
+	codec, err := ParseCompression(c.Compression)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
 	cfg := sarama.NewConfig()
 	cfg.Producer.Return.Successes = true
 	cfg.Producer.RequiredAcks = sarama.WaitForAll
-	cfg.Producer.Retry.Max = 5
+	cfg.Producer.Retry.Max = c.RetryMax
+	cfg.Producer.Compression = codec
+	if err := c.TLS.apply(cfg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	if err := c.SASL.apply(cfg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	return cfg, nil
+}
+
+func (c Config) now() time.Time {
+	if c.Clock == nil {
+		return clock.System{}.Now()
+	}
+	return c.Clock.Now()
+}
+
+func (c Config) tracer() trace.Tracer {
+	tp := c.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(instrumentation)
+}
 
-	prod, err := sarama.NewSyncProducer(strings.Split(brokers, ","), cfg)
+func NewSaramaProducer(c Config) (*SaramaProducer, error) {
+	cfg, err := c.sarama()
+	if err != nil {
+		return nil, err
+	}
+	client, err := newClient(c.Brokers, cfg)
 	if err != nil {
 		return nil, err
 	}
+	prod, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
 
-	return &SaramaProducer{p: prod, topic: topic}, nil
+	s := NewSaramaProducerFromSync(prod, c)
+	s.client = client
+	return s, nil
 }
 
-func (s *SaramaProducer) Close() error {
-	return s.p.Close()
+// NewSaramaProducerFromSync builds a producer on top of an existing sync
+// producer, e.g. sarama/mocks in tests. Without a client Ping only reports
+// that metadata is unavailable.
+func NewSaramaProducerFromSync(p sarama.SyncProducer, c Config) *SaramaProducer {
+	return &SaramaProducer{p: p, cfg: c, tracer: c.tracer()}
 }
 
-func (s *SaramaProducer) OrderCreated(_ context.Context, o *entity.Order) error {
-	payload := createdEvent{
-		OrderID:   o.ID,
-		Status:    string(entity.OrderStatusCreated),
-		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+func (s *SaramaProducer) Close() error {
+	err := s.p.Close()
+	if s.client != nil {
+		err = errors.Join(err, s.client.Close())
 	}
+	return err
+}
 
-	b, _ := json.Marshal(payload)
-	msg := &sarama.ProducerMessage{
-		Topic: s.topic,
-		Value: sarama.ByteEncoder(b),
+// Ping refreshes the metadata of the routed topics, which fails when no
+// broker is reachable or a topic has no available leader.
+func (s *SaramaProducer) Ping(context.Context) error {
+	return ping(s.client, s.cfg.RoutedTopics()...)
+}
+
+func ping(client sarama.Client, topics ...string) error {
+	if client == nil {
+		return errors.New("kafka client is not available")
 	}
+	if err := client.RefreshMetadata(topics...); err != nil {
+		return err
+	}
+	for _, topic := range topics {
+		parts, err := client.Partitions(topic)
+		if err != nil {
+			return fmt.Errorf("topic %s: %w", topic, err)
+		}
+		for _, p := range parts {
+			if _, err := client.Leader(topic, p); err != nil {
+				return fmt.Errorf("topic %s partition %d: %w", topic, p, err)
+			}
+		}
+	}
+	return nil
+}
 
-	_, _, err := s.p.SendMessage(msg)
-	return err
+func (s *SaramaProducer) OrderCreated(ctx context.Context, o *entity.Order) error {
+	return s.send(ctx, eventOf(EventCreated, o))
 }
 
-func (s *SaramaProducer) OrderUpdated(_ context.Context, o *entity.Order) error {
-	payload := createdEvent{
-		OrderID:   o.ID,
-		Status:    string(o.Status),
-		CreatedAt: time.Now().UTC().Format(time.RFC3339),
-	}
+func (s *SaramaProducer) OrderUpdated(ctx context.Context, o *entity.Order) error {
+	return s.send(ctx, eventOf(EventUpdated, o))
+}
 
-	b, _ := json.Marshal(payload)
-	msg := &sarama.ProducerMessage{
-		Topic: s.topic,
-		Value: sarama.ByteEncoder(b),
-	}
+func (s *SaramaProducer) OrderStatusChanged(ctx context.Context, o *entity.Order) error {
+	return s.send(ctx, eventOf(EventStatusChanged, o))
+}
 
-	_, _, err := s.p.SendMessage(msg)
-	return err
+func (s *SaramaProducer) OrderDeleted(ctx context.Context, id string, userID string) error {
+	return s.send(ctx, event{typ: EventDeleted, id: id, userID: userID})
+}
+
+func (s *SaramaProducer) OrderSnapshot(ctx context.Context, o *entity.Order, replayID string) error {
+	return s.send(ctx, snapshotOf(o, replayID))
 }
 
-func (s *SaramaProducer) OrderDeleted(_ context.Context, id string, userID string) error {
-	payload := createdEvent{
-		OrderID:   id,
-		Status:    string(entity.OrderStatusDeleted),
-		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+func (s *SaramaProducer) OrderETAChanged(ctx context.Context, o *entity.Order) error {
+	return s.send(ctx, eventOf(EventETAChanged, o))
+}
+
+func (s *SaramaProducer) send(ctx context.Context, ev event) error {
+	var errs []error
+	for _, m := range s.cfg.route(ev) {
+		msg, span := startPublish(ctx, s.tracer, m)
+		partition, offset, err := s.p.SendMessage(msg)
+		endPublish(span, partition, offset, err)
+		errs = append(errs, err)
 	}
+	return errors.Join(errs...)
+}
+
+// startPublish starts the producer span and builds the message carrying its
+// context. The order id is the message key, so events of one order land in
+// one partition and keep their order.
+func startPublish(ctx context.Context, tracer trace.Tracer, m outMessage) (*sarama.ProducerMessage, trace.Span) {
+	ctx, span := tracer.Start(ctx, m.topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypePublish,
+			semconv.MessagingDestinationName(m.topic),
+			attribute.String("order.id", m.key),
+			attribute.String("order.status", m.status),
+			attribute.String("order.event_type", string(m.typ)),
+			attribute.String("order.replay_id", m.replay),
+		))
 
-	b, _ := json.Marshal(payload)
 	msg := &sarama.ProducerMessage{
-		Topic: s.topic,
-		Value: sarama.ByteEncoder(b),
+		Topic:     m.topic,
+		Key:       sarama.StringEncoder(m.key),
+		Value:     sarama.ByteEncoder(m.value),
+		Headers:   []sarama.RecordHeader{{Key: []byte(HeaderEventType), Value: []byte(m.typ)}},
+		Timestamp: m.at,
+	}
+	if m.replay != "" {
+		msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(HeaderReplay), Value: []byte(m.replay)})
 	}
+	InjectTraceContext(ctx, msg)
+	return msg, span
+}
 
-	_, _, err := s.p.SendMessage(msg)
-	return err
+func endPublish(span trace.Span, partition int32, offset int64, err error) {
+	defer span.End()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	span.SetAttributes(
+		semconv.MessagingDestinationPartitionID(fmt.Sprint(partition)),
+		semconv.MessagingKafkaMessageOffset(int(offset)),
+	)
 }
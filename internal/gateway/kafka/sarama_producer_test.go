@@ -0,0 +1,100 @@
+package kafka_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/gateway/kafka"
+	"github.com/nikolaev/service-order/internal/tracing"
+)
+
+const topic = "order.status.changed"
+
+func TestSaramaProducer_Ping(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	metadata := sarama.NewMockMetadataResponse(t).
+		SetBroker(broker.Addr(), broker.BrokerID()).
+		SetLeader(topic, 0, broker.BrokerID())
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{"MetadataRequest": metadata})
+
+	p, err := kafka.NewSaramaProducer(kafka.Config{Brokers: []string{broker.Addr()}, Topic: topic})
+	require.NoError(t, err)
+	defer p.Close()
+
+	require.NoError(t, p.Ping(context.Background()))
+}
+
+func TestSaramaProducer_PingUnknownTopic(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	metadata := sarama.NewMockMetadataResponse(t).
+		SetBroker(broker.Addr(), broker.BrokerID())
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{"MetadataRequest": metadata})
+
+	p, err := kafka.NewSaramaProducer(kafka.Config{Brokers: []string{broker.Addr()}, Topic: topic})
+	require.NoError(t, err)
+	defer p.Close()
+
+	assert.Error(t, p.Ping(context.Background()))
+}
+
+func TestSaramaProducer_PropagatesTraceContext(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	tracing.Install(tp)
+
+	sp := mocks.NewSyncProducer(t, nil)
+	var sent *sarama.ProducerMessage
+	sp.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		sent = msg
+		return nil
+	})
+	p := kafka.NewSaramaProducerFromSync(sp, kafka.Config{Topic: topic, TracerProvider: tp})
+	defer p.Close()
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "usecase")
+	require.NoError(t, p.OrderUpdated(ctx, &entity.Order{ID: "o1", Status: entity.OrderStatusPending}))
+	parent.End()
+
+	spans := rec.Ended()
+	require.Len(t, spans, 2)
+	publish := spans[0]
+	assert.Equal(t, topic+" publish", publish.Name())
+	assert.Equal(t, trace.SpanKindProducer, publish.SpanKind())
+	assert.Equal(t, parent.SpanContext().SpanID(), publish.Parent().SpanID())
+
+	// The consumer side continues the trace from the headers.
+	consumed := &sarama.ConsumerMessage{Topic: topic, Value: sent.Value.(sarama.ByteEncoder)}
+	for _, h := range sent.Headers {
+		consumed.Headers = append(consumed.Headers, &sarama.RecordHeader{Key: h.Key, Value: h.Value})
+	}
+	remote := trace.SpanContextFromContext(kafka.ExtractTraceContext(context.Background(), consumed))
+	assert.True(t, remote.IsRemote())
+	assert.Equal(t, publish.SpanContext().TraceID(), remote.TraceID())
+	assert.Equal(t, publish.SpanContext().SpanID(), remote.SpanID())
+}
+
+func TestSaramaProducer_PublishErrorMarksSpan(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	sp := mocks.NewSyncProducer(t, nil)
+	sp.ExpectSendMessageAndFail(sarama.ErrOutOfBrokers)
+	p := kafka.NewSaramaProducerFromSync(sp, kafka.Config{Topic: topic, TracerProvider: tp})
+	defer p.Close()
+
+	require.ErrorIs(t, p.OrderDeleted(context.Background(), "o1", "u1"), sarama.ErrOutOfBrokers)
+	require.Len(t, rec.Ended(), 1)
+	assert.Equal(t, codes.Error, rec.Ended()[0].Status().Code)
+}
@@ -0,0 +1,141 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/IBM/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// ErrInvalidConfig wraps errors in the Kafka settings themselves (unknown
+// codec or mechanism, unreadable certificates), as opposed to an unreachable
+// cluster.
+var ErrInvalidConfig = errors.New("invalid kafka config")
+
+// newClient connects to the brokers. Sarama validates its config there, so
+// such errors are wrapped into ErrInvalidConfig as well.
+func newClient(brokers []string, cfg *sarama.Config) (sarama.Client, error) {
+	client, err := sarama.NewClient(brokers, cfg)
+	var ce sarama.ConfigurationError
+	if errors.As(err, &ce) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	return client, err
+}
+
+// TLS encrypts broker connections; CertFile and KeyFile add a client
+// certificate for mutual TLS.
+type TLS struct {
+	Enabled bool
+	// CAFile is a PEM bundle of trusted CAs; empty uses the system pool.
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// SASL mechanisms.
+const (
+	SASLPlain       = "PLAIN"
+	SASLScramSHA256 = "SCRAM-SHA-256"
+	SASLScramSHA512 = "SCRAM-SHA-512"
+)
+
+// SASL authenticates the producer; an empty Mechanism disables it.
+type SASL struct {
+	Mechanism string
+	Username  string
+	Password  string
+}
+
+// ParseSASLMechanism validates a SASL mechanism; empty means no SASL.
+func ParseSASLMechanism(s string) (string, error) {
+	switch s {
+	case "", SASLPlain, SASLScramSHA256, SASLScramSHA512:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown SASL mechanism %q, want %s, %s or %s", s, SASLPlain, SASLScramSHA256, SASLScramSHA512)
+}
+
+func (t TLS) apply(cfg *sarama.Config) error {
+	if !t.Enabled {
+		if t.CAFile != "" || t.CertFile != "" || t.KeyFile != "" {
+			return errors.New("tls: certificate files are set but TLS is disabled")
+		}
+		return nil
+	}
+	tc := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: t.InsecureSkipVerify}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return fmt.Errorf("tls: read CA file: %w", err)
+		}
+		tc.RootCAs = x509.NewCertPool()
+		if !tc.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("tls: no PEM certificates in %s", t.CAFile)
+		}
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return errors.New("tls: client certificate and key must be set together")
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return fmt.Errorf("tls: load client certificate: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	cfg.Net.TLS.Enable = true
+	cfg.Net.TLS.Config = tc
+	return nil
+}
+
+func (s SASL) apply(cfg *sarama.Config) error {
+	if _, err := ParseSASLMechanism(s.Mechanism); err != nil {
+		return fmt.Errorf("sasl: %w", err)
+	}
+	if s.Mechanism == "" {
+		return nil
+	}
+	if s.Username == "" || s.Password == "" {
+		return fmt.Errorf("sasl: %s requires a username and password", s.Mechanism)
+	}
+	cfg.Net.SASL.Enable = true
+	cfg.Net.SASL.Mechanism = sarama.SASLMechanism(s.Mechanism)
+	cfg.Net.SASL.User = s.Username
+	cfg.Net.SASL.Password = s.Password
+	switch s.Mechanism {
+	case SASLScramSHA256:
+		cfg.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{hash: scram.SHA256} }
+	case SASLScramSHA512:
+		cfg.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{hash: scram.SHA512} }
+	}
+	return nil
+}
+
+// scramClient implements sarama.SCRAMClient.
+type scramClient struct {
+	hash scram.HashGeneratorFcn
+	conv *scram.ClientConversation
+}
+
+func (c *scramClient) Begin(user, password, authzID string) error {
+	client, err := c.hash.NewClient(user, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.conv = client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.conv.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.conv.Done()
+}
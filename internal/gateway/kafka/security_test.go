@@ -0,0 +1,129 @@
+package kafka_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/gateway/kafka"
+)
+
+// selfSigned returns a certificate for 127.0.0.1 and the path of its PEM.
+func selfSigned(t *testing.T) (tls.Certificate, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "broker"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, path
+}
+
+func TestSaramaProducer_PingOverTLS(t *testing.T) {
+	cert, caFile := selfSigned(t)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+	require.NoError(t, err)
+	broker := sarama.NewMockBrokerListener(t, 1, ln)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(topic, 0, broker.BrokerID()),
+	})
+
+	p, err := kafka.NewSaramaProducer(kafka.Config{
+		Brokers: []string{broker.Addr()},
+		Topic:   topic,
+		TLS:     kafka.TLS{Enabled: true, CAFile: caFile},
+	})
+	require.NoError(t, err)
+	defer p.Close()
+	require.NoError(t, p.Ping(context.Background()))
+}
+
+func TestSaramaProducer_SASLPlain(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"SaslHandshakeRequest":    sarama.NewMockSaslHandshakeResponse(t).SetEnabledMechanisms([]string{kafka.SASLPlain}),
+		"SaslAuthenticateRequest": sarama.NewMockSaslAuthenticateResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(topic, 0, broker.BrokerID()),
+	})
+
+	p, err := kafka.NewSaramaProducer(kafka.Config{
+		Brokers: []string{broker.Addr()},
+		Topic:   topic,
+		SASL:    kafka.SASL{Mechanism: kafka.SASLPlain, Username: "svc", Password: "secret"},
+	})
+	require.NoError(t, err)
+	defer p.Close()
+	require.NoError(t, p.Ping(context.Background()))
+
+	var handshakes int
+	for _, rr := range broker.History() {
+		if _, ok := rr.Request.(*sarama.SaslHandshakeRequest); ok {
+			handshakes++
+		}
+	}
+	assert.Positive(t, handshakes)
+}
+
+func TestNewSaramaProducer_InvalidConfig(t *testing.T) {
+	_, caFile := selfSigned(t)
+	for name, c := range map[string]kafka.Config{
+		"missing CA file":     {TLS: kafka.TLS{Enabled: true, CAFile: "/nonexistent/ca.pem"}},
+		"CA file is not PEM":  {TLS: kafka.TLS{Enabled: true, CAFile: os.Args[0]}},
+		"cert without key":    {TLS: kafka.TLS{Enabled: true, CertFile: caFile}},
+		"files without TLS":   {TLS: kafka.TLS{CAFile: caFile}},
+		"unknown mechanism":   {SASL: kafka.SASL{Mechanism: "GSSAPI", Username: "u", Password: "p"}},
+		"SCRAM without creds": {SASL: kafka.SASL{Mechanism: kafka.SASLScramSHA512}},
+		"unknown compression": {Compression: "brotli"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// The broker is never dialed: invalid settings fail first.
+			c.Brokers = []string{"127.0.0.1:1"}
+			c.Topic = topic
+			_, err := kafka.NewSaramaProducer(c)
+			assert.ErrorIs(t, err, kafka.ErrInvalidConfig)
+			_, err = kafka.NewAsyncProducer(c, nil)
+			assert.ErrorIs(t, err, kafka.ErrInvalidConfig)
+		})
+	}
+}
+
+func TestNewSaramaProducer_UnreachableIsNotInvalidConfig(t *testing.T) {
+	c := kafka.Config{Brokers: []string{"127.0.0.1:1"}, Topic: topic, SASL: kafka.SASL{Mechanism: kafka.SASLScramSHA256, Username: "u", Password: "p"}}
+	_, err := kafka.NewSaramaProducer(c)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, kafka.ErrInvalidConfig)
+}
@@ -0,0 +1,121 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Topic modes of the admin step run before the producer starts.
+const (
+	TopicsOff    = "off"    // rely on the cluster, e.g. auto-creation
+	TopicsCheck  = "check"  // fail when a topic is missing or differs from Topics
+	TopicsCreate = "create" // create missing topics, then check
+)
+
+// Topics describes how the producer's topics must look.
+type Topics struct {
+	Mode              string
+	Partitions        int32
+	ReplicationFactor int16
+	// Retention is retention.ms of the topic; zero keeps the broker default.
+	Retention time.Duration
+}
+
+// ParseTopicsMode validates a topics mode.
+func ParseTopicsMode(s string) (string, error) {
+	switch s {
+	case TopicsOff, TopicsCheck, TopicsCreate:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown topics mode %q, want off, check or create", s)
+}
+
+// SetupTopics connects an admin client with the security settings of c and
+// runs EnsureTopics for names.
+func SetupTopics(c Config, names ...string) error {
+	if c.Topics.Mode == TopicsOff || c.Topics.Mode == "" {
+		return nil
+	}
+	cfg, err := c.sarama()
+	if err != nil {
+		return err
+	}
+	client, err := newClient(c.Brokers, cfg)
+	if err != nil {
+		return fmt.Errorf("kafka admin: %w", err)
+	}
+	// Closing the admin closes the client.
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return fmt.Errorf("kafka admin: %w", err)
+	}
+	defer admin.Close()
+	return EnsureTopics(admin, c.Topics, names...)
+}
+
+// EnsureTopics checks that every topic exists with the configured
+// partitions, replication factor and retention; in create mode missing
+// topics are created first. All problems are reported at once.
+func EnsureTopics(admin sarama.ClusterAdmin, t Topics, names ...string) error {
+	if t.Mode == TopicsOff || t.Mode == "" {
+		return nil
+	}
+	existing, err := admin.ListTopics()
+	if err != nil {
+		return fmt.Errorf("kafka admin: list topics: %w", err)
+	}
+
+	var errs []error
+	for _, name := range names {
+		detail, ok := existing[name]
+		switch {
+		case ok:
+			errs = append(errs, checkTopic(name, detail, t))
+		case t.Mode == TopicsCreate:
+			if err := admin.CreateTopic(name, topicDetail(t), false); err != nil && !errors.Is(err, sarama.ErrTopicAlreadyExists) {
+				errs = append(errs, fmt.Errorf("create topic %s: %w", name, err))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("topic %s does not exist", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func topicDetail(t Topics) *sarama.TopicDetail {
+	d := &sarama.TopicDetail{NumPartitions: t.Partitions, ReplicationFactor: t.ReplicationFactor}
+	if t.Retention > 0 {
+		ms := strconv.FormatInt(t.Retention.Milliseconds(), 10)
+		d.ConfigEntries = map[string]*string{"retention.ms": &ms}
+	}
+	return d
+}
+
+func checkTopic(name string, d sarama.TopicDetail, t Topics) error {
+	var errs []error
+	if d.NumPartitions < t.Partitions {
+		errs = append(errs, fmt.Errorf("topic %s has %d partitions, want at least %d", name, d.NumPartitions, t.Partitions))
+	}
+	if d.ReplicationFactor != t.ReplicationFactor {
+		errs = append(errs, fmt.Errorf("topic %s has replication factor %d, want %d", name, d.ReplicationFactor, t.ReplicationFactor))
+	}
+	if t.Retention > 0 {
+		want := strconv.FormatInt(t.Retention.Milliseconds(), 10)
+		if got := d.ConfigEntries["retention.ms"]; got == nil || *got != want {
+			errs = append(errs, fmt.Errorf("topic %s has retention.ms %s, want %s", name, deref(got), want))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return "<default>"
+	}
+	return *s
+}
@@ -0,0 +1,73 @@
+package kafka_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/gateway/kafka"
+)
+
+// fakeAdmin implements the two ClusterAdmin calls EnsureTopics makes.
+type fakeAdmin struct {
+	sarama.ClusterAdmin
+	topics  map[string]sarama.TopicDetail
+	created map[string]*sarama.TopicDetail
+}
+
+func (a *fakeAdmin) ListTopics() (map[string]sarama.TopicDetail, error) {
+	return a.topics, nil
+}
+
+func (a *fakeAdmin) CreateTopic(name string, d *sarama.TopicDetail, _ bool) error {
+	if a.created == nil {
+		a.created = map[string]*sarama.TopicDetail{}
+	}
+	a.created[name] = d
+	return nil
+}
+
+func ptr(s string) *string { return &s }
+
+var wantTopics = kafka.Topics{Partitions: 3, ReplicationFactor: 2, Retention: 24 * time.Hour}
+
+func TestEnsureTopics_CheckReportsEveryProblem(t *testing.T) {
+	admin := &fakeAdmin{topics: map[string]sarama.TopicDetail{
+		topic: {NumPartitions: 1, ReplicationFactor: 1, ConfigEntries: map[string]*string{"retention.ms": ptr("1000")}},
+	}}
+	spec := wantTopics
+	spec.Mode = kafka.TopicsCheck
+
+	err := kafka.EnsureTopics(admin, spec, topic, deadLetterTopic)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "topic "+topic+" has 1 partitions, want at least 3")
+	assert.Contains(t, err.Error(), "replication factor 1, want 2")
+	assert.Contains(t, err.Error(), "retention.ms 1000, want 86400000")
+	assert.Contains(t, err.Error(), "topic "+deadLetterTopic+" does not exist")
+	assert.Empty(t, admin.created, "check mode never creates topics")
+}
+
+func TestEnsureTopics_CreateMissing(t *testing.T) {
+	admin := &fakeAdmin{topics: map[string]sarama.TopicDetail{
+		// More partitions than required is fine.
+		topic: {NumPartitions: 6, ReplicationFactor: 2, ConfigEntries: map[string]*string{"retention.ms": ptr("86400000")}},
+	}}
+	spec := wantTopics
+	spec.Mode = kafka.TopicsCreate
+
+	require.NoError(t, kafka.EnsureTopics(admin, spec, topic, deadLetterTopic))
+	require.Len(t, admin.created, 1)
+	d := admin.created[deadLetterTopic]
+	require.NotNil(t, d)
+	assert.Equal(t, int32(3), d.NumPartitions)
+	assert.Equal(t, int16(2), d.ReplicationFactor)
+	assert.Equal(t, "86400000", *d.ConfigEntries["retention.ms"])
+}
+
+func TestEnsureTopics_OffSkipsAdmin(t *testing.T) {
+	// A nil admin would panic if it were used.
+	assert.NoError(t, kafka.EnsureTopics(nil, kafka.Topics{Mode: kafka.TopicsOff}, topic))
+}
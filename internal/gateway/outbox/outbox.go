@@ -0,0 +1,201 @@
+// Package outbox decouples event publishing from request handling: events
+// are queued in memory and delivered to the downstream producer by a single
+// goroutine, which keeps their order. On shutdown the queue is drained.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+type Producer interface {
+	OrderCreated(ctx context.Context, o *entity.Order) error
+	OrderUpdated(ctx context.Context, o *entity.Order) error
+	OrderStatusChanged(ctx context.Context, o *entity.Order) error
+	OrderDeleted(ctx context.Context, id string, userID string) error
+	OrderSnapshot(ctx context.Context, o *entity.Order, replayID string) error
+	OrderETAChanged(ctx context.Context, o *entity.Order) error
+}
+
+var ErrClosed = errors.New("outbox is closed")
+
+type kind int
+
+const (
+	kindCreated kind = iota
+	kindUpdated
+	kindStatusChanged
+	kindDeleted
+	kindSnapshot
+	kindETAChanged
+)
+
+type event struct {
+	ctx    context.Context
+	kind   kind
+	order  *entity.Order
+	id     string
+	userID string
+	replay string    // replay id of a snapshot
+	at     time.Time // when the event was enqueued
+}
+
+// Outbox implements Producer. Enqueueing blocks while the buffer is full, so
+// a slow downstream applies backpressure instead of losing events.
+type Outbox struct {
+	down Producer
+	in   chan event
+	quit chan struct{} // closed by Stop, releases blocked senders
+	done chan struct{}
+
+	mu      sync.RWMutex
+	closed  bool
+	senders sync.WaitGroup // enqueues past the closed check
+
+	// inflight is the enqueue time (unix nanos) of the event being delivered,
+	// 0 when idle. Delivery is FIFO, so it is the oldest undelivered event.
+	inflight atomic.Int64
+}
+
+func New(down Producer, size int) *Outbox {
+	return &Outbox{down: down, in: make(chan event, size), quit: make(chan struct{}), done: make(chan struct{})}
+}
+
+func (o *Outbox) OrderCreated(ctx context.Context, ord *entity.Order) error {
+	cp := *ord
+	return o.enqueue(ctx, event{kind: kindCreated, order: &cp})
+}
+
+func (o *Outbox) OrderUpdated(ctx context.Context, ord *entity.Order) error {
+	cp := *ord
+	return o.enqueue(ctx, event{kind: kindUpdated, order: &cp})
+}
+
+func (o *Outbox) OrderStatusChanged(ctx context.Context, ord *entity.Order) error {
+	cp := *ord
+	return o.enqueue(ctx, event{kind: kindStatusChanged, order: &cp})
+}
+
+func (o *Outbox) OrderDeleted(ctx context.Context, id string, userID string) error {
+	return o.enqueue(ctx, event{kind: kindDeleted, id: id, userID: userID})
+}
+
+func (o *Outbox) OrderSnapshot(ctx context.Context, ord *entity.Order, replayID string) error {
+	cp := *ord
+	return o.enqueue(ctx, event{kind: kindSnapshot, order: &cp, replay: replayID})
+}
+
+func (o *Outbox) OrderETAChanged(ctx context.Context, ord *entity.Order) error {
+	cp := *ord
+	return o.enqueue(ctx, event{kind: kindETAChanged, order: &cp})
+}
+
+// enqueue detaches the event from ctx cancellation: a request that finished
+// (or was aborted) after its change was stored must still publish it, so
+// only Stop releases a sender waiting for room.
+func (o *Outbox) enqueue(ctx context.Context, e event) error {
+	o.mu.RLock()
+	if o.closed {
+		o.mu.RUnlock()
+		return ErrClosed
+	}
+	o.senders.Add(1)
+	o.mu.RUnlock()
+	defer o.senders.Done()
+
+	e.ctx = context.WithoutCancel(ctx)
+	e.at = time.Now()
+	select {
+	case o.in <- e:
+		return nil
+	case <-o.quit:
+		return ErrClosed
+	}
+}
+
+// Start launches the delivery goroutine.
+func (o *Outbox) Start(context.Context) error {
+	go o.run()
+	return nil
+}
+
+// Stop rejects new events and waits until the queued ones are delivered or
+// ctx expires.
+func (o *Outbox) Stop(ctx context.Context) error {
+	o.mu.Lock()
+	if !o.closed {
+		o.closed = true
+		close(o.quit)
+		// Senders still selecting may yet land their event; close in for
+		// the delivery loop only once they are gone.
+		go func() {
+			o.senders.Wait()
+			close(o.in)
+		}()
+	}
+	o.mu.Unlock()
+
+	select {
+	case <-o.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("outbox: %d events not delivered: %w", len(o.in), ctx.Err())
+	}
+}
+
+// Pending reports how many events wait for delivery.
+func (o *Outbox) Pending() int { return len(o.in) }
+
+// Lag is how long the oldest undelivered event has been waiting.
+func (o *Outbox) Lag() time.Duration {
+	n := o.inflight.Load()
+	if n == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, n))
+}
+
+// CheckLag returns a health check that fails when Lag exceeds max.
+func (o *Outbox) CheckLag(max time.Duration) func(context.Context) error {
+	return func(context.Context) error {
+		if lag := o.Lag(); lag > max {
+			return fmt.Errorf("outbox lag %s exceeds %s (%d events pending)", lag.Round(time.Millisecond), max, o.Pending())
+		}
+		return nil
+	}
+}
+
+func (o *Outbox) run() {
+	defer close(o.done)
+	for e := range o.in {
+		o.inflight.Store(e.at.UnixNano())
+		if err := o.deliver(e); err != nil {
+			slog.ErrorContext(e.ctx, "outbox: deliver event", "error", err)
+		}
+		o.inflight.Store(0)
+	}
+}
+
+func (o *Outbox) deliver(e event) error {
+	switch e.kind {
+	case kindCreated:
+		return o.down.OrderCreated(e.ctx, e.order)
+	case kindUpdated:
+		return o.down.OrderUpdated(e.ctx, e.order)
+	case kindStatusChanged:
+		return o.down.OrderStatusChanged(e.ctx, e.order)
+	case kindSnapshot:
+		return o.down.OrderSnapshot(e.ctx, e.order, e.replay)
+	case kindETAChanged:
+		return o.down.OrderETAChanged(e.ctx, e.order)
+	default:
+		return o.down.OrderDeleted(e.ctx, e.id, e.userID)
+	}
+}
@@ -0,0 +1,138 @@
+package outbox_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/gateway/outbox"
+)
+
+// slowProducer records delivered events after a delay.
+type slowProducer struct {
+	delay time.Duration
+	mu    sync.Mutex
+	got   []string
+}
+
+func (p *slowProducer) add(s string) error {
+	time.Sleep(p.delay)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.got = append(p.got, s)
+	return nil
+}
+
+func (p *slowProducer) OrderCreated(_ context.Context, o *entity.Order) error {
+	return p.add("created " + o.ID)
+}
+
+func (p *slowProducer) OrderUpdated(_ context.Context, o *entity.Order) error {
+	return p.add("updated " + o.ID + " " + string(o.Status))
+}
+
+func (p *slowProducer) OrderStatusChanged(_ context.Context, o *entity.Order) error {
+	return p.add("status " + o.ID + " " + string(o.Status))
+}
+
+func (p *slowProducer) OrderDeleted(_ context.Context, id string, _ string) error {
+	return p.add("deleted " + id)
+}
+
+func (p *slowProducer) OrderSnapshot(_ context.Context, o *entity.Order, replayID string) error {
+	return p.add("snapshot " + o.ID + " " + replayID)
+}
+
+func (p *slowProducer) OrderETAChanged(_ context.Context, o *entity.Order) error {
+	return p.add("eta " + o.ID)
+}
+
+func TestOutbox_StopDrainsInOrder(t *testing.T) {
+	down := &slowProducer{delay: time.Millisecond}
+	ob := outbox.New(down, 2)
+	require.NoError(t, ob.Start(context.Background()))
+
+	// The request context is canceled right after publishing; the event must
+	// still be delivered.
+	ctx, cancel := context.WithCancel(context.Background())
+	o := &entity.Order{ID: "o1", Status: entity.OrderStatusCreated}
+	require.NoError(t, ob.OrderCreated(ctx, o))
+	cancel()
+
+	o.Status = entity.OrderStatusPending // the queued copy is not affected
+	require.NoError(t, ob.OrderUpdated(context.Background(), o))
+	require.NoError(t, ob.OrderDeleted(context.Background(), "o1", "u1"))
+
+	require.NoError(t, ob.Stop(context.Background()))
+	assert.Equal(t, []string{"created o1", "updated o1 pending", "deleted o1"}, down.got)
+
+	assert.ErrorIs(t, ob.OrderDeleted(context.Background(), "o2", "u1"), outbox.ErrClosed)
+}
+
+func TestOutbox_StopTimeoutReportsPending(t *testing.T) {
+	down := &slowProducer{delay: 50 * time.Millisecond}
+	ob := outbox.New(down, 10)
+	require.NoError(t, ob.Start(context.Background()))
+	for range 5 {
+		require.NoError(t, ob.OrderDeleted(context.Background(), "o", "u"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := ob.Stop(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "not delivered")
+}
+
+func TestOutbox_CheckLag(t *testing.T) {
+	down := &slowProducer{delay: 50 * time.Millisecond}
+	ob := outbox.New(down, 10)
+	check := ob.CheckLag(10 * time.Millisecond)
+	require.NoError(t, check(context.Background()))
+
+	require.NoError(t, ob.Start(context.Background()))
+	require.NoError(t, ob.OrderDeleted(context.Background(), "o", "u"))
+	require.NoError(t, ob.OrderDeleted(context.Background(), "o", "u"))
+	require.Eventually(t, func() bool { return check(context.Background()) != nil }, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, ob.Stop(context.Background()))
+	assert.Zero(t, ob.Lag())
+	assert.NoError(t, check(context.Background()))
+}
+
+func TestOutbox_FullBufferWaitsPastRequestContext(t *testing.T) {
+	down := &slowProducer{}
+	ob := outbox.New(down, 1)
+	require.NoError(t, ob.OrderDeleted(context.Background(), "o1", "u1"))
+
+	// The buffer is full and the request is gone: the event waits for room
+	// instead of being dropped.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res := make(chan error, 1)
+	go func() { res <- ob.OrderDeleted(ctx, "o2", "u1") }()
+	assert.Never(t, func() bool { return len(res) > 0 }, 20*time.Millisecond, time.Millisecond)
+
+	require.NoError(t, ob.Start(context.Background()))
+	require.NoError(t, <-res)
+	require.NoError(t, ob.Stop(context.Background()))
+	assert.Equal(t, []string{"deleted o1", "deleted o2"}, down.got)
+}
+
+func TestOutbox_StopReleasesBlockedSenders(t *testing.T) {
+	ob := outbox.New(&slowProducer{}, 1)
+	require.NoError(t, ob.OrderDeleted(context.Background(), "o1", "u1"))
+	res := make(chan error, 1)
+	go func() { res <- ob.OrderDeleted(context.Background(), "o2", "u1") }()
+
+	// Never started, so nothing is delivered and Stop times out.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, ob.Stop(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, <-res, outbox.ErrClosed)
+}
@@ -0,0 +1,164 @@
+// Package webhook delivers order events to partner HTTP endpoints that cannot
+// consume Kafka. Dispatcher is another Producer: it matches each event
+// against the subscriptions and queues a signed delivery per match, retried
+// with exponential backoff until it succeeds or runs out of attempts.
+package webhook
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+type EventType string
+
+const (
+	EventCreated       EventType = "created"
+	EventUpdated       EventType = "updated"
+	EventStatusChanged EventType = "status_changed"
+	EventCanceled      EventType = "canceled"
+	EventDeleted       EventType = "deleted"
+	// EventETAChanged is a new estimated_delivery of the order.
+	EventETAChanged EventType = "eta_changed"
+)
+
+var EventTypes = []EventType{EventCreated, EventUpdated, EventStatusChanged, EventCanceled, EventDeleted, EventETAChanged}
+
+// Subscription asks for the events of Events (all when empty) of the
+// restaurant RestaurantID (all when empty) to be posted to URL.
+type Subscription struct {
+	ID  string
+	URL string
+	// Secret keys the HMAC-SHA256 signature; it is only returned by Subscribe.
+	Secret       string
+	Events       []EventType
+	RestaurantID string
+	CreatedAt    time.Time
+}
+
+type DeliveryState string
+
+const (
+	DeliveryPending   DeliveryState = "pending"
+	DeliveryRetrying  DeliveryState = "retrying"
+	DeliveryDelivered DeliveryState = "delivered"
+	// DeliveryDead is the dead letter: every attempt failed. Redeliver
+	// starts it over.
+	DeliveryDead DeliveryState = "dead"
+)
+
+// Delivery is one event on its way to one subscription.
+type Delivery struct {
+	ID             string
+	SubscriptionID string
+	EventID        string
+	Event          EventType
+	OrderID        string
+	State          DeliveryState
+	Attempts       int
+	// LastStatus is the HTTP status of the last attempt, 0 when it got none.
+	LastStatus    int
+	LastError     string
+	CreatedAt     time.Time
+	NextAttemptAt time.Time // zero once finished
+	FinishedAt    time.Time // zero until delivered or dead
+}
+
+// Service manages the subscriptions and shows their deliveries.
+type Service interface {
+	// Subscribe validates s and stores it; an empty secret is generated.
+	Subscribe(s Subscription) (Subscription, error)
+	// Subscriptions returns the subscriptions, oldest first, without secrets.
+	Subscriptions() []Subscription
+	Subscription(id string) (Subscription, error)
+	// Unsubscribe removes the subscription; its pending deliveries go dead.
+	Unsubscribe(id string) error
+	// Deliveries returns the log of the subscription, newest first.
+	Deliveries(subscriptionID string) ([]Delivery, error)
+	// Redeliver queues a dead delivery again with a fresh set of attempts.
+	Redeliver(subscriptionID, deliveryID string) (Delivery, error)
+}
+
+type Clock interface{ Now() time.Time }
+
+// Options tune the delivery; zero fields take the defaults.
+type Options struct {
+	// Timeout bounds one attempt.
+	Timeout     time.Duration
+	MaxAttempts int
+	// Backoff is the delay after the first failed attempt; it doubles after
+	// every next one, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Workers is how many deliveries are sent at once.
+	Workers int
+	// LogSize is how many finished deliveries are remembered.
+	LogSize int
+	// MaxPending is how many deliveries of one subscription may wait for an
+	// attempt; the oldest go dead to make room for new ones.
+	MaxPending int
+	// AllowedHosts limits subscription URLs to these hosts and their
+	// subdomains; empty allows any host.
+	AllowedHosts []string
+	// AllowPrivate lets subscriptions and deliveries reach loopback,
+	// private and link-local addresses, for partners on the same network.
+	AllowPrivate bool
+}
+
+var DefaultOptions = Options{
+	Timeout:     5 * time.Second,
+	MaxAttempts: 6,
+	Backoff:     time.Second,
+	MaxBackoff:  5 * time.Minute,
+	Workers:     4,
+	LogSize:     1000,
+	MaxPending:  1000,
+}
+
+func (o Options) withDefaults() Options {
+	d := DefaultOptions
+	if o.Timeout > 0 {
+		d.Timeout = o.Timeout
+	}
+	if o.MaxAttempts > 0 {
+		d.MaxAttempts = o.MaxAttempts
+	}
+	if o.Backoff > 0 {
+		d.Backoff = o.Backoff
+	}
+	if o.MaxBackoff > 0 {
+		d.MaxBackoff = o.MaxBackoff
+	}
+	if o.Workers > 0 {
+		d.Workers = o.Workers
+	}
+	if o.LogSize > 0 {
+		d.LogSize = o.LogSize
+	}
+	if o.MaxPending > 0 {
+		d.MaxPending = o.MaxPending
+	}
+	d.AllowedHosts = o.AllowedHosts
+	d.AllowPrivate = o.AllowPrivate
+	return d
+}
+
+// delay is the wait after the failed attempt n, counted from 1.
+func (o Options) delay(n int) time.Duration {
+	d := o.Backoff
+	for i := 1; i < n && d < o.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, o.MaxBackoff)
+}
+
+// ParseEventType validates an event type name.
+func ParseEventType(s string) (EventType, error) {
+	for _, t := range EventTypes {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unknown webhook event %q", entity.ErrInvalidInput, s)
+}
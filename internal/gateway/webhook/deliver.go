@@ -0,0 +1,217 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature is "sha256=" and the hex HMAC-SHA256 of the timestamp,
+	// a dot and the body, keyed with the subscription secret.
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Sign returns the HeaderSignature value for a body sent at ts.
+func Sign(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+var ErrBadSignature = errors.New("webhook signature mismatch")
+
+// Verify checks the signature headers of a received delivery, for receivers
+// written in Go.
+func Verify(secret string, h http.Header, body []byte) error {
+	ts, err := strconv.ParseInt(h.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return ErrBadSignature
+	}
+	if !hmac.Equal([]byte(h.Get(HeaderSignature)), []byte(Sign(secret, ts, body))) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// Start runs the delivery loop until Stop.
+func (d *Dispatcher) Start(context.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.done = make(chan struct{})
+	go d.loop(ctx)
+	return nil
+}
+
+// Stop ends the loop and waits for the attempts in flight.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	if d.cancel == nil {
+		return nil
+	}
+	d.cancel()
+	<-d.done
+	finished := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// loop starts the due deliveries, at most Workers at once, and sleeps until
+// the next one is due or a new one arrives.
+func (d *Dispatcher) loop(ctx context.Context) {
+	defer close(d.done)
+	sem := make(chan struct{}, d.opts.Workers)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		due, next := d.due(cap(sem) - len(sem))
+		for _, dl := range due {
+			sem <- struct{}{}
+			d.wg.Add(1)
+			go func() {
+				defer d.wg.Done()
+				d.attempt(dl)
+				<-sem
+				d.notify()
+			}()
+		}
+		wait := time.Hour
+		if !next.IsZero() {
+			wait = max(next.Sub(d.clk.Now()), 0)
+		}
+		timer.Reset(wait)
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-timer.C:
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+	}
+}
+
+// due marks up to n due deliveries in flight and returns them, with the time
+// the next waiting one is due.
+func (d *Dispatcher) due(n int) ([]*delivery, time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.clk.Now()
+	var (
+		out  []*delivery
+		next time.Time
+	)
+	for _, dl := range d.log {
+		if dl.finished() || dl.inFlight {
+			continue
+		}
+		if !dl.NextAttemptAt.After(now) && len(out) < n {
+			dl.inFlight = true
+			out = append(out, dl)
+			continue
+		}
+		if next.IsZero() || dl.NextAttemptAt.Before(next) {
+			next = dl.NextAttemptAt
+		}
+	}
+	return out, next
+}
+
+func (d *Dispatcher) attempt(dl *delivery) {
+	d.mu.Lock()
+	i, err := d.find(dl.SubscriptionID)
+	var s Subscription
+	if err == nil {
+		s = *d.subs[i]
+	}
+	d.mu.Unlock()
+
+	status, sendErr := 0, errors.New("subscription removed")
+	if err == nil {
+		status, sendErr = d.send(s, dl)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dl.inFlight = false
+	dl.Attempts++
+	dl.LastStatus = status
+	now := d.clk.Now()
+	switch {
+	case sendErr == nil:
+		dl.State = DeliveryDelivered
+		dl.LastError = ""
+		dl.NextAttemptAt = time.Time{}
+		dl.FinishedAt = now
+	case err != nil || dl.Attempts >= d.opts.MaxAttempts:
+		dl.fail(now, sendErr.Error())
+		slog.Warn("webhook delivery dead", "subscription_id", dl.SubscriptionID, "delivery_id", dl.ID,
+			"event", dl.Event, "order_id", dl.OrderID, "attempts", dl.Attempts, "error", sendErr)
+	default:
+		dl.State = DeliveryRetrying
+		dl.LastError = sendErr.Error()
+		dl.NextAttemptAt = now.Add(d.opts.delay(dl.Attempts))
+	}
+}
+
+func (d *Dispatcher) send(s Subscription, dl *delivery) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(dl.body))
+	if err != nil {
+		return 0, err
+	}
+	ts := d.clk.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "service-order-webhooks")
+	req.Header.Set(HeaderEvent, string(dl.Event))
+	req.Header.Set(HeaderDelivery, dl.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(s.Secret, ts, dl.body))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (dl *delivery) finished() bool {
+	return dl.State == DeliveryDelivered || dl.State == DeliveryDead
+}
+
+func (dl *delivery) fail(now time.Time, reason string) {
+	dl.State = DeliveryDead
+	dl.LastError = reason
+	dl.NextAttemptAt = time.Time{}
+	dl.FinishedAt = now
+}
@@ -0,0 +1,375 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+// Dispatcher implements both the order Producer and Service. Everything is
+// kept in memory; deliveries still pending at shutdown are lost.
+type Dispatcher struct {
+	opts   Options
+	clk    Clock
+	client *http.Client
+
+	mu          sync.Mutex
+	subs        []*Subscription
+	log         []*delivery // oldest first
+	restaurants map[string]string
+	wake        chan struct{}
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+type delivery struct {
+	Delivery
+	body     []byte
+	inFlight bool
+}
+
+func NewDispatcher(clk Clock, opts Options) *Dispatcher {
+	opts = opts.withDefaults()
+	return &Dispatcher{
+		opts:        opts,
+		clk:         clk,
+		client:      newHTTPClient(opts),
+		restaurants: make(map[string]string),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+var _ Service = (*Dispatcher)(nil)
+
+func (d *Dispatcher) Subscribe(s Subscription) (Subscription, error) {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Subscription{}, fmt.Errorf("%w: webhook url must be an absolute http(s) URL", entity.ErrInvalidInput)
+	}
+	if err := d.opts.checkHost(u.Hostname()); err != nil {
+		return Subscription{}, err
+	}
+	for _, t := range s.Events {
+		if _, err := ParseEventType(string(t)); err != nil {
+			return Subscription{}, err
+		}
+	}
+	if s.Secret == "" {
+		b := make([]byte, 32)
+		_, _ = rand.Read(b)
+		s.Secret = hex.EncodeToString(b)
+	}
+	s.ID = uuid.NewString()
+	s.Events = slices.Clone(s.Events)
+	s.CreatedAt = d.clk.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	cp := s
+	d.subs = append(d.subs, &cp)
+	return s, nil
+}
+
+func (d *Dispatcher) Subscriptions() []Subscription {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]Subscription, 0, len(d.subs))
+	for _, s := range d.subs {
+		out = append(out, public(s))
+	}
+	return out
+}
+
+func public(s *Subscription) Subscription {
+	cp := *s
+	cp.Secret = ""
+	cp.Events = slices.Clone(s.Events)
+	return cp
+}
+
+func (d *Dispatcher) find(id string) (int, error) {
+	for i, s := range d.subs {
+		if s.ID == id {
+			return i, nil
+		}
+	}
+	return -1, entity.ErrNotFound
+}
+
+func (d *Dispatcher) Subscription(id string) (Subscription, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i, err := d.find(id)
+	if err != nil {
+		return Subscription{}, err
+	}
+	return public(d.subs[i]), nil
+}
+
+func (d *Dispatcher) Unsubscribe(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i, err := d.find(id)
+	if err != nil {
+		return err
+	}
+	d.subs = slices.Delete(d.subs, i, i+1)
+	now := d.clk.Now()
+	for _, dl := range d.log {
+		if dl.SubscriptionID == id && !dl.finished() && !dl.inFlight {
+			dl.fail(now, "subscription removed")
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) Deliveries(subscriptionID string) ([]Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.find(subscriptionID); err != nil {
+		return nil, err
+	}
+	var out []Delivery
+	for i := len(d.log) - 1; i >= 0; i-- {
+		if d.log[i].SubscriptionID == subscriptionID {
+			out = append(out, d.log[i].Delivery)
+		}
+	}
+	return out, nil
+}
+
+func (d *Dispatcher) Redeliver(subscriptionID, deliveryID string) (Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.find(subscriptionID); err != nil {
+		return Delivery{}, err
+	}
+	for _, dl := range d.log {
+		if dl.ID != deliveryID || dl.SubscriptionID != subscriptionID {
+			continue
+		}
+		if dl.State != DeliveryDead {
+			return Delivery{}, fmt.Errorf("%w: delivery is %s, only dead ones are redelivered", entity.ErrConflict, dl.State)
+		}
+		dl.State = DeliveryPending
+		dl.Attempts = 0
+		dl.NextAttemptAt = d.clk.Now()
+		dl.FinishedAt = time.Time{}
+		d.notify()
+		return dl.Delivery, nil
+	}
+	return Delivery{}, entity.ErrNotFound
+}
+
+// Payload is the JSON body of every delivery. ID identifies the event and
+// is the same in every delivery and retry of it.
+type Payload struct {
+	ID         string    `json:"id"`
+	Type       EventType `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Order      Order     `json:"order"`
+}
+
+type Order struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id"`
+	OrderNumber  string    `json:"order_number,omitempty"`
+	RestaurantID string    `json:"restaurant_id,omitempty"`
+	Status       string    `json:"status"`
+	Items        []Item    `json:"items,omitempty"`
+	TotalPrice   int64     `json:"total_price,omitempty"`
+	Address      *Address  `json:"address,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitzero"`
+	UpdatedAt    time.Time `json:"updated_at,omitzero"`
+	// EstimatedDelivery is omitted without the ETA component.
+	EstimatedDelivery time.Time `json:"estimated_delivery,omitzero"`
+	// DeliverAt and PrepareFrom are set on scheduled orders.
+	DeliverAt   time.Time `json:"deliver_at,omitzero"`
+	PrepareFrom time.Time `json:"prepare_from,omitzero"`
+}
+
+type Item struct {
+	FoodID   string `json:"food_id"`
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+	Price    int    `json:"price"`
+}
+
+type Address struct {
+	Street    string    `json:"street"`
+	House     string    `json:"house,omitempty"`
+	Apartment string    `json:"apartment,omitempty"`
+	Floor     string    `json:"floor,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	Location  *GeoPoint `json:"location,omitempty"`
+}
+
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+func addressOf(a entity.DeliveryAddress) *Address {
+	return &Address{
+		Street:    a.Street,
+		House:     a.House,
+		Apartment: a.Apartment,
+		Floor:     a.Floor,
+		Comment:   a.Comment,
+		Location:  (*GeoPoint)(a.Location),
+	}
+}
+
+func orderOf(o *entity.Order) Order {
+	out := Order{
+		ID:           o.ID,
+		UserID:       o.UserID,
+		OrderNumber:  o.OrderNumber,
+		RestaurantID: o.RestaurantID,
+		Status:       string(o.Status),
+		TotalPrice:   o.TotalPrice,
+		Address:      addressOf(o.Address),
+		CreatedAt:    o.CreatedAt,
+		UpdatedAt:    o.UpdatedAt,
+
+		EstimatedDelivery: o.EstimatedDelivery,
+		DeliverAt:         o.DeliverAt,
+		PrepareFrom:       o.PrepareFrom,
+	}
+	for _, it := range o.Items {
+		out.Items = append(out.Items, Item(it))
+	}
+	return out
+}
+
+func (d *Dispatcher) OrderCreated(_ context.Context, o *entity.Order) error {
+	d.publish(EventCreated, orderOf(o))
+	return nil
+}
+
+func (d *Dispatcher) OrderUpdated(_ context.Context, o *entity.Order) error {
+	d.publish(EventUpdated, orderOf(o))
+	return nil
+}
+
+func (d *Dispatcher) OrderStatusChanged(_ context.Context, o *entity.Order) error {
+	typ := EventStatusChanged
+	if o.Status == entity.OrderStatusCanceled {
+		typ = EventCanceled
+	}
+	d.publish(typ, orderOf(o))
+	return nil
+}
+
+func (d *Dispatcher) OrderDeleted(_ context.Context, id string, userID string) error {
+	d.publish(EventDeleted, Order{ID: id, UserID: userID, Status: string(entity.OrderStatusDeleted)})
+	return nil
+}
+
+// OrderSnapshot is ignored: replays are for Kafka consumers.
+func (d *Dispatcher) OrderSnapshot(context.Context, *entity.Order, string) error { return nil }
+
+func (d *Dispatcher) OrderETAChanged(_ context.Context, o *entity.Order) error {
+	d.publish(EventETAChanged, orderOf(o))
+	return nil
+}
+
+// publish queues a delivery for every matching subscription. A deleted event
+// carries no restaurant, so the one seen in earlier events is used; it is
+// forgotten once the order is finished, so deleting a finished order reaches
+// only subscriptions without a restaurant.
+func (d *Dispatcher) publish(typ EventType, o Order) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case typ == EventDeleted:
+		o.RestaurantID = d.restaurants[o.ID]
+		delete(d.restaurants, o.ID)
+	case o.Status == string(entity.OrderStatusCompleted) || o.Status == string(entity.OrderStatusDelivered) ||
+		o.Status == string(entity.OrderStatusCanceled):
+		delete(d.restaurants, o.ID)
+	case o.RestaurantID != "":
+		d.restaurants[o.ID] = o.RestaurantID
+	}
+
+	now := d.clk.Now()
+	p := Payload{ID: uuid.NewString(), Type: typ, OccurredAt: now, Order: o}
+	body, _ := json.Marshal(p)
+	for _, s := range d.subs {
+		if len(s.Events) > 0 && !slices.Contains(s.Events, typ) {
+			continue
+		}
+		if s.RestaurantID != "" && s.RestaurantID != o.RestaurantID {
+			continue
+		}
+		d.shed(s.ID, now)
+		d.log = append(d.log, &delivery{
+			Delivery: Delivery{
+				ID:             uuid.NewString(),
+				SubscriptionID: s.ID,
+				EventID:        p.ID,
+				Event:          typ,
+				OrderID:        o.ID,
+				State:          DeliveryPending,
+				CreatedAt:      now,
+				NextAttemptAt:  now,
+			},
+			body: body,
+		})
+	}
+	d.trim()
+	d.notify()
+}
+
+// shed makes room for one more delivery of the subscription: beyond
+// MaxPending waiting ones the oldest go dead, so a receiver that is down for
+// long does not pile up an unbounded backlog.
+func (d *Dispatcher) shed(subscriptionID string, now time.Time) {
+	var waiting []*delivery
+	for _, dl := range d.log {
+		if dl.SubscriptionID == subscriptionID && !dl.finished() && !dl.inFlight {
+			waiting = append(waiting, dl)
+		}
+	}
+	for i := 0; i <= len(waiting)-d.opts.MaxPending; i++ {
+		waiting[i].fail(now, "dropped: too many pending deliveries")
+		slog.Warn("webhook delivery dropped", "subscription_id", subscriptionID, "delivery_id", waiting[i].ID,
+			"event", waiting[i].Event, "order_id", waiting[i].OrderID)
+	}
+}
+
+// trim drops the oldest finished deliveries beyond LogSize.
+func (d *Dispatcher) trim() {
+	extra := len(d.log) - d.opts.LogSize
+	if extra <= 0 {
+		return
+	}
+	d.log = slices.DeleteFunc(d.log, func(dl *delivery) bool {
+		if extra > 0 && dl.finished() {
+			extra--
+			return true
+		}
+		return false
+	})
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,28 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nikolaev/service-order/internal/clock"
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+func TestDispatcher_ForgetsRestaurantOfFinishedOrders(t *testing.T) {
+	d := NewDispatcher(clock.System{}, Options{})
+	ctx := context.Background()
+	for _, st := range []entity.OrderStatus{entity.OrderStatusCompleted, entity.OrderStatusDelivered, entity.OrderStatusCanceled} {
+		o := &entity.Order{ID: "o-" + string(st), RestaurantID: "r1", Status: entity.OrderStatusCreated}
+		_ = d.OrderCreated(ctx, o)
+		assert.Equal(t, "r1", d.restaurants[o.ID])
+		o.Status = st
+		_ = d.OrderStatusChanged(ctx, o)
+		assert.NotContains(t, d.restaurants, o.ID, st)
+	}
+
+	_ = d.OrderCreated(ctx, &entity.Order{ID: "o1", RestaurantID: "r1", Status: entity.OrderStatusCreated})
+	_ = d.OrderDeleted(ctx, "o1", "u1")
+	assert.Empty(t, d.restaurants)
+}
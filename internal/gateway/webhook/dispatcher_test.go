@@ -0,0 +1,274 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/clock"
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/gateway/webhook"
+)
+
+type received struct {
+	header  http.Header
+	body    []byte
+	payload webhook.Payload
+}
+
+// receiver is a partner endpoint; it fails the first fail requests.
+type receiver struct {
+	mu   sync.Mutex
+	got  []received
+	fail int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail > 0 {
+		r.fail--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var p webhook.Payload
+	_ = json.Unmarshal(body, &p)
+	r.got = append(r.got, received{header: req.Header.Clone(), body: body, payload: p})
+}
+
+func (r *receiver) deliveries() []received {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]received(nil), r.got...)
+}
+
+func start(t *testing.T, opts webhook.Options) *webhook.Dispatcher {
+	t.Helper()
+	opts.AllowPrivate = true // receivers listen on loopback
+	d := webhook.NewDispatcher(clock.System{}, opts)
+	require.NoError(t, d.Start(context.Background()))
+	t.Cleanup(func() { _ = d.Stop(context.Background()) })
+	return d
+}
+
+func order(id, restaurant string) *entity.Order {
+	return &entity.Order{
+		ID: id, UserID: "u1", RestaurantID: restaurant, Status: entity.OrderStatusCreated,
+		Items: []entity.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 500}}, TotalPrice: 500,
+	}
+}
+
+func TestDispatcher_DeliversSignedEvents(t *testing.T) {
+	rec := &receiver{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	d := start(t, webhook.Options{})
+
+	sub, err := d.Subscribe(webhook.Subscription{URL: srv.URL, Secret: "s3cret"})
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", sub.Secret)
+
+	ctx := context.Background()
+	require.NoError(t, d.OrderCreated(ctx, order("o1", "r1")))
+	require.NoError(t, d.OrderDeleted(ctx, "o1", "u1"))
+	require.Eventually(t, func() bool { return len(rec.deliveries()) == 2 }, 2*time.Second, 5*time.Millisecond)
+
+	byType := map[webhook.EventType]received{}
+	for _, r := range rec.deliveries() {
+		byType[r.payload.Type] = r
+	}
+	created, deleted := byType[webhook.EventCreated], byType[webhook.EventDeleted]
+	assert.NoError(t, webhook.Verify("s3cret", created.header, created.body))
+	assert.ErrorIs(t, webhook.Verify("other", created.header, created.body), webhook.ErrBadSignature)
+	assert.Equal(t, "created", created.header.Get(webhook.HeaderEvent))
+	assert.Equal(t, "o1", created.payload.Order.ID)
+	assert.Equal(t, int64(500), created.payload.Order.TotalPrice)
+	assert.Equal(t, "o1", deleted.payload.Order.ID)
+	assert.Equal(t, "r1", deleted.payload.Order.RestaurantID, "remembered from the created event")
+
+	require.Eventually(t, func() bool {
+		log, err := d.Deliveries(sub.ID)
+		return err == nil && len(log) == 2 && log[0].State == webhook.DeliveryDelivered && log[1].State == webhook.DeliveryDelivered
+	}, time.Second, 5*time.Millisecond)
+	log, _ := d.Deliveries(sub.ID)
+	assert.Equal(t, webhook.EventDeleted, log[0].Event, "newest first")
+	assert.Equal(t, 1, log[0].Attempts)
+	assert.Equal(t, http.StatusOK, log[0].LastStatus)
+	assert.Equal(t, deleted.header.Get(webhook.HeaderDelivery), log[0].ID)
+	assert.Equal(t, deleted.payload.ID, log[0].EventID)
+
+	subs := d.Subscriptions()
+	require.Len(t, subs, 1)
+	assert.Empty(t, subs[0].Secret, "the secret is only shown once")
+}
+
+func TestDispatcher_FiltersByEventAndRestaurant(t *testing.T) {
+	rec := &receiver{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	d := start(t, webhook.Options{})
+
+	_, err := d.Subscribe(webhook.Subscription{URL: srv.URL, Events: []webhook.EventType{webhook.EventCanceled}, RestaurantID: "r1"})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, d.OrderCreated(ctx, order("o1", "r1")))
+	o := order("o2", "r2")
+	o.Status = entity.OrderStatusCanceled
+	require.NoError(t, d.OrderStatusChanged(ctx, o))
+	o = order("o1", "r1")
+	o.Status = entity.OrderStatusCooking
+	require.NoError(t, d.OrderStatusChanged(ctx, o))
+	o.Status = entity.OrderStatusCanceled
+	require.NoError(t, d.OrderStatusChanged(ctx, o))
+	require.NoError(t, d.OrderSnapshot(ctx, o, "replay"))
+
+	require.Eventually(t, func() bool { return len(rec.deliveries()) == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	got := rec.deliveries()
+	require.Len(t, got, 1)
+	assert.Equal(t, webhook.EventCanceled, got[0].payload.Type)
+	assert.Equal(t, "o1", got[0].payload.Order.ID)
+}
+
+func TestDispatcher_RetriesWithBackoffThenDeadLetters(t *testing.T) {
+	rec := &receiver{fail: 2}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	d := start(t, webhook.Options{Backoff: 20 * time.Millisecond, MaxAttempts: 3})
+
+	sub, err := d.Subscribe(webhook.Subscription{URL: srv.URL})
+	require.NoError(t, err)
+	began := time.Now()
+	require.NoError(t, d.OrderCreated(context.Background(), order("o1", "r1")))
+
+	require.Eventually(t, func() bool { return len(rec.deliveries()) == 1 }, 2*time.Second, 5*time.Millisecond)
+	// 20ms after the first failure, 40ms after the second.
+	assert.GreaterOrEqual(t, time.Since(began), 60*time.Millisecond)
+	require.Eventually(t, func() bool {
+		log, _ := d.Deliveries(sub.ID)
+		return log[0].State == webhook.DeliveryDelivered
+	}, time.Second, 5*time.Millisecond)
+	log, _ := d.Deliveries(sub.ID)
+	assert.Equal(t, 3, log[0].Attempts)
+
+	// A receiver that keeps failing puts the delivery in the dead letter.
+	rec.mu.Lock()
+	rec.fail = 3
+	rec.mu.Unlock()
+	require.NoError(t, d.OrderCreated(context.Background(), order("o2", "r1")))
+	var dead webhook.Delivery
+	require.Eventually(t, func() bool {
+		log, _ := d.Deliveries(sub.ID)
+		dead = log[0]
+		return dead.State == webhook.DeliveryDead
+	}, 2*time.Second, 5*time.Millisecond)
+	assert.Equal(t, 3, dead.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, dead.LastStatus)
+	assert.Contains(t, dead.LastError, "503")
+
+	// Redelivery starts it over.
+	again, err := d.Redeliver(sub.ID, dead.ID)
+	require.NoError(t, err)
+	assert.Equal(t, webhook.DeliveryPending, again.State)
+	require.Eventually(t, func() bool { return len(rec.deliveries()) == 2 }, 2*time.Second, 5*time.Millisecond)
+	assert.Equal(t, rec.deliveries()[0].payload.Type, rec.deliveries()[1].payload.Type)
+
+	_, err = d.Redeliver(sub.ID, dead.ID)
+	assert.ErrorIs(t, err, entity.ErrConflict, "no longer dead")
+	_, err = d.Redeliver(sub.ID, "missing")
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+}
+
+func TestDispatcher_UnreachableReceiverAndUnsubscribe(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+	d := start(t, webhook.Options{Backoff: time.Hour, Timeout: time.Second})
+
+	sub, err := d.Subscribe(webhook.Subscription{URL: url})
+	require.NoError(t, err)
+	require.NoError(t, d.OrderCreated(context.Background(), order("o1", "r1")))
+	require.Eventually(t, func() bool {
+		log, _ := d.Deliveries(sub.ID)
+		return log[0].State == webhook.DeliveryRetrying
+	}, 2*time.Second, 5*time.Millisecond)
+	log, _ := d.Deliveries(sub.ID)
+	assert.Zero(t, log[0].LastStatus)
+	assert.NotEmpty(t, log[0].LastError)
+
+	require.NoError(t, d.Unsubscribe(sub.ID))
+	_, err = d.Deliveries(sub.ID)
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+	assert.ErrorIs(t, d.Unsubscribe(sub.ID), entity.ErrNotFound)
+}
+
+func TestDispatcher_SubscribeValidates(t *testing.T) {
+	d := webhook.NewDispatcher(clock.System{}, webhook.Options{})
+	_, err := d.Subscribe(webhook.Subscription{URL: "ftp://partner"})
+	assert.ErrorIs(t, err, entity.ErrInvalidInput)
+	_, err = d.Subscribe(webhook.Subscription{URL: "/relative"})
+	assert.ErrorIs(t, err, entity.ErrInvalidInput)
+	_, err = d.Subscribe(webhook.Subscription{URL: "https://partner.example/hook", Events: []webhook.EventType{"shipped"}})
+	assert.ErrorIs(t, err, entity.ErrInvalidInput)
+
+	sub, err := d.Subscribe(webhook.Subscription{URL: "https://partner.example/hook"})
+	require.NoError(t, err)
+	assert.Len(t, sub.Secret, 64, "generated")
+}
+
+func TestDispatcher_SubscribeRejectsInternalTargets(t *testing.T) {
+	d := webhook.NewDispatcher(clock.System{}, webhook.Options{})
+	for _, url := range []string{
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"http://10.1.2.3/hook",
+		"http://[::1]/hook",
+		"http://[::ffff:192.168.0.1]/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/hook",
+	} {
+		_, err := d.Subscribe(webhook.Subscription{URL: url})
+		assert.ErrorIs(t, err, entity.ErrInvalidInput, url)
+	}
+
+	d = webhook.NewDispatcher(clock.System{}, webhook.Options{AllowedHosts: []string{"partner.example"}})
+	_, err := d.Subscribe(webhook.Subscription{URL: "https://hooks.partner.example/orders"})
+	assert.NoError(t, err)
+	_, err = d.Subscribe(webhook.Subscription{URL: "https://PARTNER.example./orders"})
+	assert.NoError(t, err)
+	_, err = d.Subscribe(webhook.Subscription{URL: "https://notpartner.example/orders"})
+	assert.ErrorIs(t, err, entity.ErrInvalidInput)
+
+	d = webhook.NewDispatcher(clock.System{}, webhook.Options{AllowPrivate: true})
+	_, err = d.Subscribe(webhook.Subscription{URL: "http://127.0.0.1:9000/hook"})
+	assert.NoError(t, err)
+}
+
+func TestDispatcher_MaxPendingDropsOldest(t *testing.T) {
+	// Not started: every delivery keeps waiting.
+	d := webhook.NewDispatcher(clock.System{}, webhook.Options{MaxPending: 2})
+	sub, err := d.Subscribe(webhook.Subscription{URL: "https://partner.example/hook"})
+	require.NoError(t, err)
+	for _, id := range []string{"o1", "o2", "o3"} {
+		require.NoError(t, d.OrderCreated(context.Background(), order(id, "r1")))
+	}
+
+	log, err := d.Deliveries(sub.ID)
+	require.NoError(t, err)
+	require.Len(t, log, 3)
+	assert.Equal(t, webhook.DeliveryPending, log[0].State)
+	assert.Equal(t, webhook.DeliveryPending, log[1].State)
+	assert.Equal(t, webhook.DeliveryDead, log[2].State)
+	assert.Equal(t, "o1", log[2].OrderID)
+	assert.Contains(t, log[2].LastError, "too many pending")
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+// errPrivateAddress refuses a connection the subscription URL check could
+// not catch: a public name resolving, or redirecting, to an internal address.
+var errPrivateAddress = errors.New("webhook target resolves to a private address")
+
+// checkHost validates the host of a subscription URL against AllowedHosts
+// and, unless AllowPrivate, rejects localhost and internal IP literals.
+func (o Options) checkHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if len(o.AllowedHosts) > 0 && !o.allowed(host) {
+		return fmt.Errorf("%w: webhook host %q is not in webhook.allowed_hosts", entity.ErrInvalidInput, host)
+	}
+	if o.AllowPrivate {
+		return nil
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: webhook host %q is local", entity.ErrInvalidInput, host)
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && private(ip) {
+		return fmt.Errorf("%w: webhook host %q is a private address", entity.ErrInvalidInput, host)
+	}
+	return nil
+}
+
+func (o Options) allowed(host string) bool {
+	for _, a := range o.AllowedHosts {
+		a = strings.TrimSuffix(strings.ToLower(a), ".")
+		if host == a || strings.HasSuffix(host, "."+a) {
+			return true
+		}
+	}
+	return false
+}
+
+// private reports addresses a partner endpoint never has: loopback,
+// private, shared (100.64/10), link-local (cloud metadata), multicast and
+// unspecified ones.
+func private(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() ||
+		netip.MustParsePrefix("100.64.0.0/10").Contains(ip)
+}
+
+// newHTTPClient checks every address it dials and every redirect, so that
+// DNS cannot point an accepted subscription at an internal service.
+func newHTTPClient(o Options) *http.Client {
+	c := &http.Client{
+		Timeout: o.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return o.checkHost(req.URL.Hostname())
+		},
+	}
+	if o.AllowPrivate {
+		return c
+	}
+	dialer := &net.Dialer{
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip, err := netip.ParseAddr(host); err != nil || private(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		},
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.DialContext = dialer.DialContext
+	c.Transport = tr
+	return c
+}
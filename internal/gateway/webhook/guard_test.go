@@ -0,0 +1,24 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPClient_RefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	_, err := newHTTPClient(DefaultOptions).Get(srv.URL)
+	assert.ErrorIs(t, err, errPrivateAddress)
+
+	opts := DefaultOptions
+	opts.AllowPrivate = true
+	resp, err := newHTTPClient(opts).Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+}
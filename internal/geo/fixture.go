@@ -0,0 +1,96 @@
+package geo
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+// FixtureEntry locates a house, or the whole street when House is empty.
+type FixtureEntry struct {
+	Street string  `json:"street"`
+	House  string  `json:"house,omitempty"`
+	Lat    float64 `json:"lat"`
+	Lon    float64 `json:"lon"`
+}
+
+// Fixture is an offline geocoder for tests and local runs. It knows only
+// the addresses of its table; a house it does not list gets the point of
+// its street, if the street is listed. Street and house match regardless
+// of case and extra spaces.
+type Fixture struct {
+	points map[string]entity.GeoPoint // by key(street, house)
+}
+
+//go:embed fixture.json
+var fixtureJSON []byte
+
+// DefaultFixture knows the streets of the demo seed scenario and of loadgen.
+func DefaultFixture() *Fixture {
+	f, err := ParseFixture(fixtureJSON)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// LoadFixture reads a JSON array of FixtureEntry.
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ParseFixture(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+func ParseFixture(data []byte) (*Fixture, error) {
+	var entries []FixtureEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	f := &Fixture{points: make(map[string]entity.GeoPoint, len(entries))}
+	var errs []error
+	for i, e := range entries {
+		p := entity.GeoPoint{Lat: e.Lat, Lon: e.Lon}
+		switch {
+		case normalize(e.Street) == "":
+			errs = append(errs, fmt.Errorf("entry %d: street is required", i))
+		case !p.Valid():
+			errs = append(errs, fmt.Errorf("entry %d: %g,%g is not a position", i, e.Lat, e.Lon))
+		default:
+			f.points[key(e.Street, e.House)] = p
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Geocode returns nil when the fixture does not know the address.
+func (f *Fixture) Geocode(_ context.Context, a entity.DeliveryAddress) (*entity.GeoPoint, error) {
+	for _, k := range []string{key(a.Street, a.House), key(a.Street, "")} {
+		if p, ok := f.points[k]; ok {
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
+
+func key(street, house string) string {
+	return normalize(street) + "\x00" + normalize(house)
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
@@ -0,0 +1,39 @@
+// Package geo places delivery addresses on the map. A Fixture geocodes
+// addresses offline from a table of known streets, Zones tells from GeoJSON
+// polygons whether a restaurant delivers to a point and how far the point
+// is from the restaurant.
+package geo
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+// Geocoders, selected by geo.geocoder.
+const (
+	GeocoderNone    = "none"
+	GeocoderFixture = "fixture"
+)
+
+// ParseGeocoder validates a geocoder name.
+func ParseGeocoder(s string) (string, error) {
+	switch s {
+	case GeocoderNone, GeocoderFixture:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown geocoder %q, want none or fixture", s)
+}
+
+const earthRadiusKm = 6371.0088
+
+// Km is the great-circle distance between a and b.
+func Km(a, b entity.GeoPoint) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat, dLon := lat2-lat1, radians(b.Lon-a.Lon)
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
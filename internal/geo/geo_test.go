@@ -0,0 +1,92 @@
+package geo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/geo"
+)
+
+func TestKm(t *testing.T) {
+	moscow := entity.GeoPoint{Lat: 55.7558, Lon: 37.6173}
+	petersburg := entity.GeoPoint{Lat: 59.9386, Lon: 30.3141}
+	assert.InDelta(t, 634, geo.Km(moscow, petersburg), 2)
+	assert.Zero(t, geo.Km(moscow, moscow))
+}
+
+func TestFixture_Geocode(t *testing.T) {
+	f, err := geo.ParseFixture([]byte(`[
+		{"street": "Tverskaya", "house": "11", "lat": 55.7617, "lon": 37.6086},
+		{"street": "Tverskaya", "lat": 55.7642, "lon": 37.6056}
+	]`))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	p, err := f.Geocode(ctx, entity.DeliveryAddress{Street: " tverskaya ", House: "11", Apartment: "5"})
+	require.NoError(t, err)
+	assert.Equal(t, &entity.GeoPoint{Lat: 55.7617, Lon: 37.6086}, p)
+
+	p, _ = f.Geocode(ctx, entity.DeliveryAddress{Street: "Tverskaya", House: "99"})
+	assert.Equal(t, &entity.GeoPoint{Lat: 55.7642, Lon: 37.6056}, p, "an unknown house falls back to its street")
+
+	p, err = f.Geocode(ctx, entity.DeliveryAddress{Street: "Nowhere", House: "1"})
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	_, err = geo.ParseFixture([]byte(`[{"street": "", "lat": 1, "lon": 1}, {"street": "X", "lat": 91, "lon": 0}]`))
+	assert.ErrorContains(t, err, "entry 0: street is required")
+	assert.ErrorContains(t, err, "entry 1: 91,0 is not a position")
+}
+
+func TestDefaultFixture_KnowsTheDemoStreets(t *testing.T) {
+	f := geo.DefaultFixture()
+	for _, street := range []string{"Tverskaya", "Arbat", "Lenina", "Nevsky", "Тверская", "Ленина"} {
+		p, err := f.Geocode(context.Background(), entity.DeliveryAddress{Street: street, House: "10"})
+		require.NoError(t, err)
+		assert.NotNil(t, p, street)
+	}
+}
+
+func TestZones(t *testing.T) {
+	z, err := geo.LoadZones("testdata/zones.geojson")
+	require.NoError(t, err)
+
+	tverskaya := entity.GeoPoint{Lat: 55.7617, Lon: 37.6086}
+	assert.True(t, z.Zoned("r1"))
+	assert.True(t, z.Covers("r1", tverskaya))
+	assert.False(t, z.Covers("r1", entity.GeoPoint{Lat: 55.7565, Lon: 37.602}), "the hole is not covered")
+	assert.False(t, z.Covers("r1", entity.GeoPoint{Lat: 59.9359, Lon: 30.3148}))
+	assert.True(t, z.Covers("r2", entity.GeoPoint{Lat: 59.9359, Lon: 30.3148}), "first polygon of the multipolygon")
+	assert.True(t, z.Covers("r2", entity.GeoPoint{Lat: 55.8894, Lon: 37.4449}), "second polygon of the multipolygon")
+	assert.False(t, z.Zoned("r3"))
+	assert.False(t, z.Covers("r3", tverskaya))
+
+	ctx := context.Background()
+	km, ok := z.Km(ctx, "r1", entity.DeliveryAddress{Location: &tverskaya})
+	require.True(t, ok)
+	assert.InDelta(t, 0.83, km, 0.05)
+	_, ok = z.Km(ctx, "r1", entity.DeliveryAddress{Street: "Tverskaya"})
+	assert.False(t, ok, "the address is not located")
+	_, ok = z.Km(ctx, "r2", entity.DeliveryAddress{Location: &tverskaya})
+	assert.False(t, ok, "r2 has no point")
+}
+
+func TestParseZones_Rejects(t *testing.T) {
+	_, err := geo.ParseZones([]byte(`{"type": "Feature"}`))
+	assert.ErrorContains(t, err, "want a FeatureCollection")
+
+	_, err = geo.ParseZones([]byte(`{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "properties": {}, "geometry": {"type": "Point", "coordinates": [37, 55]}},
+		{"type": "Feature", "properties": {"restaurant_id": "r1"}, "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 1]]]}},
+		{"type": "Feature", "properties": {"restaurant_id": "r1"}, "geometry": {"type": "LineString", "coordinates": [[0, 0], [1, 1]]}},
+		{"type": "Feature", "properties": {"restaurant_id": "r1"}, "geometry": {"type": "Point", "coordinates": [200, 55]}}
+	]}`))
+	assert.ErrorContains(t, err, "feature 0: properties.restaurant_id is required")
+	assert.ErrorContains(t, err, "feature 1: ring 0 is not closed")
+	assert.ErrorContains(t, err, `feature 2: geometry "LineString" is not a Point, Polygon or MultiPolygon`)
+	assert.ErrorContains(t, err, "feature 3: position [200 55] is out of range")
+}
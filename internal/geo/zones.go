@@ -0,0 +1,216 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+// Zones are the delivery zones of restaurants, read from a GeoJSON
+// FeatureCollection. Every feature names its restaurant in the property
+// restaurant_id: a Polygon or MultiPolygon adds to where it delivers, a
+// Point is where it cooks. A restaurant may have several zones and at most
+// one point.
+type Zones struct {
+	zones  map[string][]polygon
+	places map[string]entity.GeoPoint
+}
+
+// polygon is an outer ring followed by its holes.
+type polygon [][]entity.GeoPoint
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string `json:"type"`
+	Properties struct {
+		RestaurantID string `json:"restaurant_id"`
+	} `json:"properties"`
+	Geometry *struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+}
+
+// LoadZones reads a GeoJSON file.
+func LoadZones(path string) (*Zones, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	z, err := ParseZones(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return z, nil
+}
+
+func ParseZones(data []byte) (*Zones, error) {
+	var fc featureCollection
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return nil, err
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("want a FeatureCollection, got %q", fc.Type)
+	}
+	z := &Zones{zones: map[string][]polygon{}, places: map[string]entity.GeoPoint{}}
+	var errs []error
+	for i, f := range fc.Features {
+		if err := z.add(f); err != nil {
+			errs = append(errs, fmt.Errorf("feature %d: %w", i, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+func (z *Zones) add(f feature) error {
+	id := f.Properties.RestaurantID
+	switch {
+	case f.Type != "Feature":
+		return fmt.Errorf("want a Feature, got %q", f.Type)
+	case id == "":
+		return errors.New("properties.restaurant_id is required")
+	case f.Geometry == nil:
+		return errors.New("geometry is required")
+	}
+	switch g := f.Geometry; g.Type {
+	case "Point":
+		var pos []float64
+		if err := json.Unmarshal(g.Coordinates, &pos); err != nil {
+			return err
+		}
+		p, err := point(pos)
+		if err != nil {
+			return err
+		}
+		if _, dup := z.places[id]; dup {
+			return fmt.Errorf("restaurant %s has a second point", id)
+		}
+		z.places[id] = p
+	case "Polygon":
+		var rings [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &rings); err != nil {
+			return err
+		}
+		pg, err := toPolygon(rings)
+		if err != nil {
+			return err
+		}
+		z.zones[id] = append(z.zones[id], pg)
+	case "MultiPolygon":
+		var polys [][][][]float64
+		if err := json.Unmarshal(g.Coordinates, &polys); err != nil {
+			return err
+		}
+		for j, rings := range polys {
+			pg, err := toPolygon(rings)
+			if err != nil {
+				return fmt.Errorf("polygon %d: %w", j, err)
+			}
+			z.zones[id] = append(z.zones[id], pg)
+		}
+	default:
+		return fmt.Errorf("geometry %q is not a Point, Polygon or MultiPolygon", g.Type)
+	}
+	return nil
+}
+
+// point reads a GeoJSON position, longitude first.
+func point(pos []float64) (entity.GeoPoint, error) {
+	if len(pos) < 2 {
+		return entity.GeoPoint{}, fmt.Errorf("position %v needs longitude and latitude", pos)
+	}
+	p := entity.GeoPoint{Lat: pos[1], Lon: pos[0]}
+	if !p.Valid() {
+		return entity.GeoPoint{}, fmt.Errorf("position %v is out of range", pos)
+	}
+	return p, nil
+}
+
+func toPolygon(rings [][][]float64) (polygon, error) {
+	if len(rings) == 0 {
+		return nil, errors.New("polygon has no rings")
+	}
+	pg := make(polygon, 0, len(rings))
+	for i, ring := range rings {
+		if len(ring) < 4 {
+			return nil, fmt.Errorf("ring %d has %d positions, want at least 4", i, len(ring))
+		}
+		pts := make([]entity.GeoPoint, 0, len(ring))
+		for _, pos := range ring {
+			p, err := point(pos)
+			if err != nil {
+				return nil, fmt.Errorf("ring %d: %w", i, err)
+			}
+			pts = append(pts, p)
+		}
+		if pts[0] != pts[len(pts)-1] {
+			return nil, fmt.Errorf("ring %d is not closed", i)
+		}
+		pg = append(pg, pts)
+	}
+	return pg, nil
+}
+
+// Zoned reports whether the restaurant has any zone; a restaurant without
+// one delivers anywhere.
+func (z *Zones) Zoned(restaurantID string) bool {
+	return len(z.zones[restaurantID]) > 0
+}
+
+// Covers reports whether p lies in one of the restaurant's zones.
+func (z *Zones) Covers(restaurantID string, p entity.GeoPoint) bool {
+	for _, pg := range z.zones[restaurantID] {
+		if pg.contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Km is the straight-line distance from the restaurant's point to the
+// address; ok is false when either is not known. It makes Zones an
+// eta.Distance.
+func (z *Zones) Km(_ context.Context, restaurantID string, a entity.DeliveryAddress) (float64, bool) {
+	from, ok := z.places[restaurantID]
+	if !ok || a.Location == nil {
+		return 0, false
+	}
+	return Km(from, *a.Location), true
+}
+
+func (pg polygon) contains(p entity.GeoPoint) bool {
+	if !inRing(pg[0], p) {
+		return false
+	}
+	for _, hole := range pg[1:] {
+		if inRing(hole, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// inRing casts a ray from p along the latitude; the coordinates are taken
+// as planar, which is accurate enough at the scale of a city.
+func inRing(ring []entity.GeoPoint, p entity.GeoPoint) bool {
+	in := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			in = !in
+		}
+	}
+	return in
+}
@@ -0,0 +1,171 @@
+package grpcapi
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	uc "github.com/nikolaev/service-order/internal/usecase/order"
+	orderv1 "github.com/nikolaev/service-order/pkg/api/order/v1"
+)
+
+var statusToProto = map[entity.OrderStatus]orderv1.OrderStatus{
+	entity.OrderStatusCreated:    orderv1.OrderStatus_ORDER_STATUS_CREATED,
+	entity.OrderStatusPending:    orderv1.OrderStatus_ORDER_STATUS_PENDING,
+	entity.OrderStatusConfirmed:  orderv1.OrderStatus_ORDER_STATUS_CONFIRMED,
+	entity.OrderStatusCooking:    orderv1.OrderStatus_ORDER_STATUS_COOKING,
+	entity.OrderStatusDelivering: orderv1.OrderStatus_ORDER_STATUS_DELIVERING,
+	entity.OrderStatusDelivered:  orderv1.OrderStatus_ORDER_STATUS_DELIVERED,
+	entity.OrderStatusCompleted:  orderv1.OrderStatus_ORDER_STATUS_COMPLETED,
+	entity.OrderStatusCanceled:   orderv1.OrderStatus_ORDER_STATUS_CANCELED,
+	entity.OrderStatusDeleted:    orderv1.OrderStatus_ORDER_STATUS_DELETED,
+	entity.OrderStatusUpdated:    orderv1.OrderStatus_ORDER_STATUS_UPDATED,
+	entity.OrderStatusScheduled:  orderv1.OrderStatus_ORDER_STATUS_SCHEDULED,
+}
+
+func toProtoStatus(s entity.OrderStatus) orderv1.OrderStatus {
+	return statusToProto[s]
+}
+
+func toProto(o *entity.Order) *orderv1.Order {
+	return &orderv1.Order{
+		Id:                o.ID,
+		UserId:            o.UserID,
+		OrderNumber:       o.OrderNumber,
+		Fio:               o.FIO,
+		RestaurantId:      o.RestaurantID,
+		Items:             toProtoItems(o.Items),
+		TotalPrice:        o.TotalPrice,
+		Address:           toProtoAddress(o.Address),
+		Status:            toProtoStatus(o.Status),
+		CreatedAt:         toTimestamp(o.CreatedAt),
+		UpdatedAt:         toTimestamp(o.UpdatedAt),
+		EstimatedDelivery: toTimestamp(o.EstimatedDelivery),
+		DeliverAt:         toTimestamp(o.DeliverAt),
+		PrepareFrom:       toTimestamp(o.PrepareFrom),
+	}
+}
+
+func toDomainCreate(in *orderv1.CreateOrderRequest) uc.CreateInput {
+	return uc.CreateInput{
+		OrderNumber:  in.GetOrderNumber(),
+		FIO:          in.GetFio(),
+		RestaurantID: in.GetRestaurantId(),
+		Items:        toDomainItems(in.GetItems()),
+		TotalPrice:   in.GetTotalPrice(),
+		Address:      toDomainAddress(in.GetAddress()),
+		DeliverAt:    toTime(in.GetDeliverAt()),
+		PrepareFrom:  toTime(in.GetPrepareFrom()),
+	}
+}
+
+func toDomainUpdate(in *orderv1.UpdateOrderRequest) uc.UpdateInput {
+	var items *[]entity.Item
+	if in.Items != nil {
+		v := toDomainItems(in.GetItems().GetItems())
+		items = &v
+	}
+	var addr *entity.DeliveryAddress
+	if in.Address != nil {
+		v := toDomainAddress(in.GetAddress())
+		addr = &v
+	}
+
+	return uc.UpdateInput{
+		OrderNumber: in.OrderNumber,
+		FIO:         in.Fio,
+		Items:       items,
+		TotalPrice:  in.TotalPrice,
+		Address:     addr,
+		DeliverAt:   toTimePtr(in.GetDeliverAt()),
+		PrepareFrom: toTimePtr(in.GetPrepareFrom()),
+	}
+}
+
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// toTime maps an unset timestamp to the zero time.
+func toTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func toTimePtr(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+func toDomainItems(items []*orderv1.Item) []entity.Item {
+	out := make([]entity.Item, 0, len(items))
+	for _, it := range items {
+		out = append(out, entity.Item{
+			FoodID:   it.GetFoodId(),
+			Name:     it.GetName(),
+			Quantity: int(it.GetQuantity()),
+			Price:    int(it.GetPrice()),
+		})
+	}
+
+	return out
+}
+
+func toDomainAddress(a *orderv1.DeliveryAddress) entity.DeliveryAddress {
+	return entity.DeliveryAddress{
+		Street:    a.GetStreet(),
+		House:     a.GetHouse(),
+		Apartment: a.GetApartment(),
+		Floor:     a.GetFloor(),
+		Comment:   a.GetComment(),
+		Location:  toDomainPoint(a.GetLocation()),
+	}
+}
+
+func toDomainPoint(p *orderv1.GeoPoint) *entity.GeoPoint {
+	if p == nil {
+		return nil
+	}
+	return &entity.GeoPoint{Lat: p.GetLat(), Lon: p.GetLon()}
+}
+
+func toProtoItems(items []entity.Item) []*orderv1.Item {
+	out := make([]*orderv1.Item, 0, len(items))
+	for _, it := range items {
+		out = append(out, &orderv1.Item{
+			FoodId:   it.FoodID,
+			Name:     it.Name,
+			Quantity: int32(it.Quantity),
+			Price:    int32(it.Price),
+		})
+	}
+
+	return out
+}
+
+func toProtoAddress(a entity.DeliveryAddress) *orderv1.DeliveryAddress {
+	return &orderv1.DeliveryAddress{
+		Street:    a.Street,
+		House:     a.House,
+		Apartment: a.Apartment,
+		Floor:     a.Floor,
+		Comment:   a.Comment,
+		Location:  toProtoPoint(a.Location),
+	}
+}
+
+func toProtoPoint(p *entity.GeoPoint) *orderv1.GeoPoint {
+	if p == nil {
+		return nil
+	}
+	return &orderv1.GeoPoint{Lat: p.Lat, Lon: p.Lon}
+}
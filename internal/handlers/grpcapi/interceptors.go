@@ -0,0 +1,157 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/logging"
+)
+
+// Metadata keys mirror the HTTP auth headers (lower-cased as gRPC requires).
+const (
+	MetadataBypass    = "x-bypass-auth"
+	MetadataUserID    = "x-user-id"
+	MetadataRequestID = "x-request-id"
+)
+
+type userIDKey struct{}
+
+func userIDFrom(ctx context.Context) string {
+	v, _ := ctx.Value(userIDKey{}).(string)
+	return v
+}
+
+func authenticate(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	userID := first(md, MetadataUserID)
+	if first(md, MetadataBypass) == "true" {
+		userID = "default-user"
+	}
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+func first(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// UnaryInterceptors returns auth, logging and error mapping in the order they
+// must be chained.
+func UnaryInterceptors() []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{unaryAuth, unaryLogging, unaryErrors}
+}
+
+// StreamInterceptors is the streaming counterpart of UnaryInterceptors.
+func StreamInterceptors() []grpc.StreamServerInterceptor {
+	return []grpc.StreamServerInterceptor{streamAuth, streamLogging, streamErrors}
+}
+
+func unaryAuth(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(authenticate(ctx), req)
+}
+
+// withLogFields adds request_id (from metadata or generated) and user_id to
+// the log fields of ctx.
+func withLogFields(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := first(md, MetadataRequestID)
+	if id == "" {
+		id = uuid.NewString()
+	}
+	args := []any{"request_id", id}
+	if u := userIDFrom(ctx); u != "" {
+		args = append(args, "user_id", u)
+	}
+	return logging.With(ctx, args...)
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(toStatus(err))
+	level := slog.LevelInfo
+	if code == codes.Internal || code == codes.Unknown {
+		level = slog.LevelError
+	}
+	slog.Log(ctx, level, "grpc call",
+		"method", method,
+		"code", code.String(),
+		"duration_ms", float64(time.Since(start).Microseconds())/1000,
+	)
+}
+
+func unaryLogging(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx = withLogFields(ctx)
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func unaryErrors(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	return resp, toStatus(err)
+}
+
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w wrappedStream) Context() context.Context { return w.ctx }
+
+func streamAuth(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, wrappedStream{ServerStream: ss, ctx: authenticate(ss.Context())})
+}
+
+func streamLogging(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := withLogFields(ss.Context())
+	start := time.Now()
+	err := handler(srv, wrappedStream{ServerStream: ss, ctx: ctx})
+	logCall(ctx, info.FullMethod, start, err)
+	return err
+}
+
+func streamErrors(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return toStatus(handler(srv, ss))
+}
+
+// toStatus maps entity errors to the gRPC codes matching the HTTP statuses
+// of the HTTP handler: a foreign order is a bad argument (400), a conflict is
+// an order in the wrong status for the call (409).
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code := codes.Internal
+	switch {
+	case errors.Is(err, entity.ErrUnauthorized):
+		code = codes.Unauthenticated
+	case errors.Is(err, entity.ErrForbidden):
+		code = codes.PermissionDenied
+	case errors.Is(err, entity.ErrInvalidInput), errors.Is(err, entity.ErrInvalidID), errors.Is(err, entity.ErrForeignOwnership):
+		code = codes.InvalidArgument
+	case errors.Is(err, entity.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, entity.ErrConflict):
+		code = codes.FailedPrecondition
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	}
+	return status.Error(code, err.Error())
+}
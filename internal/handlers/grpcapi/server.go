@@ -0,0 +1,171 @@
+package grpcapi
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/nikolaev/service-order/internal/clock"
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	uc "github.com/nikolaev/service-order/internal/usecase/order"
+	orderv1 "github.com/nikolaev/service-order/pkg/api/order/v1"
+)
+
+// Watcher delivers changes of a single order; it closes the channel when
+// the subscriber falls behind. Implemented by broadcast.Hub.
+type Watcher interface {
+	Subscribe(orderID string) (<-chan *entity.Order, func())
+}
+
+// OrderServer exposes usecase/order.Service over gRPC.
+type OrderServer struct {
+	orderv1.UnimplementedOrderServiceServer
+
+	uc uc.Service
+	w  Watcher
+}
+
+func NewOrderServer(uc uc.Service, w Watcher) *OrderServer {
+	return &OrderServer{uc: uc, w: w}
+}
+
+// NewServer builds a gRPC server with the interceptors and OrderServer registered.
+func NewServer(s *OrderServer) *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryInterceptors()...),
+		grpc.ChainStreamInterceptor(StreamInterceptors()...),
+	)
+	orderv1.RegisterOrderServiceServer(srv, s)
+	return srv
+}
+
+func (s *OrderServer) CreateOrder(ctx context.Context, req *orderv1.CreateOrderRequest) (*orderv1.Order, error) {
+	o, err := s.uc.Create(ctx, userIDFrom(ctx), toDomainCreate(req))
+	if err != nil {
+		return nil, err
+	}
+	return toProto(o), nil
+}
+
+func (s *OrderServer) GetOrder(ctx context.Context, req *orderv1.GetOrderRequest) (*orderv1.Order, error) {
+	o, err := s.uc.Get(ctx, userIDFrom(ctx), req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return toProto(o), nil
+}
+
+func (s *OrderServer) GetOrderStatus(ctx context.Context, req *orderv1.GetOrderStatusRequest) (*orderv1.GetOrderStatusResponse, error) {
+	st, err := s.uc.GetStatus(ctx, userIDFrom(ctx), req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return &orderv1.GetOrderStatusResponse{OrderId: req.GetId(), Status: toProtoStatus(st)}, nil
+}
+
+func (s *OrderServer) ListOrders(ctx context.Context, req *orderv1.ListOrdersRequest) (*orderv1.ListOrdersResponse, error) {
+	var from time.Time
+	if req.GetFrom() != nil {
+		from = req.GetFrom().AsTime()
+	}
+	orders, err := s.uc.ListFrom(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	resp := &orderv1.ListOrdersResponse{Orders: make([]*orderv1.Order, 0, len(orders))}
+	for _, o := range orders {
+		resp.Orders = append(resp.Orders, toProto(o))
+	}
+	return resp, nil
+}
+
+func (s *OrderServer) UpdateOrder(ctx context.Context, req *orderv1.UpdateOrderRequest) (*orderv1.Order, error) {
+	o, err := s.uc.Update(ctx, userIDFrom(ctx), req.GetId(), toDomainUpdate(req))
+	if err != nil {
+		return nil, err
+	}
+	return toProto(o), nil
+}
+
+func (s *OrderServer) DeleteOrder(ctx context.Context, req *orderv1.DeleteOrderRequest) (*orderv1.DeleteOrderResponse, error) {
+	if err := s.uc.Delete(ctx, userIDFrom(ctx), req.GetId()); err != nil {
+		return nil, err
+	}
+	return &orderv1.DeleteOrderResponse{Id: req.GetId(), Status: orderv1.OrderStatus_ORDER_STATUS_DELETED}, nil
+}
+
+func (s *OrderServer) CancelOrder(ctx context.Context, req *orderv1.CancelOrderRequest) (*orderv1.Order, error) {
+	o, err := s.uc.Cancel(ctx, userIDFrom(ctx), req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return toProto(o), nil
+}
+
+func (s *OrderServer) WatchOrder(req *orderv1.WatchOrderRequest, stream orderv1.OrderService_WatchOrderServer) error {
+	ctx := stream.Context()
+	// Subscribe before reading the current state so no change slips in between.
+	changes, cancel := s.w.Subscribe(req.GetId())
+	defer cancel()
+
+	o, err := s.uc.Get(ctx, userIDFrom(ctx), req.GetId())
+	if err != nil {
+		return err
+	}
+	last := o.Status
+	if err := stream.Send(toProtoEvent(o)); err != nil {
+		return err
+	}
+	if isTerminal(last) {
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ch, ok := <-changes:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "watcher fell behind the order changes, watch again")
+			}
+			if ch.Status == last {
+				continue
+			}
+			last = ch.Status
+			if err := stream.Send(toProtoEvent(ch)); err != nil {
+				return err
+			}
+			if isTerminal(last) {
+				return nil
+			}
+		}
+	}
+}
+
+func isTerminal(s entity.OrderStatus) bool {
+	switch s {
+	case entity.OrderStatusCompleted, entity.OrderStatusCanceled, entity.OrderStatusDeleted:
+		return true
+	default:
+		return false
+	}
+}
+
+func toProtoEvent(o *entity.Order) *orderv1.OrderStatusEvent {
+	at := o.StatusChangedAt
+	if at.IsZero() {
+		at = o.UpdatedAt
+	}
+	if at.IsZero() {
+		at = clock.System{}.Now()
+	}
+	return &orderv1.OrderStatusEvent{
+		OrderId:   o.ID,
+		Status:    toProtoStatus(o.Status),
+		ChangedAt: timestamppb.New(at),
+	}
+}
@@ -0,0 +1,217 @@
+package grpcapi_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/gateway/broadcast"
+	"github.com/nikolaev/service-order/internal/handlers"
+	"github.com/nikolaev/service-order/internal/handlers/grpcapi"
+	repo "github.com/nikolaev/service-order/internal/repository/order"
+	uc "github.com/nikolaev/service-order/internal/usecase/order"
+	orderv1 "github.com/nikolaev/service-order/pkg/api/order/v1"
+)
+
+func setupClient(t *testing.T) (orderv1.OrderServiceClient, *repo.InMemory, *broadcast.Hub) {
+	t.Helper()
+	mem := repo.NewInMemory()
+	hub := broadcast.NewHub()
+	svc := uc.New(mem, hub)
+	return dial(t, grpcapi.NewOrderServer(svc, hub)), mem, hub
+}
+
+func dial(t *testing.T, s *grpcapi.OrderServer) orderv1.OrderServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpcapi.NewServer(s)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return orderv1.NewOrderServiceClient(conn)
+}
+
+func asUser(userID string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), grpcapi.MetadataUserID, userID)
+}
+
+func createReq() *orderv1.CreateOrderRequest {
+	return &orderv1.CreateOrderRequest{
+		RestaurantId: "rest-1",
+		Items:        []*orderv1.Item{{FoodId: "f1", Name: "Pizza", Quantity: 1, Price: 500}},
+		TotalPrice:   500,
+		Address:      &orderv1.DeliveryAddress{Street: "Main"},
+	}
+}
+
+func TestOrderServer_CRUD(t *testing.T) {
+	client, _, _ := setupClient(t)
+	ctx := asUser("u1")
+
+	created, err := client.CreateOrder(ctx, createReq())
+	require.NoError(t, err)
+	assert.Equal(t, "u1", created.GetUserId())
+	assert.Equal(t, orderv1.OrderStatus_ORDER_STATUS_CREATED, created.GetStatus())
+
+	got, err := client.GetOrder(ctx, &orderv1.GetOrderRequest{Id: created.GetId()})
+	require.NoError(t, err)
+	assert.Equal(t, created.GetId(), got.GetId())
+
+	st, err := client.GetOrderStatus(ctx, &orderv1.GetOrderStatusRequest{Id: created.GetId()})
+	require.NoError(t, err)
+	assert.Equal(t, created.GetId(), st.GetOrderId())
+
+	list, err := client.ListOrders(ctx, &orderv1.ListOrdersRequest{})
+	require.NoError(t, err)
+	assert.Len(t, list.GetOrders(), 1)
+
+	fio := "Petrov P.P."
+	upd, err := client.UpdateOrder(ctx, &orderv1.UpdateOrderRequest{Id: created.GetId(), Fio: &fio})
+	require.NoError(t, err)
+	assert.Equal(t, fio, upd.GetFio())
+	assert.Len(t, upd.GetItems(), 1)
+
+	del, err := client.DeleteOrder(ctx, &orderv1.DeleteOrderRequest{Id: created.GetId()})
+	require.NoError(t, err)
+	assert.Equal(t, orderv1.OrderStatus_ORDER_STATUS_DELETED, del.GetStatus())
+
+	_, err = client.GetOrder(ctx, &orderv1.GetOrderRequest{Id: created.GetId()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestOrderServer_ErrorMapping(t *testing.T) {
+	client, _, _ := setupClient(t)
+
+	_, err := client.CreateOrder(context.Background(), createReq())
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.CreateOrder(asUser("u1"), &orderv1.CreateOrderRequest{RestaurantId: "rest-1"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	created, err := client.CreateOrder(asUser("u1"), createReq())
+	require.NoError(t, err)
+	_, err = client.DeleteOrder(asUser("u2"), &orderv1.DeleteOrderRequest{Id: created.GetId()})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// failingService fails every Get with err.
+type failingService struct {
+	uc.Service
+	err error
+}
+
+func (f failingService) Get(context.Context, string, string) (*entity.Order, error) {
+	return nil, f.err
+}
+
+func TestOrderServer_ErrorCodesMatchHTTP(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		http int
+		grpc codes.Code
+	}{
+		{entity.ErrUnauthorized, http.StatusUnauthorized, codes.Unauthenticated},
+		{entity.ErrForbidden, http.StatusForbidden, codes.PermissionDenied},
+		{entity.ErrInvalidInput, http.StatusBadRequest, codes.InvalidArgument},
+		{entity.ErrInvalidID, http.StatusBadRequest, codes.InvalidArgument},
+		{entity.ErrForeignOwnership, http.StatusBadRequest, codes.InvalidArgument},
+		{entity.ErrNotFound, http.StatusNotFound, codes.NotFound},
+		{fmt.Errorf("%w: order is cooking and can no longer be canceled", entity.ErrConflict), http.StatusConflict, codes.FailedPrecondition},
+		{errors.New("boom"), http.StatusInternalServerError, codes.Internal},
+	} {
+		svc := failingService{err: tc.err}
+
+		r := chi.NewRouter()
+		r.Mount("/public/api/v1", handlers.NewOrderHandler(svc).Routes())
+		req := httptest.NewRequest(http.MethodGet, "/public/api/v1/order/o1", nil)
+		req.Header.Set(handlers.HeaderUserID, "u1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, tc.http, w.Code, tc.err)
+
+		_, err := dial(t, grpcapi.NewOrderServer(svc, broadcast.NewHub())).GetOrder(asUser("u1"), &orderv1.GetOrderRequest{Id: "o1"})
+		assert.Equal(t, tc.grpc, status.Code(err), tc.err)
+	}
+}
+
+func TestOrderServer_WatchOrder(t *testing.T) {
+	client, mem, hub := setupClient(t)
+	ctx, cancel := context.WithTimeout(asUser("u1"), 5*time.Second)
+	defer cancel()
+
+	created, err := client.CreateOrder(ctx, createReq())
+	require.NoError(t, err)
+
+	stream, err := client.WatchOrder(ctx, &orderv1.WatchOrderRequest{Id: created.GetId()})
+	require.NoError(t, err)
+
+	ev, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, orderv1.OrderStatus_ORDER_STATUS_CREATED, ev.GetStatus())
+
+	// Emulate the status worker: advance and publish until the stream sees pending.
+	go func() {
+		now := time.Now().UTC().Add(2 * time.Second)
+		for _, o := range mem.AdvanceStatuses(now) {
+			_ = hub.OrderUpdated(ctx, o)
+		}
+	}()
+	ev, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, orderv1.OrderStatus_ORDER_STATUS_PENDING, ev.GetStatus())
+
+	_, err = client.DeleteOrder(ctx, &orderv1.DeleteOrderRequest{Id: created.GetId()})
+	require.NoError(t, err)
+	ev, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, orderv1.OrderStatus_ORDER_STATUS_DELETED, ev.GetStatus())
+}
+
+// droppedWatcher behaves like a hub that gave up on a slow subscriber.
+type droppedWatcher struct{}
+
+func (droppedWatcher) Subscribe(string) (<-chan *entity.Order, func()) {
+	ch := make(chan *entity.Order)
+	close(ch)
+	return ch, func() {}
+}
+
+func TestOrderServer_WatchOrderEndsWhenDropped(t *testing.T) {
+	mem := repo.NewInMemory()
+	svc := uc.New(mem, broadcast.NewHub())
+	client := dial(t, grpcapi.NewOrderServer(svc, droppedWatcher{}))
+	ctx, cancel := context.WithTimeout(asUser("u1"), 5*time.Second)
+	defer cancel()
+
+	created, err := client.CreateOrder(ctx, createReq())
+	require.NoError(t, err)
+	stream, err := client.WatchOrder(ctx, &orderv1.WatchOrderRequest{Id: created.GetId()})
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
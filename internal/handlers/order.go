@@ -1,25 +1,40 @@
 package handlers
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 
 	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/gateway/webhook"
 	"github.com/nikolaev/service-order/internal/handlers/types/convert"
 	"github.com/nikolaev/service-order/internal/handlers/types/transport"
+	"github.com/nikolaev/service-order/internal/logging"
 	seed "github.com/nikolaev/service-order/internal/usecase/debug/seed"
 	uc "github.com/nikolaev/service-order/internal/usecase/order"
+	"github.com/nikolaev/service-order/internal/usecase/replay"
+	"github.com/nikolaev/service-order/pkg/api/openapi"
 )
 
+// OrderHandler implements the strict server interface generated from
+// api_openapi.yaml; routing and request decoding come from the generated code.
 type OrderHandler struct {
 	uc  uc.Service
 	dbg seed.Service
+	rp  replay.Service
+	wh  webhook.Service
+
+	adminToken string
 }
 
+var _ openapi.StrictServerInterface = (*OrderHandler)(nil)
+
 // NewOrderHandler constructs OrderHandler. Debug seeder is optional.
 func NewOrderHandler(uc uc.Service, dbg ...seed.Service) *OrderHandler {
 	var d seed.Service
@@ -29,24 +44,53 @@ func NewOrderHandler(uc uc.Service, dbg ...seed.Service) *OrderHandler {
 	return &OrderHandler{uc: uc, dbg: d}
 }
 
+// WithReplay enables the /admin/replay endpoints.
+func (h *OrderHandler) WithReplay(rp replay.Service) *OrderHandler {
+	h.rp = rp
+	return h
+}
+
+// WithWebhooks enables the /admin/webhooks endpoints.
+func (h *OrderHandler) WithWebhooks(wh webhook.Service) *OrderHandler {
+	h.wh = wh
+	return h
+}
+
+// WithAdminToken enables the operations the spec secures with adminToken;
+// without a token they answer 403.
+func (h *OrderHandler) WithAdminToken(token string) *OrderHandler {
+	h.adminToken = token
+	return h
+}
+
 func (h *OrderHandler) Routes() http.Handler {
 	r := chi.NewRouter()
-	r.Post("/order", h.create)
-	r.Get("/order/{id}", h.get)
-	r.Get("/order/{id}/status", h.getStatus)
-	r.Get("/orders", h.list)
-	r.Put("/order/{id}", h.update)
-	r.Delete("/order/{id}", h.delete)
-	// debug route to seed orders
-	r.Post("/debug/seed", h.seedDebug)
-	return r
+	r.Use(h.withUserID)
+	si := openapi.NewStrictHandlerWithOptions(h, nil, openapi.StrictHTTPServerOptions{
+		RequestErrorHandlerFunc: func(w http.ResponseWriter, _ *http.Request, _ error) {
+			h.writeError(w, entity.ErrInvalidInput)
+		},
+		ResponseErrorHandlerFunc: func(w http.ResponseWriter, _ *http.Request, err error) {
+			h.writeError(w, err)
+		},
+	})
+	return openapi.HandlerWithOptions(optionalBodies{ServerInterface: si, h: h}, openapi.ChiServerOptions{
+		BaseRouter:  r,
+		Middlewares: []openapi.MiddlewareFunc{h.requireAdmin},
+		ErrorHandlerFunc: func(w http.ResponseWriter, _ *http.Request, _ error) {
+			h.writeError(w, entity.ErrInvalidInput)
+		},
+	})
 }
 
 const (
-	HeaderBypass = "X-Bypass-Auth"
-	HeaderUserID = "X-User-ID"
+	HeaderBypass     = "X-Bypass-Auth"
+	HeaderUserID     = "X-User-ID"
+	HeaderAdminToken = "X-Admin-Token"
 )
 
+type userIDKey struct{}
+
 func (h *OrderHandler) userIDFrom(r *http.Request) string {
 	if r.Header.Get(HeaderBypass) == "true" {
 		return "default-user"
@@ -55,6 +99,75 @@ func (h *OrderHandler) userIDFrom(r *http.Request) string {
 	return r.Header.Get(HeaderUserID)
 }
 
+// withUserID moves the caller identity from headers into the context, since
+// strict handlers do not see the request, and into the log fields.
+func (h *OrderHandler) withUserID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := h.userIDFrom(r)
+		ctx := context.WithValue(r.Context(), userIDKey{}, id)
+		if id != "" {
+			ctx = logging.With(ctx, "user_id", id)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireAdmin guards the operations the spec secures with adminToken: the
+// generated wrapper marks them with AdminTokenScopes before the middlewares
+// run.
+func (h *OrderHandler) requireAdmin(next http.Handler) http.Handler {
+	guarded := h.AdminOnly(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Context().Value(openapi.AdminTokenScopes) == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		guarded.ServeHTTP(w, r)
+	})
+}
+
+// AdminOnly lets through only requests with the admin token; it guards the
+// debug routes mounted outside the spec.
+func (h *OrderHandler) AdminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case h.adminToken == "":
+			h.writeError(w, fmt.Errorf("%w: admin API is disabled, set http.admin_token", entity.ErrForbidden))
+		case subtle.ConstantTimeCompare([]byte(r.Header.Get(HeaderAdminToken)), []byte(h.adminToken)) != 1:
+			h.writeError(w, fmt.Errorf("%w: %s is missing or wrong", entity.ErrUnauthorized, HeaderAdminToken))
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// optionalBodies serves the operations whose body the spec marks optional:
+// the generated strict handler always decodes one, so an empty request is
+// passed to OrderHandler here with a nil Body.
+type optionalBodies struct {
+	openapi.ServerInterface
+	h *OrderHandler
+}
+
+func (s optionalBodies) SeedDebugOrders(w http.ResponseWriter, r *http.Request) {
+	if r.ContentLength != 0 {
+		s.ServerInterface.SeedDebugOrders(w, r)
+		return
+	}
+	resp, err := s.h.SeedDebugOrders(r.Context(), openapi.SeedDebugOrdersRequestObject{})
+	if err == nil {
+		err = resp.VisitSeedDebugOrdersResponse(w)
+	}
+	if err != nil {
+		s.h.writeError(w, err)
+	}
+}
+
+func userID(ctx context.Context) string {
+	v, _ := ctx.Value(userIDKey{}).(string)
+	return v
+}
+
 func (h *OrderHandler) writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -77,121 +190,232 @@ func (h *OrderHandler) writeError(w http.ResponseWriter, err error) {
 	case errors.Is(err, entity.ErrNotFound):
 		code = http.StatusNotFound
 		s = "not_found"
+	case errors.Is(err, entity.ErrConflict):
+		code = http.StatusConflict
+		s = "conflict"
 	}
 	h.writeJSON(w, code, transport.Error{Code: s, Message: err.Error()})
 }
 
-func (h *OrderHandler) create(w http.ResponseWriter, r *http.Request) {
-	userID := h.userIDFrom(r)
-	var req transport.CreateOrderRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, entity.ErrInvalidInput)
-		return
+func (h *OrderHandler) CreateOrder(ctx context.Context, req openapi.CreateOrderRequestObject) (openapi.CreateOrderResponseObject, error) {
+	order, err := h.uc.Create(ctx, userID(ctx), convert.ToDomainCreate(*req.Body))
+	if err != nil {
+		return nil, err
 	}
-	order, err := h.uc.Create(r.Context(), userID, convert.ToDomainCreate(req))
+	return openapi.CreateOrder201JSONResponse(convert.ToTransport(order)), nil
+}
+
+func (h *OrderHandler) GetOrder(ctx context.Context, req openapi.GetOrderRequestObject) (openapi.GetOrderResponseObject, error) {
+	order, err := h.uc.Get(ctx, userID(ctx), req.Id)
 	if err != nil {
-		h.writeError(w, err)
-		return
+		return nil, err
 	}
-	resp := convert.ToTransport(order)
-	h.writeJSON(w, http.StatusCreated, resp)
+	return openapi.GetOrder200JSONResponse(convert.ToTransport(order)), nil
 }
 
-func (h *OrderHandler) get(w http.ResponseWriter, r *http.Request) {
-	userID := h.userIDFrom(r)
-	id := chi.URLParam(r, "id")
-	order, err := h.uc.Get(r.Context(), userID, id)
+func (h *OrderHandler) GetOrderStatus(ctx context.Context, req openapi.GetOrderStatusRequestObject) (openapi.GetOrderStatusResponseObject, error) {
+	status, err := h.uc.GetStatus(ctx, userID(ctx), req.Id)
 	if err != nil {
-		h.writeError(w, err)
-		return
+		return nil, err
 	}
-	resp := convert.ToTransport(order)
-	h.writeJSON(w, http.StatusOK, resp)
+	return openapi.GetOrderStatus200JSONResponse{OrderID: req.Id, Status: transport.OrderStatus(status)}, nil
 }
 
-func (h *OrderHandler) getStatus(w http.ResponseWriter, r *http.Request) {
-	userID := h.userIDFrom(r)
-	id := chi.URLParam(r, "id")
-	status, err := h.uc.GetStatus(r.Context(), userID, id)
+func (h *OrderHandler) ListOrders(ctx context.Context, req openapi.ListOrdersRequestObject) (openapi.ListOrdersResponseObject, error) {
+	from := time.Unix(0, 0).UTC()
+	if req.Params.From != nil {
+		from = *req.Params.From
+	}
+	orders, err := h.uc.ListFrom(ctx, from)
 	if err != nil {
-		h.writeError(w, err)
-		return
+		return nil, err
 	}
-	h.writeJSON(w, http.StatusOK, map[string]string{"order_id": id, "status": string(status)})
+	resp := make(openapi.ListOrders200JSONResponse, 0, len(orders))
+	for _, o := range orders {
+		resp = append(resp, convert.ToTransport(o))
+	}
+	return resp, nil
+}
+
+func (h *OrderHandler) UpdateOrder(ctx context.Context, req openapi.UpdateOrderRequestObject) (openapi.UpdateOrderResponseObject, error) {
+	order, err := h.uc.Update(ctx, userID(ctx), req.Id, convert.ToDomainUpdate(*req.Body))
+	if err != nil {
+		return nil, err
+	}
+	return openapi.UpdateOrder200JSONResponse(convert.ToTransport(order)), nil
 }
 
-func (h *OrderHandler) list(w http.ResponseWriter, r *http.Request) {
-	fromStr := r.URL.Query().Get("from")
-	if fromStr == "" {
-		fromStr = "1970-01-01T00:00:00Z"
+func (h *OrderHandler) DeleteOrder(ctx context.Context, req openapi.DeleteOrderRequestObject) (openapi.DeleteOrderResponseObject, error) {
+	if err := h.uc.Delete(ctx, userID(ctx), req.Id); err != nil {
+		return nil, err
 	}
-	from, err := time.Parse(time.RFC3339, fromStr)
+	return openapi.DeleteOrder200JSONResponse{
+		ID:     req.Id,
+		Status: transport.OrderStatus(entity.OrderStatusDeleted),
+	}, nil
+}
+
+func (h *OrderHandler) CancelOrder(ctx context.Context, req openapi.CancelOrderRequestObject) (openapi.CancelOrderResponseObject, error) {
+	order, err := h.uc.Cancel(ctx, userID(ctx), req.Id)
 	if err != nil {
-		h.writeError(w, entity.ErrInvalidInput)
-		return
+		return nil, err
 	}
-	orders, err := h.uc.ListFrom(r.Context(), from)
+	return openapi.CancelOrder200JSONResponse(convert.ToTransport(order)), nil
+}
+
+// SeedDebugOrders creates demo orders from the requested scenario, the
+// configured one when the body is empty.
+func (h *OrderHandler) SeedDebugOrders(ctx context.Context, req openapi.SeedDebugOrdersRequestObject) (openapi.SeedDebugOrdersResponseObject, error) {
+	if h.dbg == nil {
+		return nil, errors.New("debug seeder is not configured")
+	}
+	var body transport.SeedRequest
+	if req.Body != nil {
+		body = *req.Body
+	}
+	sr, err := convert.ToSeedRequest(body)
 	if err != nil {
-		h.writeError(w, err)
-		return
+		return nil, err
+	}
+
+	orders, err := h.dbg.Seed(ctx, userID(ctx), sr)
+	if err != nil {
+		return nil, err
 	}
-	resp := make([]transport.OrderResponse, 0, len(orders))
+
+	resp := make(openapi.SeedDebugOrders201JSONResponse, 0, len(orders))
 	for _, o := range orders {
 		resp = append(resp, convert.ToTransport(o))
 	}
-	h.writeJSON(w, http.StatusOK, resp)
+	return resp, nil
 }
 
-func (h *OrderHandler) update(w http.ResponseWriter, r *http.Request) {
-	userID := h.userIDFrom(r)
-	id := chi.URLParam(r, "id")
-	var req transport.UpdateOrderRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, entity.ErrInvalidInput)
-		return
+var errReplayDisabled = fmt.Errorf("%w: replay is not configured", entity.ErrNotFound)
+
+// StartReplay republishes snapshots of the matching orders in the background;
+// the job is polled through GetReplay.
+func (h *OrderHandler) StartReplay(ctx context.Context, req openapi.StartReplayRequestObject) (openapi.StartReplayResponseObject, error) {
+	if h.rp == nil {
+		return nil, errReplayDisabled
+	}
+	var body transport.ReplayRequest
+	if req.Body != nil {
+		body = *req.Body
+	}
+	f, rate := convert.ToReplayFilter(body)
+	job, err := h.rp.Start(ctx, f, rate)
+	if err != nil {
+		return nil, err
 	}
+	return openapi.StartReplay202JSONResponse(convert.ToTransportReplay(job)), nil
+}
+
+func (h *OrderHandler) ListReplays(_ context.Context, _ openapi.ListReplaysRequestObject) (openapi.ListReplaysResponseObject, error) {
+	if h.rp == nil {
+		return nil, errReplayDisabled
+	}
+	jobs := h.rp.List()
+	resp := make(openapi.ListReplays200JSONResponse, 0, len(jobs))
+	for _, j := range jobs {
+		resp = append(resp, convert.ToTransportReplay(j))
+	}
+	return resp, nil
+}
 
-	order, err := h.uc.Update(r.Context(), userID, id, convert.ToDomainUpdate(req))
+func (h *OrderHandler) GetReplay(_ context.Context, req openapi.GetReplayRequestObject) (openapi.GetReplayResponseObject, error) {
+	if h.rp == nil {
+		return nil, errReplayDisabled
+	}
+	job, err := h.rp.Get(req.Id)
 	if err != nil {
-		h.writeError(w, err)
-		return
+		return nil, err
 	}
+	return openapi.GetReplay200JSONResponse(convert.ToTransportReplay(job)), nil
+}
 
-	resp := convert.ToTransport(order)
-	h.writeJSON(w, http.StatusOK, resp)
+func (h *OrderHandler) CancelReplay(_ context.Context, req openapi.CancelReplayRequestObject) (openapi.CancelReplayResponseObject, error) {
+	if h.rp == nil {
+		return nil, errReplayDisabled
+	}
+	job, err := h.rp.Cancel(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return openapi.CancelReplay200JSONResponse(convert.ToTransportReplay(job)), nil
 }
 
-func (h *OrderHandler) delete(w http.ResponseWriter, r *http.Request) {
-	userID := h.userIDFrom(r)
-	id := chi.URLParam(r, "id")
-	if err := h.uc.Delete(r.Context(), userID, id); err != nil {
-		h.writeError(w, err)
-		return
+var errWebhooksDisabled = fmt.Errorf("%w: webhooks are not configured", entity.ErrNotFound)
+
+func (h *OrderHandler) CreateWebhook(_ context.Context, req openapi.CreateWebhookRequestObject) (openapi.CreateWebhookResponseObject, error) {
+	if h.wh == nil {
+		return nil, errWebhooksDisabled
+	}
+	if req.Body == nil {
+		return nil, entity.ErrInvalidInput
+	}
+	s, err := h.wh.Subscribe(convert.ToWebhookSubscription(*req.Body))
+	if err != nil {
+		return nil, err
 	}
+	return openapi.CreateWebhook201JSONResponse(convert.ToTransportWebhook(s)), nil
+}
 
-	h.writeJSON(w, http.StatusOK, transport.DeleteOrderResponse{
-		ID:     id,
-		Status: string(entity.OrderStatusDeleted),
-	})
+func (h *OrderHandler) ListWebhooks(_ context.Context, _ openapi.ListWebhooksRequestObject) (openapi.ListWebhooksResponseObject, error) {
+	if h.wh == nil {
+		return nil, errWebhooksDisabled
+	}
+	subs := h.wh.Subscriptions()
+	resp := make(openapi.ListWebhooks200JSONResponse, 0, len(subs))
+	for _, s := range subs {
+		resp = append(resp, convert.ToTransportWebhook(s))
+	}
+	return resp, nil
 }
 
-// seedDebug creates N=10 demo orders for the current user using current time.
-func (h *OrderHandler) seedDebug(w http.ResponseWriter, r *http.Request) {
-	userID := h.userIDFrom(r)
-	if h.dbg == nil {
-		h.writeError(w, errors.New("debug seeder is not configured"))
-		return
+func (h *OrderHandler) GetWebhook(_ context.Context, req openapi.GetWebhookRequestObject) (openapi.GetWebhookResponseObject, error) {
+	if h.wh == nil {
+		return nil, errWebhooksDisabled
 	}
+	s, err := h.wh.Subscription(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return openapi.GetWebhook200JSONResponse(convert.ToTransportWebhook(s)), nil
+}
 
-	orders, err := h.dbg.Seed(r.Context(), userID, 10)
+func (h *OrderHandler) DeleteWebhook(_ context.Context, req openapi.DeleteWebhookRequestObject) (openapi.DeleteWebhookResponseObject, error) {
+	if h.wh == nil {
+		return nil, errWebhooksDisabled
+	}
+	if err := h.wh.Unsubscribe(req.Id); err != nil {
+		return nil, err
+	}
+	return openapi.DeleteWebhook204Response{}, nil
+}
+
+func (h *OrderHandler) ListWebhookDeliveries(_ context.Context, req openapi.ListWebhookDeliveriesRequestObject) (openapi.ListWebhookDeliveriesResponseObject, error) {
+	if h.wh == nil {
+		return nil, errWebhooksDisabled
+	}
+	log, err := h.wh.Deliveries(req.Id)
 	if err != nil {
-		h.writeError(w, err)
-		return
+		return nil, err
 	}
+	resp := make(openapi.ListWebhookDeliveries200JSONResponse, 0, len(log))
+	for _, d := range log {
+		resp = append(resp, convert.ToTransportDelivery(d))
+	}
+	return resp, nil
+}
 
-	resp := make([]transport.OrderResponse, 0, len(orders))
-	for _, o := range orders {
-		resp = append(resp, convert.ToTransport(o))
+// RetryWebhookDelivery takes a delivery out of the dead letter.
+func (h *OrderHandler) RetryWebhookDelivery(_ context.Context, req openapi.RetryWebhookDeliveryRequestObject) (openapi.RetryWebhookDeliveryResponseObject, error) {
+	if h.wh == nil {
+		return nil, errWebhooksDisabled
+	}
+	d, err := h.wh.Redeliver(req.Id, req.DeliveryID)
+	if err != nil {
+		return nil, err
 	}
-	h.writeJSON(w, http.StatusCreated, resp)
+	return openapi.RetryWebhookDelivery200JSONResponse(convert.ToTransportDelivery(d)), nil
 }
@@ -12,10 +12,14 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/nikolaev/service-order/internal/clock"
 	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/gateway/webhook"
 	"github.com/nikolaev/service-order/internal/handlers"
 	"github.com/nikolaev/service-order/internal/handlers/types/transport"
+	seed "github.com/nikolaev/service-order/internal/usecase/debug/seed"
 	uc "github.com/nikolaev/service-order/internal/usecase/order"
+	"github.com/nikolaev/service-order/internal/usecase/replay"
 )
 
 type fakeService struct {
@@ -25,6 +29,7 @@ type fakeService struct {
 	ListFromFn  func(ctx context.Context, from time.Time) ([]*entity.Order, error)
 	UpdateFn    func(ctx context.Context, userID, id string, in uc.UpdateInput) (*entity.Order, error)
 	DeleteFn    func(ctx context.Context, userID, id string) error
+	CancelFn    func(ctx context.Context, userID, id string) (*entity.Order, error)
 }
 
 func (f fakeService) Create(ctx context.Context, userID string, in uc.CreateInput) (*entity.Order, error) {
@@ -51,6 +56,10 @@ func (f fakeService) Delete(ctx context.Context, userID, id string) error {
 	return f.DeleteFn(ctx, userID, id)
 }
 
+func (f fakeService) Cancel(ctx context.Context, userID, id string) (*entity.Order, error) {
+	return f.CancelFn(ctx, userID, id)
+}
+
 func setupRouter(h *handlers.OrderHandler) *chi.Mux {
 	r := chi.NewRouter()
 	r.Mount("/public/api/v1", h.Routes())
@@ -173,3 +182,229 @@ func TestOrderHandler_List_OK(t *testing.T) {
 	assert.Len(t, list, 1)
 	assert.Equal(t, "o1", list[0].ID)
 }
+
+type nopSnapshots struct{}
+
+func (nopSnapshots) OrderSnapshot(context.Context, *entity.Order, string) error { return nil }
+
+func TestOrderHandler_Replay(t *testing.T) {
+	fake := fakeService{
+		ListFromFn: func(ctx context.Context, from time.Time) ([]*entity.Order, error) {
+			return []*entity.Order{
+				{ID: "o1", RestaurantID: "r1", Status: entity.OrderStatusPending},
+				{ID: "o2", RestaurantID: "r2", Status: entity.OrderStatusPending},
+			}, nil
+		},
+	}
+	rp := replay.NewWithLimits(fake, nopSnapshots{}, clock.System{}, replay.Limits{Rate: 1, MaxRate: 10})
+	defer rp.Stop(context.Background())
+	r := setupRouter(handlers.NewOrderHandler(fake).WithReplay(rp).WithAdminToken("admin"))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/public/api/v1"+path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(handlers.HeaderAdminToken, "admin")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/admin/replay", `{"restaurant_id":"r1"}`)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var job transport.ReplayJob
+	_ = json.NewDecoder(w.Body).Decode(&job)
+	assert.Equal(t, "running", string(job.State))
+	assert.Equal(t, 1, job.Total)
+	assert.Equal(t, float64(1), job.Rate)
+
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/admin/replay", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/admin/replay", `{"rate":100}`).Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/admin/replay/"+job.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/admin/replay/missing", "").Code)
+
+	w = do(http.MethodDelete, "/admin/replay/"+job.ID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	_ = json.NewDecoder(w.Body).Decode(&job)
+	assert.Equal(t, "canceled", string(job.State))
+	assert.NotNil(t, job.FinishedAt)
+
+	var jobs []transport.ReplayJob
+	w = do(http.MethodGet, "/admin/replay", "")
+	_ = json.NewDecoder(w.Body).Decode(&jobs)
+	assert.Len(t, jobs, 1)
+}
+
+func TestOrderHandler_ReplayDisabled(t *testing.T) {
+	r := setupRouter(handlers.NewOrderHandler(fakeService{}).WithAdminToken("admin"))
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/public/api/v1"+path, bytes.NewBufferString(body))
+		req.Header.Set(handlers.HeaderAdminToken, "admin")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/admin/replay", `{}`).Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/admin/replay", "").Code)
+	w := do(http.MethodGet, "/admin/replay/r1", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "replay is not configured")
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/admin/replay/r1", "").Code)
+}
+
+func TestOrderHandler_WebhooksDisabled(t *testing.T) {
+	r := setupRouter(handlers.NewOrderHandler(fakeService{}).WithAdminToken("admin"))
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/public/api/v1"+path, bytes.NewBufferString(body))
+		req.Header.Set(handlers.HeaderAdminToken, "admin")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/admin/webhooks", `{"url":"https://partner.example/hook"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "webhooks are not configured")
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/admin/webhooks", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/admin/webhooks/w1", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/admin/webhooks/w1", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/admin/webhooks/w1/deliveries", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/admin/webhooks/w1/deliveries/d1/retry", "").Code)
+}
+
+func TestOrderHandler_AdminToken(t *testing.T) {
+	rp := replay.NewWithLimits(fakeService{}, nopSnapshots{}, clock.System{}, replay.Limits{Rate: 1, MaxRate: 10})
+	defer rp.Stop(context.Background())
+
+	do := func(h *handlers.OrderHandler, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/public/api/v1/admin/replay", nil)
+		req.Header.Set("X-Bypass-Auth", "true")
+		if token != "" {
+			req.Header.Set(handlers.HeaderAdminToken, token)
+		}
+		w := httptest.NewRecorder()
+		setupRouter(h).ServeHTTP(w, req)
+		return w.Code
+	}
+
+	enabled := handlers.NewOrderHandler(fakeService{}).WithReplay(rp).WithAdminToken("admin")
+	assert.Equal(t, http.StatusOK, do(enabled, "admin"))
+	assert.Equal(t, http.StatusUnauthorized, do(enabled, ""))
+	assert.Equal(t, http.StatusUnauthorized, do(enabled, "wrong"))
+	assert.Equal(t, http.StatusForbidden, do(handlers.NewOrderHandler(fakeService{}).WithReplay(rp), "admin"))
+}
+
+func TestOrderHandler_AdminOnly(t *testing.T) {
+	debug := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	do := func(h *handlers.OrderHandler, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/debug/simulation", nil)
+		if token != "" {
+			req.Header.Set(handlers.HeaderAdminToken, token)
+		}
+		w := httptest.NewRecorder()
+		h.AdminOnly(debug).ServeHTTP(w, req)
+		return w.Code
+	}
+
+	enabled := handlers.NewOrderHandler(fakeService{}).WithAdminToken("admin")
+	assert.Equal(t, http.StatusNoContent, do(enabled, "admin"))
+	assert.Equal(t, http.StatusUnauthorized, do(enabled, ""))
+	assert.Equal(t, http.StatusUnauthorized, do(enabled, "wrong"))
+	assert.Equal(t, http.StatusForbidden, do(handlers.NewOrderHandler(fakeService{}), "admin"))
+}
+
+func TestOrderHandler_Webhooks(t *testing.T) {
+	// Not started: deliveries stay pending.
+	wh := webhook.NewDispatcher(clock.System{}, webhook.Options{})
+	r := setupRouter(handlers.NewOrderHandler(fakeService{}).WithWebhooks(wh).WithAdminToken("admin"))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/public/api/v1"+path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(handlers.HeaderAdminToken, "admin")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/admin/webhooks", `{"url":"https://partner.example/hook","events":["canceled"],"secret":"s"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var sub transport.WebhookSubscription
+	_ = json.NewDecoder(w.Body).Decode(&sub)
+	assert.Equal(t, "s", sub.Secret)
+	assert.Equal(t, []transport.WebhookEvent{"canceled"}, sub.Events)
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/admin/webhooks", `{"url":"partner"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/admin/webhooks", `{"url":"https://p.example","events":["shipped"]}`).Code)
+
+	w = do(http.MethodGet, "/admin/webhooks/"+sub.ID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got transport.WebhookSubscription
+	_ = json.NewDecoder(w.Body).Decode(&got)
+	assert.Empty(t, got.Secret)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/admin/webhooks/missing", "").Code)
+
+	_ = wh.OrderStatusChanged(context.Background(), &entity.Order{ID: "o1", Status: entity.OrderStatusCanceled})
+	w = do(http.MethodGet, "/admin/webhooks/"+sub.ID+"/deliveries", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var log []transport.WebhookDelivery
+	_ = json.NewDecoder(w.Body).Decode(&log)
+	if assert.Len(t, log, 1) {
+		assert.Equal(t, "pending", string(log[0].State))
+		assert.Equal(t, "o1", log[0].OrderID)
+		assert.Nil(t, log[0].LastStatus)
+		path := "/admin/webhooks/" + sub.ID + "/deliveries/" + log[0].ID + "/retry"
+		assert.Equal(t, http.StatusConflict, do(http.MethodPost, path, "").Code, "not dead yet")
+	}
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/admin/webhooks/"+sub.ID+"/deliveries/missing/retry", "").Code)
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/admin/webhooks/"+sub.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/admin/webhooks/"+sub.ID, "").Code)
+	w = do(http.MethodGet, "/admin/webhooks", "")
+	assert.JSONEq(t, `[]`, w.Body.String())
+}
+
+type fakeSeeder struct{ got *[]seed.Request }
+
+func (f fakeSeeder) Seed(_ context.Context, userID string, req seed.Request) ([]*entity.Order, error) {
+	*f.got = append(*f.got, req)
+	if req.Scenario == "missing" {
+		return nil, entity.ErrNotFound
+	}
+	return []*entity.Order{{ID: "o1", UserID: userID, Status: entity.OrderStatusCreated}}, nil
+}
+
+func TestOrderHandler_SeedScenarios(t *testing.T) {
+	var got []seed.Request
+	r := setupRouter(handlers.NewOrderHandler(fakeService{}, fakeSeeder{got: &got}))
+
+	do := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/public/api/v1/debug/seed", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Bypass-Auth", "true")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("")
+	assert.Equal(t, http.StatusCreated, w.Code, "the body is optional")
+	var orders []transport.OrderResponse
+	_ = json.NewDecoder(w.Body).Decode(&orders)
+	assert.Equal(t, "default-user", orders[0].UserID)
+
+	assert.Equal(t, http.StatusCreated, do(`{"scenario":"lunch","count":5,"seed":42}`).Code)
+	assert.Equal(t, http.StatusCreated, do(`{"inline":{"restaurants":["r1"],"items":[{"food_id":"f1","name":"Tea","price":90}],"age":{"max":"10m"}}}`).Code)
+	if assert.Len(t, got, 3) {
+		assert.Equal(t, seed.Request{}, got[0])
+		assert.Equal(t, seed.Request{Scenario: "lunch", Count: 5, Seed: 42}, got[1])
+		if assert.NotNil(t, got[2].Inline) {
+			assert.Equal(t, 10*time.Minute, got[2].Inline.Age.Max)
+			assert.Equal(t, "Tea", got[2].Inline.Items[0].Name)
+		}
+	}
+
+	assert.Equal(t, http.StatusNotFound, do(`{"scenario":"missing"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(`{"inline":{"restaurants":["r1"]}}`).Code, "no items")
+	assert.Equal(t, http.StatusBadRequest, do(`{"inline":{"name":"x","statuses":{"delivered":1}}}`).Code)
+}
@@ -0,0 +1,71 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/handlers"
+	"github.com/nikolaev/service-order/pkg/api/openapi"
+)
+
+const specPath = "../../api_openapi.yaml"
+
+// TestSpec_GeneratedCodeIsUpToDate fails when api_openapi.yaml was edited
+// without running `make generate`.
+func TestSpec_GeneratedCodeIsUpToDate(t *testing.T) {
+	onDisk, err := openapi3.NewLoader().LoadFromFile(specPath)
+	require.NoError(t, err)
+	embedded, err := openapi.GetSwagger()
+	require.NoError(t, err)
+
+	want, _ := json.Marshal(normalizeOperationIDs(onDisk))
+	got, _ := json.Marshal(normalizeOperationIDs(embedded))
+	assert.JSONEq(t, string(want), string(got), "api_openapi.yaml differs from pkg/api/openapi, run make generate")
+}
+
+// normalizeOperationIDs undoes the capitalization oapi-codegen applies to
+// operation IDs in the embedded spec.
+func normalizeOperationIDs(doc *openapi3.T) *openapi3.T {
+	for _, item := range doc.Paths.Map() {
+		for _, op := range item.Operations() {
+			op.OperationID = strings.ToLower(op.OperationID[:1]) + op.OperationID[1:]
+		}
+	}
+	return doc
+}
+
+// TestSpec_RoutesMatchSpec fails when OrderHandler serves a route that the
+// spec does not describe or the other way round.
+func TestSpec_RoutesMatchSpec(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromFile(specPath)
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(t.Context()))
+
+	var want []string
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			want = append(want, method+" "+path)
+		}
+	}
+
+	routes, ok := handlers.NewOrderHandler(fakeService{}).Routes().(chi.Routes)
+	require.True(t, ok)
+	var got []string
+	err = chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		got = append(got, method+" "+strings.TrimSuffix(route, "/"))
+		return nil
+	})
+	require.NoError(t, err)
+
+	sort.Strings(want)
+	sort.Strings(got)
+	assert.Equal(t, want, got)
+}
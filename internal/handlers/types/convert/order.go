@@ -16,6 +16,8 @@ func ToDomainCreate(in transport.CreateOrderRequest) uc.CreateInput {
 		Items:        toDomainItems(in.Items),
 		TotalPrice:   in.TotalPrice,
 		Address:      toDomainAddress(in.Address),
+		DeliverAt:    deref(in.DeliverAt),
+		PrepareFrom:  deref(in.PrepareFrom),
 	}
 }
 
@@ -37,6 +39,8 @@ func ToDomainUpdate(in transport.UpdateOrderRequest) uc.UpdateInput {
 		Items:       items,
 		TotalPrice:  in.TotalPrice,
 		Address:     addr,
+		DeliverAt:   in.DeliverAt,
+		PrepareFrom: in.PrepareFrom,
 	}
 }
 
@@ -50,13 +54,29 @@ func ToTransport(o *entity.Order) transport.OrderResponse {
 		Items:             toTransportItems(o.Items),
 		TotalPrice:        o.TotalPrice,
 		Address:           toTransportAddress(o.Address),
-		Status:            string(o.Status),
-		CreatedAt:         o.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:         o.UpdatedAt.Format(time.RFC3339),
-		EstimatedDelivery: o.EstimatedDelivery.Format(time.RFC3339),
+		Status:            transport.OrderStatus(o.Status),
+		CreatedAt:         o.CreatedAt,
+		UpdatedAt:         o.UpdatedAt,
+		EstimatedDelivery: o.EstimatedDelivery,
+		DeliverAt:         timeOrNil(o.DeliverAt),
+		PrepareFrom:       timeOrNil(o.PrepareFrom),
 	}
 }
 
+func deref(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
 func toDomainItems(items []transport.Item) []entity.Item {
 	out := make([]entity.Item, 0, len(items))
 	for _, it := range items {
@@ -78,6 +98,7 @@ func toDomainAddress(a transport.DeliveryAddress) entity.DeliveryAddress {
 		Apartment: a.Apartment,
 		Floor:     a.Floor,
 		Comment:   a.Comment,
+		Location:  (*entity.GeoPoint)(a.Location),
 	}
 }
 
@@ -102,5 +123,6 @@ func toTransportAddress(a entity.DeliveryAddress) transport.DeliveryAddress {
 		Apartment: a.Apartment,
 		Floor:     a.Floor,
 		Comment:   a.Comment,
+		Location:  (*transport.GeoPoint)(a.Location),
 	}
 }
@@ -0,0 +1,70 @@
+package convert
+
+import (
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/handlers/types/transport"
+	"github.com/nikolaev/service-order/internal/usecase/replay"
+	"github.com/nikolaev/service-order/pkg/api/openapi"
+)
+
+// ToReplayFilter returns the filter and the requested rate, 0 when absent.
+func ToReplayFilter(in transport.ReplayRequest) (replay.Filter, float64) {
+	var f replay.Filter
+	if in.From != nil {
+		f.From = *in.From
+	}
+	if in.To != nil {
+		f.To = *in.To
+	}
+	if in.Statuses != nil {
+		for _, s := range *in.Statuses {
+			f.Statuses = append(f.Statuses, entity.OrderStatus(s))
+		}
+	}
+	if in.RestaurantID != nil {
+		f.RestaurantID = *in.RestaurantID
+	}
+	var rate float64
+	if in.Rate != nil {
+		rate = *in.Rate
+	}
+	return f, rate
+}
+
+func ToTransportReplay(j replay.Job) transport.ReplayJob {
+	out := transport.ReplayJob{
+		ID:        j.ID,
+		State:     openapi.ReplayJobState(j.State),
+		Filter:    toTransportFilter(j.Filter),
+		Rate:      j.Rate,
+		Total:     j.Total,
+		Published: j.Published,
+		StartedAt: j.StartedAt,
+		Error:     j.Error,
+	}
+	if !j.FinishedAt.IsZero() {
+		out.FinishedAt = &j.FinishedAt
+	}
+	return out
+}
+
+func toTransportFilter(f replay.Filter) transport.ReplayRequest {
+	var out transport.ReplayRequest
+	if !f.From.IsZero() {
+		out.From = &f.From
+	}
+	if !f.To.IsZero() {
+		out.To = &f.To
+	}
+	if len(f.Statuses) > 0 {
+		statuses := make([]transport.OrderStatus, 0, len(f.Statuses))
+		for _, s := range f.Statuses {
+			statuses = append(statuses, transport.OrderStatus(s))
+		}
+		out.Statuses = &statuses
+	}
+	if f.RestaurantID != "" {
+		out.RestaurantID = &f.RestaurantID
+	}
+	return out
+}
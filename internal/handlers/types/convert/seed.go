@@ -0,0 +1,43 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/handlers/types/transport"
+	"github.com/nikolaev/service-order/internal/usecase/debug/seed"
+)
+
+// ToSeedRequest parses the inline scenario, if any, in the format of the
+// scenario files; it may go without a name.
+func ToSeedRequest(in transport.SeedRequest) (seed.Request, error) {
+	var out seed.Request
+	if in.Scenario != nil {
+		out.Scenario = *in.Scenario
+	}
+	if in.Count != nil {
+		out.Count = *in.Count
+	}
+	if in.Seed != nil {
+		out.Seed = uint64(*in.Seed)
+	}
+	if in.Inline != nil {
+		inline := *in.Inline
+		if _, ok := inline["name"]; !ok {
+			inline = maps.Clone(inline)
+			inline["name"] = "inline"
+		}
+		b, err := json.Marshal(inline)
+		if err != nil {
+			return seed.Request{}, fmt.Errorf("%w: inline scenario: %w", entity.ErrInvalidInput, err)
+		}
+		sc, err := seed.ParseScenario(b)
+		if err != nil {
+			return seed.Request{}, err
+		}
+		out.Inline = &sc
+	}
+	return out, nil
+}
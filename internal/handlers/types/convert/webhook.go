@@ -0,0 +1,67 @@
+package convert
+
+import (
+	"github.com/nikolaev/service-order/internal/gateway/webhook"
+	"github.com/nikolaev/service-order/internal/handlers/types/transport"
+	"github.com/nikolaev/service-order/pkg/api/openapi"
+)
+
+func ToWebhookSubscription(in transport.WebhookSubscriptionRequest) webhook.Subscription {
+	out := webhook.Subscription{URL: in.URL}
+	if in.Secret != nil {
+		out.Secret = *in.Secret
+	}
+	if in.Events != nil {
+		for _, e := range *in.Events {
+			out.Events = append(out.Events, webhook.EventType(e))
+		}
+	}
+	if in.RestaurantID != nil {
+		out.RestaurantID = *in.RestaurantID
+	}
+	return out
+}
+
+// ToTransportWebhook lists every event type for a subscription to all of them.
+func ToTransportWebhook(s webhook.Subscription) transport.WebhookSubscription {
+	events := s.Events
+	if len(events) == 0 {
+		events = webhook.EventTypes
+	}
+	out := transport.WebhookSubscription{
+		ID:           s.ID,
+		URL:          s.URL,
+		Secret:       s.Secret,
+		Events:       make([]transport.WebhookEvent, 0, len(events)),
+		RestaurantID: s.RestaurantID,
+		CreatedAt:    s.CreatedAt,
+	}
+	for _, e := range events {
+		out.Events = append(out.Events, transport.WebhookEvent(e))
+	}
+	return out
+}
+
+func ToTransportDelivery(d webhook.Delivery) transport.WebhookDelivery {
+	out := transport.WebhookDelivery{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		Event:          transport.WebhookEvent(d.Event),
+		OrderID:        d.OrderID,
+		State:          openapi.WebhookDeliveryState(d.State),
+		Attempts:       d.Attempts,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+	}
+	if d.LastStatus != 0 {
+		out.LastStatus = &d.LastStatus
+	}
+	if !d.NextAttemptAt.IsZero() {
+		out.NextAttemptAt = &d.NextAttemptAt
+	}
+	if !d.FinishedAt.IsZero() {
+		out.FinishedAt = &d.FinishedAt
+	}
+	return out
+}
@@ -0,0 +1,171 @@
+// Package validation checks HTTP traffic against api_openapi.yaml at runtime.
+package validation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+
+	"github.com/nikolaev/service-order/internal/handlers/types/transport"
+	"github.com/nikolaev/service-order/pkg/api/openapi"
+)
+
+// Mode selects how strictly traffic is checked.
+type Mode string
+
+const (
+	// ModeOff disables validation.
+	ModeOff Mode = "off"
+	// ModeRequest rejects requests that do not match the spec.
+	ModeRequest Mode = "request"
+	// ModeLog validates requests and logs responses that do not match the spec.
+	ModeLog Mode = "log"
+	// ModeStrict validates requests and replaces off-spec responses with 500.
+	// Meant for tests and staging.
+	ModeStrict Mode = "strict"
+)
+
+// ParseMode converts a config value to Mode. Empty means ModeRequest.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case "":
+		return ModeRequest, nil
+	case ModeOff, ModeRequest, ModeLog, ModeStrict:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown openapi validation mode %q", s)
+	}
+}
+
+// Middleware returns a chi-compatible middleware validating traffic against
+// the embedded api_openapi.yaml. Requests to paths outside the spec (health,
+// metrics) pass through untouched.
+func Middleware(mode Mode) (func(http.Handler) http.Handler, error) {
+	if mode == ModeOff {
+		return func(next http.Handler) http.Handler { return next }, nil
+	}
+
+	doc, err := openapi.GetSwagger()
+	if err != nil {
+		return nil, fmt.Errorf("load openapi spec: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %w", err)
+	}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("build openapi router: %w", err)
+	}
+
+	v := &validator{router: router, mode: mode}
+	return v.handler, nil
+}
+
+type validator struct {
+	router routers.Router
+	mode   Mode
+}
+
+func (v *validator) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := v.router.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		in := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				MultiError:         true,
+				AuthenticationFunc: authenticate,
+			},
+		}
+		if err := openapi3filter.ValidateRequest(r.Context(), in); err != nil {
+			writeRequestError(w, err)
+			return
+		}
+
+		if v.mode == ModeRequest {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &recorder{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		out := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: in,
+			Status:                 rec.status,
+			Header:                 rec.header,
+			Body:                   io.NopCloser(bytes.NewReader(rec.body.Bytes())),
+			Options:                &openapi3filter.Options{MultiError: true, IncludeResponseStatus: true},
+		}
+		if err := openapi3filter.ValidateResponse(r.Context(), out); err != nil {
+			slog.WarnContext(r.Context(), "openapi: response does not match spec",
+				"method", r.Method, "path", r.URL.Path, "error", err)
+			if v.mode == ModeStrict {
+				writeJSON(w, http.StatusInternalServerError, transport.Error{Code: "invalid_response", Message: err.Error()})
+				return
+			}
+		}
+		rec.flush(w)
+	})
+}
+
+// authenticate only checks that the header of the security scheme is present;
+// the identity itself is resolved by the handlers.
+func authenticate(_ context.Context, in *openapi3filter.AuthenticationInput) error {
+	if in.SecurityScheme.Type != "apiKey" || in.SecurityScheme.In != "header" {
+		return nil
+	}
+	if in.RequestValidationInput.Request.Header.Get(in.SecurityScheme.Name) == "" {
+		return fmt.Errorf("header %s is missing", in.SecurityScheme.Name)
+	}
+	return nil
+}
+
+func writeRequestError(w http.ResponseWriter, err error) {
+	var secErr *openapi3filter.SecurityRequirementsError
+	if errors.As(err, &secErr) {
+		writeJSON(w, http.StatusUnauthorized, transport.Error{Code: "unauthorized", Message: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusBadRequest, transport.Error{Code: "bad_request", Message: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// recorder buffers a response so it can be validated before it is sent.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header         { return r.header }
+func (r *recorder) Write(b []byte) (int, error) { return r.body.Write(b) }
+func (r *recorder) WriteHeader(status int)      { r.status = status }
+
+func (r *recorder) flush(w http.ResponseWriter) {
+	for k, v := range r.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(r.status)
+	_, _ = w.Write(r.body.Bytes())
+}
@@ -0,0 +1,96 @@
+package validation_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/gateway/kafka"
+	"github.com/nikolaev/service-order/internal/handlers"
+	"github.com/nikolaev/service-order/internal/handlers/validation"
+	repo "github.com/nikolaev/service-order/internal/repository/order"
+	uc "github.com/nikolaev/service-order/internal/usecase/order"
+)
+
+const validOrder = `{"restaurant_id":"rest-1","items":[{"food_id":"f1","name":"Pizza","quantity":1,"price":500}],"total_price":500,"address":{"street":"Main"}}`
+
+func setupRouter(t *testing.T, mode validation.Mode, api http.Handler) *chi.Mux {
+	t.Helper()
+	mw, err := validation.Middleware(mode)
+	require.NoError(t, err)
+	r := chi.NewRouter()
+	r.Use(mw)
+	r.Mount("/public/api/v1", api)
+	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	return r
+}
+
+func orderAPI() http.Handler {
+	return handlers.NewOrderHandler(uc.New(repo.NewInMemory(), kafka.NoopProducer{})).Routes()
+}
+
+func do(r http.Handler, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware_Requests(t *testing.T) {
+	r := setupRouter(t, validation.ModeRequest, orderAPI())
+	auth := map[string]string{handlers.HeaderUserID: "u1"}
+
+	w := do(r, http.MethodPost, "/public/api/v1/order", validOrder, auth)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = do(r, http.MethodPost, "/public/api/v1/order", validOrder, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = do(r, http.MethodPost, "/public/api/v1/order", `{"items":[],"total_price":-1}`, auth)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "restaurant_id")
+
+	w = do(r, http.MethodGet, "/public/api/v1/orders?from=yesterday", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(r, http.MethodGet, "/healthz", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestMiddleware_StrictResponses(t *testing.T) {
+	offSpec := chi.NewRouter()
+	offSpec.Get("/order/{id}/status", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"order_id":"o1","status":"teleported"}`))
+	})
+
+	w := do(setupRouter(t, validation.ModeStrict, offSpec), http.MethodGet, "/public/api/v1/order/o1/status", "", nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_response")
+
+	w = do(setupRouter(t, validation.ModeLog, offSpec), http.MethodGet, "/public/api/v1/order/o1/status", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = do(setupRouter(t, validation.ModeStrict, orderAPI()), http.MethodPost, "/public/api/v1/order", validOrder, map[string]string{handlers.HeaderBypass: "true"})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+}
+
+func TestParseMode(t *testing.T) {
+	m, err := validation.ParseMode("")
+	require.NoError(t, err)
+	assert.Equal(t, validation.ModeRequest, m)
+
+	_, err = validation.ParseMode("loose")
+	assert.Error(t, err)
+}
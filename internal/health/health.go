@@ -0,0 +1,160 @@
+// Package health serves liveness (/healthz) and readiness (/readyz) probes
+// built from checks that components register when they are constructed.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check reports a problem with a dependency or component; nil means healthy.
+type Check func(ctx context.Context) error
+
+var ErrShuttingDown = errors.New("service is shutting down")
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Response is the JSON body of both probes.
+type Response struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type CheckResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Health collects liveness and readiness checks. Every liveness check is also
+// a readiness check: a component that should be restarted must not get traffic.
+type Health struct {
+	timeout time.Duration
+
+	mu        sync.RWMutex
+	liveness  []namedCheck
+	readiness []namedCheck
+
+	shuttingDown atomic.Bool
+}
+
+// New creates Health; timeout bounds each check.
+func New(timeout time.Duration) *Health {
+	return &Health{timeout: timeout}
+}
+
+func (h *Health) AddLiveness(name string, c Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.liveness = append(h.liveness, namedCheck{name, c})
+}
+
+func (h *Health) AddReadiness(name string, c Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readiness = append(h.readiness, namedCheck{name, c})
+}
+
+// SetShuttingDown makes readiness fail so that the orchestrator stops
+// routing traffic while the service drains.
+func (h *Health) SetShuttingDown() { h.shuttingDown.Store(true) }
+
+func (h *Health) Liveness(ctx context.Context) Response {
+	h.mu.RLock()
+	checks := append([]namedCheck(nil), h.liveness...)
+	h.mu.RUnlock()
+	return h.run(ctx, checks)
+}
+
+func (h *Health) Readiness(ctx context.Context) Response {
+	h.mu.RLock()
+	checks := append(append([]namedCheck(nil), h.liveness...), h.readiness...)
+	h.mu.RUnlock()
+	checks = append(checks, namedCheck{"shutdown", func(context.Context) error {
+		if h.shuttingDown.Load() {
+			return ErrShuttingDown
+		}
+		return nil
+	}})
+	return h.run(ctx, checks)
+}
+
+// LivenessHandler serves /healthz: 200 when every liveness check passes, 503 otherwise.
+func (h *Health) LivenessHandler() http.Handler { return handler(h.Liveness) }
+
+// ReadinessHandler serves /readyz: 200 when every check passes, 503 otherwise.
+func (h *Health) ReadinessHandler() http.Handler { return handler(h.Readiness) }
+
+func handler(probe func(context.Context) Response) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := probe(r.Context())
+		code := http.StatusOK
+		if resp.Status != StatusOK {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// run executes checks concurrently, each with its own timeout.
+func (h *Health) run(ctx context.Context, checks []namedCheck) Response {
+	resp := Response{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := h.runOne(ctx, c.check)
+			mu.Lock()
+			defer mu.Unlock()
+			resp.Checks[c.name] = res
+			if res.Status != StatusOK {
+				resp.Status = StatusFail
+			}
+		}()
+	}
+	wg.Wait()
+	return resp
+}
+
+func (h *Health) runOne(ctx context.Context, c Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() { errc <- c(ctx) }()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	res := CheckResult{Status: StatusOK, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
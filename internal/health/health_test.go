@@ -0,0 +1,96 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/health"
+)
+
+func ok(context.Context) error { return nil }
+
+func probe(t *testing.T, h http.Handler) (int, health.Response) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var resp health.Response
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return rec.Code, resp
+}
+
+func TestHealth_AllPass(t *testing.T) {
+	h := health.New(time.Second)
+	h.AddLiveness("worker", ok)
+	h.AddReadiness("repository", ok)
+
+	code, resp := probe(t, h.LivenessHandler())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusOK, resp.Status)
+	assert.Equal(t, []string{"worker"}, keys(resp.Checks))
+
+	code, resp = probe(t, h.ReadinessHandler())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"repository", "shutdown", "worker"}, keys(resp.Checks))
+}
+
+func TestHealth_FailingReadinessKeepsLiveness(t *testing.T) {
+	h := health.New(time.Second)
+	h.AddLiveness("worker", ok)
+	h.AddReadiness("kafka", func(context.Context) error { return errors.New("no brokers") })
+
+	code, _ := probe(t, h.LivenessHandler())
+	assert.Equal(t, http.StatusOK, code)
+
+	code, resp := probe(t, h.ReadinessHandler())
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusFail, resp.Status)
+	assert.Equal(t, health.StatusFail, resp.Checks["kafka"].Status)
+	assert.Equal(t, "no brokers", resp.Checks["kafka"].Error)
+	assert.Equal(t, health.StatusOK, resp.Checks["worker"].Status)
+}
+
+func TestHealth_CheckTimeout(t *testing.T) {
+	h := health.New(10 * time.Millisecond)
+	h.AddReadiness("stuck", func(ctx context.Context) error {
+		time.Sleep(time.Second) // ignores ctx on purpose
+		return nil
+	})
+
+	start := time.Now()
+	resp := h.Readiness(context.Background())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, context.DeadlineExceeded.Error(), resp.Checks["stuck"].Error)
+}
+
+func TestHealth_ShuttingDown(t *testing.T) {
+	h := health.New(time.Second)
+	h.AddLiveness("worker", ok)
+	h.SetShuttingDown()
+
+	code, resp := probe(t, h.ReadinessHandler())
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.ErrShuttingDown.Error(), resp.Checks["shutdown"].Error)
+
+	// The process is still alive while it drains.
+	code, _ = probe(t, h.LivenessHandler())
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func keys(m map[string]health.CheckResult) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	slices.Sort(out)
+	return out
+}
@@ -0,0 +1,166 @@
+// Package hours tells whether a restaurant takes orders at a given time.
+// Opening hours are daily ranges of local time such as
+// "10:00-15:00,17:00-23:30"; a range that ends before it starts runs past
+// midnight, "00:00-24:00" is the whole day.
+package hours
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // time zones work without the system database
+)
+
+const day = 24 * time.Hour
+
+// Range is a part of the day, as offsets from midnight.
+type Range struct {
+	From time.Duration
+	To   time.Duration
+}
+
+func (r Range) contains(clock time.Duration) bool {
+	if r.From < r.To {
+		return clock >= r.From && clock < r.To
+	}
+	return clock >= r.From || clock < r.To
+}
+
+func (r Range) String() string {
+	return offset(r.From) + "-" + offset(r.To)
+}
+
+func offset(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
+
+// Schedule is the opening hours of one restaurant; an empty one is always
+// open.
+type Schedule []Range
+
+// Parse reads comma-separated ranges; an empty string is always open.
+func Parse(s string) (Schedule, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var out Schedule
+	for part := range strings.SplitSeq(s, ",") {
+		from, to, ok := strings.Cut(strings.TrimSpace(part), "-")
+		if !ok {
+			return nil, fmt.Errorf("range %q: want HH:MM-HH:MM", part)
+		}
+		r, err := parseRange(from, to)
+		if err != nil {
+			return nil, fmt.Errorf("range %q: %w", part, err)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func parseRange(from, to string) (Range, error) {
+	var r Range
+	var err error
+	if r.From, err = parseOffset(from); err != nil {
+		return Range{}, err
+	}
+	if r.To, err = parseOffset(to); err != nil {
+		return Range{}, err
+	}
+	if r.From == day {
+		return Range{}, errors.New("24:00 can only end a range")
+	}
+	if r.From == r.To {
+		return Range{}, errors.New("range is empty")
+	}
+	return r, nil
+}
+
+func parseOffset(s string) (time.Duration, error) {
+	var h, m int
+	if n, err := fmt.Sscanf(strings.TrimSpace(s), "%d:%d", &h, &m); err != nil || n != 2 {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	d := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute
+	if h < 0 || m < 0 || m > 59 || d > day {
+		return 0, fmt.Errorf("%q is not a time of day", s)
+	}
+	return d, nil
+}
+
+func (s Schedule) String() string {
+	if len(s) == 0 {
+		return "always"
+	}
+	parts := make([]string, len(s))
+	for i, r := range s {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func (s Schedule) open(clock time.Duration) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, r := range s {
+		if r.contains(clock) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hours are the schedules of the restaurants, all in one time zone.
+type Hours struct {
+	loc         *time.Location
+	def         Schedule
+	restaurants map[string]Schedule
+}
+
+// New parses the default schedule and the restaurants' own, which replace
+// it.
+func New(loc *time.Location, def string, restaurants map[string]string) (*Hours, error) {
+	h := &Hours{loc: loc, restaurants: make(map[string]Schedule, len(restaurants))}
+	var errs []error
+	var err error
+	if h.def, err = Parse(def); err != nil {
+		errs = append(errs, fmt.Errorf("default: %w", err))
+	}
+	for id, s := range restaurants {
+		if h.restaurants[id], err = Parse(s); err != nil {
+			errs = append(errs, fmt.Errorf("restaurant %s: %w", id, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Of returns the schedule of the restaurant.
+func (h *Hours) Of(restaurantID string) Schedule {
+	if s, ok := h.restaurants[restaurantID]; ok {
+		return s
+	}
+	return h.def
+}
+
+// Open reports whether the restaurant takes orders at t.
+func (h *Hours) Open(restaurantID string, t time.Time) bool {
+	local := t.In(h.loc)
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second
+	return h.Of(restaurantID).open(clock)
+}
+
+// Check returns an error naming the opening hours when the restaurant is
+// closed at t.
+func (h *Hours) Check(restaurantID string, t time.Time) error {
+	if h.Open(restaurantID, t) {
+		return nil
+	}
+	return fmt.Errorf("restaurant %s is closed at %s, it is open %s (%s)",
+		restaurantID, t.In(h.loc).Format("15:04"), h.Of(restaurantID), h.loc)
+}
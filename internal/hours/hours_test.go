@@ -0,0 +1,56 @@
+package hours_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/hours"
+)
+
+func TestHours_Open(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+	h, err := hours.New(moscow, "10:00-15:00, 17:00-23:30", map[string]string{
+		"bar":   "18:00-02:00",
+		"diner": "00:00-24:00",
+		"any":   "",
+	})
+	require.NoError(t, err)
+	at := func(clock string) time.Time {
+		t.Helper()
+		v, err := time.ParseInLocation("2006-01-02 15:04", "2026-10-01 "+clock, moscow)
+		require.NoError(t, err)
+		return v.UTC()
+	}
+
+	assert.False(t, h.Open("r1", at("09:59")))
+	assert.True(t, h.Open("r1", at("10:00")))
+	assert.False(t, h.Open("r1", at("15:00")), "the end is exclusive")
+	assert.True(t, h.Open("r1", at("23:29")))
+	assert.True(t, h.Open("bar", at("01:30")), "past midnight")
+	assert.False(t, h.Open("bar", at("12:00")))
+	assert.True(t, h.Open("diner", at("04:00")))
+	assert.True(t, h.Open("any", at("04:00")), "an empty schedule is always open")
+
+	assert.NoError(t, h.Check("r1", at("12:00")))
+	assert.EqualError(t, h.Check("r1", at("08:30")),
+		"restaurant r1 is closed at 08:30, it is open 10:00-15:00,17:00-23:30 (Europe/Moscow)")
+}
+
+func TestParse_Rejects(t *testing.T) {
+	for spec, want := range map[string]string{
+		"10-22":       `"10" is not HH:MM`,
+		"10:00":       "want HH:MM-HH:MM",
+		"25:00-26:00": `"25:00" is not a time of day`,
+		"10:00-10:00": "range is empty",
+		"24:00-02:00": "24:00 can only end a range",
+	} {
+		_, err := hours.Parse(spec)
+		assert.ErrorContains(t, err, want, spec)
+	}
+	_, err := hours.New(time.UTC, "", map[string]string{"r1": "9-5"})
+	assert.ErrorContains(t, err, "restaurant r1")
+}
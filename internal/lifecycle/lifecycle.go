@@ -0,0 +1,92 @@
+// Package lifecycle runs ordered start/stop hooks for the long-lived
+// components of the service (servers, workers, producers).
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Hook is a named pair of start/stop callbacks. Either callback may be nil.
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Lifecycle collects hooks from component constructors. Since a constructor
+// runs after the constructors of its dependencies, hooks appended in
+// construction order start dependencies first and stop them last.
+type Lifecycle struct {
+	mu      sync.Mutex
+	hooks   []Hook
+	started int
+}
+
+func New() *Lifecycle { return &Lifecycle{} }
+
+func (l *Lifecycle) Append(h Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, h)
+}
+
+// Start runs OnStart hooks in order. If a hook fails, the hooks that already
+// started are stopped in reverse order and the start error is returned.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.started < len(l.hooks) {
+		h := l.hooks[l.started]
+		if h.OnStart != nil {
+			slog.InfoContext(ctx, "lifecycle: starting", "component", h.Name)
+			if err := h.OnStart(ctx); err != nil {
+				err = fmt.Errorf("start %s: %w", h.Name, err)
+				return errors.Join(err, l.stop(ctx))
+			}
+		}
+		l.started++
+	}
+	return nil
+}
+
+// Stop runs OnStop hooks of the started components in reverse order. Every
+// hook runs even if an earlier one fails; the errors are joined.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stop(ctx)
+}
+
+func (l *Lifecycle) stop(ctx context.Context) error {
+	var errs []error
+	for ; l.started > 0; l.started-- {
+		h := l.hooks[l.started-1]
+		if h.OnStop == nil {
+			continue
+		}
+		slog.InfoContext(ctx, "lifecycle: stopping", "component", h.Name)
+		if err := h.OnStop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", h.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run starts all components, blocks until ctx is done (typically on SIGINT or
+// SIGTERM) and then stops them, giving the whole shutdown stopTimeout.
+func (l *Lifecycle) Run(ctx context.Context, stopTimeout time.Duration) error {
+	if err := l.Start(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	slog.InfoContext(ctx, "lifecycle: shutting down", "timeout", stopTimeout.String())
+
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), stopTimeout)
+	defer cancel()
+	return l.Stop(stopCtx)
+}
@@ -0,0 +1,95 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/lifecycle"
+)
+
+type recorder []string
+
+func (r *recorder) hook(name string, startErr error) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: name,
+		OnStart: func(context.Context) error {
+			*r = append(*r, "start "+name)
+			return startErr
+		},
+		OnStop: func(context.Context) error {
+			*r = append(*r, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestLifecycle_StartStopOrder(t *testing.T) {
+	var rec recorder
+	lc := lifecycle.New()
+	lc.Append(rec.hook("producer", nil))
+	lc.Append(lifecycle.Hook{Name: "no-op"})
+	lc.Append(rec.hook("worker", nil))
+	lc.Append(rec.hook("server", nil))
+
+	require.NoError(t, lc.Start(context.Background()))
+	require.NoError(t, lc.Stop(context.Background()))
+
+	assert.Equal(t, recorder{
+		"start producer", "start worker", "start server",
+		"stop server", "stop worker", "stop producer",
+	}, rec)
+}
+
+func TestLifecycle_StartFailureStopsStarted(t *testing.T) {
+	var rec recorder
+	boom := errors.New("boom")
+	lc := lifecycle.New()
+	lc.Append(rec.hook("producer", nil))
+	lc.Append(rec.hook("server", boom))
+	lc.Append(rec.hook("never", nil))
+
+	err := lc.Start(context.Background())
+	require.ErrorIs(t, err, boom)
+	assert.Contains(t, err.Error(), "start server")
+	assert.Equal(t, recorder{"start producer", "start server", "stop producer"}, rec)
+
+	// Nothing is left to stop.
+	require.NoError(t, lc.Stop(context.Background()))
+	assert.Len(t, rec, 3)
+}
+
+func TestLifecycle_StopRunsEveryHook(t *testing.T) {
+	var stopped []string
+	lc := lifecycle.New()
+	for _, name := range []string{"a", "b"} {
+		lc.Append(lifecycle.Hook{Name: name, OnStop: func(context.Context) error {
+			stopped = append(stopped, name)
+			return errors.New(name + " failed")
+		}})
+	}
+	require.NoError(t, lc.Start(context.Background()))
+
+	err := lc.Stop(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stop a")
+	assert.Contains(t, err.Error(), "stop b")
+	assert.Equal(t, []string{"b", "a"}, stopped)
+}
+
+func TestLifecycle_RunStopsWithTimeout(t *testing.T) {
+	lc := lifecycle.New()
+	lc.Append(lifecycle.Hook{Name: "slow", OnStop: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := lc.Run(ctx, 10*time.Millisecond)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+// Logger adapts the default slog logger to the log interfaces that packages
+// declare for themselves (WithFields, Info, Error).
+type Logger struct{}
+
+// WithFields adds fields to ctx; see With.
+func (Logger) WithFields(ctx context.Context, fields map[string]any) context.Context {
+	args := make([]any, 0, 2*len(fields))
+	for _, k := range slices.Sorted(maps.Keys(fields)) {
+		args = append(args, k, fields[k])
+	}
+	return With(ctx, args...)
+}
+
+// Info logs args as message followed by key/value pairs, like slog.Info.
+func (Logger) Info(ctx context.Context, args ...any) { logArgs(ctx, slog.LevelInfo, args) }
+
+// Error is Info at error level.
+func (Logger) Error(ctx context.Context, args ...any) { logArgs(ctx, slog.LevelError, args) }
+
+func logArgs(ctx context.Context, level slog.Level, args []any) {
+	msg := ""
+	if len(args) > 0 {
+		if s, ok := args[0].(string); ok {
+			msg, args = s, args[1:]
+		} else {
+			msg, args = fmt.Sprint(args...), nil
+		}
+	}
+	slog.Log(ctx, level, msg, args...)
+}
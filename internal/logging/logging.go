@@ -0,0 +1,119 @@
+// Package logging configures log/slog for the service: JSON or text output,
+// a minimum level and fields carried in the context (request_id, user_id,
+// order_id), so one order can be followed across HTTP, usecase, outbox,
+// producers and the status worker.
+//
+// Components log through the default slog logger with the *Context
+// functions; main installs the logger built by New as the default.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// ParseLevel accepts debug, info, warn and error.
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+	return l, nil
+}
+
+func ParseFormat(s string) (string, error) {
+	switch f := strings.ToLower(s); f {
+	case FormatJSON, FormatText:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown log format %q (want json or text)", s)
+	}
+}
+
+// New builds a logger that writes to w and adds the context fields to every
+// record logged with a context.
+func New(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	f, err := ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler = slog.NewJSONHandler(w, opts)
+	if f == FormatText {
+		h = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(contextHandler{h}), nil
+}
+
+type fieldsKey struct{}
+
+// With returns a context whose log records carry args (key/value pairs or
+// slog.Attr values) in addition to the fields already in ctx. A key that is
+// already present is replaced.
+func With(ctx context.Context, args ...any) context.Context {
+	r := slog.Record{}
+	r.Add(args...)
+	attrs := append([]slog.Attr(nil), fields(ctx)...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = slices.DeleteFunc(attrs, func(b slog.Attr) bool { return b.Key == a.Key })
+		attrs = append(attrs, a)
+		return true
+	})
+	return context.WithValue(ctx, fieldsKey{}, attrs)
+}
+
+func fields(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(fieldsKey{}).([]slog.Attr)
+	return attrs
+}
+
+type contextHandler struct{ slog.Handler }
+
+// Handle adds the context fields that the record does not set itself, so an
+// explicit order_id argument does not produce a duplicate key, and the
+// trace_id/span_id of the current span.
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := fields(ctx)
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs[:len(attrs):len(attrs)],
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()))
+	}
+	if len(attrs) == 0 {
+		return h.Handler.Handle(ctx, r)
+	}
+	own := make(map[string]bool, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		own[a.Key] = true
+		return true
+	})
+	r = r.Clone()
+	for _, a := range attrs {
+		if !own[a.Key] {
+			r.AddAttrs(a)
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,131 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/logging"
+)
+
+func records(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &m), line)
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestNew_ContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(&buf, "json", slog.LevelInfo)
+	require.NoError(t, err)
+
+	ctx := logging.With(context.Background(), "request_id", "r1", "user_id", "u1")
+	ctx = logging.With(ctx, "order_id", "o1")
+	l.InfoContext(ctx, "order created", "status", "created")
+	// An explicit argument wins over the context field of the same key.
+	l.InfoContext(ctx, "other order", "order_id", "o2")
+	l.DebugContext(ctx, "filtered by level")
+
+	recs := records(t, &buf)
+	require.Len(t, recs, 2)
+	assert.Equal(t, "order created", recs[0]["msg"])
+	assert.Equal(t, "INFO", recs[0]["level"])
+	for k, v := range map[string]string{"request_id": "r1", "user_id": "u1", "order_id": "o1", "status": "created"} {
+		assert.Equal(t, v, recs[0][k], k)
+	}
+	assert.Equal(t, "o2", recs[1]["order_id"])
+	assert.Equal(t, 1, strings.Count(strings.Split(buf.String(), "\n")[1], `"order_id"`))
+}
+
+func TestWith_ReplacesKey(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(&buf, "text", slog.LevelInfo)
+	require.NoError(t, err)
+
+	ctx := logging.With(context.Background(), "user_id", "u1")
+	ctx = logging.With(ctx, "user_id", "u2")
+	l.InfoContext(ctx, "hello")
+
+	assert.Contains(t, buf.String(), "user_id=u2")
+	assert.NotContains(t, buf.String(), "user_id=u1")
+}
+
+func TestParse(t *testing.T) {
+	l, err := logging.ParseLevel("warn")
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, l)
+	_, err = logging.ParseLevel("loud")
+	assert.Error(t, err)
+
+	_, err = logging.New(&bytes.Buffer{}, "xml", slog.LevelInfo)
+	assert.Error(t, err)
+}
+
+func TestLogger_Adapter(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(&buf, "json", slog.LevelInfo)
+	require.NoError(t, err)
+	prev := slog.Default()
+	slog.SetDefault(l)
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	var lg logging.Logger
+	ctx := lg.WithFields(context.Background(), map[string]any{"order_id": "o1"})
+	lg.Info(ctx, "order updated", "status", "pending")
+	lg.Error(ctx, "publish failed")
+
+	recs := records(t, &buf)
+	require.Len(t, recs, 2)
+	assert.Equal(t, "order updated", recs[0]["msg"])
+	assert.Equal(t, "pending", recs[0]["status"])
+	assert.Equal(t, "o1", recs[0]["order_id"])
+	assert.Equal(t, "ERROR", recs[1]["level"])
+}
+
+func TestHTTPMiddleware_RequestID(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(&buf, "json", slog.LevelInfo)
+	require.NoError(t, err)
+	prev := slog.Default()
+	slog.SetDefault(l)
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	h := logging.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slog.InfoContext(r.Context(), "inside")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/x", nil)
+	req.Header.Set(logging.HeaderRequestID, "req-42")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, "req-42", rec.Header().Get(logging.HeaderRequestID))
+
+	recs := records(t, &buf)
+	require.Len(t, recs, 2)
+	assert.Equal(t, "req-42", recs[0]["request_id"])
+	assert.Equal(t, "http request", recs[1]["msg"])
+	assert.Equal(t, "req-42", recs[1]["request_id"])
+	assert.EqualValues(t, http.StatusTeapot, recs[1]["status"])
+
+	// Without the header an ID is generated.
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x", nil))
+	assert.NotEmpty(t, rec.Header().Get(logging.HeaderRequestID))
+}
@@ -0,0 +1,153 @@
+// Package metrics exposes service metrics in the Prometheus format. Metrics
+// implements the consumer-side metric interfaces of the usecase, worker and
+// kafka packages and provides wrappers for HTTP handlers and producers.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "service_order"
+
+type Metrics struct {
+	reg *prometheus.Registry
+
+	events            *prometheus.CounterVec
+	httpDuration      *prometheus.HistogramVec
+	httpInFlight      prometheus.Gauge
+	publishDuration   *prometheus.HistogramVec
+	publishErrors     *prometheus.CounterVec
+	kafkaDelivery     *prometheus.HistogramVec
+	kafkaDeadLetters  *prometheus.CounterVec
+	transitions       *prometheus.CounterVec
+	timeInStatus      *prometheus.HistogramVec
+	ordersInStatus    *prometheus.GaugeVec
+	workerTick        prometheus.Histogram
+	workerTickChanges prometheus.Counter
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		reg: prometheus.NewRegistry(),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_total",
+			Help:      "Usecase events by key, e.g. order.created, order.updated, order.deleted.",
+		}, []string{"key"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request duration by method, route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "code"}),
+		httpInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests being served.",
+		}),
+		publishDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "producer_publish_duration_seconds",
+			Help:      "Event publish latency by producer and event.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"producer", "event"}),
+		publishErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "producer_publish_errors_total",
+			Help:      "Failed event publishes by producer and event.",
+		}, []string{"producer", "event"}),
+		kafkaDelivery: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "kafka_delivery_duration_seconds",
+			Help:      "Time from enqueueing a message in the async producer to its acknowledgement or failure, by topic and result.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"topic", "result"}),
+		kafkaDeadLetters: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "kafka_dead_lettered_total",
+			Help:      "Messages routed to the dead-letter topic after failing all retries, by original topic.",
+		}, []string{"topic"}),
+		transitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "order_status_transitions_total",
+			Help:      "Order status transitions by previous and new status.",
+		}, []string{"from", "to"}),
+		timeInStatus: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "order_status_duration_seconds",
+			Help:      "Time an order spent in a status before leaving it.",
+			Buckets:   []float64{1, 5, 10, 30, 60, 300, 600, 1800, 3600},
+		}, []string{"status"}),
+		ordersInStatus: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "orders_in_status",
+			Help:      "Orders currently in each status.",
+		}, []string{"status"}),
+		workerTick: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "worker_tick_duration_seconds",
+			Help:      "Duration of a status worker tick, including publishing.",
+			Buckets:   []float64{.0005, .001, .005, .01, .05, .1, .5, 1},
+		}),
+		workerTickChanges: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "worker_status_changes_total",
+			Help:      "Status changes applied by the status worker.",
+		}),
+	}
+	m.reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.events, m.httpDuration, m.httpInFlight,
+		m.publishDuration, m.publishErrors,
+		m.kafkaDelivery, m.kafkaDeadLetters,
+		m.transitions, m.timeInStatus, m.ordersInStatus,
+		m.workerTick, m.workerTickChanges,
+	)
+	return m
+}
+
+// Handler serves /metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.reg, promhttp.HandlerOpts{Registry: m.reg})
+}
+
+// Gauge registers a gauge whose value is read on every scrape, e.g. the
+// outbox queue length.
+func (m *Metrics) Gauge(name, help string, value func() float64) {
+	m.reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, value))
+}
+
+// Increment implements the usecase metric interface.
+func (m *Metrics) Increment(key string) {
+	m.events.WithLabelValues(key).Inc()
+}
+
+// ObserveTick implements the worker metric interface.
+func (m *Metrics) ObserveTick(d time.Duration, changed int) {
+	m.workerTick.Observe(d.Seconds())
+	m.workerTickChanges.Add(float64(changed))
+}
+
+// ObserveDelivery implements the kafka metric interface.
+func (m *Metrics) ObserveDelivery(topic string, d time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.kafkaDelivery.WithLabelValues(topic, result).Observe(d.Seconds())
+}
+
+// IncDeadLettered implements the kafka metric interface.
+func (m *Metrics) IncDeadLettered(topic string) {
+	m.kafkaDeadLetters.WithLabelValues(topic).Inc()
+}
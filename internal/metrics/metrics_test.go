@@ -0,0 +1,109 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/metrics"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	b, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestMetrics_HTTPMiddlewareUsesRoutePattern(t *testing.T) {
+	m := metrics.New()
+	api := chi.NewRouter()
+	api.Get("/order/{id}", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNotFound) })
+	r := chi.NewRouter()
+	r.Use(m.HTTPMiddleware)
+	r.Mount("/public/api/v1", api)
+
+	for _, id := range []string{"a", "b"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/public/api/v1/order/"+id, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+	out := scrape(t, m)
+	assert.Contains(t, out, `service_order_http_request_duration_seconds_count{code="404",method="GET",route="/public/api/v1/order/{id}"} 2`)
+	assert.Contains(t, out, `service_order_http_request_duration_seconds_count{code="404",method="GET",route="unmatched"} 1`)
+}
+
+type failingProducer struct{}
+
+func (failingProducer) OrderCreated(context.Context, *entity.Order) error { return nil }
+func (failingProducer) OrderUpdated(context.Context, *entity.Order) error {
+	return errors.New("broker down")
+}
+func (failingProducer) OrderStatusChanged(context.Context, *entity.Order) error { return nil }
+func (failingProducer) OrderDeleted(context.Context, string, string) error      { return nil }
+func (failingProducer) OrderSnapshot(context.Context, *entity.Order, string) error {
+	return nil
+}
+func (failingProducer) OrderETAChanged(context.Context, *entity.Order) error { return nil }
+
+func TestMetrics_InstrumentProducer(t *testing.T) {
+	m := metrics.New()
+	p := m.InstrumentProducer("kafka", failingProducer{})
+
+	require.NoError(t, p.OrderCreated(context.Background(), &entity.Order{ID: "o1"}))
+	require.Error(t, p.OrderUpdated(context.Background(), &entity.Order{ID: "o1"}))
+
+	out := scrape(t, m)
+	assert.Contains(t, out, `service_order_producer_publish_duration_seconds_count{event="created",producer="kafka"} 1`)
+	assert.Contains(t, out, `service_order_producer_publish_errors_total{event="updated",producer="kafka"} 1`)
+	assert.NotContains(t, out, `service_order_producer_publish_errors_total{event="created"`)
+}
+
+func TestMetrics_StatusTracker(t *testing.T) {
+	m := metrics.New()
+	tr := m.StatusTracker()
+	ctx := context.Background()
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, tr.OrderCreated(ctx, &entity.Order{ID: "o1", Status: entity.OrderStatusCreated, StatusChangedAt: t0}))
+	require.NoError(t, tr.OrderCreated(ctx, &entity.Order{ID: "o2", Status: entity.OrderStatusCreated, StatusChangedAt: t0}))
+	require.NoError(t, tr.OrderUpdated(ctx, &entity.Order{ID: "o1", Status: entity.OrderStatusPending, StatusChangedAt: t0.Add(2 * time.Second)}))
+	// An update that keeps the status is not a transition.
+	require.NoError(t, tr.OrderUpdated(ctx, &entity.Order{ID: "o1", Status: entity.OrderStatusPending, StatusChangedAt: t0.Add(3 * time.Second)}))
+	require.NoError(t, tr.OrderDeleted(ctx, "o2", "u1"))
+
+	out := scrape(t, m)
+	assert.Contains(t, out, `service_order_order_status_transitions_total{from="created",to="pending"} 1`)
+	assert.Contains(t, out, `service_order_order_status_transitions_total{from="created",to="deleted"} 1`)
+	assert.Contains(t, out, `service_order_order_status_duration_seconds_sum{status="created"}`)
+	assert.Contains(t, out, `service_order_order_status_duration_seconds_bucket{status="created",le="5"} 1`)
+	assert.Contains(t, out, `service_order_orders_in_status{status="created"} 0`)
+	assert.Contains(t, out, `service_order_orders_in_status{status="pending"} 1`)
+	assert.NotContains(t, out, `service_order_orders_in_status{status="deleted"}`, "finished orders are not tracked")
+}
+
+func TestMetrics_IncrementAndGauge(t *testing.T) {
+	m := metrics.New()
+	m.Increment("order.created")
+	m.Increment("order.created")
+	m.ObserveTick(time.Millisecond, 3)
+	m.Gauge("outbox_pending_events", "Events waiting in the outbox.", func() float64 { return 7 })
+
+	out := scrape(t, m)
+	assert.Contains(t, out, `service_order_events_total{key="order.created"} 2`)
+	assert.Contains(t, out, `service_order_worker_status_changes_total 3`)
+	assert.Contains(t, out, `service_order_worker_tick_duration_seconds_count 1`)
+	assert.Contains(t, out, `service_order_outbox_pending_events 7`)
+}
@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nikolaev/service-order/internal/clock"
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+type Producer interface {
+	OrderCreated(ctx context.Context, o *entity.Order) error
+	OrderUpdated(ctx context.Context, o *entity.Order) error
+	OrderStatusChanged(ctx context.Context, o *entity.Order) error
+	OrderDeleted(ctx context.Context, id string, userID string) error
+	OrderSnapshot(ctx context.Context, o *entity.Order, replayID string) error
+	OrderETAChanged(ctx context.Context, o *entity.Order) error
+}
+
+type instrumented struct {
+	m    *Metrics
+	name string
+	p    Producer
+}
+
+// InstrumentProducer records publish latency and errors of p under name.
+func (m *Metrics) InstrumentProducer(name string, p Producer) Producer {
+	return instrumented{m: m, name: name, p: p}
+}
+
+func (i instrumented) observe(event string, start time.Time, err error) error {
+	i.m.publishDuration.WithLabelValues(i.name, event).Observe(time.Since(start).Seconds())
+	if err != nil {
+		i.m.publishErrors.WithLabelValues(i.name, event).Inc()
+	}
+	return err
+}
+
+func (i instrumented) OrderCreated(ctx context.Context, o *entity.Order) error {
+	start := time.Now()
+	return i.observe("created", start, i.p.OrderCreated(ctx, o))
+}
+
+func (i instrumented) OrderUpdated(ctx context.Context, o *entity.Order) error {
+	start := time.Now()
+	return i.observe("updated", start, i.p.OrderUpdated(ctx, o))
+}
+
+func (i instrumented) OrderStatusChanged(ctx context.Context, o *entity.Order) error {
+	start := time.Now()
+	return i.observe("status_changed", start, i.p.OrderStatusChanged(ctx, o))
+}
+
+func (i instrumented) OrderDeleted(ctx context.Context, id string, userID string) error {
+	start := time.Now()
+	return i.observe("deleted", start, i.p.OrderDeleted(ctx, id, userID))
+}
+
+func (i instrumented) OrderSnapshot(ctx context.Context, o *entity.Order, replayID string) error {
+	start := time.Now()
+	return i.observe("snapshot", start, i.p.OrderSnapshot(ctx, o, replayID))
+}
+
+func (i instrumented) OrderETAChanged(ctx context.Context, o *entity.Order) error {
+	start := time.Now()
+	return i.observe("eta_changed", start, i.p.OrderETAChanged(ctx, o))
+}
+
+type statusSince struct {
+	status entity.OrderStatus
+	since  time.Time
+}
+
+// StatusTracker derives status metrics from the event stream: transitions
+// from→to, time spent in each status and orders currently in each status.
+// It is a Producer so it can sit next to the real producers.
+type StatusTracker struct {
+	m   *Metrics
+	now func() time.Time
+
+	mu     sync.Mutex
+	orders map[string]statusSince
+}
+
+func (m *Metrics) StatusTracker() *StatusTracker {
+	return &StatusTracker{m: m, now: clock.System{}.Now, orders: make(map[string]statusSince)}
+}
+
+// WithClock dates deletions, and events without a status time, with c
+// instead of the wall clock, so that durations match the order timestamps.
+func (t *StatusTracker) WithClock(c interface{ Now() time.Time }) *StatusTracker {
+	t.now = c.Now
+	return t
+}
+
+func (t *StatusTracker) OrderCreated(_ context.Context, o *entity.Order) error {
+	t.mu.Lock()
+	_, known := t.orders[o.ID]
+	t.mu.Unlock()
+	// An update may overtake the creation event; the order is already tracked then.
+	if !known {
+		t.move(o.ID, o.Status, o.StatusChangedAt)
+	}
+	return nil
+}
+
+func (t *StatusTracker) OrderUpdated(_ context.Context, o *entity.Order) error {
+	t.move(o.ID, o.Status, o.StatusChangedAt)
+	return nil
+}
+
+func (t *StatusTracker) OrderStatusChanged(_ context.Context, o *entity.Order) error {
+	t.move(o.ID, o.Status, o.StatusChangedAt)
+	return nil
+}
+
+func (t *StatusTracker) OrderDeleted(_ context.Context, id string, _ string) error {
+	t.move(id, entity.OrderStatusDeleted, t.now())
+	return nil
+}
+
+// OrderSnapshot is ignored: a replayed state is not a transition.
+func (t *StatusTracker) OrderSnapshot(context.Context, *entity.Order, string) error { return nil }
+
+// OrderETAChanged is ignored: the status stays.
+func (t *StatusTracker) OrderETAChanged(context.Context, *entity.Order) error { return nil }
+
+func (t *StatusTracker) move(id string, to entity.OrderStatus, at time.Time) {
+	if at.IsZero() {
+		at = t.now()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prev, known := t.orders[id]
+	if known && prev.status == to {
+		return
+	}
+	if known {
+		t.m.ordersInStatus.WithLabelValues(string(prev.status)).Dec()
+		t.m.transitions.WithLabelValues(string(prev.status), string(to)).Inc()
+		if d := at.Sub(prev.since); d >= 0 {
+			t.m.timeInStatus.WithLabelValues(string(prev.status)).Observe(d.Seconds())
+		}
+	}
+	switch to {
+	case entity.OrderStatusCompleted, entity.OrderStatusDelivered, entity.OrderStatusCanceled, entity.OrderStatusDeleted:
+		// Terminal: nothing leaves it, so forget the order to bound memory.
+		delete(t.orders, id)
+	default:
+		t.orders[id] = statusSince{status: to, since: at}
+		t.m.ordersInStatus.WithLabelValues(string(to)).Inc()
+	}
+}
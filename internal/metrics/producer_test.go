@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+func TestStatusTracker_ForgetsFinishedOrders(t *testing.T) {
+	tr := New().StatusTracker()
+	ctx := context.Background()
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, st := range []entity.OrderStatus{entity.OrderStatusCompleted, entity.OrderStatusDelivered, entity.OrderStatusCanceled} {
+		o := &entity.Order{ID: string(st), Status: entity.OrderStatusCreated, StatusChangedAt: t0}
+		require.NoError(t, tr.OrderCreated(ctx, o))
+		o.Status, o.StatusChangedAt = st, t0.Add(time.Minute)
+		require.NoError(t, tr.OrderStatusChanged(ctx, o))
+	}
+	require.NoError(t, tr.OrderCreated(ctx, &entity.Order{ID: "gone", Status: entity.OrderStatusCreated, StatusChangedAt: t0}))
+	require.NoError(t, tr.OrderDeleted(ctx, "gone", "u1"))
+	require.NoError(t, tr.OrderCreated(ctx, &entity.Order{ID: "live", Status: entity.OrderStatusCreated, StatusChangedAt: t0}))
+
+	assert.Len(t, tr.orders, 1)
+	assert.Contains(t, tr.orders, "live")
+}
@@ -0,0 +1,109 @@
+package order
+
+import (
+	"slices"
+	"time"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+type EventKind string
+
+const (
+	EventCreated        EventKind = "created"
+	EventItemsChanged   EventKind = "items_changed"
+	EventAddressChanged EventKind = "address_changed"
+	// EventDetailsChanged covers the order number, the FIO and the ETA.
+	EventDetailsChanged EventKind = "details_changed"
+	EventStatusAdvanced EventKind = "status_advanced"
+	EventDeleted        EventKind = "deleted"
+	// EventScheduleChanged moves DeliverAt and PrepareFrom of a scheduled
+	// order.
+	EventScheduleChanged EventKind = "schedule_changed"
+	// EventETAChanged is a new estimate of the ETA component; it is not an
+	// edit and leaves UpdatedAt as it was.
+	EventETAChanged EventKind = "eta_changed"
+)
+
+// Event is one change of an order. Only the fields of its kind are set.
+type Event struct {
+	OrderID string
+	// Version numbers the events of one order from 1.
+	Version int
+	Kind    EventKind
+	At      time.Time
+
+	// created
+	Order *entity.Order
+	// items_changed
+	Items      []entity.Item
+	TotalPrice int64
+	// address_changed
+	Address entity.DeliveryAddress
+	// details_changed, eta_changed
+	OrderNumber       string
+	FIO               string
+	EstimatedDelivery time.Time
+	// status_advanced
+	Status entity.OrderStatus
+	// schedule_changed
+	DeliverAt   time.Time
+	PrepareFrom time.Time
+}
+
+// apply folds e into o; o is nil before the created event.
+func apply(o *entity.Order, e Event) *entity.Order {
+	if e.Kind == EventCreated {
+		cp := *e.Order
+		cp.Items = slices.Clone(cp.Items)
+		return &cp
+	}
+	switch e.Kind {
+	case EventItemsChanged:
+		o.Items = slices.Clone(e.Items)
+		o.TotalPrice = e.TotalPrice
+	case EventAddressChanged:
+		o.Address = e.Address
+	case EventDetailsChanged:
+		o.OrderNumber = e.OrderNumber
+		o.FIO = e.FIO
+		o.EstimatedDelivery = e.EstimatedDelivery
+	case EventScheduleChanged:
+		o.DeliverAt = e.DeliverAt
+		o.PrepareFrom = e.PrepareFrom
+	case EventStatusAdvanced:
+		o.Status = e.Status
+		o.StatusChangedAt = e.At
+	case EventDeleted:
+		o.IsDeleted = true
+		o.Status = entity.OrderStatusDeleted
+	case EventETAChanged:
+		o.EstimatedDelivery = e.EstimatedDelivery
+		return o
+	}
+	o.UpdatedAt = e.At
+	return o
+}
+
+// diff returns the events that turn old into o, without versions. They are
+// all stamped with o.UpdatedAt, so that a stream is ordered by time.
+func diff(old, o *entity.Order) []Event {
+	var out []Event
+	at := o.UpdatedAt
+	if !slices.Equal(old.Items, o.Items) || old.TotalPrice != o.TotalPrice {
+		out = append(out, Event{Kind: EventItemsChanged, At: at, Items: slices.Clone(o.Items), TotalPrice: o.TotalPrice})
+	}
+	if !old.Address.Equal(o.Address) {
+		out = append(out, Event{Kind: EventAddressChanged, At: at, Address: o.Address})
+	}
+	if old.OrderNumber != o.OrderNumber || old.FIO != o.FIO || !old.EstimatedDelivery.Equal(o.EstimatedDelivery) {
+		out = append(out, Event{Kind: EventDetailsChanged, At: at, OrderNumber: o.OrderNumber, FIO: o.FIO, EstimatedDelivery: o.EstimatedDelivery})
+	}
+	if !old.DeliverAt.Equal(o.DeliverAt) || !old.PrepareFrom.Equal(o.PrepareFrom) {
+		out = append(out, Event{Kind: EventScheduleChanged, At: at, DeliverAt: o.DeliverAt, PrepareFrom: o.PrepareFrom})
+	}
+	if old.Status != o.Status || !old.StatusChangedAt.Equal(o.StatusChangedAt) {
+		out = append(out, Event{Kind: EventStatusAdvanced, At: at, Status: o.Status})
+	}
+	return out
+}
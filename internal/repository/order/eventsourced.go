@@ -0,0 +1,305 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+// DefaultSnapshotEvery is how many events of an order EventSourced folds at
+// most before it takes a snapshot.
+const DefaultSnapshotEvery = 50
+
+// EventSourced stores every order as a stream of events and rebuilds its state
+// by folding them, starting from the latest snapshot. List queries and the
+// status worker read the list projection instead.
+type EventSourced struct {
+	mu            sync.RWMutex
+	streams       map[string]*stream
+	ids           []string // in creation order, to replay the log
+	schedule      Schedule
+	snapshotEvery int
+	list          *listProjection
+	projections   []Projection
+}
+
+type stream struct {
+	events    []Event
+	snapshots []snapshot // oldest first
+}
+
+// snapshot is the state after the event Version.
+type snapshot struct {
+	Version int
+	At      time.Time
+	Order   entity.Order
+}
+
+// Projection is a read model fed from the event log. Apply gets every event
+// in the order of its stream, with the state of the order after it; it runs
+// under the store's lock and must not call back into the store.
+type Projection interface {
+	Apply(e Event, o *entity.Order)
+}
+
+func NewEventSourced(s Schedule, snapshotEvery int) *EventSourced {
+	if snapshotEvery <= 0 {
+		snapshotEvery = DefaultSnapshotEvery
+	}
+	list := newListProjection()
+	return &EventSourced{
+		streams:       make(map[string]*stream),
+		schedule:      s,
+		snapshotEvery: snapshotEvery,
+		list:          list,
+		projections:   []Projection{list},
+	}
+}
+
+// AddProjection replays the log into p and keeps it up to date.
+func (r *EventSourced) AddProjection(p Projection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range r.ids {
+		var o *entity.Order
+		for _, e := range r.streams[id].events {
+			o = apply(o, e)
+			cp := *o
+			p.Apply(e, &cp)
+		}
+	}
+	r.projections = append(r.projections, p)
+}
+
+// append versions evs, folds them into the state of s and feeds the
+// projections. The caller holds the lock.
+func (r *EventSourced) append(s *stream, state *entity.Order, evs ...Event) *entity.Order {
+	for _, e := range evs {
+		e.Version = len(s.events) + 1
+		s.events = append(s.events, e)
+		state = apply(state, e)
+		if e.Version%r.snapshotEvery == 0 {
+			s.snapshots = append(s.snapshots, snapshot{Version: e.Version, At: e.At, Order: *state})
+		}
+		for _, p := range r.projections {
+			cp := *state
+			p.Apply(e, &cp)
+		}
+	}
+	return state
+}
+
+// fold rebuilds the state after the first n events of s.
+func (s *stream) fold(n int) *entity.Order {
+	var o *entity.Order
+	from := 0
+	if i := sort.Search(len(s.snapshots), func(i int) bool { return s.snapshots[i].Version > n }); i > 0 {
+		snap := s.snapshots[i-1]
+		cp := snap.Order
+		o, from = &cp, snap.Version
+	}
+	for _, e := range s.events[from:n] {
+		o = apply(o, e)
+	}
+	return o
+}
+
+func (r *EventSourced) Create(_ context.Context, o *entity.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.streams[o.ID]; ok {
+		return errors.New("duplicate id")
+	}
+	s := &stream{}
+	r.streams[o.ID] = s
+	r.ids = append(r.ids, o.ID)
+	cp := *o
+	r.append(s, nil, Event{OrderID: o.ID, Kind: EventCreated, At: o.CreatedAt, Order: &cp})
+	return nil
+}
+
+func (r *EventSourced) GetByID(_ context.Context, id string) (*entity.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.streams[id]
+	if !ok {
+		return nil, entity.ErrNotFound
+	}
+	return s.fold(len(s.events)), nil
+}
+
+// GetAt rebuilds the order as it was at the given time, from the events up
+// to and including at.
+func (r *EventSourced) GetAt(_ context.Context, id string, at time.Time) (*entity.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.streams[id]
+	if !ok {
+		return nil, entity.ErrNotFound
+	}
+	n := sort.Search(len(s.events), func(i int) bool { return s.events[i].At.After(at) })
+	if n == 0 {
+		return nil, entity.ErrNotFound
+	}
+	return s.fold(n), nil
+}
+
+// Events returns the stream of the order, oldest first.
+func (r *EventSourced) Events(_ context.Context, id string) ([]Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.streams[id]
+	if !ok {
+		return nil, entity.ErrNotFound
+	}
+	return append([]Event(nil), s.events...), nil
+}
+
+// Update records the difference between the stored order and o.
+func (r *EventSourced) Update(_ context.Context, o *entity.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.streams[o.ID]
+	if !ok {
+		return entity.ErrNotFound
+	}
+	old := s.fold(len(s.events))
+	evs := diff(old, o)
+	for i := range evs {
+		evs[i].OrderID = o.ID
+	}
+	r.append(s, old, evs...)
+	return nil
+}
+
+func (r *EventSourced) MarkDeleted(_ context.Context, id string, userID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.streams[id]
+	if !ok {
+		return entity.ErrNotFound
+	}
+	o := s.fold(len(s.events))
+	if o.UserID != userID {
+		return entity.ErrForeignOwnership
+	}
+	r.append(s, o, Event{OrderID: id, Kind: EventDeleted, At: at})
+	return nil
+}
+
+// SetEstimatedDelivery records an eta_changed event; like InMemory it does
+// not count as an edit.
+func (r *EventSourced) SetEstimatedDelivery(_ context.Context, id string, eta time.Time, at time.Time) (*entity.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.streams[id]
+	if !ok {
+		return nil, entity.ErrNotFound
+	}
+	o := s.fold(len(s.events))
+	if o.IsDeleted {
+		return nil, entity.ErrNotFound
+	}
+	o = r.append(s, o, Event{OrderID: id, Kind: EventETAChanged, At: at, EstimatedDelivery: eta})
+	cp := *o
+	return &cp, nil
+}
+
+func (r *EventSourced) ListFrom(_ context.Context, from time.Time) ([]*entity.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.list.from(from), nil
+}
+
+// AdvanceStatuses applies the rules of InMemory.AdvanceStatuses, recording a
+// status event for every order that moves.
+func (r *EventSourced) AdvanceStatuses(now time.Time) []*entity.Order {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changed := make([]*entity.Order, 0)
+	for _, o := range r.list.from(time.Time{}) {
+		for {
+			next, at, ok := r.schedule.Next(o, now)
+			if !ok {
+				break
+			}
+			at = later(at, o.UpdatedAt)
+			o = r.append(r.streams[o.ID], o, Event{OrderID: o.ID, Kind: EventStatusAdvanced, At: at, Status: next})
+			cp := *o
+			changed = append(changed, &cp)
+		}
+	}
+	sortChanges(changed)
+	return changed
+}
+
+// Ping reports whether the store's lock can be taken, like InMemory.Ping.
+func (r *EventSourced) Ping(ctx context.Context) error {
+	locked := make(chan struct{})
+	go func() {
+		r.mu.RLock()
+		r.mu.RUnlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// listProjection keeps the current state of live orders sorted by
+// created_at, for ListFrom and the status worker.
+type listProjection struct {
+	byID   map[string]*entity.Order
+	sorted []*entity.Order
+}
+
+func newListProjection() *listProjection {
+	return &listProjection{byID: make(map[string]*entity.Order)}
+}
+
+func (p *listProjection) Apply(e Event, o *entity.Order) {
+	cur, ok := p.byID[e.OrderID]
+	switch {
+	case e.Kind == EventDeleted:
+		if ok {
+			delete(p.byID, e.OrderID)
+			i := p.index(cur)
+			p.sorted = append(p.sorted[:i], p.sorted[i+1:]...)
+		}
+	case ok:
+		*cur = *o
+	case !o.IsDeleted:
+		p.byID[e.OrderID] = o
+		i := sort.Search(len(p.sorted), func(i int) bool { return p.sorted[i].CreatedAt.After(o.CreatedAt) })
+		p.sorted = append(p.sorted, nil)
+		copy(p.sorted[i+1:], p.sorted[i:])
+		p.sorted[i] = o
+	}
+}
+
+// index finds o among the orders created at the same time.
+func (p *listProjection) index(o *entity.Order) int {
+	i := sort.Search(len(p.sorted), func(i int) bool { return !p.sorted[i].CreatedAt.Before(o.CreatedAt) })
+	for p.sorted[i] != o {
+		i++
+	}
+	return i
+}
+
+// from returns copies of the orders created at or after from.
+func (p *listProjection) from(from time.Time) []*entity.Order {
+	i := sort.Search(len(p.sorted), func(i int) bool { return !p.sorted[i].CreatedAt.Before(from) })
+	out := make([]*entity.Order, 0, len(p.sorted)-i)
+	for _, o := range p.sorted[i:] {
+		cp := *o
+		out = append(out, &cp)
+	}
+	return out
+}
@@ -0,0 +1,169 @@
+package order
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/nikolaev/service-order/internal/debughttp"
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+// Handler serves the history of orders, meant to be mounted under a debug
+// prefix:
+//
+//	GET /orders/{id}         the current state, or the state as of ?at (RFC3339)
+//	GET /orders/{id}/events  the event stream, oldest first
+func (r *EventSourced) Handler() http.Handler {
+	mux := chi.NewRouter()
+	mux.Get("/orders/{id}", func(w http.ResponseWriter, req *http.Request) {
+		id := chi.URLParam(req, "id")
+		var (
+			o   *entity.Order
+			err error
+		)
+		if v := req.URL.Query().Get("at"); v != "" {
+			at, perr := time.Parse(time.RFC3339Nano, v)
+			if perr != nil {
+				debughttp.Error(w, http.StatusBadRequest, perr)
+				return
+			}
+			o, err = r.GetAt(req.Context(), id, at)
+		} else {
+			o, err = r.GetByID(req.Context(), id)
+		}
+		if err != nil {
+			debughttp.Error(w, http.StatusNotFound, err)
+			return
+		}
+		debughttp.JSON(w, http.StatusOK, orderViewOf(o))
+	})
+	mux.Get("/orders/{id}/events", func(w http.ResponseWriter, req *http.Request) {
+		evs, err := r.Events(req.Context(), chi.URLParam(req, "id"))
+		if err != nil {
+			debughttp.Error(w, http.StatusNotFound, err)
+			return
+		}
+		out := make([]EventView, 0, len(evs))
+		for _, e := range evs {
+			out = append(out, eventViewOf(e))
+		}
+		debughttp.JSON(w, http.StatusOK, out)
+	})
+	return mux
+}
+
+// OrderView is an order as the history API shows it.
+type OrderView struct {
+	ID                string             `json:"id"`
+	UserID            string             `json:"user_id"`
+	OrderNumber       string             `json:"order_number,omitempty"`
+	FIO               string             `json:"fio,omitempty"`
+	RestaurantID      string             `json:"restaurant_id"`
+	Items             []ItemView         `json:"items"`
+	TotalPrice        int64              `json:"total_price"`
+	Address           AddressView        `json:"address"`
+	Status            entity.OrderStatus `json:"status"`
+	CreatedAt         time.Time          `json:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at"`
+	StatusChangedAt   time.Time          `json:"status_changed_at"`
+	EstimatedDelivery *time.Time         `json:"estimated_delivery,omitempty"`
+	DeliverAt         time.Time          `json:"deliver_at,omitzero"`
+	PrepareFrom       time.Time          `json:"prepare_from,omitzero"`
+	Deleted           bool               `json:"deleted,omitempty"`
+}
+
+func orderViewOf(o *entity.Order) OrderView {
+	v := OrderView{
+		ID:              o.ID,
+		UserID:          o.UserID,
+		OrderNumber:     o.OrderNumber,
+		FIO:             o.FIO,
+		RestaurantID:    o.RestaurantID,
+		Items:           itemViewsOf(o.Items),
+		TotalPrice:      o.TotalPrice,
+		Address:         addressViewOf(o.Address),
+		Status:          o.Status,
+		CreatedAt:       o.CreatedAt,
+		UpdatedAt:       o.UpdatedAt,
+		StatusChangedAt: o.StatusChangedAt,
+		DeliverAt:       o.DeliverAt,
+		PrepareFrom:     o.PrepareFrom,
+		Deleted:         o.IsDeleted,
+	}
+	if !o.EstimatedDelivery.IsZero() {
+		v.EstimatedDelivery = &o.EstimatedDelivery
+	}
+	return v
+}
+
+type ItemView struct {
+	FoodID   string `json:"food_id"`
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+	Price    int    `json:"price"`
+}
+
+func itemViewsOf(items []entity.Item) []ItemView {
+	out := make([]ItemView, 0, len(items))
+	for _, it := range items {
+		out = append(out, ItemView(it))
+	}
+	return out
+}
+
+type AddressView struct {
+	Street    string        `json:"street"`
+	House     string        `json:"house,omitempty"`
+	Apartment string        `json:"apartment,omitempty"`
+	Floor     string        `json:"floor,omitempty"`
+	Comment   string        `json:"comment,omitempty"`
+	Location  *GeoPointView `json:"location,omitempty"`
+}
+
+type GeoPointView struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+func addressViewOf(a entity.DeliveryAddress) AddressView {
+	return AddressView{
+		Street:    a.Street,
+		House:     a.House,
+		Apartment: a.Apartment,
+		Floor:     a.Floor,
+		Comment:   a.Comment,
+		Location:  (*GeoPointView)(a.Location),
+	}
+}
+
+// EventView is an event as the history API shows it; Data holds the fields
+// of its kind.
+type EventView struct {
+	Version int            `json:"version"`
+	Kind    EventKind      `json:"kind"`
+	At      time.Time      `json:"at"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+func eventViewOf(e Event) EventView {
+	v := EventView{Version: e.Version, Kind: e.Kind, At: e.At}
+	switch e.Kind {
+	case EventCreated:
+		v.Data = map[string]any{"order": orderViewOf(e.Order)}
+	case EventItemsChanged:
+		v.Data = map[string]any{"items": itemViewsOf(e.Items), "total_price": e.TotalPrice}
+	case EventAddressChanged:
+		v.Data = map[string]any{"address": addressViewOf(e.Address)}
+	case EventDetailsChanged:
+		v.Data = map[string]any{"order_number": e.OrderNumber, "fio": e.FIO, "estimated_delivery": e.EstimatedDelivery}
+	case EventScheduleChanged:
+		v.Data = map[string]any{"deliver_at": e.DeliverAt, "prepare_from": e.PrepareFrom}
+	case EventStatusAdvanced:
+		v.Data = map[string]any{"status": e.Status}
+	case EventETAChanged:
+		v.Data = map[string]any{"estimated_delivery": e.EstimatedDelivery}
+	}
+	return v
+}
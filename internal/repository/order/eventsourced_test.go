@@ -0,0 +1,267 @@
+package order_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/logging"
+	repo "github.com/nikolaev/service-order/internal/repository/order"
+	uc "github.com/nikolaev/service-order/internal/usecase/order"
+)
+
+var t0 = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+type stepClock struct{ now time.Time }
+
+func (c *stepClock) Now() time.Time { return c.now }
+
+func newOrder(id string, at time.Time) *entity.Order {
+	return &entity.Order{
+		ID: id, UserID: "u1", RestaurantID: "r1",
+		Items:      []entity.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 500}},
+		TotalPrice: 500, Address: entity.DeliveryAddress{Street: "Main"},
+		Status: entity.OrderStatusCreated, CreatedAt: at, UpdatedAt: at, StatusChangedAt: at,
+	}
+}
+
+func kinds(evs []repo.Event) []repo.EventKind {
+	out := make([]repo.EventKind, 0, len(evs))
+	for _, e := range evs {
+		out = append(out, e.Kind)
+	}
+	return out
+}
+
+func TestEventSourced_RecordsChangesAndRebuildsAnyPointInTime(t *testing.T) {
+	ctx := context.Background()
+	es := repo.NewEventSourced(repo.DefaultStatusTimers, 0)
+	require.NoError(t, es.Create(ctx, newOrder("o1", t0)))
+
+	o, err := es.GetByID(ctx, "o1")
+	require.NoError(t, err)
+	o.Items = append(o.Items, entity.Item{FoodID: "f2", Name: "Cola", Quantity: 2, Price: 100})
+	o.TotalPrice = 700
+	o.FIO = "Ivanov I.I."
+	o.Status = entity.OrderStatusUpdated
+	o.UpdatedAt, o.StatusChangedAt = t0.Add(time.Minute), t0.Add(time.Minute)
+	require.NoError(t, es.Update(ctx, o))
+
+	o.Address.Street = "Arbat"
+	o.UpdatedAt = t0.Add(2 * time.Minute)
+	require.NoError(t, es.Update(ctx, o))
+	assert.ErrorIs(t, es.MarkDeleted(ctx, "o1", "u2", t0.Add(3*time.Minute)), entity.ErrForeignOwnership)
+	require.NoError(t, es.MarkDeleted(ctx, "o1", "u1", t0.Add(3*time.Minute)))
+
+	evs, err := es.Events(ctx, "o1")
+	require.NoError(t, err)
+	assert.Equal(t, []repo.EventKind{
+		repo.EventCreated, repo.EventItemsChanged, repo.EventDetailsChanged, repo.EventStatusAdvanced,
+		repo.EventAddressChanged, repo.EventDeleted,
+	}, kinds(evs))
+	for i, e := range evs {
+		assert.Equal(t, i+1, e.Version)
+	}
+
+	cur, err := es.GetByID(ctx, "o1")
+	require.NoError(t, err)
+	assert.True(t, cur.IsDeleted)
+	assert.Equal(t, entity.OrderStatusDeleted, cur.Status)
+	assert.Equal(t, "Arbat", cur.Address.Street)
+
+	at, err := es.GetAt(ctx, "o1", t0.Add(90*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(700), at.TotalPrice)
+	assert.Len(t, at.Items, 2)
+	assert.Equal(t, "Ivanov I.I.", at.FIO)
+	assert.Equal(t, entity.OrderStatusUpdated, at.Status)
+	assert.Equal(t, "Main", at.Address.Street)
+	assert.False(t, at.IsDeleted)
+
+	at, err = es.GetAt(ctx, "o1", t0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), at.TotalPrice)
+	assert.Equal(t, entity.OrderStatusCreated, at.Status)
+
+	_, err = es.GetAt(ctx, "o1", t0.Add(-time.Second))
+	assert.ErrorIs(t, err, entity.ErrNotFound, "not created yet")
+	_, err = es.GetByID(ctx, "missing")
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+}
+
+func TestEventSourced_SnapshotsGiveTheSameState(t *testing.T) {
+	ctx := context.Background()
+	plain := repo.NewEventSourced(repo.DefaultStatusTimers, 1000)
+	snap := repo.NewEventSourced(repo.DefaultStatusTimers, 3)
+	for _, es := range []*repo.EventSourced{plain, snap} {
+		require.NoError(t, es.Create(ctx, newOrder("o1", t0)))
+		o := newOrder("o1", t0)
+		for i := 1; i <= 10; i++ {
+			o.TotalPrice = int64(500 + i)
+			o.UpdatedAt = t0.Add(time.Duration(i) * time.Minute)
+			require.NoError(t, es.Update(ctx, o))
+		}
+	}
+
+	for _, at := range []time.Time{t0, t0.Add(4 * time.Minute), t0.Add(9*time.Minute + time.Second), t0.Add(time.Hour)} {
+		want, err := plain.GetAt(ctx, "o1", at)
+		require.NoError(t, err)
+		got, err := snap.GetAt(ctx, "o1", at)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, at)
+	}
+	got, err := snap.GetByID(ctx, "o1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(510), got.TotalPrice)
+	assert.Equal(t, t0.Add(10*time.Minute), got.UpdatedAt)
+}
+
+type countProjection map[repo.EventKind]int
+
+func (p countProjection) Apply(e repo.Event, _ *entity.Order) { p[e.Kind]++ }
+
+func TestEventSourced_ProjectionsFeedListsAndWorker(t *testing.T) {
+	ctx := context.Background()
+	es := repo.NewEventSourced(repo.StatusTimers{Created: time.Second, Pending: time.Hour}, 0)
+	require.NoError(t, es.Create(ctx, newOrder("o2", t0.Add(2*time.Minute))))
+	require.NoError(t, es.Create(ctx, newOrder("o1", t0.Add(time.Minute))))
+	require.NoError(t, es.Create(ctx, newOrder("o3", t0.Add(3*time.Minute))))
+	require.NoError(t, es.MarkDeleted(ctx, "o3", "u1", t0.Add(3*time.Minute)))
+
+	list, err := es.ListFrom(ctx, t0.Add(90*time.Second))
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "o2", list[0].ID)
+
+	changed := es.AdvanceStatuses(t0.Add(5 * time.Minute))
+	require.Len(t, changed, 2)
+	for _, o := range changed {
+		assert.Equal(t, entity.OrderStatusPending, o.Status)
+	}
+	assert.Empty(t, es.AdvanceStatuses(t0.Add(6*time.Minute)), "pending lasts an hour")
+
+	list, err = es.ListFrom(ctx, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "o1", list[0].ID)
+	assert.Equal(t, entity.OrderStatusPending, list[1].Status)
+
+	// A projection added later sees the whole log first.
+	counts := countProjection{}
+	es.AddProjection(counts)
+	require.NoError(t, es.Create(ctx, newOrder("o4", t0)))
+	assert.Equal(t, countProjection{repo.EventCreated: 4, repo.EventDeleted: 1, repo.EventStatusAdvanced: 2}, counts)
+}
+
+func TestEventSourced_ScheduledOrders(t *testing.T) {
+	ctx := context.Background()
+	es := repo.NewEventSourced(repo.StatusTimers{Created: time.Hour}, 0)
+	o := newOrder("o1", t0)
+	o.Status, o.PrepareFrom = entity.OrderStatusScheduled, t0.Add(2*time.Hour)
+	require.NoError(t, es.Create(ctx, o))
+
+	o.PrepareFrom, o.UpdatedAt = t0.Add(3*time.Hour), t0.Add(time.Minute)
+	require.NoError(t, es.Update(ctx, o))
+	evs, err := es.Events(ctx, "o1")
+	require.NoError(t, err)
+	assert.Equal(t, []repo.EventKind{repo.EventCreated, repo.EventScheduleChanged}, kinds(evs))
+
+	assert.Empty(t, es.AdvanceStatuses(t0.Add(2*time.Hour)), "rescheduled to 15:00")
+	changed := es.AdvanceStatuses(t0.Add(3*time.Hour + time.Minute))
+	require.Len(t, changed, 1)
+	assert.Equal(t, entity.OrderStatusCreated, changed[0].Status)
+	assert.Equal(t, t0.Add(3*time.Hour), changed[0].StatusChangedAt, "activated at prepare_from")
+
+	at, err := es.GetAt(ctx, "o1", t0.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, entity.OrderStatusScheduled, at.Status)
+	assert.Equal(t, t0.Add(2*time.Hour), at.PrepareFrom)
+}
+
+// The usecase runs on the event-sourced store unchanged.
+func TestEventSourced_BehindUsecase(t *testing.T) {
+	ctx := context.Background()
+	clk := &stepClock{now: t0}
+	es := repo.NewEventSourced(repo.DefaultStatusTimers, 2)
+	svc := uc.NewWithDeps(es, nopProducer{}, clk, logging.Logger{}, nopMetric{})
+
+	o, err := svc.Create(ctx, "u1", uc.CreateInput{RestaurantID: "r1", Items: []entity.Item{{FoodID: "f1", Quantity: 1, Price: 100}}, TotalPrice: 100})
+	require.NoError(t, err)
+	clk.now = t0.Add(time.Minute)
+	street := entity.DeliveryAddress{Street: "Tverskaya"}
+	_, err = svc.Update(ctx, "u1", o.ID, uc.UpdateInput{Address: &street})
+	require.NoError(t, err)
+
+	got, err := svc.Get(ctx, "u1", o.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Tverskaya", got.Address.Street)
+	assert.Equal(t, t0.Add(time.Minute), got.UpdatedAt)
+
+	require.NoError(t, svc.Delete(ctx, "u1", o.ID))
+	_, err = svc.Get(ctx, "u1", o.ID)
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+	list, err := svc.ListFrom(ctx, time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+type nopMetric struct{}
+
+func (nopMetric) Increment(string) {}
+
+type nopProducer struct{}
+
+func (nopProducer) OrderCreated(context.Context, *entity.Order) error       { return nil }
+func (nopProducer) OrderUpdated(context.Context, *entity.Order) error       { return nil }
+func (nopProducer) OrderStatusChanged(context.Context, *entity.Order) error { return nil }
+func (nopProducer) OrderDeleted(context.Context, string, string) error      { return nil }
+func (nopProducer) OrderSnapshot(context.Context, *entity.Order, string) error {
+	return nil
+}
+func (nopProducer) OrderETAChanged(context.Context, *entity.Order) error { return nil }
+
+func TestEventSourced_Handler(t *testing.T) {
+	ctx := context.Background()
+	es := repo.NewEventSourced(repo.DefaultStatusTimers, 0)
+	require.NoError(t, es.Create(ctx, newOrder("o1", t0)))
+	o := newOrder("o1", t0)
+	o.TotalPrice = 900
+	o.UpdatedAt = t0.Add(time.Minute)
+	require.NoError(t, es.Update(ctx, o))
+	srv := httptest.NewServer(es.Handler())
+	defer srv.Close()
+
+	get := func(path string, v any) int {
+		resp, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if v != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+		}
+		return resp.StatusCode
+	}
+
+	var cur, past repo.OrderView
+	require.Equal(t, http.StatusOK, get("/orders/o1", &cur))
+	assert.Equal(t, int64(900), cur.TotalPrice)
+	require.Equal(t, http.StatusOK, get("/orders/o1?at="+t0.Format(time.RFC3339), &past))
+	assert.Equal(t, int64(500), past.TotalPrice)
+	assert.Equal(t, "Main", past.Address.Street)
+
+	var evs []repo.EventView
+	require.Equal(t, http.StatusOK, get("/orders/o1/events", &evs))
+	require.Len(t, evs, 2)
+	assert.Equal(t, repo.EventItemsChanged, evs[1].Kind)
+	assert.Equal(t, float64(900), evs[1].Data["total_price"])
+
+	assert.Equal(t, http.StatusNotFound, get("/orders/missing", nil))
+	assert.Equal(t, http.StatusNotFound, get("/orders/o1?at="+t0.Add(-time.Hour).Format(time.RFC3339), nil))
+	assert.Equal(t, http.StatusBadRequest, get("/orders/o1?at=yesterday", nil))
+}
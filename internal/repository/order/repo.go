@@ -3,6 +3,8 @@ package order
 import (
 	"context"
 	"errors"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,8 +12,35 @@ import (
 )
 
 type InMemory struct {
-	mu    sync.RWMutex
-	store map[string]*entity.Order
+	mu       sync.RWMutex
+	store    map[string]*entity.Order
+	schedule Schedule
+}
+
+// Schedule decides when AdvanceStatuses moves an order on and to which
+// status. StatusTimers is the fixed schedule; internal/simulation has
+// switchable ones.
+type Schedule interface {
+	// Next returns the status o moves to by now and when it became due.
+	Next(o *entity.Order, now time.Time) (entity.OrderStatus, time.Time, bool)
+}
+
+// StatusTimers is how long an order stays in a status before AdvanceStatuses
+// moves it to the next one.
+type StatusTimers struct {
+	Created    time.Duration
+	Pending    time.Duration
+	Confirmed  time.Duration
+	Cooking    time.Duration
+	Delivering time.Duration
+}
+
+var DefaultStatusTimers = StatusTimers{
+	Created:    1 * time.Second,
+	Pending:    5 * time.Second,
+	Confirmed:  5 * time.Second,
+	Cooking:    5 * time.Minute,
+	Delivering: 10 * time.Minute,
 }
 
 func (r *InMemory) ListFrom(_ context.Context, from time.Time) ([]*entity.Order, error) {
@@ -32,7 +61,32 @@ func (r *InMemory) ListFrom(_ context.Context, from time.Time) ([]*entity.Order,
 }
 
 func NewInMemory() *InMemory {
-	return &InMemory{store: make(map[string]*entity.Order)}
+	return NewInMemoryWithTimers(DefaultStatusTimers)
+}
+
+func NewInMemoryWithTimers(t StatusTimers) *InMemory {
+	return NewInMemoryWithSchedule(t)
+}
+
+func NewInMemoryWithSchedule(s Schedule) *InMemory {
+	return &InMemory{store: make(map[string]*entity.Order), schedule: s}
+}
+
+// Ping reports whether the store is usable; for the in-memory store that is
+// whether its lock can be taken, which catches a deadlocked writer.
+func (r *InMemory) Ping(ctx context.Context) error {
+	locked := make(chan struct{})
+	go func() {
+		r.mu.RLock()
+		r.mu.RUnlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (r *InMemory) Create(_ context.Context, o *entity.Order) error {
@@ -67,7 +121,21 @@ func (r *InMemory) Update(_ context.Context, o *entity.Order) error {
 	return nil
 }
 
-func (r *InMemory) MarkDeleted(_ context.Context, id string, userID string) error {
+// SetEstimatedDelivery changes only the ETA of the order, so that it cannot
+// undo a concurrent status change; UpdatedAt stays, as no one edited it.
+func (r *InMemory) SetEstimatedDelivery(_ context.Context, id string, eta time.Time, _ time.Time) (*entity.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o, ok := r.store[id]
+	if !ok || o.IsDeleted {
+		return nil, entity.ErrNotFound
+	}
+	o.EstimatedDelivery = eta
+	copy := *o
+	return &copy, nil
+}
+
+func (r *InMemory) MarkDeleted(_ context.Context, id string, userID string, at time.Time) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	o, ok := r.store[id]
@@ -79,67 +147,111 @@ func (r *InMemory) MarkDeleted(_ context.Context, id string, userID string) erro
 	}
 	o.IsDeleted = true
 	o.Status = entity.OrderStatusDeleted
-	o.UpdatedAt = time.Now().UTC()
+	o.UpdatedAt = at
 	return nil
 }
 
-// AdvanceStatuses updates order statuses based on elapsed time.
-// Rules:
+// AdvanceStatuses updates order statuses based on elapsed time. Each change
+// is dated when it became due, and an order whose next timers have run out
+// too moves on again, so after a long pause or a jump of the clock every
+// intermediate status is still reported. Rules of StatusTimers (defaults in
+// brackets):
 //
-//	created -> after 1s -> pending
-//	pending -> after 5s -> confirmed
-//	confirmed -> after 5s -> cooking
-//	cooking -> after 5m -> delivering
-//	delivering -> after 10m -> completed
+//	scheduled -> at PrepareFrom -> created
+//	created -> after Created [1s] -> pending
+//	pending -> after Pending [5s] -> confirmed
+//	confirmed -> after Confirmed [5s] -> cooking
+//	cooking -> after Cooking [5m] -> delivering
+//	delivering -> after Delivering [10m] -> completed
 func (r *InMemory) AdvanceStatuses(now time.Time) []*entity.Order {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	changed := make([]*entity.Order, 0)
 	for _, o := range r.store {
-		if o.IsDeleted || o.Status == entity.OrderStatusCanceled || o.Status == entity.OrderStatusCompleted {
-			continue
-		}
-		last := o.StatusChangedAt
-		if last.IsZero() {
-			last = o.CreatedAt
-		}
-		prev := o.Status
-		switch o.Status {
-		case entity.OrderStatusCreated:
-			if now.Sub(last) >= 1*time.Second {
-				o.Status = entity.OrderStatusPending
-				o.StatusChangedAt = now
-				o.UpdatedAt = now
-			}
-		case entity.OrderStatusPending:
-			if now.Sub(last) >= 5*time.Second {
-				o.Status = entity.OrderStatusConfirmed
-				o.StatusChangedAt = now
-				o.UpdatedAt = now
+		for {
+			next, at, ok := r.schedule.Next(o, now)
+			if !ok {
+				break
 			}
-		case entity.OrderStatusConfirmed:
-			if now.Sub(last) >= 5*time.Second {
-				o.Status = entity.OrderStatusCooking
-				o.StatusChangedAt = now
-				o.UpdatedAt = now
-			}
-		case entity.OrderStatusCooking:
-			if now.Sub(last) >= 5*time.Minute {
-				o.Status = entity.OrderStatusDelivering
-				o.StatusChangedAt = now
-				o.UpdatedAt = now
-			}
-		case entity.OrderStatusDelivering:
-			if now.Sub(last) >= 10*time.Minute {
-				o.Status = entity.OrderStatusCompleted
-				o.StatusChangedAt = now
-				o.UpdatedAt = now
-			}
-		}
-		if o.Status != prev {
+			at = later(at, o.UpdatedAt)
+			o.Status = next
+			o.StatusChangedAt = at
+			o.UpdatedAt = at
 			cp := *o
 			changed = append(changed, &cp)
 		}
 	}
+	sortChanges(changed)
 	return changed
 }
+
+// later keeps a status change from predating the last edit of the order.
+func later(at, updated time.Time) time.Time {
+	if updated.After(at) {
+		return updated
+	}
+	return at
+}
+
+// sortChanges orders the changes of one AdvanceStatuses call by the time
+// they became due, then by order ID, so a jump of the clock yields the
+// same events in the same order every time.
+func sortChanges(changed []*entity.Order) {
+	slices.SortStableFunc(changed, func(a, b *entity.Order) int {
+		if c := a.StatusChangedAt.Compare(b.StatusChangedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+}
+
+// Next returns the status o moves to, if its timer has run out by now.
+func (t StatusTimers) Next(o *entity.Order, now time.Time) (entity.OrderStatus, time.Time, bool) {
+	if o.IsDeleted {
+		return "", time.Time{}, false
+	}
+	if o.Status == entity.OrderStatusScheduled {
+		return Activate(o, now)
+	}
+	after, to, ok := t.For(o.Status)
+	due := StatusSince(o).Add(after)
+	if !ok || now.Before(due) {
+		return "", time.Time{}, false
+	}
+	return to, due, true
+}
+
+// Activate moves a scheduled order to created once its PrepareFrom has
+// come; every Schedule starts with it.
+func Activate(o *entity.Order, now time.Time) (entity.OrderStatus, time.Time, bool) {
+	if o.Status != entity.OrderStatusScheduled || now.Before(o.PrepareFrom) {
+		return "", time.Time{}, false
+	}
+	return entity.OrderStatusCreated, o.PrepareFrom, true
+}
+
+// For returns how long an order stays in status s and the status it moves
+// to; ok is false for the statuses the timers never leave.
+func (t StatusTimers) For(s entity.OrderStatus) (after time.Duration, to entity.OrderStatus, ok bool) {
+	switch s {
+	case entity.OrderStatusCreated:
+		return t.Created, entity.OrderStatusPending, true
+	case entity.OrderStatusPending:
+		return t.Pending, entity.OrderStatusConfirmed, true
+	case entity.OrderStatusConfirmed:
+		return t.Confirmed, entity.OrderStatusCooking, true
+	case entity.OrderStatusCooking:
+		return t.Cooking, entity.OrderStatusDelivering, true
+	case entity.OrderStatusDelivering:
+		return t.Delivering, entity.OrderStatusCompleted, true
+	}
+	return 0, "", false
+}
+
+// StatusSince is when o entered its current status.
+func StatusSince(o *entity.Order) time.Time {
+	if o.StatusChangedAt.IsZero() {
+		return o.CreatedAt
+	}
+	return o.StatusChangedAt
+}
@@ -0,0 +1,42 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+// Store is what InMemory and EventSourced both provide: the repository of
+// the order usecase, the status advance of the worker and the readiness ping.
+type Store interface {
+	Create(ctx context.Context, o *entity.Order) error
+	GetByID(ctx context.Context, id string) (*entity.Order, error)
+	Update(ctx context.Context, o *entity.Order) error
+	MarkDeleted(ctx context.Context, id string, userID string, at time.Time) error
+	SetEstimatedDelivery(ctx context.Context, id string, eta time.Time, at time.Time) (*entity.Order, error)
+	ListFrom(ctx context.Context, from time.Time) ([]*entity.Order, error)
+	AdvanceStatuses(now time.Time) []*entity.Order
+	Ping(ctx context.Context) error
+}
+
+var (
+	_ Store = (*InMemory)(nil)
+	_ Store = (*EventSourced)(nil)
+)
+
+// Store kinds, selected by repository.kind.
+const (
+	KindMemory       = "memory"
+	KindEventSourced = "eventsourced"
+)
+
+// ParseKind validates a store kind.
+func ParseKind(s string) (string, error) {
+	switch s {
+	case KindMemory, KindEventSourced:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown repository kind %q, want memory or eventsourced", s)
+}
@@ -0,0 +1,87 @@
+package order
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+const instrumentation = "github.com/nikolaev/service-order/internal/repository/order"
+
+// Traced wraps a Store with a client span per repository call.
+// AdvanceStatuses and Ping are not traced; the worker and health checks call
+// them on the embedded Store.
+type Traced struct {
+	Store
+	tracer trace.Tracer
+	name   string
+}
+
+func NewTraced(r Store, tp trace.TracerProvider) *Traced {
+	name := "repo.InMemory/"
+	if _, ok := r.(*EventSourced); ok {
+		name = "repo.EventSourced/"
+	}
+	return &Traced{Store: r, tracer: tp.Tracer(instrumentation), name: name}
+}
+
+func (t *Traced) start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("db.system", "memory"), attribute.String("db.operation.name", op))
+	return t.tracer.Start(ctx, t.name+op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t *Traced) Create(ctx context.Context, o *entity.Order) error {
+	ctx, span := t.start(ctx, "Create", attribute.String("order.id", o.ID))
+	err := t.Store.Create(ctx, o)
+	end(span, err)
+	return err
+}
+
+func (t *Traced) GetByID(ctx context.Context, id string) (*entity.Order, error) {
+	ctx, span := t.start(ctx, "GetByID", attribute.String("order.id", id))
+	o, err := t.Store.GetByID(ctx, id)
+	end(span, err)
+	return o, err
+}
+
+func (t *Traced) Update(ctx context.Context, o *entity.Order) error {
+	ctx, span := t.start(ctx, "Update", attribute.String("order.id", o.ID))
+	err := t.Store.Update(ctx, o)
+	end(span, err)
+	return err
+}
+
+func (t *Traced) MarkDeleted(ctx context.Context, id string, userID string, at time.Time) error {
+	ctx, span := t.start(ctx, "MarkDeleted", attribute.String("order.id", id))
+	err := t.Store.MarkDeleted(ctx, id, userID, at)
+	end(span, err)
+	return err
+}
+
+func (t *Traced) SetEstimatedDelivery(ctx context.Context, id string, eta time.Time, at time.Time) (*entity.Order, error) {
+	ctx, span := t.start(ctx, "SetEstimatedDelivery", attribute.String("order.id", id))
+	o, err := t.Store.SetEstimatedDelivery(ctx, id, eta, at)
+	end(span, err)
+	return o, err
+}
+
+func (t *Traced) ListFrom(ctx context.Context, from time.Time) ([]*entity.Order, error) {
+	ctx, span := t.start(ctx, "ListFrom")
+	out, err := t.Store.ListFrom(ctx, from)
+	span.SetAttributes(attribute.Int("orders.count", len(out)))
+	end(span, err)
+	return out, err
+}
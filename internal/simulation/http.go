@@ -0,0 +1,114 @@
+package simulation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/nikolaev/service-order/internal/debughttp"
+	repo "github.com/nikolaev/service-order/internal/repository/order"
+)
+
+// Handler serves the simulation settings, meant to be mounted under a debug
+// prefix:
+//
+//	GET /  the current profile, time scale and every known profile
+//	PUT /  switch with {"profile": "fast", "time_scale": 0.5}; either may be omitted
+func (s *Simulator) Handler() http.Handler {
+	mux := chi.NewRouter()
+	mux.Get("/", func(w http.ResponseWriter, _ *http.Request) {
+		st := s.State()
+		v := StateView{Profile: st.Profile, TimeScale: st.TimeScale}
+		for _, p := range s.Profiles() {
+			v.Profiles = append(v.Profiles, profileViewOf(p))
+		}
+		debughttp.JSON(w, http.StatusOK, v)
+	})
+	mux.Put("/", func(w http.ResponseWriter, req *http.Request) {
+		var in SelectRequest
+		if err := json.NewDecoder(req.Body).Decode(&in); err != nil {
+			debughttp.Error(w, http.StatusBadRequest, err)
+			return
+		}
+		st, err := s.Select(in.Profile, in.TimeScale)
+		switch {
+		case errors.Is(err, ErrUnknownProfile):
+			debughttp.Error(w, http.StatusNotFound, err)
+			return
+		case err != nil:
+			debughttp.Error(w, http.StatusBadRequest, err)
+			return
+		}
+		debughttp.JSON(w, http.StatusOK, StateView{Profile: st.Profile, TimeScale: st.TimeScale})
+	})
+	return mux
+}
+
+type SelectRequest struct {
+	Profile   string  `json:"profile,omitempty"`
+	TimeScale float64 `json:"time_scale,omitempty"`
+}
+
+type StateView struct {
+	Profile   string        `json:"profile"`
+	TimeScale float64       `json:"time_scale"`
+	Profiles  []ProfileView `json:"profiles,omitempty"`
+}
+
+type ProfileView struct {
+	Name          string                `json:"name"`
+	Timers        TimersView            `json:"timers"`
+	Jitter        Distribution          `json:"jitter"`
+	JitterSpread  float64               `json:"jitter_spread,omitempty"`
+	CancelPercent float64               `json:"cancel_percent,omitempty"`
+	Restaurants   map[string]TimersView `json:"restaurants,omitempty"`
+}
+
+// TimersView shows durations as Go duration strings; overrides leave the
+// inherited ones empty.
+type TimersView struct {
+	Created    string `json:"created,omitempty"`
+	Pending    string `json:"pending,omitempty"`
+	Confirmed  string `json:"confirmed,omitempty"`
+	Cooking    string `json:"cooking,omitempty"`
+	Delivering string `json:"delivering,omitempty"`
+}
+
+func profileViewOf(p Profile) ProfileView {
+	v := ProfileView{
+		Name:          p.Name,
+		Timers:        timersViewOf(p.Timers),
+		Jitter:        p.Jitter.Distribution,
+		JitterSpread:  p.Jitter.Spread,
+		CancelPercent: p.CancelPercent,
+	}
+	if v.Jitter == "" {
+		v.Jitter = JitterNone
+	}
+	if len(p.Restaurants) > 0 {
+		v.Restaurants = make(map[string]TimersView, len(p.Restaurants))
+		for id, t := range p.Restaurants {
+			v.Restaurants[id] = timersViewOf(t)
+		}
+	}
+	return v
+}
+
+func timersViewOf(t repo.StatusTimers) TimersView {
+	str := func(d time.Duration) string {
+		if d == 0 {
+			return ""
+		}
+		return d.String()
+	}
+	return TimersView{
+		Created:    str(t.Created),
+		Pending:    str(t.Pending),
+		Confirmed:  str(t.Confirmed),
+		Cooking:    str(t.Cooking),
+		Delivering: str(t.Delivering),
+	}
+}
@@ -0,0 +1,166 @@
+// Package simulation drives the status worker with named profiles instead of
+// fixed timers: a profile sets the time in each status, per-restaurant
+// overrides, a random jitter and a share of orders the restaurant rejects.
+// The Simulator is the repository's Schedule; the profile and the global
+// time scale can be switched while the service runs.
+package simulation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	repo "github.com/nikolaev/service-order/internal/repository/order"
+)
+
+type Distribution string
+
+const (
+	JitterNone        Distribution = "none"
+	JitterUniform     Distribution = "uniform"
+	JitterNormal      Distribution = "normal"
+	JitterExponential Distribution = "exponential"
+)
+
+func ParseDistribution(s string) (Distribution, error) {
+	switch d := Distribution(s); d {
+	case "", JitterNone:
+		return JitterNone, nil
+	case JitterUniform, JitterNormal, JitterExponential:
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown jitter distribution %q", s)
+}
+
+// Jitter spreads each timer around its value; Spread is a share of it, from
+// 0 to 1. Uniform draws from ±Spread, normal uses Spread as the standard
+// deviation, exponential keeps the mean with a long tail of up to 1-Spread
+// below it.
+type Jitter struct {
+	Distribution Distribution
+	Spread       float64
+}
+
+// factor is the multiplier of one timer; never negative.
+func (j Jitter) factor(r *rand.Rand) float64 {
+	var f float64
+	switch j.Distribution {
+	case JitterUniform:
+		f = 1 + j.Spread*(2*r.Float64()-1)
+	case JitterNormal:
+		f = 1 + j.Spread*r.NormFloat64()
+	case JitterExponential:
+		f = 1 - j.Spread + j.Spread*r.ExpFloat64()
+	default:
+		return 1
+	}
+	return math.Max(f, 0)
+}
+
+type Profile struct {
+	Name   string
+	Timers repo.StatusTimers
+	Jitter Jitter
+	// CancelPercent of orders, from 0 to 100, are rejected by the restaurant:
+	// they leave pending for canceled instead of confirmed.
+	CancelPercent float64
+	// Restaurants override Timers; zero fields keep the profile's.
+	Restaurants map[string]repo.StatusTimers
+}
+
+// Fast runs the whole lifecycle in about ten seconds.
+var Fast = Profile{
+	Name: "fast",
+	Timers: repo.StatusTimers{
+		Created:    500 * time.Millisecond,
+		Pending:    time.Second,
+		Confirmed:  time.Second,
+		Cooking:    3 * time.Second,
+		Delivering: 5 * time.Second,
+	},
+}
+
+// Realistic is a busy evening: timers vary by a quarter and some orders
+// are rejected.
+var Realistic = Profile{
+	Name: "realistic",
+	Timers: repo.StatusTimers{
+		Created:    2 * time.Second,
+		Pending:    30 * time.Second,
+		Confirmed:  time.Minute,
+		Cooking:    15 * time.Minute,
+		Delivering: 25 * time.Minute,
+	},
+	Jitter:        Jitter{Distribution: JitterNormal, Spread: 0.25},
+	CancelPercent: 3,
+}
+
+// DefaultProfile is the name of the profile built from the configured
+// status timers, without jitter or cancellations.
+const DefaultProfile = "default"
+
+// Builtin returns the default profile with timers t, Fast and Realistic.
+func Builtin(t repo.StatusTimers) []Profile {
+	return []Profile{{Name: DefaultProfile, Timers: t}, Fast, Realistic}
+}
+
+func (p Profile) validate() error {
+	var errs []error
+	if p.Name == "" {
+		errs = append(errs, errors.New("profile name is required"))
+	}
+	if err := positive(p.Timers); err != nil {
+		errs = append(errs, err)
+	}
+	for id, t := range p.Restaurants {
+		if err := positive(merge(p.Timers, t)); err != nil {
+			errs = append(errs, fmt.Errorf("restaurant %s: %w", id, err))
+		}
+	}
+	if _, err := ParseDistribution(string(p.Jitter.Distribution)); err != nil {
+		errs = append(errs, err)
+	}
+	if p.Jitter.Spread < 0 || p.Jitter.Spread > 1 {
+		errs = append(errs, fmt.Errorf("jitter spread must be within [0, 1], got %g", p.Jitter.Spread))
+	}
+	if p.CancelPercent < 0 || p.CancelPercent > 100 {
+		errs = append(errs, fmt.Errorf("cancel percent must be within [0, 100], got %g", p.CancelPercent))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("profile %q: %w", p.Name, err)
+	}
+	return nil
+}
+
+func positive(t repo.StatusTimers) error {
+	if t.Created <= 0 || t.Pending <= 0 || t.Confirmed <= 0 || t.Cooking <= 0 || t.Delivering <= 0 {
+		return fmt.Errorf("every timer must be positive, got %+v", t)
+	}
+	return nil
+}
+
+// merge returns base with the non-zero fields of over.
+func merge(base, over repo.StatusTimers) repo.StatusTimers {
+	pick := func(b, o time.Duration) time.Duration {
+		if o != 0 {
+			return o
+		}
+		return b
+	}
+	return repo.StatusTimers{
+		Created:    pick(base.Created, over.Created),
+		Pending:    pick(base.Pending, over.Pending),
+		Confirmed:  pick(base.Confirmed, over.Confirmed),
+		Cooking:    pick(base.Cooking, over.Cooking),
+		Delivering: pick(base.Delivering, over.Delivering),
+	}
+}
+
+func (p Profile) timers(restaurantID string) repo.StatusTimers {
+	if t, ok := p.Restaurants[restaurantID]; ok {
+		return merge(p.Timers, t)
+	}
+	return p.Timers
+}
@@ -0,0 +1,133 @@
+package simulation
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	repo "github.com/nikolaev/service-order/internal/repository/order"
+)
+
+// Simulator schedules the status changes with the selected profile. Every
+// random draw is seeded from the order ID and status, so an order keeps its
+// timers and its fate between worker ticks.
+type Simulator struct {
+	mu       sync.RWMutex
+	profiles map[string]Profile
+	current  string
+	scale    float64
+}
+
+var _ repo.Schedule = (*Simulator)(nil)
+
+var ErrUnknownProfile = errors.New("unknown simulation profile")
+
+// New validates the profiles; later ones replace earlier ones of the same
+// name. timeScale multiplies every timer: 0.1 runs ten times faster.
+func New(profiles []Profile, current string, timeScale float64) (*Simulator, error) {
+	s := &Simulator{profiles: make(map[string]Profile, len(profiles))}
+	var errs []error
+	for _, p := range profiles {
+		if err := p.validate(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.profiles[p.Name] = p
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if _, err := s.Select(current, timeScale); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// State is what the worker currently runs with.
+type State struct {
+	Profile   string
+	TimeScale float64
+}
+
+// Select switches to the named profile; a zero timeScale keeps the current
+// one. Orders already in a status are measured against the new timers from
+// the moment they entered it.
+func (s *Simulator) Select(name string, timeScale float64) (State, error) {
+	if timeScale < 0 {
+		return State{}, fmt.Errorf("time scale must not be negative, got %g", timeScale)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name == "" {
+		name = s.current
+	}
+	if _, ok := s.profiles[name]; !ok {
+		return State{}, fmt.Errorf("%w %q", ErrUnknownProfile, name)
+	}
+	s.current = name
+	if timeScale > 0 {
+		s.scale = timeScale
+	} else if s.scale == 0 {
+		s.scale = 1
+	}
+	return State{Profile: s.current, TimeScale: s.scale}, nil
+}
+
+func (s *Simulator) State() State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return State{Profile: s.current, TimeScale: s.scale}
+}
+
+// Profiles returns the known profiles sorted by name.
+func (s *Simulator) Profiles() []Profile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Profile, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		out = append(out, p)
+	}
+	slices.SortFunc(out, func(a, b Profile) int { return cmp.Compare(a.Name, b.Name) })
+	return out
+}
+
+// Next implements repo.Schedule.
+func (s *Simulator) Next(o *entity.Order, now time.Time) (entity.OrderStatus, time.Time, bool) {
+	if o.IsDeleted {
+		return "", time.Time{}, false
+	}
+	if o.Status == entity.OrderStatusScheduled {
+		return repo.Activate(o, now)
+	}
+	s.mu.RLock()
+	p, scale := s.profiles[s.current], s.scale
+	s.mu.RUnlock()
+
+	after, to, ok := p.timers(o.RestaurantID).For(o.Status)
+	if !ok {
+		return "", time.Time{}, false
+	}
+	r := draw(o.ID, string(o.Status))
+	due := repo.StatusSince(o).Add(time.Duration(float64(after) * scale * p.Jitter.factor(r)))
+	if now.Before(due) {
+		return "", time.Time{}, false
+	}
+	if o.Status == entity.OrderStatusPending && draw(o.ID, "cancel").Float64()*100 < p.CancelPercent {
+		return entity.OrderStatusCanceled, due, true
+	}
+	return to, due, true
+}
+
+func draw(orderID, salt string) *rand.Rand {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(orderID))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(salt))
+	return rand.New(rand.NewPCG(h.Sum64(), 0x5eed))
+}
@@ -0,0 +1,238 @@
+package simulation_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	repo "github.com/nikolaev/service-order/internal/repository/order"
+	"github.com/nikolaev/service-order/internal/simulation"
+)
+
+var t0 = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func order(id, restaurant string, status entity.OrderStatus) *entity.Order {
+	return &entity.Order{ID: id, RestaurantID: restaurant, Status: status, CreatedAt: t0, StatusChangedAt: t0}
+}
+
+func newSimulator(t *testing.T, profiles ...simulation.Profile) *simulation.Simulator {
+	t.Helper()
+	s, err := simulation.New(append(simulation.Builtin(repo.DefaultStatusTimers), profiles...), simulation.DefaultProfile, 1)
+	require.NoError(t, err)
+	return s
+}
+
+func TestSimulator_ProfilesAndTimeScale(t *testing.T) {
+	s := newSimulator(t)
+	o := order("o1", "r1", entity.OrderStatusCooking)
+
+	_, _, ok := s.Next(o, t0.Add(4*time.Minute))
+	assert.False(t, ok, "default cooking takes 5m")
+	next, _, ok := s.Next(o, t0.Add(5*time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, entity.OrderStatusDelivering, next)
+
+	st, err := s.Select("fast", 0)
+	require.NoError(t, err)
+	assert.Equal(t, simulation.State{Profile: "fast", TimeScale: 1}, st)
+	_, _, ok = s.Next(o, t0.Add(3*time.Second))
+	assert.True(t, ok, "fast cooking takes 3s")
+
+	st, err = s.Select("", 10)
+	require.NoError(t, err)
+	assert.Equal(t, simulation.State{Profile: "fast", TimeScale: 10}, st)
+	_, _, ok = s.Next(o, t0.Add(29*time.Second))
+	assert.False(t, ok, "ten times slower")
+	_, _, ok = s.Next(o, t0.Add(30*time.Second))
+	assert.True(t, ok)
+
+	_, _, ok = s.Next(order("o2", "r1", entity.OrderStatusCompleted), t0.Add(time.Hour))
+	assert.False(t, ok, "final status")
+
+	_, err = s.Select("missing", 0)
+	assert.ErrorIs(t, err, simulation.ErrUnknownProfile)
+	_, err = s.Select("", -1)
+	assert.Error(t, err)
+	assert.Equal(t, simulation.State{Profile: "fast", TimeScale: 10}, s.State(), "failed selects change nothing")
+}
+
+func TestSimulator_RestaurantOverrides(t *testing.T) {
+	slow := simulation.Fast
+	slow.Name = "slow-kitchen"
+	slow.Restaurants = map[string]repo.StatusTimers{"r2": {Cooking: time.Hour}}
+	s := newSimulator(t, slow)
+	_, err := s.Select("slow-kitchen", 0)
+	require.NoError(t, err)
+
+	_, _, ok := s.Next(order("o1", "r1", entity.OrderStatusCooking), t0.Add(3*time.Second))
+	assert.True(t, ok)
+	_, _, ok = s.Next(order("o2", "r2", entity.OrderStatusCooking), t0.Add(59*time.Minute))
+	assert.False(t, ok)
+	_, _, ok = s.Next(order("o2", "r2", entity.OrderStatusDelivering), t0.Add(5*time.Second))
+	assert.True(t, ok, "other timers are the profile's")
+}
+
+func TestSimulator_JitterIsStablePerOrder(t *testing.T) {
+	for _, d := range []simulation.Distribution{simulation.JitterUniform, simulation.JitterNormal, simulation.JitterExponential} {
+		t.Run(string(d), func(t *testing.T) {
+			p := simulation.Profile{Name: "jitter", Timers: repo.DefaultStatusTimers, Jitter: simulation.Jitter{Distribution: d, Spread: 0.5}}
+			s := newSimulator(t, p)
+			_, err := s.Select("jitter", 0)
+			require.NoError(t, err)
+
+			// leaves is the first second the order may leave cooking (5m ± jitter) at.
+			leaves := func(o *entity.Order) time.Duration {
+				for at := time.Duration(0); at < time.Hour; at += time.Second {
+					if _, _, ok := s.Next(o, t0.Add(at)); ok {
+						return at
+					}
+				}
+				return time.Hour
+			}
+			var (
+				seen = map[time.Duration]bool{}
+				sum  time.Duration
+			)
+			const n = 200
+			for i := range n {
+				o := order(fmt.Sprintf("o%d", i), "r1", entity.OrderStatusCooking)
+				at := leaves(o)
+				assert.Equal(t, at, leaves(o), "same order, same timer")
+				if d == simulation.JitterUniform {
+					assert.InDelta(t, 5*time.Minute, at, float64(150*time.Second+time.Second))
+				}
+				if d == simulation.JitterExponential {
+					assert.GreaterOrEqual(t, at, 150*time.Second)
+				}
+				seen[at] = true
+				sum += at
+			}
+			assert.Greater(t, len(seen), n/4, "timers vary between orders")
+			assert.InDelta(t, 5*time.Minute, sum/n, float64(40*time.Second), "mean stays around the timer")
+		})
+	}
+}
+
+func TestSimulator_CancelPercent(t *testing.T) {
+	p := simulation.Fast
+	p.Name = "flaky"
+	p.CancelPercent = 30
+	s := newSimulator(t, p)
+	_, err := s.Select("flaky", 0)
+	require.NoError(t, err)
+
+	canceled := 0
+	const n = 2000
+	for i := range n {
+		o := order(fmt.Sprintf("o%d", i), "r1", entity.OrderStatusPending)
+		next, _, ok := s.Next(o, t0.Add(time.Second))
+		require.True(t, ok)
+		again, _, _ := s.Next(o, t0.Add(time.Second))
+		assert.Equal(t, next, again)
+		if next == entity.OrderStatusCanceled {
+			canceled++
+		} else {
+			assert.Equal(t, entity.OrderStatusConfirmed, next)
+		}
+	}
+	assert.InDelta(t, 0.3, float64(canceled)/n, 0.04)
+
+	next, _, _ := s.Next(order("o1", "r1", entity.OrderStatusCreated), t0.Add(time.Second))
+	assert.Equal(t, entity.OrderStatusPending, next, "only pending orders are rejected")
+}
+
+func TestNew_ValidatesProfiles(t *testing.T) {
+	_, err := simulation.New(simulation.Builtin(repo.DefaultStatusTimers), "missing", 1)
+	assert.ErrorIs(t, err, simulation.ErrUnknownProfile)
+
+	bad := []simulation.Profile{
+		{Name: "zero"},
+		{Name: "spread", Timers: repo.DefaultStatusTimers, Jitter: simulation.Jitter{Distribution: simulation.JitterNormal, Spread: 2}},
+		{Name: "dist", Timers: repo.DefaultStatusTimers, Jitter: simulation.Jitter{Distribution: "poisson"}},
+		{Name: "cancel", Timers: repo.DefaultStatusTimers, CancelPercent: 101},
+		{Name: "override", Timers: repo.DefaultStatusTimers, Restaurants: map[string]repo.StatusTimers{"r1": {Cooking: -time.Second}}},
+	}
+	for _, p := range bad {
+		_, err := simulation.New([]simulation.Profile{p}, p.Name, 1)
+		assert.ErrorContains(t, err, fmt.Sprintf("profile %q", p.Name))
+	}
+}
+
+func TestSimulator_DrivesRepository(t *testing.T) {
+	s := newSimulator(t)
+	st := repo.NewInMemoryWithSchedule(s)
+	require.NoError(t, st.Create(context.Background(), order("o1", "r1", entity.OrderStatusCooking)))
+
+	assert.Empty(t, st.AdvanceStatuses(t0.Add(time.Minute)))
+	_, err := s.Select("fast", 0)
+	require.NoError(t, err)
+	changed := st.AdvanceStatuses(t0.Add(time.Minute))
+	require.Len(t, changed, 2, "a minute covers cooking and delivering")
+	assert.Equal(t, entity.OrderStatusDelivering, changed[0].Status)
+	assert.Equal(t, t0.Add(3*time.Second), changed[0].StatusChangedAt)
+	assert.Equal(t, entity.OrderStatusCompleted, changed[1].Status)
+	assert.Equal(t, t0.Add(8*time.Second), changed[1].StatusChangedAt)
+}
+
+func TestSimulator_ActivatesScheduledOrders(t *testing.T) {
+	s := newSimulator(t, simulation.Fast)
+	_, err := s.Select("fast", 0)
+	require.NoError(t, err)
+	st := repo.NewInMemoryWithSchedule(s)
+	o := order("o1", "r1", entity.OrderStatusScheduled)
+	o.PrepareFrom = t0.Add(time.Hour)
+	require.NoError(t, st.Create(context.Background(), o))
+
+	assert.Empty(t, st.AdvanceStatuses(t0.Add(59*time.Minute)), "held until prepare_from")
+	changed := st.AdvanceStatuses(t0.Add(time.Hour + 600*time.Millisecond))
+	require.Len(t, changed, 2)
+	assert.Equal(t, entity.OrderStatusCreated, changed[0].Status)
+	assert.Equal(t, t0.Add(time.Hour), changed[0].StatusChangedAt, "dated at prepare_from")
+	assert.Equal(t, entity.OrderStatusPending, changed[1].Status, "then the profile's timers run")
+	assert.Equal(t, t0.Add(time.Hour+500*time.Millisecond), changed[1].StatusChangedAt)
+}
+
+func TestSimulator_Handler(t *testing.T) {
+	srv := httptest.NewServer(newSimulator(t).Handler())
+	defer srv.Close()
+
+	put := func(body string) (int, simulation.StateView) {
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+"/", bytes.NewBufferString(body))
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var v simulation.StateView
+		_ = json.NewDecoder(resp.Body).Decode(&v)
+		return resp.StatusCode, v
+	}
+
+	resp, err := http.Get(srv.URL + "/")
+	require.NoError(t, err)
+	var v simulation.StateView
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&v))
+	resp.Body.Close()
+	assert.Equal(t, "default", v.Profile)
+	require.Len(t, v.Profiles, 3)
+	assert.Equal(t, "fast", v.Profiles[1].Name)
+	assert.Equal(t, "3s", v.Profiles[1].Timers.Cooking)
+	assert.Equal(t, simulation.JitterNormal, v.Profiles[2].Jitter)
+
+	code, v := put(`{"profile":"realistic","time_scale":0.01}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, simulation.StateView{Profile: "realistic", TimeScale: 0.01}, v)
+	code, _ = put(`{"profile":"turbo"}`)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = put(`{"time_scale":-2}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = put(`nope`)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
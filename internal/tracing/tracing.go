@@ -0,0 +1,90 @@
+// Package tracing sets up OpenTelemetry tracing: the tracer provider with the
+// configured exporter, W3C trace context propagation and the HTTP middleware.
+// Other packages receive a trace.TracerProvider and create their own spans.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const ServiceName = "service-order"
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+type Config struct {
+	// Exporter is none, stdout (spans as JSON to stdout; logs go to stderr)
+	// or otlp.
+	Exporter string
+	// OTLPEndpoint is the OTLP/HTTP collector URL, e.g. http://localhost:4318.
+	OTLPEndpoint string
+}
+
+func ParseExporter(s string) (string, error) {
+	switch s {
+	case ExporterNone, ExporterStdout, ExporterOTLP:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown trace exporter %q (want none, stdout or otlp)", s)
+	}
+}
+
+// New builds a tracer provider for cfg and installs it, together with the
+// W3C trace context and baggage propagators, as the otel globals. With
+// exporter none spans are still created (trace IDs reach logs and Kafka
+// headers) but not exported. Shutdown flushes pending spans.
+func New(ctx context.Context, cfg Config) (*sdktrace.TracerProvider, error) {
+	var opts []sdktrace.TracerProviderOption
+	switch cfg.Exporter {
+	case ExporterNone:
+	case ExporterStdout:
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	case ExporterOTLP:
+		exp, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+		if err != nil {
+			return nil, fmt.Errorf("otlp exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	default:
+		_, err := ParseExporter(cfg.Exporter)
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL, semconv.ServiceName(ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, sdktrace.WithResource(res))
+
+	tp := sdktrace.NewTracerProvider(opts...)
+	Install(tp)
+	return tp, nil
+}
+
+// Install makes tp and the W3C propagators the otel globals; tests use it
+// with an in-memory recorder.
+func Install(tp *sdktrace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+}
@@ -0,0 +1,68 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nikolaev/service-order/internal/tracing"
+)
+
+func recorder(t *testing.T) (*tracetest.SpanRecorder, *sdktrace.TracerProvider) {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	tracing.Install(tp)
+	return rec, tp
+}
+
+func TestHTTPMiddleware_ContinuesTraceAndNamesByRoute(t *testing.T) {
+	rec, tp := recorder(t)
+
+	var inner trace.SpanContext
+	api := chi.NewRouter()
+	api.Get("/order/{id}", func(w http.ResponseWriter, r *http.Request) {
+		inner = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	r := chi.NewRouter()
+	r.Use(tracing.HTTPMiddleware(tp))
+	r.Mount("/public/api/v1", api)
+
+	req := httptest.NewRequest(http.MethodGet, "/public/api/v1/order/o1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	s := spans[0]
+	assert.Equal(t, "GET /public/api/v1/order/{id}", s.Name())
+	assert.Equal(t, trace.SpanKindServer, s.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", s.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", s.Parent().SpanID().String())
+	assert.Equal(t, s.SpanContext().SpanID(), inner.SpanID(), "handler sees the server span")
+	assert.Equal(t, codes.Error, s.Status().Code)
+	assert.Contains(t, s.Attributes(), attribute.Int("http.response.status_code", 500))
+	assert.Contains(t, s.Attributes(), attribute.String("http.route", "/public/api/v1/order/{id}"))
+}
+
+func TestNew_Exporters(t *testing.T) {
+	for _, exp := range []string{tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP} {
+		tp, err := tracing.New(context.Background(), tracing.Config{Exporter: exp, OTLPEndpoint: "http://127.0.0.1:4318"})
+		require.NoError(t, err, exp)
+		require.NoError(t, tp.Shutdown(context.Background()), exp)
+	}
+
+	_, err := tracing.New(context.Background(), tracing.Config{Exporter: "zipkin"})
+	assert.ErrorContains(t, err, "unknown trace exporter")
+}
@@ -7,21 +7,86 @@ import (
 	"time"
 
 	"github.com/nikolaev/service-order/internal/domain/entity"
+	uc "github.com/nikolaev/service-order/internal/usecase/order"
 )
 
+// Orders is the order use case: seeded orders are created through it, so
+// their events fire like for any other order.
+type Orders interface {
+	Create(ctx context.Context, userID string, in uc.CreateInput) (*entity.Order, error)
+	Delete(ctx context.Context, userID string, id string) error
+}
+
+// Repository and Producer move a created order on to the status its
+// scenario drew, the way the status worker does.
 type Repository interface {
-	Create(ctx context.Context, o *entity.Order) error
+	Update(ctx context.Context, o *entity.Order) error
+}
+
+type Producer interface {
+	OrderStatusChanged(ctx context.Context, o *entity.Order) error
 }
 
 type Clock interface{ Now() time.Time }
 
 type Service interface {
-	Seed(ctx context.Context, userID string, n int) ([]*entity.Order, error)
+	// Seed creates demo orders from a scenario; userID stands in for the
+	// scenario users without an ID.
+	Seed(ctx context.Context, userID string, req Request) ([]*entity.Order, error)
+}
+
+// Request picks the scenario and overrides its count and seed; zero values
+// keep the scenario's.
+type Request struct {
+	// Scenario names a loaded scenario; empty is Options.Default.
+	Scenario string
+	// Inline is a whole scenario and wins over Scenario.
+	Inline *Scenario
+	Count  int
+	Seed   uint64
+}
+
+// Options configure the seeder. Scenarios come on top of the built-in demo
+// one; Count is used when neither the request nor the scenario sets one.
+type Options struct {
+	Scenarios []Scenario
+	Default   string
+	Count     int
 }
 
+const defaultCount = 10
+
 type service struct {
-	repo Repository
-	clk  Clock
+	orders    Orders
+	repo      Repository
+	prod      Producer
+	clk       Clock
+	scenarios map[string]Scenario
+	def       string
+	count     int
 }
 
-func New(repo Repository, clk Clock) Service { return &service{repo: repo, clk: clk} }
+// New validates the scenarios; a later one replaces an earlier one of the
+// same name.
+func New(orders Orders, repo Repository, prod Producer, clk Clock, opts Options) (Service, error) {
+	s := &service{
+		orders: orders, repo: repo, prod: prod, clk: clk,
+		scenarios: map[string]Scenario{}, def: opts.Default, count: opts.Count,
+	}
+	if s.def == "" {
+		s.def = DemoScenario
+	}
+	if s.count <= 0 {
+		s.count = defaultCount
+	}
+	for _, sc := range append([]Scenario{Demo()}, opts.Scenarios...) {
+		if err := sc.validate(); err != nil {
+			return nil, err
+		}
+		s.scenarios[sc.Name] = sc
+	}
+	if _, ok := s.scenarios[s.def]; !ok {
+		return nil, unknown(s.def)
+	}
+	return s, nil
+}
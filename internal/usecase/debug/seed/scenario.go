@@ -0,0 +1,215 @@
+package seed
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+// Scenario describes a batch of demo orders. Scenario files are YAML or
+// JSON with the keys of the yaml tags; see scenarios/demo.yaml.
+type Scenario struct {
+	Name string `yaml:"name"`
+	// Count is how many orders to create; the request's count wins.
+	Count int `yaml:"count"`
+	// Seed makes the batch reproducible; zero draws a new one every time.
+	Seed uint64 `yaml:"seed"`
+	// Users place the orders, picked at random; a user without an ID is the
+	// caller. No users means the caller alone.
+	Users       []User   `yaml:"users"`
+	Restaurants []string `yaml:"restaurants"`
+	// Items are the menu; an order takes from one to ItemsPerOrder (2 by
+	// default) different ones.
+	Items         []ItemTemplate `yaml:"items"`
+	ItemsPerOrder int            `yaml:"items_per_order"`
+	// Statuses weigh the status each order is moved to after creation;
+	// empty leaves them all created.
+	Statuses map[entity.OrderStatus]float64 `yaml:"statuses"`
+	// Age is how long ago the orders were placed, uniformly within it.
+	Age Age `yaml:"age"`
+}
+
+type User struct {
+	ID      string  `yaml:"id"`
+	FIO     string  `yaml:"fio"`
+	Address Address `yaml:"address"`
+}
+
+type Address struct {
+	Street    string `yaml:"street"`
+	House     string `yaml:"house"`
+	Apartment string `yaml:"apartment"`
+	Floor     string `yaml:"floor"`
+	Comment   string `yaml:"comment"`
+	// Location is optional; without it the geocoder, if any, finds one.
+	Location *GeoPoint `yaml:"location"`
+}
+
+type GeoPoint struct {
+	Lat float64 `yaml:"lat"`
+	Lon float64 `yaml:"lon"`
+}
+
+func (a Address) domain() entity.DeliveryAddress {
+	return entity.DeliveryAddress{
+		Street:    a.Street,
+		House:     a.House,
+		Apartment: a.Apartment,
+		Floor:     a.Floor,
+		Comment:   a.Comment,
+		Location:  (*entity.GeoPoint)(a.Location),
+	}
+}
+
+// ItemTemplate is a menu entry; an order takes 1 to MaxQuantity (1 by
+// default) of it.
+type ItemTemplate struct {
+	FoodID      string `yaml:"food_id"`
+	Name        string `yaml:"name"`
+	Price       int    `yaml:"price"`
+	MaxQuantity int    `yaml:"max_quantity"`
+}
+
+type Age struct {
+	Min time.Duration `yaml:"min"`
+	Max time.Duration `yaml:"max"`
+}
+
+// DemoScenario is the name of the built-in scenario.
+const DemoScenario = "demo"
+
+//go:embed scenarios/demo.yaml
+var demoYAML []byte
+
+// Demo returns the built-in scenario: a few restaurants and a mixed menu,
+// orders of the caller placed within the last quarter of an hour, in every
+// status of the lifecycle.
+func Demo() Scenario {
+	sc, err := ParseScenario(demoYAML)
+	if err != nil {
+		panic(err)
+	}
+	return sc
+}
+
+// maxCount bounds one batch.
+const maxCount = 10000
+
+// seedable are the statuses an order can be moved to right after creation.
+var seedable = []entity.OrderStatus{
+	entity.OrderStatusCreated,
+	entity.OrderStatusPending,
+	entity.OrderStatusConfirmed,
+	entity.OrderStatusCooking,
+	entity.OrderStatusDelivering,
+	entity.OrderStatusCompleted,
+	entity.OrderStatusCanceled,
+	entity.OrderStatusDeleted,
+}
+
+// ParseScenario reads a YAML or JSON scenario; unknown keys are errors.
+func ParseScenario(data []byte) (Scenario, error) {
+	sc, err := decode(data)
+	if err != nil {
+		return Scenario{}, fmt.Errorf("%w: scenario: %w", entity.ErrInvalidInput, err)
+	}
+	return sc, sc.validate()
+}
+
+func decode(data []byte) (Scenario, error) {
+	var sc Scenario
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err := dec.Decode(&sc)
+	return sc, err
+}
+
+// LoadDir reads every .yaml, .yml and .json file of dir; a scenario without
+// a name is named after its file. An empty dir loads nothing.
+func LoadDir(dir string) ([]Scenario, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		out  []Scenario
+		errs []error
+	)
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sc, err := decode(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.Name(), err))
+			continue
+		}
+		if sc.Name == "" {
+			sc.Name = strings.TrimSuffix(e.Name(), ext)
+		}
+		if err := sc.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.Name(), err))
+			continue
+		}
+		out = append(out, sc)
+	}
+	return out, errors.Join(errs...)
+}
+
+// validate wraps every error in entity.ErrInvalidInput.
+func (sc Scenario) validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	check(sc.Name != "", "name is required")
+	check(sc.Count >= 0 && sc.Count <= maxCount, "count must be within [0, %d], got %d", maxCount, sc.Count)
+	check(len(sc.Restaurants) > 0, "at least one restaurant is required")
+	for _, r := range sc.Restaurants {
+		check(r != "", "restaurant ids must not be empty")
+	}
+	check(len(sc.Items) > 0, "at least one item is required")
+	for _, it := range sc.Items {
+		check(it.FoodID != "" && it.Name != "", "items need a food_id and a name")
+		check(it.Price >= 0, "item %s: price must not be negative", it.FoodID)
+		check(it.MaxQuantity >= 0, "item %s: max_quantity must not be negative", it.FoodID)
+	}
+	check(sc.ItemsPerOrder >= 0, "items_per_order must not be negative")
+	var total float64
+	for st, w := range sc.Statuses {
+		check(slices.Contains(seedable, st), "status %q cannot be seeded", st)
+		check(w >= 0, "status %s: weight must not be negative", st)
+		total += w
+	}
+	check(len(sc.Statuses) == 0 || total > 0, "status weights must not all be zero")
+	check(sc.Age.Min >= 0 && sc.Age.Max >= sc.Age.Min, "age must satisfy 0 <= min <= max, got %s..%s", sc.Age.Min, sc.Age.Max)
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w: scenario %q: %w", entity.ErrInvalidInput, sc.Name, err)
+	}
+	return nil
+}
+
+func unknown(name string) error {
+	return fmt.Errorf("%w: seed scenario %q", entity.ErrNotFound, name)
+}
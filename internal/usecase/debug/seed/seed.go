@@ -1,158 +1,156 @@
 package seed
 
 import (
+	"cmp"
 	"context"
-	"math/rand"
+	"fmt"
+	"math/rand/v2"
+	"slices"
 	"time"
 
-	"github.com/google/uuid"
-
 	"github.com/nikolaev/service-order/internal/domain/entity"
+	uc "github.com/nikolaev/service-order/internal/usecase/order"
 )
 
-// Seed creates n orders for the user with meaningful fields and varied statuses/timestamps.
-func (s *service) Seed(ctx context.Context, userID string, n int) ([]*entity.Order, error) {
-	if n <= 0 {
-		n = 10
+// Seed creates the orders of the scenario one by one through the use case,
+// then moves each to its drawn status and publishes the change. With a seed
+// the users, menus, statuses and ages repeat exactly; order IDs do not.
+func (s *service) Seed(ctx context.Context, userID string, req Request) ([]*entity.Order, error) {
+	sc, err := s.scenario(req)
+	if err != nil {
+		return nil, err
+	}
+	n := cmp.Or(req.Count, sc.Count, s.count)
+	if n < 0 {
+		return nil, fmt.Errorf("%w: count must not be negative, got %d", entity.ErrInvalidInput, n)
 	}
+	if n > maxCount {
+		return nil, fmt.Errorf("%w: count must not exceed %d, got %d", entity.ErrInvalidInput, maxCount, n)
+	}
+	seed := cmp.Or(req.Seed, sc.Seed, rand.Uint64())
 	if userID == "" {
 		userID = "default-user"
 	}
+
+	d := newDraw(sc, seed)
 	now := s.clk.Now()
 	out := make([]*entity.Order, 0, n)
-	statuses := []entity.OrderStatus{
-		entity.OrderStatusCreated,
-		entity.OrderStatusPending,
-		entity.OrderStatusConfirmed,
-		entity.OrderStatusCooking,
-		entity.OrderStatusDelivering,
-		entity.OrderStatusDelivered,
-		entity.OrderStatusCanceled,
-		entity.OrderStatusUpdated,
-		entity.OrderStatusCompleted,
-		entity.OrderStatusPending,
-	}
-
-	for i := 0; i < n; i++ {
-		st := statuses[i%len(statuses)]
-		createdAt := now.Add(minutes(-(15 - i)))
-		updatedAt := createdAt
-		statusChangedAt := createdAt
-		estimated := createdAt.Add(minutes(30 + i))
-
-		items := []entity.Item{
-			{
-				FoodID:   "f-" + itoa(i, 1),
-				Name:     pick([]string{"Burger", "Pizza", "Sushi", "Pasta"}, i),
-				Quantity: 1 + (i % 3),
-				Price:    300 + 50*(i%5),
-			},
-			{
-				FoodID:   "d-" + itoa(i, 2),
-				Name:     pick([]string{"Cola", "Tea", "Juice"}, i+1),
-				Quantity: 1,
-				Price:    120 + 10*(i%4),
-			},
+	for i := range n {
+		user, in, status := d.order(i, now)
+		if user == "" {
+			user = userID
 		}
-		total := int64(0)
-		for _, it := range items {
-			total += int64(it.Price * it.Quantity)
+		o, err := s.orders.Create(ctx, user, in)
+		if err != nil {
+			return out, fmt.Errorf("seed order %d of %d: %w", i+1, n, err)
 		}
-		addr := entity.DeliveryAddress{
-			Street:    pick([]string{"Lenina", "Tverskaya", "Nevsky", "Arbat"}, i),
-			House:     itoa(10+i, 0),
-			Apartment: itoa(20+i*3, 0),
-			Floor:     itoa(1+(i%10), 0),
-			Comment:   pick([]string{"Позвонить за 5 минут", "Код домофона 1234", "Оставить у двери"}, i),
+		if err := s.move(ctx, o, status, now); err != nil {
+			return out, fmt.Errorf("seed order %d of %d: %w", i+1, n, err)
 		}
+		out = append(out, o)
+	}
+	return out, nil
+}
 
-		// Adjust timestamps to be consistent for chosen status
-		switch st {
-		case entity.OrderStatusPending:
-			statusChangedAt = createdAt.Add(seconds(2))
-			updatedAt = statusChangedAt
-		case entity.OrderStatusConfirmed:
-			statusChangedAt = createdAt.Add(seconds(7))
-			updatedAt = statusChangedAt
-		case entity.OrderStatusCooking:
-			statusChangedAt = createdAt.Add(seconds(12))
-			updatedAt = statusChangedAt
-		case entity.OrderStatusDelivering:
-			statusChangedAt = createdAt.Add(minutes(6))
-			updatedAt = statusChangedAt
-		case entity.OrderStatusDelivered, entity.OrderStatusCompleted:
-			statusChangedAt = createdAt.Add(minutes(20))
-			updatedAt = statusChangedAt
-		case entity.OrderStatusCanceled, entity.OrderStatusDeleted:
-			statusChangedAt = createdAt.Add(seconds(3))
-			updatedAt = statusChangedAt
-		case entity.OrderStatusUpdated:
-			statusChangedAt = createdAt.Add(seconds(15))
-			updatedAt = statusChangedAt
+func (s *service) scenario(req Request) (Scenario, error) {
+	if req.Inline != nil {
+		sc := *req.Inline
+		if sc.Name == "" {
+			sc.Name = "inline"
 		}
+		return sc, sc.validate()
+	}
+	name := cmp.Or(req.Scenario, s.def)
+	sc, ok := s.scenarios[name]
+	if !ok {
+		return Scenario{}, unknown(name)
+	}
+	return sc, nil
+}
 
-		o := &entity.Order{
-			ID:                uuid.NewString(),
-			UserID:            userID,
-			OrderNumber:       sprintf("%06d", 1000+i),
-			FIO:               pick([]string{"Иван Иванов", "Петр Петров", "Анна Смирнова", "John Doe"}, i),
-			RestaurantID:      pick([]string{"rest-1", "rest-2", "rest-3"}, i),
-			Items:             items,
-			TotalPrice:        total,
-			Address:           addr,
-			Status:            st,
-			CreatedAt:         createdAt,
-			UpdatedAt:         updatedAt,
-			EstimatedDelivery: estimated,
-			StatusChangedAt:   statusChangedAt,
-			IsDeleted:         st == entity.OrderStatusDeleted,
-		}
-		if err := s.repo.Create(ctx, o); err != nil {
-			return nil, err
+// move puts a freshly created order in status as of now; deletion goes
+// through the use case.
+func (s *service) move(ctx context.Context, o *entity.Order, status entity.OrderStatus, now time.Time) error {
+	switch status {
+	case entity.OrderStatusCreated:
+		return nil
+	case entity.OrderStatusDeleted:
+		if err := s.orders.Delete(ctx, o.UserID, o.ID); err != nil {
+			return err
 		}
-		out = append(out, o)
+		o.Status, o.IsDeleted = entity.OrderStatusDeleted, true
+		return nil
 	}
-	// slight random shuffle for realism
-	rand.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
-	return out, nil
+	o.Status = status
+	o.StatusChangedAt = now
+	o.UpdatedAt = now
+	if err := s.repo.Update(ctx, o); err != nil {
+		return err
+	}
+	return s.prod.OrderStatusChanged(ctx, o)
 }
 
-// Helpers without importing fmt to minimize deps
-func itoa(v int, salt int) string    { return fmtInt(v + salt) }
-func sprintf(_ string, v int) string { return fmtPadded(v) }
+// draw makes the random choices of one batch.
+type draw struct {
+	sc       Scenario
+	r        *rand.Rand
+	statuses []entity.OrderStatus // sorted, for a stable draw
+	total    float64
+}
 
-func pick[T any](arr []T, i int) T { return arr[i%len(arr)] }
+func newDraw(sc Scenario, seed uint64) *draw {
+	d := &draw{sc: sc, r: rand.New(rand.NewPCG(seed, seed^0x5eed))}
+	for st, w := range sc.Statuses {
+		if w > 0 {
+			d.statuses = append(d.statuses, st)
+			d.total += w
+		}
+	}
+	slices.Sort(d.statuses)
+	return d
+}
 
-func seconds(s int) time.Duration { return time.Duration(s) * time.Second }
-func minutes(m int) time.Duration { return time.Duration(m) * time.Minute }
+// order draws the i-th order: its user ID (empty for the caller), what the
+// use case creates and the status it moves on to.
+func (d *draw) order(i int, now time.Time) (string, uc.CreateInput, entity.OrderStatus) {
+	var u User
+	if len(d.sc.Users) > 0 {
+		u = d.sc.Users[d.r.IntN(len(d.sc.Users))]
+	}
+	in := uc.CreateInput{
+		OrderNumber:  fmt.Sprintf("%06d", 1000+i),
+		FIO:          u.FIO,
+		RestaurantID: d.sc.Restaurants[d.r.IntN(len(d.sc.Restaurants))],
+		Address:      u.Address.domain(),
+		PlacedAt:     now.Add(-d.age()),
+	}
+	perOrder := min(cmp.Or(d.sc.ItemsPerOrder, 2), len(d.sc.Items))
+	for _, k := range d.r.Perm(len(d.sc.Items))[:1+d.r.IntN(perOrder)] {
+		t := d.sc.Items[k]
+		it := entity.Item{FoodID: t.FoodID, Name: t.Name, Price: t.Price, Quantity: 1 + d.r.IntN(max(t.MaxQuantity, 1))}
+		in.Items = append(in.Items, it)
+		in.TotalPrice += int64(it.Price * it.Quantity)
+	}
+	return u.ID, in, d.status()
+}
 
-// tiny integer formatting helpers
-func fmtInt(v int) string {
-	if v == 0 {
-		return "0"
-	}
-	neg := false
-	if v < 0 {
-		neg = true
-		v = -v
-	}
-	buf := make([]byte, 0, 12)
-	for v > 0 {
-		d := byte('0' + (v % 10))
-		buf = append([]byte{d}, buf...)
-		v /= 10
-	}
-	if neg {
-		buf = append([]byte{'-'}, buf...)
-	}
-	return string(buf)
+func (d *draw) age() time.Duration {
+	span := d.sc.Age.Max - d.sc.Age.Min
+	if span <= 0 {
+		return d.sc.Age.Min
+	}
+	return d.sc.Age.Min + time.Duration(d.r.Int64N(int64(span)+1))
 }
 
-func fmtPadded(v int) string {
-	// 6-digit zero padded
-	s := fmtInt(v)
-	for len(s) < 6 {
-		s = "0" + s
+func (d *draw) status() entity.OrderStatus {
+	if len(d.statuses) == 0 {
+		return entity.OrderStatusCreated
+	}
+	x := d.r.Float64() * d.total
+	for _, st := range d.statuses {
+		if x -= d.sc.Statuses[st]; x < 0 {
+			return st
+		}
 	}
-	return s
+	return d.statuses[len(d.statuses)-1]
 }
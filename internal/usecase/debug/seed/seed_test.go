@@ -0,0 +1,210 @@
+package seed_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/clock"
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	repo "github.com/nikolaev/service-order/internal/repository/order"
+	"github.com/nikolaev/service-order/internal/usecase/debug/seed"
+	uc "github.com/nikolaev/service-order/internal/usecase/order"
+)
+
+var t0 = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// events records what the use case and the seeder publish.
+type events struct {
+	mu      sync.Mutex
+	created int
+	changed []entity.OrderStatus
+	deleted int
+}
+
+func (e *events) OrderCreated(context.Context, *entity.Order) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.created++
+	return nil
+}
+
+func (e *events) OrderUpdated(context.Context, *entity.Order) error { return nil }
+
+func (e *events) OrderStatusChanged(_ context.Context, o *entity.Order) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.changed = append(e.changed, o.Status)
+	return nil
+}
+
+func (e *events) OrderDeleted(context.Context, string, string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.deleted++
+	return nil
+}
+
+func (e *events) OrderSnapshot(context.Context, *entity.Order, string) error { return nil }
+func (e *events) OrderETAChanged(context.Context, *entity.Order) error       { return nil }
+
+func newSeeder(t *testing.T, opts seed.Options) (seed.Service, *repo.InMemory, *events) {
+	t.Helper()
+	mem, ev := repo.NewInMemory(), &events{}
+	s, err := seed.New(uc.New(mem, ev), mem, ev, clock.NewVirtual(t0, true), opts)
+	require.NoError(t, err)
+	return s, mem, ev
+}
+
+type drawn struct {
+	user, restaurant, fio string
+	items                 []entity.Item
+	status                entity.OrderStatus
+	created               time.Time
+}
+
+func summary(orders []*entity.Order) []drawn {
+	out := make([]drawn, 0, len(orders))
+	for _, o := range orders {
+		out = append(out, drawn{o.UserID, o.RestaurantID, o.FIO, o.Items, o.Status, o.CreatedAt})
+	}
+	return out
+}
+
+func TestSeed_DemoIsReproducibleWithASeed(t *testing.T) {
+	s, _, _ := newSeeder(t, seed.Options{})
+	ctx := context.Background()
+
+	a, err := s.Seed(ctx, "u1", seed.Request{Seed: 42})
+	require.NoError(t, err)
+	require.Len(t, a, 10, "the default count")
+	b, err := s.Seed(ctx, "u1", seed.Request{Seed: 42})
+	require.NoError(t, err)
+	assert.Equal(t, summary(a), summary(b))
+	assert.NotEqual(t, a[0].ID, b[0].ID, "orders are new every time")
+
+	c, err := s.Seed(ctx, "u1", seed.Request{Seed: 43, Count: 30})
+	require.NoError(t, err)
+	require.Len(t, c, 30)
+	assert.NotEqual(t, summary(a), summary(c)[:10])
+
+	statuses := map[entity.OrderStatus]bool{}
+	for _, o := range c {
+		assert.Equal(t, "u1", o.UserID, "demo users stand for the caller")
+		assert.NotEmpty(t, o.FIO)
+		assert.NotEmpty(t, o.Items)
+		assert.False(t, o.CreatedAt.After(t0))
+		assert.False(t, o.CreatedAt.Before(t0.Add(-15*time.Minute)), "placed within the last 15 minutes")
+		var total int64
+		for _, it := range o.Items {
+			total += int64(it.Price * it.Quantity)
+		}
+		assert.Equal(t, total, o.TotalPrice)
+		statuses[o.Status] = true
+	}
+	assert.Greater(t, len(statuses), 3, "statuses follow the weights")
+}
+
+func TestSeed_CreatesThroughUseCaseAndMovesStatuses(t *testing.T) {
+	s, mem, ev := newSeeder(t, seed.Options{})
+	ctx := context.Background()
+	sc := seed.Scenario{
+		Name:        "kitchen",
+		Users:       []seed.User{{ID: "alice", FIO: "Alice"}, {FIO: "Caller"}},
+		Restaurants: []string{"r1"},
+		Items:       []seed.ItemTemplate{{FoodID: "f1", Name: "Soup", Price: 200}},
+		Statuses:    map[entity.OrderStatus]float64{entity.OrderStatusCooking: 1, entity.OrderStatusDeleted: 1},
+		Age:         seed.Age{Min: time.Hour, Max: time.Hour},
+	}
+	orders, err := s.Seed(ctx, "bob", seed.Request{Inline: &sc, Count: 20, Seed: 1})
+	require.NoError(t, err)
+	require.Len(t, orders, 20)
+
+	var cooking, deleted int
+	for _, o := range orders {
+		assert.Contains(t, []string{"alice", "bob"}, o.UserID)
+		assert.Equal(t, o.UserID == "alice", o.FIO == "Alice")
+		assert.Equal(t, t0.Add(-time.Hour), o.CreatedAt)
+		stored, err := mem.GetByID(ctx, o.ID)
+		require.NoError(t, err)
+		switch o.Status {
+		case entity.OrderStatusCooking:
+			cooking++
+			assert.Equal(t, entity.OrderStatusCooking, stored.Status)
+			assert.Equal(t, t0, stored.StatusChangedAt, "the status is entered now")
+		case entity.OrderStatusDeleted:
+			deleted++
+			assert.True(t, stored.IsDeleted)
+		}
+	}
+	assert.Equal(t, 20, cooking+deleted)
+	assert.Positive(t, cooking)
+	assert.Positive(t, deleted)
+
+	assert.Equal(t, 20, ev.created, "every order announces its creation")
+	assert.Len(t, ev.changed, cooking)
+	assert.Equal(t, deleted, ev.deleted)
+}
+
+func TestSeed_Errors(t *testing.T) {
+	s, _, _ := newSeeder(t, seed.Options{})
+	ctx := context.Background()
+
+	_, err := s.Seed(ctx, "u1", seed.Request{Scenario: "missing"})
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+	_, err = s.Seed(ctx, "u1", seed.Request{Count: 10001})
+	assert.ErrorIs(t, err, entity.ErrInvalidInput)
+	_, err = s.Seed(ctx, "u1", seed.Request{Count: -1})
+	assert.ErrorIs(t, err, entity.ErrInvalidInput)
+	_, err = s.Seed(ctx, "u1", seed.Request{Inline: &seed.Scenario{Restaurants: []string{"r1"}}})
+	assert.ErrorIs(t, err, entity.ErrInvalidInput, "no items")
+
+	_, err = seed.ParseScenario([]byte("name: x\nrestaurants: [r1]\nitems: [{food_id: f1, name: Tea}]\nstatuses: {delivered: 1}\n"))
+	assert.ErrorIs(t, err, entity.ErrInvalidInput)
+	assert.ErrorContains(t, err, `status "delivered" cannot be seeded`)
+	_, err = seed.ParseScenario([]byte("name: x\nrestaurnts: [r1]\n"))
+	assert.ErrorIs(t, err, entity.ErrInvalidInput, "unknown keys are rejected")
+	_, err = seed.ParseScenario([]byte("name: x\nrestaurants: [r1]\nitems: [{food_id: f1, name: Tea}]\nage: {min: 1h, max: 1m}\n"))
+	assert.ErrorContains(t, err, "age must satisfy")
+
+	_, err = seed.New(nil, nil, nil, clock.NewVirtual(t0, true), seed.Options{Default: "missing"})
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(body), 0o600))
+	}
+	write("lunch.yaml", "count: 3\nseed: 9\nrestaurants: [r1]\nitems: [{food_id: f1, name: Soup, price: 100}]\n")
+	write("night.json", `{"name": "late", "restaurants": ["r2"], "items": [{"food_id": "f2", "name": "Pizza", "price": 500}], "age": {"max": "30m"}}`)
+	write("notes.txt", "not a scenario")
+
+	scenarios, err := seed.LoadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, scenarios, 2)
+	assert.Equal(t, "lunch", scenarios[0].Name, "named after the file")
+	assert.Equal(t, "late", scenarios[1].Name)
+	assert.Equal(t, 30*time.Minute, scenarios[1].Age.Max)
+
+	s, _, _ := newSeeder(t, seed.Options{Scenarios: scenarios, Default: "lunch"})
+	orders, err := s.Seed(context.Background(), "u1", seed.Request{})
+	require.NoError(t, err)
+	assert.Len(t, orders, 3, "the scenario's count")
+
+	write("broken.yaml", "name: broken\n")
+	_, err = seed.LoadDir(dir)
+	assert.ErrorContains(t, err, "broken.yaml")
+
+	_, err = seed.LoadDir(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+	scenarios, err = seed.LoadDir("")
+	assert.NoError(t, err)
+	assert.Empty(t, scenarios)
+}
@@ -0,0 +1,55 @@
+package order
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+// Cancel stops an order the restaurant has not confirmed yet, in particular
+// a scheduled one. Unlike Delete the order stays visible as canceled.
+func (s *service) Cancel(ctx context.Context, userID string, id string) (*entity.Order, error) {
+	if userID == "" {
+		return nil, entity.ErrUnauthorized
+	}
+
+	if id == "" {
+		return nil, entity.ErrInvalidID
+	}
+
+	ctx = s.log.WithFields(ctx, map[string]any{"order_id": id})
+	o, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if o == nil || o.IsDeleted {
+		return nil, entity.ErrNotFound
+	}
+
+	if o.UserID != userID {
+		return nil, entity.ErrForeignOwnership
+	}
+
+	switch o.Status {
+	case entity.OrderStatusScheduled, entity.OrderStatusCreated, entity.OrderStatusPending:
+	default:
+		return nil, fmt.Errorf("%w: order is %s and can no longer be canceled", entity.ErrConflict, o.Status)
+	}
+
+	now := s.clock.Now()
+	o.Status = entity.OrderStatusCanceled
+	o.StatusChangedAt = now
+	o.UpdatedAt = now
+	if err := s.repo.Update(ctx, o); err != nil {
+		s.log.Error(ctx, "cancel order", "error", err)
+		return nil, err
+	}
+	if err := s.producer.OrderStatusChanged(ctx, o); err != nil {
+		s.log.Error(ctx, "publish order canceled", "error", err)
+	}
+	s.metric.Increment("order.canceled")
+	s.log.Info(ctx, "order canceled")
+	return o, nil
+}
@@ -4,8 +4,10 @@ package order
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/nikolaev/service-order/internal/clock"
 	"github.com/nikolaev/service-order/internal/domain/entity"
 )
 
@@ -13,14 +15,22 @@ type Repository interface {
 	Create(ctx context.Context, o *entity.Order) error
 	GetByID(ctx context.Context, id string) (*entity.Order, error)
 	Update(ctx context.Context, o *entity.Order) error
-	MarkDeleted(ctx context.Context, id string, userID string) error
+	MarkDeleted(ctx context.Context, id string, userID string, at time.Time) error
 	ListFrom(ctx context.Context, from time.Time) ([]*entity.Order, error)
 }
 
+// Producer publishes order events. OrderUpdated reports a user edit and
+// OrderStatusChanged a status change made by the status worker or by a
+// cancellation. OrderSnapshot republishes the current state of an order for
+// the replay job replayID, and OrderETAChanged reports a new
+// EstimatedDelivery.
 type Producer interface {
 	OrderCreated(ctx context.Context, o *entity.Order) error
 	OrderUpdated(ctx context.Context, o *entity.Order) error
+	OrderStatusChanged(ctx context.Context, o *entity.Order) error
 	OrderDeleted(ctx context.Context, id string, userID string) error
+	OrderSnapshot(ctx context.Context, o *entity.Order, replayID string) error
+	OrderETAChanged(ctx context.Context, o *entity.Order) error
 }
 
 type Service interface {
@@ -30,6 +40,7 @@ type Service interface {
 	ListFrom(ctx context.Context, from time.Time) ([]*entity.Order, error)
 	Update(ctx context.Context, userID string, id string, in UpdateInput) (*entity.Order, error)
 	Delete(ctx context.Context, userID string, id string) error
+	Cancel(ctx context.Context, userID string, id string) (*entity.Order, error)
 }
 
 type CreateInput struct {
@@ -39,6 +50,11 @@ type CreateInput struct {
 	Items        []entity.Item
 	TotalPrice   int64
 	Address      entity.DeliveryAddress
+	// DeliverAt or PrepareFrom, not both, schedule the order for later.
+	DeliverAt   time.Time
+	PrepareFrom time.Time
+	// PlacedAt backdates the order; zero is now. Only the debug seeder sets it.
+	PlacedAt time.Time
 }
 
 type UpdateInput struct {
@@ -47,13 +63,52 @@ type UpdateInput struct {
 	Items       *[]entity.Item
 	TotalPrice  *int64
 	Address     *entity.DeliveryAddress
+	// DeliverAt or PrepareFrom reschedule an order that is still scheduled.
+	DeliverAt   *time.Time
+	PrepareFrom *time.Time
 }
 
 type Clock interface{ Now() time.Time }
 
+// Estimator fills EstimatedDelivery; ok is false when the order gets none.
+type Estimator interface {
+	Estimate(ctx context.Context, o *entity.Order, now time.Time) (time.Time, bool)
+}
+
+// Geocoder locates an address; a nil point without an error means the
+// address is not known.
+type Geocoder interface {
+	Geocode(ctx context.Context, a entity.DeliveryAddress) (*entity.GeoPoint, error)
+}
+
+// Zones tells where restaurants deliver; a restaurant without zones
+// delivers anywhere.
+type Zones interface {
+	Zoned(restaurantID string) bool
+	Covers(restaurantID string, p entity.GeoPoint) bool
+}
+
+// Hours tells when restaurants take orders; Check explains why not.
+type Hours interface {
+	Check(restaurantID string, at time.Time) error
+}
+
+// Scheduling bounds scheduled orders.
+type Scheduling struct {
+	// Lead is how long before DeliverAt an order goes to the kitchen when
+	// there is no Estimator to tell.
+	Lead time.Duration
+	// MaxAhead is how far ahead an order can be scheduled.
+	MaxAhead time.Duration
+}
+
+var DefaultScheduling = Scheduling{Lead: 45 * time.Minute, MaxAhead: 7 * 24 * time.Hour}
+
+// log takes a message followed by key/value pairs, like log/slog.
 type log interface {
 	WithFields(ctx context.Context, fields map[string]any) context.Context
 	Info(ctx context.Context, args ...any)
+	Error(ctx context.Context, args ...any)
 }
 
 type metric interface{ Increment(key string) }
@@ -64,20 +119,151 @@ type service struct {
 	clock    Clock
 	log      log
 	metric   metric
+	eta      Estimator
+	geocoder Geocoder
+	zones    Zones
+	hours    Hours
+	sched    Scheduling
+}
+
+type Option func(*service)
+
+// WithEstimator makes Create and Update fill EstimatedDelivery.
+func WithEstimator(e Estimator) Option {
+	return func(s *service) { s.eta = e }
+}
+
+// WithGeocoder makes Create and Update locate addresses sent without a
+// location.
+func WithGeocoder(g Geocoder) Option {
+	return func(s *service) { s.geocoder = g }
+}
+
+// WithZones makes Create and Update reject addresses outside the delivery
+// zones of the restaurant.
+func WithZones(z Zones) Option {
+	return func(s *service) { s.zones = z }
+}
+
+// WithHours makes Create reject orders the restaurant is closed for, at the
+// time they go to the kitchen.
+func WithHours(h Hours) Option {
+	return func(s *service) { s.hours = h }
+}
+
+// WithScheduling replaces DefaultScheduling.
+func WithScheduling(sc Scheduling) Option {
+	return func(s *service) { s.sched = sc }
 }
 
 func New(repo Repository, producer Producer) Service {
-	return NewWithDeps(repo, producer, systemClock{}, noopLog{}, noopMetric{})
+	return NewWithDeps(repo, producer, clock.System{}, noopLog{}, noopMetric{})
+}
+
+func NewWithDeps(repo Repository, producer Producer, clk Clock, l log, m metric, opts ...Option) Service {
+	s := &service{repo: repo, producer: producer, clock: clk, log: l, metric: m, sched: DefaultScheduling}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// estimate sets the ETA of o and reports whether it changed.
+func (s *service) estimate(ctx context.Context, o *entity.Order, now time.Time) bool {
+	if s.eta == nil {
+		return false
+	}
+	eta, ok := s.eta.Estimate(ctx, o, now)
+	if !ok || eta.Equal(o.EstimatedDelivery) {
+		return false
+	}
+	o.EstimatedDelivery = eta
+	return true
+}
+
+// locate geocodes a unless it has a location and checks it against the
+// delivery zones of the restaurant.
+func (s *service) locate(ctx context.Context, restaurantID string, a *entity.DeliveryAddress) error {
+	if a.Location != nil && !a.Location.Valid() {
+		return fmt.Errorf("%w: address location %g,%g is out of range", entity.ErrInvalidInput, a.Location.Lat, a.Location.Lon)
+	}
+	if a.Location == nil && s.geocoder != nil {
+		p, err := s.geocoder.Geocode(ctx, *a)
+		if err != nil {
+			// Orders of restaurants without zones do not need the location.
+			s.log.Error(ctx, "geocode address", "error", err)
+		} else {
+			a.Location = p
+		}
+	}
+	if s.zones == nil || !s.zones.Zoned(restaurantID) {
+		return nil
+	}
+	if a.Location == nil {
+		return fmt.Errorf("%w: address could not be located, restaurant %s delivers only within its zones", entity.ErrInvalidInput, restaurantID)
+	}
+	if !s.zones.Covers(restaurantID, *a.Location) {
+		return fmt.Errorf("%w: address is outside the delivery zones of restaurant %s", entity.ErrInvalidInput, restaurantID)
+	}
+	return nil
+}
+
+// schedule sets when o goes to the kitchen: at prepareFrom, ahead of
+// deliverAt by the lead time or, when both are zero, right away. It returns
+// that time; a scheduled order waits in OrderStatusScheduled until then.
+func (s *service) schedule(ctx context.Context, o *entity.Order, deliverAt, prepareFrom, now time.Time) (time.Time, error) {
+	switch {
+	case !deliverAt.IsZero() && !prepareFrom.IsZero():
+		return time.Time{}, fmt.Errorf("%w: set deliver_at or prepare_from, not both", entity.ErrInvalidInput)
+	case !deliverAt.IsZero():
+		lead := s.lead(ctx, o, now)
+		prepareFrom = deliverAt.Add(-lead)
+		if !prepareFrom.After(now) {
+			return time.Time{}, fmt.Errorf("%w: deliver_at %s is too soon, the earliest is %s",
+				entity.ErrInvalidInput, deliverAt.Format(time.RFC3339), now.Add(lead).Format(time.RFC3339))
+		}
+	case !prepareFrom.IsZero() && !prepareFrom.After(now):
+		return time.Time{}, fmt.Errorf("%w: prepare_from %s is not in the future", entity.ErrInvalidInput, prepareFrom.Format(time.RFC3339))
+	}
+	if prepareFrom.Sub(now) > s.sched.MaxAhead {
+		return time.Time{}, fmt.Errorf("%w: an order can be scheduled at most %s ahead", entity.ErrInvalidInput, s.sched.MaxAhead)
+	}
+	o.DeliverAt, o.PrepareFrom = deliverAt, prepareFrom
+	if prepareFrom.IsZero() {
+		return now, nil
+	}
+	o.Status = entity.OrderStatusScheduled
+	return prepareFrom, nil
 }
 
-func NewWithDeps(repo Repository, producer Producer, clk Clock, l log, m metric) Service {
-	return &service{repo: repo, producer: producer, clock: clk, log: l, metric: m}
+// lead is how long before its delivery o has to go to the kitchen.
+func (s *service) lead(ctx context.Context, o *entity.Order, now time.Time) time.Duration {
+	if s.eta != nil {
+		probe := *o
+		probe.Status, probe.PrepareFrom, probe.DeliverAt = entity.OrderStatusScheduled, now, time.Time{}
+		if at, ok := s.eta.Estimate(ctx, &probe, now); ok {
+			return at.Sub(now)
+		}
+	}
+	return s.sched.Lead
+}
+
+// open checks that the restaurant takes orders at the time.
+func (s *service) open(restaurantID string, at time.Time) error {
+	if s.hours == nil {
+		return nil
+	}
+	if err := s.hours.Check(restaurantID, at); err != nil {
+		return fmt.Errorf("%w: %w", entity.ErrInvalidInput, err)
+	}
+	return nil
 }
 
 func advanceStatus(now time.Time, o *entity.Order) {
 	dur := now.Sub(o.CreatedAt)
 	switch {
-	case o.Status == entity.OrderStatusCanceled || o.Status == entity.OrderStatusDeleted:
+	case o.Status == entity.OrderStatusCanceled || o.Status == entity.OrderStatusDeleted || o.Status == entity.OrderStatusCompleted ||
+		o.Status == entity.OrderStatusScheduled:
 		return
 	case dur >= 10*time.Minute:
 		o.Status = entity.OrderStatusDelivering
@@ -92,14 +278,11 @@ func advanceStatus(now time.Time, o *entity.Order) {
 	}
 }
 
-type systemClock struct{}
-
-func (systemClock) Now() time.Time { return time.Now().UTC() }
-
 type noopLog struct{}
 
 func (noopLog) WithFields(ctx context.Context, fields map[string]any) context.Context { return ctx }
 func (noopLog) Info(ctx context.Context, args ...any)                                 {}
+func (noopLog) Error(ctx context.Context, args ...any)                                {}
 
 type noopMetric struct{}
 
@@ -15,7 +15,17 @@ func (s *service) Create(ctx context.Context, userID string, in CreateInput) (*e
 	if in.RestaurantID == "" || len(in.Items) == 0 || in.TotalPrice < 0 {
 		return nil, entity.ErrInvalidInput
 	}
+	addr := in.Address
+	if err := s.locate(ctx, in.RestaurantID, &addr); err != nil {
+		return nil, err
+	}
 	now := s.clock.Now()
+	if !in.PlacedAt.IsZero() {
+		if in.PlacedAt.After(now) {
+			return nil, entity.ErrInvalidInput
+		}
+		now = in.PlacedAt
+	}
 	o := &entity.Order{
 		ID:              uuid.NewString(),
 		UserID:          userID,
@@ -24,17 +34,34 @@ func (s *service) Create(ctx context.Context, userID string, in CreateInput) (*e
 		RestaurantID:    in.RestaurantID,
 		Items:           in.Items,
 		TotalPrice:      in.TotalPrice,
-		Address:         in.Address,
+		Address:         addr,
 		Status:          entity.OrderStatusCreated,
 		CreatedAt:       now,
 		UpdatedAt:       now,
 		StatusChangedAt: now,
 	}
+	start, err := s.schedule(ctx, o, in.DeliverAt, in.PrepareFrom, now)
+	if err != nil {
+		return nil, err
+	}
+	// The seeder backdates its orders, which were placed at any hour.
+	if in.PlacedAt.IsZero() {
+		if err := s.open(o.RestaurantID, start); err != nil {
+			return nil, err
+		}
+	}
 	advanceStatus(now, o)
-	if err := s.repo.Create(ctx, o); err != nil {
+	s.estimate(ctx, o, now)
+	ctx = s.log.WithFields(ctx, map[string]any{"order_id": o.ID})
+	if err = s.repo.Create(ctx, o); err != nil {
+		s.log.Error(ctx, "create order", "error", err)
 		return nil, err
 	}
-	_ = s.producer.OrderCreated(ctx, o)
+	if err := s.producer.OrderCreated(ctx, o); err != nil {
+		s.log.Error(ctx, "publish order created", "error", err)
+	}
+	s.metric.Increment("order.created")
+	s.log.Info(ctx, "order created", "restaurant_id", o.RestaurantID, "status", o.Status, "total_price", o.TotalPrice)
 
 	return o, nil
 }
@@ -15,6 +15,7 @@ func (s *service) Delete(ctx context.Context, userID string, id string) error {
 		return entity.ErrInvalidID
 	}
 
+	ctx = s.log.WithFields(ctx, map[string]any{"order_id": id})
 	o, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return err
@@ -28,10 +29,15 @@ func (s *service) Delete(ctx context.Context, userID string, id string) error {
 		return entity.ErrForeignOwnership
 	}
 
-	if err := s.repo.MarkDeleted(ctx, id, userID); err != nil {
+	if err := s.repo.MarkDeleted(ctx, id, userID, s.clock.Now()); err != nil {
+		s.log.Error(ctx, "delete order", "error", err)
 		return err
 	}
 
-	_ = s.producer.OrderDeleted(ctx, id, userID)
+	if err := s.producer.OrderDeleted(ctx, id, userID); err != nil {
+		s.log.Error(ctx, "publish order deleted", "error", err)
+	}
+	s.metric.Increment("order.deleted")
+	s.log.Info(ctx, "order deleted")
 	return nil
 }
@@ -67,14 +67,14 @@ func (mr *MockRepositoryMockRecorder) Update(ctx, o interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), ctx, o)
 }
 
-func (m *MockRepository) MarkDeleted(ctx context.Context, id string, userID string) error {
+func (m *MockRepository) MarkDeleted(ctx context.Context, id string, userID string, at time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "MarkDeleted", ctx, id, userID)
+	ret := m.ctrl.Call(m, "MarkDeleted", ctx, id, userID, at)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
-func (mr *MockRepositoryMockRecorder) MarkDeleted(ctx, id, userID interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkDeleted", reflect.TypeOf((*MockRepository)(nil).MarkDeleted), ctx, id, userID)
+func (mr *MockRepositoryMockRecorder) MarkDeleted(ctx, id, userID, at interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkDeleted", reflect.TypeOf((*MockRepository)(nil).MarkDeleted), ctx, id, userID, at)
 }
 
 func (m *MockRepository) ListFrom(ctx context.Context, from time.Time) ([]*entity.Order, error) {
@@ -124,6 +124,16 @@ func (mr *MockProducerMockRecorder) OrderUpdated(ctx, o interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OrderUpdated", reflect.TypeOf((*MockProducer)(nil).OrderUpdated), ctx, o)
 }
 
+func (m *MockProducer) OrderStatusChanged(ctx context.Context, o *entity.Order) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OrderStatusChanged", ctx, o)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+func (mr *MockProducerMockRecorder) OrderStatusChanged(ctx, o interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OrderStatusChanged", reflect.TypeOf((*MockProducer)(nil).OrderStatusChanged), ctx, o)
+}
+
 func (m *MockProducer) OrderDeleted(ctx context.Context, id string, userID string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "OrderDeleted", ctx, id, userID)
@@ -133,3 +143,23 @@ func (m *MockProducer) OrderDeleted(ctx context.Context, id string, userID strin
 func (mr *MockProducerMockRecorder) OrderDeleted(ctx, id, userID interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OrderDeleted", reflect.TypeOf((*MockProducer)(nil).OrderDeleted), ctx, id, userID)
 }
+
+func (m *MockProducer) OrderSnapshot(ctx context.Context, o *entity.Order, replayID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OrderSnapshot", ctx, o, replayID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+func (mr *MockProducerMockRecorder) OrderSnapshot(ctx, o, replayID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OrderSnapshot", reflect.TypeOf((*MockProducer)(nil).OrderSnapshot), ctx, o, replayID)
+}
+
+func (m *MockProducer) OrderETAChanged(ctx context.Context, o *entity.Order) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OrderETAChanged", ctx, o)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+func (mr *MockProducerMockRecorder) OrderETAChanged(ctx, o interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OrderETAChanged", reflect.TypeOf((*MockProducer)(nil).OrderETAChanged), ctx, o)
+}
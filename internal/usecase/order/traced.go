@@ -0,0 +1,89 @@
+package order
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+const instrumentation = "github.com/nikolaev/service-order/internal/usecase/order"
+
+type traced struct {
+	next   Service
+	tracer trace.Tracer
+}
+
+// NewTraced wraps s with a span per Service method.
+func NewTraced(s Service, tp trace.TracerProvider) Service {
+	return &traced{next: s, tracer: tp.Tracer(instrumentation)}
+}
+
+func (t *traced) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "order.Service/"+name, trace.WithAttributes(attrs...))
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t *traced) Create(ctx context.Context, userID string, in CreateInput) (*entity.Order, error) {
+	ctx, span := t.start(ctx, "Create", attribute.String("user.id", userID), attribute.String("restaurant.id", in.RestaurantID))
+	o, err := t.next.Create(ctx, userID, in)
+	if o != nil {
+		span.SetAttributes(attribute.String("order.id", o.ID))
+	}
+	end(span, err)
+	return o, err
+}
+
+func (t *traced) Get(ctx context.Context, userID string, id string) (*entity.Order, error) {
+	ctx, span := t.start(ctx, "Get", attribute.String("order.id", id))
+	o, err := t.next.Get(ctx, userID, id)
+	end(span, err)
+	return o, err
+}
+
+func (t *traced) GetStatus(ctx context.Context, userID string, id string) (entity.OrderStatus, error) {
+	ctx, span := t.start(ctx, "GetStatus", attribute.String("order.id", id))
+	st, err := t.next.GetStatus(ctx, userID, id)
+	end(span, err)
+	return st, err
+}
+
+func (t *traced) ListFrom(ctx context.Context, from time.Time) ([]*entity.Order, error) {
+	ctx, span := t.start(ctx, "ListFrom", attribute.String("from", from.Format(time.RFC3339)))
+	out, err := t.next.ListFrom(ctx, from)
+	span.SetAttributes(attribute.Int("orders.count", len(out)))
+	end(span, err)
+	return out, err
+}
+
+func (t *traced) Update(ctx context.Context, userID string, id string, in UpdateInput) (*entity.Order, error) {
+	ctx, span := t.start(ctx, "Update", attribute.String("user.id", userID), attribute.String("order.id", id))
+	o, err := t.next.Update(ctx, userID, id, in)
+	end(span, err)
+	return o, err
+}
+
+func (t *traced) Delete(ctx context.Context, userID string, id string) error {
+	ctx, span := t.start(ctx, "Delete", attribute.String("user.id", userID), attribute.String("order.id", id))
+	err := t.next.Delete(ctx, userID, id)
+	end(span, err)
+	return err
+}
+
+func (t *traced) Cancel(ctx context.Context, userID string, id string) (*entity.Order, error) {
+	ctx, span := t.start(ctx, "Cancel", attribute.String("user.id", userID), attribute.String("order.id", id))
+	o, err := t.next.Cancel(ctx, userID, id)
+	end(span, err)
+	return o, err
+}
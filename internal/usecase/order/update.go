@@ -2,6 +2,8 @@ package order
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/nikolaev/service-order/internal/domain/entity"
 )
@@ -15,6 +17,7 @@ func (s *service) Update(ctx context.Context, userID string, id string, in Updat
 		return nil, entity.ErrInvalidID
 	}
 
+	ctx = s.log.WithFields(ctx, map[string]any{"order_id": id})
 	o, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -28,6 +31,11 @@ func (s *service) Update(ctx context.Context, userID string, id string, in Updat
 		return nil, entity.ErrForeignOwnership
 	}
 
+	switch o.Status {
+	case entity.OrderStatusCanceled, entity.OrderStatusCompleted, entity.OrderStatusDelivered, entity.OrderStatusDeleted:
+		return nil, fmt.Errorf("%w: order is %s and can no longer be updated", entity.ErrConflict, o.Status)
+	}
+
 	if in.OrderNumber != nil {
 		o.OrderNumber = *in.OrderNumber
 	}
@@ -45,19 +53,57 @@ func (s *service) Update(ctx context.Context, userID string, id string, in Updat
 	}
 
 	if in.Address != nil {
-		o.Address = *in.Address
+		addr := *in.Address
+		if err := s.locate(ctx, o.RestaurantID, &addr); err != nil {
+			return nil, err
+		}
+		o.Address = addr
 	}
 
 	now := s.clock.Now()
+	if in.DeliverAt != nil || in.PrepareFrom != nil {
+		if o.Status != entity.OrderStatusScheduled {
+			return nil, fmt.Errorf("%w: order is %s, only a scheduled one can be rescheduled", entity.ErrConflict, o.Status)
+		}
+		start, err := s.schedule(ctx, o, deref(in.DeliverAt), deref(in.PrepareFrom), now)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.open(o.RestaurantID, start); err != nil {
+			return nil, err
+		}
+	}
+
 	o.UpdatedAt = now
-	o.Status = entity.OrderStatusUpdated
-	o.StatusChangedAt = now
+	// A scheduled order stays so until it goes to the kitchen.
+	if o.Status != entity.OrderStatusScheduled {
+		o.Status = entity.OrderStatusUpdated
+		o.StatusChangedAt = now
+	}
 	advanceStatus(now, o)
+	etaChanged := s.estimate(ctx, o, now)
 
 	if err := s.repo.Update(ctx, o); err != nil {
+		s.log.Error(ctx, "update order", "error", err)
 		return nil, err
 	}
-	_ = s.producer.OrderUpdated(ctx, o)
+	if err := s.producer.OrderUpdated(ctx, o); err != nil {
+		s.log.Error(ctx, "publish order updated", "error", err)
+	}
+	if etaChanged {
+		if err := s.producer.OrderETAChanged(ctx, o); err != nil {
+			s.log.Error(ctx, "publish order eta changed", "error", err)
+		}
+	}
+	s.metric.Increment("order.updated")
+	s.log.Info(ctx, "order updated", "status", o.Status)
 
 	return o, nil
 }
+
+func deref(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
@@ -7,8 +7,10 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/hours"
 	uc "github.com/nikolaev/service-order/internal/usecase/order"
 )
 
@@ -20,11 +22,16 @@ type nopLog struct{}
 
 func (nopLog) WithFields(ctx context.Context, fields map[string]any) context.Context { return ctx }
 func (nopLog) Info(ctx context.Context, args ...any)                                 {}
+func (nopLog) Error(ctx context.Context, args ...any)                                {}
 
 type nopMetric struct{}
 
 func (nopMetric) Increment(key string) {}
 
+type keysMetric []string
+
+func (m *keysMetric) Increment(key string) { *m = append(*m, key) }
+
 func TestService_Create_ProducesAndPersists(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -65,6 +72,35 @@ func TestService_Create_ProducesAndPersists(t *testing.T) {
 	}
 }
 
+func TestService_Create_PlacedAt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := NewMockRepository(ctrl)
+	prod := NewMockProducer(ctrl)
+	fixed := time.Date(2025, 8, 31, 12, 0, 0, 0, time.UTC)
+	svc := uc.NewWithDeps(repo, prod, fixedClock{t: fixed}, nopLog{}, nopMetric{})
+
+	in := uc.CreateInput{
+		RestaurantID: "rest-1",
+		Items:        []entity.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 500}},
+		TotalPrice:   500,
+		PlacedAt:     fixed.Add(-time.Hour),
+	}
+	repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+	prod.EXPECT().OrderCreated(gomock.Any(), gomock.Any()).Return(nil)
+
+	o, err := svc.Create(context.Background(), "user-1", in)
+	require.NoError(t, err)
+	assert.Equal(t, fixed.Add(-time.Hour), o.CreatedAt)
+	assert.Equal(t, fixed.Add(-time.Hour), o.StatusChangedAt)
+	assert.Equal(t, entity.OrderStatusCreated, o.Status)
+
+	in.PlacedAt = fixed.Add(time.Minute)
+	_, err = svc.Create(context.Background(), "user-1", in)
+	assert.ErrorIs(t, err, entity.ErrInvalidInput, "not in the future")
+}
+
 func TestService_Delete_ProducerCalled(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -73,7 +109,8 @@ func TestService_Delete_ProducerCalled(t *testing.T) {
 	prod := NewMockProducer(ctrl)
 
 	clk := fixedClock{t: time.Now().UTC()}
-	svc := uc.NewWithDeps(repo, prod, clk, nopLog{}, nopMetric{})
+	var keys keysMetric
+	svc := uc.NewWithDeps(repo, prod, clk, nopLog{}, &keys)
 	order := &entity.Order{
 		ID:     "id-1",
 		UserID: "u1",
@@ -81,9 +118,276 @@ func TestService_Delete_ProducerCalled(t *testing.T) {
 	}
 
 	repo.EXPECT().GetByID(gomock.Any(), "id-1").Return(order, nil)
-	repo.EXPECT().MarkDeleted(gomock.Any(), "id-1", "u1").Return(nil)
+	repo.EXPECT().MarkDeleted(gomock.Any(), "id-1", "u1", clk.t).Return(nil)
 	prod.EXPECT().OrderDeleted(gomock.Any(), "id-1", "u1").Return(nil)
 
 	err := svc.Delete(context.Background(), "u1", "id-1")
 	assert.NoError(t, err)
+	assert.Equal(t, keysMetric{"order.deleted"}, keys)
+}
+
+// itemsETA delivers an hour after now plus a minute per item.
+type itemsETA struct{}
+
+func (itemsETA) Estimate(_ context.Context, o *entity.Order, now time.Time) (time.Time, bool) {
+	return now.Add(time.Hour + time.Duration(len(o.Items))*time.Minute), true
+}
+
+func TestService_EstimatedDelivery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := NewMockRepository(ctrl)
+	prod := NewMockProducer(ctrl)
+	fixed := time.Date(2025, 8, 31, 12, 0, 0, 0, time.UTC)
+	svc := uc.NewWithDeps(repo, prod, fixedClock{t: fixed}, nopLog{}, nopMetric{}, uc.WithEstimator(itemsETA{}))
+
+	repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+	prod.EXPECT().OrderCreated(gomock.Any(), gomock.Any()).Return(nil)
+	o, err := svc.Create(context.Background(), "user-1", uc.CreateInput{
+		RestaurantID: "rest-1",
+		Items:        []entity.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 500}},
+		TotalPrice:   500,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, fixed.Add(61*time.Minute), o.EstimatedDelivery)
+
+	stored := *o
+	items := append(o.Items, entity.Item{FoodID: "f2", Name: "Tea", Quantity: 1, Price: 100})
+	repo.EXPECT().GetByID(gomock.Any(), o.ID).Return(&stored, nil)
+	repo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+	prod.EXPECT().OrderUpdated(gomock.Any(), gomock.Any()).Return(nil)
+	prod.EXPECT().OrderETAChanged(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, o *entity.Order) error {
+		assert.Equal(t, fixed.Add(62*time.Minute), o.EstimatedDelivery)
+		return nil
+	})
+	_, err = svc.Update(context.Background(), "user-1", o.ID, uc.UpdateInput{Items: &items})
+	require.NoError(t, err)
+
+	fio := "Ivanov"
+	again := stored
+	repo.EXPECT().GetByID(gomock.Any(), o.ID).Return(&again, nil)
+	repo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+	prod.EXPECT().OrderUpdated(gomock.Any(), gomock.Any()).Return(nil)
+	_, err = svc.Update(context.Background(), "user-1", o.ID, uc.UpdateInput{FIO: &fio})
+	require.NoError(t, err, "an unchanged estimate publishes no ETA event")
+}
+
+type streetGeocoder map[string]entity.GeoPoint
+
+func (g streetGeocoder) Geocode(_ context.Context, a entity.DeliveryAddress) (*entity.GeoPoint, error) {
+	p, ok := g[a.Street]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+// northZone lets rest-1 deliver north of the equator only.
+type northZone struct{}
+
+func (northZone) Zoned(restaurantID string) bool          { return restaurantID == "rest-1" }
+func (northZone) Covers(_ string, p entity.GeoPoint) bool { return p.Lat > 0 }
+
+func TestService_DeliveryZones(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := NewMockRepository(ctrl)
+	prod := NewMockProducer(ctrl)
+	geocoder := streetGeocoder{"North": {Lat: 10, Lon: 20}, "South": {Lat: -10, Lon: 20}}
+	svc := uc.NewWithDeps(repo, prod, fixedClock{t: time.Now()}, nopLog{}, nopMetric{},
+		uc.WithGeocoder(geocoder), uc.WithZones(northZone{}))
+	create := func(restaurantID string, a entity.DeliveryAddress) (*entity.Order, error) {
+		return svc.Create(context.Background(), "user-1", uc.CreateInput{
+			RestaurantID: restaurantID,
+			Items:        []entity.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 500}},
+			TotalPrice:   500,
+			Address:      a,
+		})
+	}
+
+	repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	prod.EXPECT().OrderCreated(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	o, err := create("rest-1", entity.DeliveryAddress{Street: "North"})
+	require.NoError(t, err)
+	assert.Equal(t, &entity.GeoPoint{Lat: 10, Lon: 20}, o.Address.Location, "geocoded")
+	other, err := create("rest-2", entity.DeliveryAddress{Street: "Nowhere"})
+	require.NoError(t, err, "a restaurant without zones delivers anywhere")
+	assert.Nil(t, other.Address.Location)
+
+	_, err = create("rest-1", entity.DeliveryAddress{Street: "South"})
+	assert.ErrorIs(t, err, entity.ErrInvalidInput)
+	assert.ErrorContains(t, err, "outside the delivery zones of restaurant rest-1")
+	_, err = create("rest-1", entity.DeliveryAddress{Street: "North", Location: &entity.GeoPoint{Lat: -1, Lon: 0}})
+	assert.ErrorContains(t, err, "outside the delivery zones", "a location sent by the client wins over the geocoder")
+	_, err = create("rest-1", entity.DeliveryAddress{Street: "Nowhere"})
+	assert.ErrorContains(t, err, "address could not be located")
+	_, err = create("rest-2", entity.DeliveryAddress{Location: &entity.GeoPoint{Lat: 95, Lon: 0}})
+	assert.ErrorContains(t, err, "address location 95,0 is out of range")
+
+	stored := *o
+	repo.EXPECT().GetByID(gomock.Any(), o.ID).Return(&stored, nil)
+	_, err = svc.Update(context.Background(), "user-1", o.ID, uc.UpdateInput{Address: &entity.DeliveryAddress{Street: "South"}})
+	assert.ErrorIs(t, err, entity.ErrInvalidInput, "an edit cannot move the order out of the zones either")
+}
+
+func TestService_ScheduledOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := NewMockRepository(ctrl)
+	prod := NewMockProducer(ctrl)
+	now := time.Date(2025, 8, 31, 12, 0, 0, 0, time.UTC)
+	h, err := hours.New(time.UTC, "10:00-22:00", map[string]string{"night": "22:00-04:00"})
+	require.NoError(t, err)
+	svc := uc.NewWithDeps(repo, prod, fixedClock{t: now}, nopLog{}, nopMetric{},
+		uc.WithHours(h), uc.WithScheduling(uc.Scheduling{Lead: 45 * time.Minute, MaxAhead: 48 * time.Hour}))
+	in := func(restaurantID string, deliverAt, prepareFrom time.Time) uc.CreateInput {
+		return uc.CreateInput{
+			RestaurantID: restaurantID,
+			Items:        []entity.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 500}},
+			TotalPrice:   500,
+			DeliverAt:    deliverAt,
+			PrepareFrom:  prepareFrom,
+		}
+	}
+
+	repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	prod.EXPECT().OrderCreated(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	o, err := svc.Create(context.Background(), "user-1", in("rest-1", now.Add(3*time.Hour), time.Time{}))
+	require.NoError(t, err)
+	assert.Equal(t, entity.OrderStatusScheduled, o.Status)
+	assert.Equal(t, now.Add(3*time.Hour-45*time.Minute), o.PrepareFrom, "the lead time ahead of the delivery")
+	night, err := svc.Create(context.Background(), "user-1", in("night", time.Time{}, now.Add(11*time.Hour)))
+	require.NoError(t, err, "the night restaurant is open at 23:00")
+	assert.Equal(t, entity.OrderStatusScheduled, night.Status)
+	assert.True(t, night.DeliverAt.IsZero())
+
+	for name, tc := range map[string]struct {
+		in   uc.CreateInput
+		want string
+	}{
+		"both":       {in("rest-1", now.Add(3*time.Hour), now.Add(2*time.Hour)), "set deliver_at or prepare_from, not both"},
+		"too soon":   {in("rest-1", now.Add(30*time.Minute), time.Time{}), "deliver_at 2025-08-31T12:30:00Z is too soon, the earliest is 2025-08-31T12:45:00Z"},
+		"past":       {in("rest-1", time.Time{}, now.Add(-time.Minute)), "prepare_from 2025-08-31T11:59:00Z is not in the future"},
+		"too far":    {in("rest-1", time.Time{}, now.Add(72*time.Hour)), "at most 48h0m0s ahead"},
+		"closed":     {in("rest-1", time.Time{}, now.Add(11*time.Hour)), "restaurant rest-1 is closed at 23:00, it is open 10:00-22:00 (UTC)"},
+		"closed now": {in("night", time.Time{}, time.Time{}), "restaurant night is closed at 12:00"},
+	} {
+		_, err := svc.Create(context.Background(), "user-1", tc.in)
+		assert.ErrorIs(t, err, entity.ErrInvalidInput, name)
+		assert.ErrorContains(t, err, tc.want, name)
+	}
+
+	stored := *o
+	prepareFrom := now.Add(5 * time.Hour)
+	repo.EXPECT().GetByID(gomock.Any(), o.ID).Return(&stored, nil)
+	repo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+	prod.EXPECT().OrderUpdated(gomock.Any(), gomock.Any()).Return(nil)
+	moved, err := svc.Update(context.Background(), "user-1", o.ID, uc.UpdateInput{PrepareFrom: &prepareFrom})
+	require.NoError(t, err)
+	assert.Equal(t, entity.OrderStatusScheduled, moved.Status, "a scheduled order stays scheduled")
+	assert.Equal(t, prepareFrom, moved.PrepareFrom)
+	assert.True(t, moved.DeliverAt.IsZero(), "prepare_from replaces deliver_at")
+
+	cooking := *o
+	cooking.Status = entity.OrderStatusCooking
+	repo.EXPECT().GetByID(gomock.Any(), o.ID).Return(&cooking, nil)
+	_, err = svc.Update(context.Background(), "user-1", o.ID, uc.UpdateInput{PrepareFrom: &prepareFrom})
+	assert.ErrorIs(t, err, entity.ErrConflict)
+}
+
+func TestService_ScheduledLeadFromEstimator(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := NewMockRepository(ctrl)
+	prod := NewMockProducer(ctrl)
+	now := time.Date(2025, 8, 31, 12, 0, 0, 0, time.UTC)
+	svc := uc.NewWithDeps(repo, prod, fixedClock{t: now}, nopLog{}, nopMetric{}, uc.WithEstimator(itemsETA{}))
+
+	repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+	prod.EXPECT().OrderCreated(gomock.Any(), gomock.Any()).Return(nil)
+	o, err := svc.Create(context.Background(), "user-1", uc.CreateInput{
+		RestaurantID: "rest-1",
+		Items:        []entity.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 500}},
+		TotalPrice:   500,
+		DeliverAt:    now.Add(3 * time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(3*time.Hour-61*time.Minute), o.PrepareFrom)
+}
+
+func TestService_Cancel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := NewMockRepository(ctrl)
+	prod := NewMockProducer(ctrl)
+	now := time.Date(2025, 8, 31, 12, 0, 0, 0, time.UTC)
+	var keys keysMetric
+	svc := uc.NewWithDeps(repo, prod, fixedClock{t: now}, nopLog{}, &keys)
+	order := func(status entity.OrderStatus) *entity.Order {
+		return &entity.Order{ID: "o1", UserID: "user-1", Status: status, CreatedAt: now.Add(-time.Minute)}
+	}
+
+	repo.EXPECT().GetByID(gomock.Any(), "o1").Return(order(entity.OrderStatusScheduled), nil)
+	repo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+	prod.EXPECT().OrderStatusChanged(gomock.Any(), gomock.Any()).Return(nil)
+	o, err := svc.Cancel(context.Background(), "user-1", "o1")
+	require.NoError(t, err)
+	assert.Equal(t, entity.OrderStatusCanceled, o.Status)
+	assert.Equal(t, now, o.StatusChangedAt)
+	assert.Equal(t, []string{"order.canceled"}, []string(keys))
+
+	repo.EXPECT().GetByID(gomock.Any(), "o1").Return(order(entity.OrderStatusCooking), nil)
+	_, err = svc.Cancel(context.Background(), "user-1", "o1")
+	assert.ErrorIs(t, err, entity.ErrConflict)
+
+	repo.EXPECT().GetByID(gomock.Any(), "o1").Return(order(entity.OrderStatusCreated), nil)
+	_, err = svc.Cancel(context.Background(), "user-2", "o1")
+	assert.ErrorIs(t, err, entity.ErrForeignOwnership)
+
+	_, err = svc.Cancel(context.Background(), "", "o1")
+	assert.ErrorIs(t, err, entity.ErrUnauthorized)
+}
+
+func TestService_UpdateAfterCancel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := NewMockRepository(ctrl)
+	prod := NewMockProducer(ctrl)
+	now := time.Date(2025, 8, 31, 12, 0, 0, 0, time.UTC)
+	svc := uc.NewWithDeps(repo, prod, fixedClock{t: now}, nopLog{}, nopMetric{})
+	o := &entity.Order{ID: "o1", UserID: "user-1", Status: entity.OrderStatusCreated, CreatedAt: now.Add(-time.Minute)}
+
+	repo.EXPECT().GetByID(gomock.Any(), "o1").Return(o, nil).Times(2)
+	repo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+	prod.EXPECT().OrderStatusChanged(gomock.Any(), gomock.Any()).Return(nil)
+	_, err := svc.Cancel(context.Background(), "user-1", "o1")
+	require.NoError(t, err)
+
+	fio := "New Name"
+	_, err = svc.Update(context.Background(), "user-1", "o1", uc.UpdateInput{FIO: &fio})
+	assert.ErrorIs(t, err, entity.ErrConflict)
+	assert.Equal(t, entity.OrderStatusCanceled, o.Status)
+}
+
+func TestService_UpdateAfterCompleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := NewMockRepository(ctrl)
+	prod := NewMockProducer(ctrl)
+	now := time.Date(2025, 8, 31, 12, 0, 0, 0, time.UTC)
+	svc := uc.NewWithDeps(repo, prod, fixedClock{t: now}, nopLog{}, nopMetric{})
+	o := &entity.Order{ID: "o1", UserID: "user-1", Status: entity.OrderStatusCompleted, CreatedAt: now.Add(-time.Hour)}
+
+	repo.EXPECT().GetByID(gomock.Any(), "o1").Return(o, nil)
+	fio := "New Name"
+	_, err := svc.Update(context.Background(), "user-1", "o1", uc.UpdateInput{FIO: &fio})
+	assert.ErrorIs(t, err, entity.ErrConflict)
+	assert.Equal(t, entity.OrderStatusCompleted, o.Status)
 }
@@ -0,0 +1,105 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+type Repository interface {
+	ListFrom(ctx context.Context, from time.Time) ([]*entity.Order, error)
+}
+
+type Producer interface {
+	OrderSnapshot(ctx context.Context, o *entity.Order, replayID string) error
+}
+
+type Clock interface{ Now() time.Time }
+
+// Filter selects the orders to replay; zero fields match every order.
+type Filter struct {
+	// From and To bound created_at: From <= created_at < To.
+	From         time.Time
+	To           time.Time
+	Statuses     []entity.OrderStatus
+	RestaurantID string
+}
+
+type State string
+
+const (
+	StateRunning  State = "running"
+	StateDone     State = "done"
+	StateFailed   State = "failed"
+	StateCanceled State = "canceled"
+)
+
+// Job is the progress of one replay.
+type Job struct {
+	ID     string
+	Filter Filter
+	// Rate is the limit in events per second.
+	Rate       float64
+	State      State
+	Total      int
+	Published  int
+	StartedAt  time.Time
+	FinishedAt time.Time // zero while running
+	Error      string
+}
+
+type Service interface {
+	// Start selects the orders matching f and republishes a snapshot of each
+	// in the background, at most rate per second; rate <= 0 means the
+	// configured default. Only one replay runs at a time.
+	Start(ctx context.Context, f Filter, rate float64) (Job, error)
+	Get(id string) (Job, error)
+	// List returns the recent jobs, newest first.
+	List() []Job
+	Cancel(id string) (Job, error)
+	// Stop cancels the running job and waits for it, e.g. on shutdown.
+	Stop(ctx context.Context) error
+}
+
+// ErrRunning wraps entity.ErrConflict: another replay has not finished yet.
+var ErrRunning = fmt.Errorf("%w: a replay is already running", entity.ErrConflict)
+
+const (
+	defaultRate = 100
+	// keep is how many finished jobs List remembers.
+	keep = 20
+)
+
+// Limits bounds the rate a caller may ask for.
+type Limits struct {
+	Rate    float64 // default rate
+	MaxRate float64
+}
+
+type service struct {
+	repo   Repository
+	prod   Producer
+	clk    Clock
+	limits Limits
+
+	mu   sync.Mutex
+	jobs []*job // oldest first
+	wg   sync.WaitGroup
+}
+
+func New(repo Repository, prod Producer, clk Clock) Service {
+	return NewWithLimits(repo, prod, clk, Limits{})
+}
+
+func NewWithLimits(repo Repository, prod Producer, clk Clock, l Limits) Service {
+	if l.Rate <= 0 {
+		l.Rate = defaultRate
+	}
+	if l.MaxRate < l.Rate {
+		l.MaxRate = l.Rate
+	}
+	return &service{repo: repo, prod: prod, clk: clk, limits: l}
+}
@@ -0,0 +1,189 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nikolaev/service-order/internal/domain/entity"
+)
+
+type job struct {
+	Job
+	cancel context.CancelFunc
+	done   chan struct{} // closed once the final state is recorded
+}
+
+func (s *service) Start(ctx context.Context, f Filter, rate float64) (Job, error) {
+	if !f.To.IsZero() && !f.From.Before(f.To) {
+		return Job{}, fmt.Errorf("%w: from must be before to", entity.ErrInvalidInput)
+	}
+	if rate <= 0 {
+		rate = s.limits.Rate
+	}
+	if rate > s.limits.MaxRate {
+		return Job{}, fmt.Errorf("%w: rate %g exceeds the limit %g per second", entity.ErrInvalidInput, rate, s.limits.MaxRate)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.State == StateRunning {
+			return Job{}, ErrRunning
+		}
+	}
+	// Taken under the lock, so that two replays cannot select at once.
+	orders, err := s.repo.ListFrom(ctx, f.From)
+	if err != nil {
+		return Job{}, err
+	}
+	orders = slices.DeleteFunc(orders, func(o *entity.Order) bool { return !f.match(o) })
+	// Oldest first, like the original events.
+	sort.SliceStable(orders, func(i, j int) bool { return orders[i].CreatedAt.Before(orders[j].CreatedAt) })
+
+	// The job outlives the request but keeps its trace and log fields.
+	jctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	j := &job{
+		Job: Job{
+			ID:        uuid.NewString(),
+			Filter:    f,
+			Rate:      rate,
+			State:     StateRunning,
+			Total:     len(orders),
+			StartedAt: s.clk.Now(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	s.jobs = append(s.jobs, j)
+	if len(s.jobs) > keep {
+		s.jobs = s.jobs[len(s.jobs)-keep:]
+	}
+
+	s.wg.Add(1)
+	go s.run(jctx, j, orders)
+	slog.InfoContext(ctx, "replay started", "replay_id", j.ID, "orders", j.Total, "rate", rate)
+	return j.Job, nil
+}
+
+func (f Filter) match(o *entity.Order) bool {
+	switch {
+	case !f.To.IsZero() && !o.CreatedAt.Before(f.To):
+		return false
+	case len(f.Statuses) > 0 && !slices.Contains(f.Statuses, o.Status):
+		return false
+	case f.RestaurantID != "" && o.RestaurantID != f.RestaurantID:
+		return false
+	}
+	return true
+}
+
+// run publishes the snapshots, one per tick of the rate limit.
+func (s *service) run(ctx context.Context, j *job, orders []*entity.Order) {
+	defer s.wg.Done()
+	defer j.cancel()
+
+	tick := time.NewTicker(time.Duration(float64(time.Second) / j.Rate))
+	defer tick.Stop()
+	var err error
+	for _, o := range orders {
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if err == nil {
+			err = s.prod.OrderSnapshot(ctx, o, j.ID)
+		}
+		if err != nil {
+			break
+		}
+		s.mu.Lock()
+		j.Published++
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer close(j.done)
+	j.FinishedAt = s.clk.Now()
+	switch {
+	case err == nil:
+		j.State = StateDone
+	case errors.Is(err, context.Canceled):
+		j.State = StateCanceled
+	default:
+		j.State = StateFailed
+		j.Error = err.Error()
+	}
+	slog.InfoContext(ctx, "replay finished", "replay_id", j.ID, "state", j.State, "published", j.Published, "total", j.Total)
+}
+
+func (s *service) find(id string) (*job, error) {
+	for _, j := range s.jobs {
+		if j.ID == id {
+			return j, nil
+		}
+	}
+	return nil, entity.ErrNotFound
+}
+
+func (s *service) Get(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, err := s.find(id)
+	if err != nil {
+		return Job{}, err
+	}
+	return j.Job, nil
+}
+
+func (s *service) List() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Job, 0, len(s.jobs))
+	for i := len(s.jobs) - 1; i >= 0; i-- {
+		out = append(out, s.jobs[i].Job)
+	}
+	return out
+}
+
+// Cancel stops a running job and waits until it has recorded its state; a
+// finished job is returned as is.
+func (s *service) Cancel(id string) (Job, error) {
+	s.mu.Lock()
+	j, err := s.find(id)
+	s.mu.Unlock()
+	if err != nil {
+		return Job{}, err
+	}
+	j.cancel()
+	<-j.done
+	return s.Get(id)
+}
+
+func (s *service) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	for _, j := range s.jobs {
+		j.cancel()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
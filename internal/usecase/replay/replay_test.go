@@ -0,0 +1,160 @@
+package replay_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/clock"
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/usecase/replay"
+)
+
+type fakeRepo []*entity.Order
+
+func (r fakeRepo) ListFrom(_ context.Context, from time.Time) ([]*entity.Order, error) {
+	var out []*entity.Order
+	for _, o := range r {
+		if !o.CreatedAt.Before(from) {
+			cp := *o
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+type recorder struct {
+	mu   sync.Mutex
+	ids  []string
+	jobs map[string]bool
+	err  error
+}
+
+func (p *recorder) OrderSnapshot(_ context.Context, o *entity.Order, replayID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.ids = append(p.ids, o.ID)
+	if p.jobs == nil {
+		p.jobs = map[string]bool{}
+	}
+	p.jobs[replayID] = true
+	return nil
+}
+
+func (p *recorder) published() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.ids...)
+}
+
+var t0 = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func orders() fakeRepo {
+	return fakeRepo{
+		{ID: "o3", RestaurantID: "r1", Status: entity.OrderStatusCooking, CreatedAt: t0.Add(3 * time.Hour)},
+		{ID: "o1", RestaurantID: "r1", Status: entity.OrderStatusPending, CreatedAt: t0.Add(1 * time.Hour)},
+		{ID: "o2", RestaurantID: "r2", Status: entity.OrderStatusPending, CreatedAt: t0.Add(2 * time.Hour)},
+		{ID: "o0", RestaurantID: "r1", Status: entity.OrderStatusPending, CreatedAt: t0},
+		{ID: "o4", RestaurantID: "r1", Status: entity.OrderStatusPending, CreatedAt: t0.Add(4 * time.Hour)},
+	}
+}
+
+func wait(t *testing.T, svc replay.Service, id string) replay.Job {
+	t.Helper()
+	var job replay.Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = svc.Get(id)
+		require.NoError(t, err)
+		return job.State != replay.StateRunning
+	}, 2*time.Second, time.Millisecond)
+	return job
+}
+
+func TestReplay_FiltersAndPublishesOldestFirst(t *testing.T) {
+	prod := &recorder{}
+	svc := replay.NewWithLimits(orders(), prod, clock.NewVirtual(t0, true), replay.Limits{Rate: 1000})
+
+	job, err := svc.Start(context.Background(), replay.Filter{
+		From:         t0.Add(time.Hour),
+		To:           t0.Add(4 * time.Hour),
+		Statuses:     []entity.OrderStatus{entity.OrderStatusPending, entity.OrderStatusCooking},
+		RestaurantID: "r1",
+	}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, replay.StateRunning, job.State)
+	assert.Equal(t, 2, job.Total)
+	assert.Equal(t, float64(1000), job.Rate)
+
+	job = wait(t, svc, job.ID)
+	assert.Equal(t, replay.StateDone, job.State)
+	assert.Equal(t, 2, job.Published)
+	assert.Equal(t, []string{"o1", "o3"}, prod.published())
+	assert.Equal(t, map[string]bool{job.ID: true}, prod.jobs)
+	assert.Equal(t, []replay.Job{job}, svc.List())
+}
+
+func TestReplay_RateLimited(t *testing.T) {
+	prod := &recorder{}
+	svc := replay.NewWithLimits(orders(), prod, clock.NewVirtual(t0, true), replay.Limits{Rate: 50})
+
+	start := time.Now()
+	job, err := svc.Start(context.Background(), replay.Filter{}, 0)
+	require.NoError(t, err)
+	job = wait(t, svc, job.ID)
+	assert.Equal(t, 5, job.Published)
+	// One event per 20ms.
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	_, err = svc.Start(context.Background(), replay.Filter{}, 51)
+	assert.ErrorIs(t, err, entity.ErrInvalidInput, "above the limit")
+}
+
+func TestReplay_OneAtATimeAndCancel(t *testing.T) {
+	prod := &recorder{}
+	svc := replay.NewWithLimits(orders(), prod, clock.NewVirtual(t0, true), replay.Limits{Rate: 10})
+
+	job, err := svc.Start(context.Background(), replay.Filter{}, 0)
+	require.NoError(t, err)
+	_, err = svc.Start(context.Background(), replay.Filter{}, 0)
+	assert.ErrorIs(t, err, entity.ErrConflict)
+
+	job, err = svc.Cancel(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, replay.StateCanceled, job.State)
+	assert.Less(t, job.Published, job.Total)
+	assert.False(t, job.FinishedAt.IsZero())
+
+	_, err = svc.Start(context.Background(), replay.Filter{}, 0)
+	assert.NoError(t, err, "a finished replay does not block the next one")
+	require.NoError(t, svc.Stop(context.Background()))
+
+	_, err = svc.Get("missing")
+	assert.ErrorIs(t, err, entity.ErrNotFound)
+}
+
+func TestReplay_ProducerErrorFailsJob(t *testing.T) {
+	prod := &recorder{err: errors.New("outbox is closed")}
+	svc := replay.NewWithLimits(orders(), prod, clock.NewVirtual(t0, true), replay.Limits{Rate: 1000})
+
+	job, err := svc.Start(context.Background(), replay.Filter{}, 0)
+	require.NoError(t, err)
+	job = wait(t, svc, job.ID)
+	assert.Equal(t, replay.StateFailed, job.State)
+	assert.Equal(t, "outbox is closed", job.Error)
+	assert.Zero(t, job.Published)
+}
+
+func TestReplay_InvalidRange(t *testing.T) {
+	svc := replay.New(orders(), &recorder{}, clock.NewVirtual(t0, true))
+	_, err := svc.Start(context.Background(), replay.Filter{From: t0, To: t0}, 0)
+	assert.ErrorIs(t, err, entity.ErrInvalidInput)
+}
@@ -0,0 +1,179 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nikolaev/service-order/internal/clock"
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/logging"
+)
+
+type ETAStore interface {
+	ListFrom(ctx context.Context, from time.Time) ([]*entity.Order, error)
+	SetEstimatedDelivery(ctx context.Context, id string, eta time.Time, at time.Time) (*entity.Order, error)
+}
+
+type ETAProducer interface {
+	OrderETAChanged(ctx context.Context, o *entity.Order) error
+}
+
+type Estimator interface {
+	Observe(orders []*entity.Order)
+	Estimate(ctx context.Context, o *entity.Order, now time.Time) (time.Time, bool)
+}
+
+// ETAWorker periodically re-estimates the delivery time of every order and
+// stores and publishes the estimates that moved by at least minChange, so
+// that small drifts of a waiting order do not flood the consumers.
+type ETAWorker struct {
+	tick      time.Duration
+	minChange time.Duration
+	repo      ETAStore
+	prod      ETAProducer
+	est       Estimator
+	clock     Clock
+
+	mu      sync.Mutex // serializes ticks with Refresh
+	running bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+func NewETAWorker(tick, minChange time.Duration, repo ETAStore, prod ETAProducer, est Estimator) *ETAWorker {
+	return &ETAWorker{tick: tick, minChange: minChange, repo: repo, prod: prod, est: est, clock: clock.System{}}
+}
+
+// WithClock replaces the wall clock the estimates are made on; call it
+// before Start.
+func (w *ETAWorker) WithClock(c Clock) *ETAWorker {
+	w.clock = c
+	return w
+}
+
+func (w *ETAWorker) Start(context.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+	w.mu.Lock()
+	w.running = true
+	w.mu.Unlock()
+	go w.run(ctx)
+	return nil
+}
+
+// Stop cancels the loop and waits for the tick in progress.
+func (w *ETAWorker) Stop(ctx context.Context) error {
+	if w.cancel == nil {
+		return nil
+	}
+	w.cancel()
+	w.mu.Lock()
+	w.running = false
+	w.mu.Unlock()
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *ETAWorker) run(ctx context.Context) {
+	defer close(w.done)
+	ticker := time.NewTicker(w.tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			w.refresh(context.WithoutCancel(ctx))
+			w.mu.Unlock()
+		}
+	}
+}
+
+// Refresh re-estimates right away, e.g. after the clock was moved, and
+// returns how many estimates changed. It does nothing unless the worker is
+// running.
+func (w *ETAWorker) Refresh(ctx context.Context) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.running {
+		return 0
+	}
+	return w.refresh(ctx)
+}
+
+// refresh is one tick; w.mu is held.
+func (w *ETAWorker) refresh(ctx context.Context) int {
+	orders, err := w.repo.ListFrom(ctx, time.Time{})
+	if err != nil {
+		slog.ErrorContext(ctx, "eta: list orders", "error", err)
+		return 0
+	}
+	w.est.Observe(orders)
+	now := w.clock.Now()
+	changed := 0
+	for _, o := range orders {
+		if settled(o) {
+			continue
+		}
+		eta, ok := w.est.Estimate(ctx, o, now)
+		if !ok || !w.moved(o, eta) {
+			continue
+		}
+		if w.store(ctx, o.ID, eta, now) {
+			changed++
+		}
+	}
+	return changed
+}
+
+// settled reports whether o needs no estimate anymore: canceled and deleted
+// orders have none, and a completed one already carries the time it was
+// delivered.
+func settled(o *entity.Order) bool {
+	switch o.Status {
+	case entity.OrderStatusCanceled, entity.OrderStatusDeleted:
+		return true
+	case entity.OrderStatusCompleted, entity.OrderStatusDelivered:
+		return o.EstimatedDelivery.Equal(o.StatusChangedAt.UTC().Truncate(time.Second))
+	}
+	return o.IsDeleted
+}
+
+// moved reports whether eta is worth publishing. The delivery time of a
+// completed order is exact, so any difference counts.
+func (w *ETAWorker) moved(o *entity.Order, eta time.Time) bool {
+	old := o.EstimatedDelivery
+	switch {
+	case old.IsZero():
+		return true
+	case o.Status == entity.OrderStatusCompleted || o.Status == entity.OrderStatusDelivered:
+		return !old.Equal(eta)
+	}
+	d := eta.Sub(old)
+	return d >= w.minChange || -d >= w.minChange
+}
+
+func (w *ETAWorker) store(ctx context.Context, id string, eta, now time.Time) bool {
+	ctx = logging.With(ctx, "order_id", id)
+	o, err := w.repo.SetEstimatedDelivery(ctx, id, eta, now)
+	if errors.Is(err, entity.ErrNotFound) {
+		return false // deleted since the list
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "eta: store estimate", "error", err)
+		return false
+	}
+	if err := w.prod.OrderETAChanged(ctx, o); err != nil {
+		slog.ErrorContext(ctx, "publish order eta", "error", err)
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package worker_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/clock"
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	repo "github.com/nikolaev/service-order/internal/repository/order"
+	"github.com/nikolaev/service-order/internal/worker"
+)
+
+// fixedEstimator returns eta for every order and records which it was
+// asked about.
+type fixedEstimator struct {
+	mu    sync.Mutex
+	eta   time.Time
+	asked []string
+}
+
+func (e *fixedEstimator) Observe([]*entity.Order) {}
+
+func (e *fixedEstimator) Estimate(_ context.Context, o *entity.Order, _ time.Time) (time.Time, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.asked = append(e.asked, o.ID)
+	return e.eta, true
+}
+
+func (e *fixedEstimator) set(eta time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.eta = eta
+	e.asked = nil
+}
+
+func startETAWorker(t *testing.T, mem *repo.InMemory, est worker.Estimator) (*worker.ETAWorker, *recorder) {
+	t.Helper()
+	rec := &recorder{}
+	w := worker.NewETAWorker(time.Hour, time.Minute, mem, rec, est).WithClock(clock.NewVirtual(t0, true))
+	require.NoError(t, w.Start(context.Background()))
+	t.Cleanup(func() { _ = w.Stop(context.Background()) })
+	return w, rec
+}
+
+func TestETAWorker_RefreshPublishesMovedEstimates(t *testing.T) {
+	mem := repo.NewInMemory()
+	require.NoError(t, mem.Create(context.Background(), &entity.Order{ID: "o1", Status: entity.OrderStatusCooking, CreatedAt: t0}))
+	est := &fixedEstimator{eta: t0.Add(30 * time.Minute)}
+	w, rec := startETAWorker(t, mem, est)
+
+	assert.Equal(t, 1, w.Refresh(context.Background()))
+	o, err := mem.GetByID(context.Background(), "o1")
+	require.NoError(t, err)
+	assert.Equal(t, t0.Add(30*time.Minute), o.EstimatedDelivery)
+
+	// Below eta.min_change the stored estimate stays.
+	est.set(t0.Add(30*time.Minute + 30*time.Second))
+	assert.Equal(t, 0, w.Refresh(context.Background()))
+
+	est.set(t0.Add(35 * time.Minute))
+	assert.Equal(t, 1, w.Refresh(context.Background()))
+	assert.Equal(t, []string{"o1 2026-10-01T12:30:00Z", "o1 2026-10-01T12:35:00Z"}, rec.events())
+}
+
+func TestETAWorker_RefreshSkipsFinishedOrders(t *testing.T) {
+	mem := repo.NewInMemory()
+	done := t0.Add(-time.Hour)
+	for _, o := range []*entity.Order{
+		{ID: "canceled", Status: entity.OrderStatusCanceled, CreatedAt: t0},
+		{ID: "deleted", Status: entity.OrderStatusCreated, CreatedAt: t0},
+		{ID: "completed", Status: entity.OrderStatusCompleted, CreatedAt: t0, StatusChangedAt: done, EstimatedDelivery: done},
+		// Completed since the last estimate: it still gets the exact time.
+		{ID: "just-completed", Status: entity.OrderStatusCompleted, CreatedAt: t0, StatusChangedAt: done, EstimatedDelivery: t0},
+		{ID: "cooking", Status: entity.OrderStatusCooking, CreatedAt: t0},
+	} {
+		require.NoError(t, mem.Create(context.Background(), o))
+	}
+	require.NoError(t, mem.MarkDeleted(context.Background(), "deleted", "", t0))
+	est := &fixedEstimator{eta: done}
+	w, _ := startETAWorker(t, mem, est)
+
+	assert.Equal(t, 2, w.Refresh(context.Background()))
+	assert.ElementsMatch(t, []string{"just-completed", "cooking"}, est.asked)
+}
+
+func TestETAWorker_RefreshOnlyWhileRunning(t *testing.T) {
+	mem := repo.NewInMemory()
+	require.NoError(t, mem.Create(context.Background(), &entity.Order{ID: "o1", Status: entity.OrderStatusCooking, CreatedAt: t0}))
+	est := &fixedEstimator{eta: t0.Add(30 * time.Minute)}
+	w := worker.NewETAWorker(time.Hour, time.Minute, mem, &recorder{}, est)
+	require.NoError(t, w.Stop(context.Background()), "stop before start")
+	assert.Equal(t, 0, w.Refresh(context.Background()))
+
+	require.NoError(t, w.Start(context.Background()))
+	require.NoError(t, w.Stop(context.Background()))
+	assert.Equal(t, 0, w.Refresh(context.Background()))
+	assert.Empty(t, est.asked)
+}
+
+func TestETAWorker_Ticks(t *testing.T) {
+	mem := repo.NewInMemory()
+	require.NoError(t, mem.Create(context.Background(), &entity.Order{ID: "o1", Status: entity.OrderStatusCooking, CreatedAt: t0}))
+	rec := &recorder{}
+	w := worker.NewETAWorker(5*time.Millisecond, time.Minute, mem, rec, &fixedEstimator{eta: t0.Add(30 * time.Minute)})
+	require.NoError(t, w.Start(context.Background()))
+	defer func() { require.NoError(t, w.Stop(context.Background())) }()
+
+	require.Eventually(t, func() bool { return len(rec.events()) == 1 }, time.Second, 5*time.Millisecond)
+}
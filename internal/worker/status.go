@@ -0,0 +1,192 @@
+// Package worker contains background jobs of the service.
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/nikolaev/service-order/internal/clock"
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/logging"
+)
+
+type Advancer interface {
+	AdvanceStatuses(now time.Time) []*entity.Order
+}
+
+type Producer interface {
+	OrderStatusChanged(ctx context.Context, o *entity.Order) error
+}
+
+// Clock dates the ticks; the ticker itself and the heartbeat stay on wall
+// time, so a frozen clock does not look like a stuck worker.
+type Clock interface {
+	Now() time.Time
+}
+
+type metric interface {
+	ObserveTick(d time.Duration, changed int)
+}
+
+type noopMetric struct{}
+
+func (noopMetric) ObserveTick(time.Duration, int) {}
+
+// StatusWorker periodically moves orders along the status timeline and
+// publishes every change.
+type StatusWorker struct {
+	tick   time.Duration
+	repo   Advancer
+	prod   Producer
+	metric metric
+	tracer trace.Tracer
+	clock  Clock
+
+	mu        sync.Mutex // serializes ticks with CatchUp
+	running   bool
+	cancel    context.CancelFunc
+	done      chan struct{}
+	heartbeat atomic.Int64 // unix nanos of the last finished tick
+}
+
+func NewStatusWorker(tick time.Duration, repo Advancer, prod Producer) *StatusWorker {
+	return NewStatusWorkerWithDeps(tick, repo, prod, noopMetric{}, noop.NewTracerProvider())
+}
+
+func NewStatusWorkerWithDeps(tick time.Duration, repo Advancer, prod Producer, m metric, tp trace.TracerProvider) *StatusWorker {
+	return &StatusWorker{
+		tick: tick, repo: repo, prod: prod, metric: m, clock: clock.System{},
+		tracer: tp.Tracer("github.com/nikolaev/service-order/internal/worker"),
+	}
+}
+
+// WithClock replaces the wall clock the ticks read; call it before Start.
+func (w *StatusWorker) WithClock(c Clock) *StatusWorker {
+	w.clock = c
+	return w
+}
+
+func (w *StatusWorker) Start(context.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+	w.heartbeat.Store(time.Now().UnixNano())
+	w.mu.Lock()
+	w.running = true
+	w.mu.Unlock()
+	go w.run(ctx)
+	return nil
+}
+
+// Stop cancels the loop and waits for the tick in progress, so changes that
+// were already applied to the repository are published before it returns.
+func (w *StatusWorker) Stop(ctx context.Context) error {
+	if w.cancel == nil {
+		return nil
+	}
+	w.cancel()
+	w.mu.Lock()
+	w.running = false
+	w.mu.Unlock()
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *StatusWorker) run(ctx context.Context) {
+	defer close(w.done)
+	ticker := time.NewTicker(w.tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Publish with a detached context: the changes are stored already.
+			w.step(context.WithoutCancel(ctx))
+		}
+	}
+}
+
+// CatchUp runs a tick right away, e.g. after the clock was moved forward,
+// and returns once its changes are published. It does nothing unless the
+// worker is running.
+func (w *StatusWorker) CatchUp(ctx context.Context) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.running {
+		w.advance(ctx)
+	}
+}
+
+func (w *StatusWorker) step(ctx context.Context) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.advance(ctx)
+}
+
+// advance is one tick; w.mu is held.
+func (w *StatusWorker) advance(ctx context.Context) {
+	start := time.Now()
+	changed := w.repo.AdvanceStatuses(w.clock.Now())
+	for _, o := range changed {
+		w.publish(ctx, o)
+	}
+	w.metric.ObserveTick(time.Since(start), len(changed))
+	w.heartbeat.Store(time.Now().UnixNano())
+}
+
+// publish reports one auto-advance. Each gets its own trace, which the
+// producer continues into the Kafka message headers.
+func (w *StatusWorker) publish(ctx context.Context, o *entity.Order) {
+	ctx, span := w.tracer.Start(ctx, "StatusWorker/advance", trace.WithAttributes(
+		attribute.String("order.id", o.ID),
+		attribute.String("order.status", string(o.Status)),
+	))
+	defer span.End()
+
+	ctx = logging.With(ctx, "order_id", o.ID)
+	slog.InfoContext(ctx, "order status advanced", "status", o.Status)
+	if err := w.prod.OrderStatusChanged(ctx, o); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		slog.ErrorContext(ctx, "publish order status", "error", err)
+	}
+}
+
+// LastHeartbeat returns when the worker last finished a tick, or zero time
+// before Start.
+func (w *StatusWorker) LastHeartbeat() time.Time {
+	n := w.heartbeat.Load()
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// CheckHeartbeat returns a health check that fails when the last tick is
+// older than maxAge, i.e. the loop is stuck or has exited.
+func (w *StatusWorker) CheckHeartbeat(maxAge time.Duration) func(context.Context) error {
+	return func(context.Context) error {
+		last := w.LastHeartbeat()
+		if last.IsZero() {
+			return fmt.Errorf("status worker is not started")
+		}
+		if age := time.Since(last); age > maxAge {
+			return fmt.Errorf("status worker heartbeat is %s old (max %s)", age.Round(time.Millisecond), maxAge)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,126 @@
+package worker_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/clock"
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	repo "github.com/nikolaev/service-order/internal/repository/order"
+	"github.com/nikolaev/service-order/internal/worker"
+)
+
+var t0 = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// recorder collects the published changes as "id status".
+type recorder struct {
+	mu  sync.Mutex
+	got []string
+}
+
+func (r *recorder) add(s string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.got = append(r.got, s)
+}
+
+func (r *recorder) events() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.got...)
+}
+
+func (r *recorder) OrderStatusChanged(_ context.Context, o *entity.Order) error {
+	r.add(o.ID + " " + string(o.Status))
+	return nil
+}
+
+func (r *recorder) OrderETAChanged(_ context.Context, o *entity.Order) error {
+	r.add(o.ID + " " + o.EstimatedDelivery.Format(time.RFC3339))
+	return nil
+}
+
+func startStatusWorker(t *testing.T, tick time.Duration, mem *repo.InMemory, vc *clock.Virtual) (*worker.StatusWorker, *recorder) {
+	t.Helper()
+	rec := &recorder{}
+	w := worker.NewStatusWorker(tick, mem, rec).WithClock(vc)
+	require.NoError(t, w.Start(context.Background()))
+	t.Cleanup(func() { _ = w.Stop(context.Background()) })
+	return w, rec
+}
+
+func TestStatusWorker_ActivatesScheduledOrder(t *testing.T) {
+	mem := repo.NewInMemory()
+	vc := clock.NewVirtual(t0, true)
+	require.NoError(t, mem.Create(context.Background(), &entity.Order{
+		ID: "o1", Status: entity.OrderStatusScheduled, CreatedAt: t0, PrepareFrom: t0.Add(time.Hour),
+	}))
+	w, rec := startStatusWorker(t, time.Hour, mem, vc)
+
+	w.CatchUp(context.Background())
+	assert.Empty(t, rec.events())
+
+	_, err := vc.Advance(time.Hour)
+	require.NoError(t, err)
+	w.CatchUp(context.Background())
+	assert.Equal(t, []string{"o1 created"}, rec.events())
+
+	o, err := mem.GetByID(context.Background(), "o1")
+	require.NoError(t, err)
+	assert.Equal(t, t0.Add(time.Hour), o.StatusChangedAt)
+}
+
+func TestStatusWorker_CatchUpReportsEveryStatus(t *testing.T) {
+	mem := repo.NewInMemory()
+	vc := clock.NewVirtual(t0, true)
+	require.NoError(t, mem.Create(context.Background(), &entity.Order{
+		ID: "o1", Status: entity.OrderStatusCreated, CreatedAt: t0, StatusChangedAt: t0,
+	}))
+	w, rec := startStatusWorker(t, time.Hour, mem, vc)
+
+	_, err := vc.Advance(24 * time.Hour)
+	require.NoError(t, err)
+	w.CatchUp(context.Background())
+	assert.Equal(t, []string{"o1 pending", "o1 confirmed", "o1 cooking", "o1 delivering", "o1 completed"}, rec.events())
+}
+
+func TestStatusWorker_Ticks(t *testing.T) {
+	mem := repo.NewInMemory()
+	vc := clock.NewVirtual(t0, true)
+	require.NoError(t, mem.Create(context.Background(), &entity.Order{
+		ID: "o1", Status: entity.OrderStatusCreated, CreatedAt: t0, StatusChangedAt: t0,
+	}))
+	w, rec := startStatusWorker(t, 5*time.Millisecond, mem, vc)
+	require.NoError(t, w.CheckHeartbeat(time.Minute)(context.Background()))
+
+	_, err := vc.Advance(time.Second)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(rec.events()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"o1 pending"}, rec.events())
+}
+
+func TestStatusWorker_Stop(t *testing.T) {
+	mem := repo.NewInMemory()
+	vc := clock.NewVirtual(t0, true)
+	require.NoError(t, mem.Create(context.Background(), &entity.Order{
+		ID: "o1", Status: entity.OrderStatusCreated, CreatedAt: t0, StatusChangedAt: t0,
+	}))
+	rec := &recorder{}
+	w := worker.NewStatusWorker(time.Hour, mem, rec).WithClock(vc)
+	require.NoError(t, w.Stop(context.Background()), "stop before start")
+	require.Error(t, w.CheckHeartbeat(time.Minute)(context.Background()))
+
+	require.NoError(t, w.Start(context.Background()))
+	require.NoError(t, w.Stop(context.Background()))
+
+	// A stopped worker no longer catches up.
+	_, err := vc.Advance(time.Hour)
+	require.NoError(t, err)
+	w.CatchUp(context.Background())
+	assert.Empty(t, rec.events())
+}
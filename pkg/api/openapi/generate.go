@@ -0,0 +1,7 @@
+// Package openapi holds the HTTP API types, chi strict server and typed client
+// generated from api_openapi.yaml. Run `make generate` after editing the spec.
+package openapi
+
+//go:generate oapi-codegen -config types.cfg.yaml ../../../api_openapi.yaml
+//go:generate oapi-codegen -config server.cfg.yaml ../../../api_openapi.yaml
+//go:generate oapi-codegen -config client.cfg.yaml ../../../api_openapi.yaml
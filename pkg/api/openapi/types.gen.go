@@ -0,0 +1,295 @@
+// Package openapi provides primitives to interact with the openapi HTTP API.
+//
+// Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.4.1 DO NOT EDIT.
+package openapi
+
+import (
+	"time"
+)
+
+const (
+	AdminTokenScopes = "adminToken.Scopes"
+	BypassAuthScopes = "bypassAuth.Scopes"
+	UserIdScopes     = "userId.Scopes"
+)
+
+// Defines values for OrderStatus.
+const (
+	OrderStatusCanceled   OrderStatus = "canceled"
+	OrderStatusCompleted  OrderStatus = "completed"
+	OrderStatusConfirmed  OrderStatus = "confirmed"
+	OrderStatusCooking    OrderStatus = "cooking"
+	OrderStatusCreated    OrderStatus = "created"
+	OrderStatusDeleted    OrderStatus = "deleted"
+	OrderStatusDelivered  OrderStatus = "delivered"
+	OrderStatusDelivering OrderStatus = "delivering"
+	OrderStatusPending    OrderStatus = "pending"
+	OrderStatusScheduled  OrderStatus = "scheduled"
+	OrderStatusUpdated    OrderStatus = "updated"
+)
+
+// Defines values for ReplayJobState.
+const (
+	ReplayJobStateCanceled ReplayJobState = "canceled"
+	ReplayJobStateDone     ReplayJobState = "done"
+	ReplayJobStateFailed   ReplayJobState = "failed"
+	ReplayJobStateRunning  ReplayJobState = "running"
+)
+
+// Defines values for WebhookDeliveryState.
+const (
+	WebhookDeliveryStateDead      WebhookDeliveryState = "dead"
+	WebhookDeliveryStateDelivered WebhookDeliveryState = "delivered"
+	WebhookDeliveryStatePending   WebhookDeliveryState = "pending"
+	WebhookDeliveryStateRetrying  WebhookDeliveryState = "retrying"
+)
+
+// Defines values for WebhookEvent.
+const (
+	WebhookEventCanceled      WebhookEvent = "canceled"
+	WebhookEventCreated       WebhookEvent = "created"
+	WebhookEventDeleted       WebhookEvent = "deleted"
+	WebhookEventEtaChanged    WebhookEvent = "eta_changed"
+	WebhookEventStatusChanged WebhookEvent = "status_changed"
+	WebhookEventUpdated       WebhookEvent = "updated"
+)
+
+// CreateOrderRequest defines model for CreateOrderRequest.
+type CreateOrderRequest struct {
+	Address DeliveryAddress `json:"address"`
+
+	// DeliverAt Schedules the order to be delivered at this time; it goes to the kitchen in time for it. Exclusive with prepare_from.
+	DeliverAt   *time.Time `json:"deliver_at,omitempty"`
+	FIO         string     `json:"fio,omitempty"`
+	Items       []Item     `json:"items"`
+	OrderNumber string     `json:"order_number,omitempty"`
+
+	// PrepareFrom Schedules the order to go to the kitchen at this time. Exclusive with deliver_at.
+	PrepareFrom  *time.Time `json:"prepare_from,omitempty"`
+	RestaurantID string     `json:"restaurant_id"`
+	TotalPrice   int64      `json:"total_price"`
+}
+
+// DeleteOrderResponse defines model for DeleteOrderResponse.
+type DeleteOrderResponse struct {
+	ID     string      `json:"id"`
+	Status OrderStatus `json:"status"`
+}
+
+// DeliveryAddress defines model for DeliveryAddress.
+type DeliveryAddress struct {
+	Apartment string `json:"apartment,omitempty"`
+	Comment   string `json:"comment,omitempty"`
+	Floor     string `json:"floor,omitempty"`
+	House     string `json:"house,omitempty"`
+
+	// Location WGS 84 position. Without it the service geocodes the address itself, if it can.
+	Location *GeoPoint `json:"location,omitempty"`
+	Street   string    `json:"street,omitempty"`
+}
+
+// Error defines model for Error.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// GeoPoint WGS 84 position. Without it the service geocodes the address itself, if it can.
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Item defines model for Item.
+type Item struct {
+	FoodID   string `json:"food_id"`
+	Name     string `json:"name"`
+	Price    int    `json:"price"`
+	Quantity int    `json:"quantity"`
+}
+
+// OrderResponse defines model for OrderResponse.
+type OrderResponse struct {
+	Address           DeliveryAddress `json:"address"`
+	CreatedAt         time.Time       `json:"created_at"`
+	DeliverAt         *time.Time      `json:"deliver_at,omitempty"`
+	EstimatedDelivery time.Time       `json:"estimated_delivery"`
+	FIO               string          `json:"fio,omitempty"`
+	ID                string          `json:"id"`
+	Items             []Item          `json:"items"`
+	OrderNumber       string          `json:"order_number,omitempty"`
+
+	// PrepareFrom When a scheduled order goes to the kitchen.
+	PrepareFrom  *time.Time  `json:"prepare_from,omitempty"`
+	RestaurantID string      `json:"restaurant_id"`
+	Status       OrderStatus `json:"status"`
+	TotalPrice   int64       `json:"total_price"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+	UserID       string      `json:"user_id"`
+}
+
+// OrderStatus defines model for OrderStatus.
+type OrderStatus string
+
+// OrderStatusResponse defines model for OrderStatusResponse.
+type OrderStatusResponse struct {
+	OrderID string      `json:"order_id"`
+	Status  OrderStatus `json:"status"`
+}
+
+// ReplayJob defines model for ReplayJob.
+type ReplayJob struct {
+	Error      string         `json:"error,omitempty"`
+	Filter     ReplayRequest  `json:"filter"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+	ID         string         `json:"id"`
+	Published  int            `json:"published"`
+	Rate       float64        `json:"rate"`
+	StartedAt  time.Time      `json:"started_at"`
+	State      ReplayJobState `json:"state"`
+	Total      int            `json:"total"`
+}
+
+// ReplayJobState defines model for ReplayJob.State.
+type ReplayJobState string
+
+// ReplayRequest defines model for ReplayRequest.
+type ReplayRequest struct {
+	// From Orders created at or after this time.
+	From *time.Time `json:"from,omitempty"`
+
+	// Rate Events per second; 0 or absent uses replay.rate.
+	Rate         *float64       `json:"rate,omitempty"`
+	RestaurantID *string        `json:"restaurant_id,omitempty"`
+	Statuses     *[]OrderStatus `json:"statuses,omitempty"`
+
+	// To Orders created before this time.
+	To *time.Time `json:"to,omitempty"`
+}
+
+// SeedRequest defines model for SeedRequest.
+type SeedRequest struct {
+	// Count Orders to create; 0 or absent uses the scenario's count, then seed.count.
+	Count *int `json:"count,omitempty"`
+
+	// Inline A whole scenario in the format of the scenario files; wins over scenario.
+	Inline *map[string]interface{} `json:"inline,omitempty"`
+
+	// Scenario Name of a loaded scenario; absent uses seed.scenario.
+	Scenario *string `json:"scenario,omitempty"`
+
+	// Seed Seed of the random choices, for a reproducible batch; 0 or absent uses the scenario's, then a random one.
+	Seed *int64 `json:"seed,omitempty"`
+}
+
+// UpdateOrderRequest defines model for UpdateOrderRequest.
+type UpdateOrderRequest struct {
+	Address *DeliveryAddress `json:"address,omitempty"`
+
+	// DeliverAt Reschedules an order that is still scheduled.
+	DeliverAt   *time.Time `json:"deliver_at,omitempty"`
+	FIO         *string    `json:"fio,omitempty"`
+	Items       *[]Item    `json:"items,omitempty"`
+	OrderNumber *string    `json:"order_number,omitempty"`
+
+	// PrepareFrom Reschedules an order that is still scheduled.
+	PrepareFrom *time.Time `json:"prepare_from,omitempty"`
+	TotalPrice  *int64     `json:"total_price,omitempty"`
+}
+
+// WebhookDelivery defines model for WebhookDelivery.
+type WebhookDelivery struct {
+	Attempts   int          `json:"attempts"`
+	CreatedAt  time.Time    `json:"created_at"`
+	Event      WebhookEvent `json:"event"`
+	EventID    string       `json:"event_id"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	ID         string       `json:"id"`
+	LastError  string       `json:"last_error,omitempty"`
+
+	// LastStatus HTTP status of the last attempt; absent when it got no response.
+	LastStatus     *int                 `json:"last_status,omitempty"`
+	NextAttemptAt  *time.Time           `json:"next_attempt_at,omitempty"`
+	OrderID        string               `json:"order_id"`
+	State          WebhookDeliveryState `json:"state"`
+	SubscriptionID string               `json:"subscription_id"`
+}
+
+// WebhookDeliveryState defines model for WebhookDelivery.State.
+type WebhookDeliveryState string
+
+// WebhookEvent defines model for WebhookEvent.
+type WebhookEvent string
+
+// WebhookSubscription defines model for WebhookSubscription.
+type WebhookSubscription struct {
+	CreatedAt    time.Time      `json:"created_at"`
+	Events       []WebhookEvent `json:"events"`
+	ID           string         `json:"id"`
+	RestaurantID string         `json:"restaurant_id,omitempty"`
+
+	// Secret Only present in the create response.
+	Secret string `json:"secret,omitempty"`
+	URL    string `json:"url"`
+}
+
+// WebhookSubscriptionRequest defines model for WebhookSubscriptionRequest.
+type WebhookSubscriptionRequest struct {
+	// Events Event types to send; all when absent.
+	Events *[]WebhookEvent `json:"events,omitempty"`
+
+	// RestaurantID Only orders of this restaurant; all when absent.
+	RestaurantID *string `json:"restaurant_id,omitempty"`
+
+	// Secret HMAC-SHA256 key; generated when absent.
+	Secret *string `json:"secret,omitempty"`
+	URL    string  `json:"url"`
+}
+
+// OrderID defines model for OrderID.
+type OrderID = string
+
+// ReplayID defines model for ReplayID.
+type ReplayID = string
+
+// WebhookID defines model for WebhookID.
+type WebhookID = string
+
+// BadRequest defines model for BadRequest.
+type BadRequest = Error
+
+// Conflict defines model for Conflict.
+type Conflict = Error
+
+// Forbidden defines model for Forbidden.
+type Forbidden = Error
+
+// Internal defines model for Internal.
+type Internal = Error
+
+// NotFound defines model for NotFound.
+type NotFound = Error
+
+// Unauthorized defines model for Unauthorized.
+type Unauthorized = Error
+
+// ListOrdersParams defines parameters for ListOrders.
+type ListOrdersParams struct {
+	// From RFC3339 date-time; returns orders with created_at >= from. If omitted, returns all.
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+}
+
+// StartReplayJSONRequestBody defines body for StartReplay for application/json ContentType.
+type StartReplayJSONRequestBody = ReplayRequest
+
+// CreateWebhookJSONRequestBody defines body for CreateWebhook for application/json ContentType.
+type CreateWebhookJSONRequestBody = WebhookSubscriptionRequest
+
+// SeedDebugOrdersJSONRequestBody defines body for SeedDebugOrders for application/json ContentType.
+type SeedDebugOrdersJSONRequestBody = SeedRequest
+
+// CreateOrderJSONRequestBody defines body for CreateOrder for application/json ContentType.
+type CreateOrderJSONRequestBody = CreateOrderRequest
+
+// UpdateOrderJSONRequestBody defines body for UpdateOrder for application/json ContentType.
+type UpdateOrderJSONRequestBody = UpdateOrderRequest
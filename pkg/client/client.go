@@ -0,0 +1,308 @@
+// Package client is a typed Go client for the service-order HTTP API.
+//
+//	c, err := client.New("http://localhost:8080", client.WithUserID("u1"))
+//	order, err := c.CreateOrder(ctx, openapi.CreateOrderRequest{...})
+//
+// Requests and responses use the types generated from api_openapi.yaml.
+package client
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nikolaev/service-order/pkg/api/openapi"
+)
+
+// APIPrefix is the path under which the order API is mounted.
+const APIPrefix = "/public/api/v1"
+
+// Auth headers understood by the service.
+const (
+	HeaderBypass        = "X-Bypass-Auth"
+	HeaderUserID        = "X-User-ID"
+	HeaderAuthorization = "Authorization"
+	HeaderAdminToken    = "X-Admin-Token"
+)
+
+type Client struct {
+	api          *openapi.ClientWithResponses
+	pollInterval time.Duration
+}
+
+type config struct {
+	httpClient   openapi.HttpRequestDoer
+	headers      http.Header
+	retry        RetryPolicy
+	pollInterval time.Duration
+}
+
+// Option configures Client.
+type Option func(*config)
+
+// WithUserID sends X-User-ID with every request.
+func WithUserID(userID string) Option {
+	return func(c *config) { c.headers.Set(HeaderUserID, userID) }
+}
+
+// WithBypassAuth sends X-Bypass-Auth=true, acting as default-user.
+func WithBypassAuth() Option {
+	return func(c *config) { c.headers.Set(HeaderBypass, "true") }
+}
+
+// WithBearerToken sends Authorization: Bearer <token>, for deployments where a
+// gateway in front of the service resolves tokens into X-User-ID.
+func WithBearerToken(token string) Option {
+	return func(c *config) { c.headers.Set(HeaderAuthorization, "Bearer "+token) }
+}
+
+// WithAdminToken sends X-Admin-Token, required by the /admin operations.
+func WithAdminToken(token string) Option {
+	return func(c *config) { c.headers.Set(HeaderAdminToken, token) }
+}
+
+// WithHTTPClient replaces http.DefaultClient.
+func WithHTTPClient(doer openapi.HttpRequestDoer) Option {
+	return func(c *config) { c.httpClient = doer }
+}
+
+// WithRetry overrides DefaultRetryPolicy. Retries only apply to idempotent calls.
+func WithRetry(p RetryPolicy) Option {
+	return func(c *config) { c.retry = p }
+}
+
+// WithPollInterval sets how often WatchOrder polls the status.
+func WithPollInterval(d time.Duration) Option {
+	return func(c *config) { c.pollInterval = d }
+}
+
+// New creates a client for the service at baseURL (scheme and host, e.g.
+// http://localhost:8080). APIPrefix is appended unless already present.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	cfg := &config{
+		httpClient:   http.DefaultClient,
+		headers:      make(http.Header),
+		retry:        DefaultRetryPolicy,
+		pollInterval: time.Second,
+	}
+	for _, o := range opts {
+		o(cfg)
+	}
+
+	server := strings.TrimSuffix(baseURL, "/")
+	if !strings.HasSuffix(server, APIPrefix) {
+		server += APIPrefix
+	}
+
+	api, err := openapi.NewClientWithResponses(server,
+		openapi.WithHTTPClient(&retryDoer{next: cfg.httpClient, policy: cfg.retry}),
+		openapi.WithRequestEditorFn(func(_ context.Context, req *http.Request) error {
+			for k, v := range cfg.headers {
+				req.Header[k] = v
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{api: api, pollInterval: cfg.pollInterval}, nil
+}
+
+func (c *Client) CreateOrder(ctx context.Context, in openapi.CreateOrderRequest) (*openapi.OrderResponse, error) {
+	resp, err := c.api.CreateOrderWithResponse(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return result(resp.StatusCode(), resp.Body, resp.JSON201)
+}
+
+func (c *Client) GetOrder(ctx context.Context, id string) (*openapi.OrderResponse, error) {
+	resp, err := c.api.GetOrderWithResponse(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return result(resp.StatusCode(), resp.Body, resp.JSON200)
+}
+
+func (c *Client) GetOrderStatus(ctx context.Context, id string) (*openapi.OrderStatusResponse, error) {
+	resp, err := c.api.GetOrderStatusWithResponse(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return result(resp.StatusCode(), resp.Body, resp.JSON200)
+}
+
+// ListOrders returns orders created at or after from. Zero from returns all.
+func (c *Client) ListOrders(ctx context.Context, from time.Time) ([]openapi.OrderResponse, error) {
+	params := &openapi.ListOrdersParams{}
+	if !from.IsZero() {
+		params.From = &from
+	}
+	resp, err := c.api.ListOrdersWithResponse(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	out, err := result(resp.StatusCode(), resp.Body, resp.JSON200)
+	if err != nil {
+		return nil, err
+	}
+	return *out, nil
+}
+
+func (c *Client) UpdateOrder(ctx context.Context, id string, in openapi.UpdateOrderRequest) (*openapi.OrderResponse, error) {
+	resp, err := c.api.UpdateOrderWithResponse(ctx, id, in)
+	if err != nil {
+		return nil, err
+	}
+	return result(resp.StatusCode(), resp.Body, resp.JSON200)
+}
+
+func (c *Client) DeleteOrder(ctx context.Context, id string) (*openapi.DeleteOrderResponse, error) {
+	resp, err := c.api.DeleteOrderWithResponse(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return result(resp.StatusCode(), resp.Body, resp.JSON200)
+}
+
+// CancelOrder cancels a scheduled, created or pending order; later statuses
+// answer ErrConflict.
+func (c *Client) CancelOrder(ctx context.Context, id string) (*openapi.OrderResponse, error) {
+	resp, err := c.api.CancelOrderWithResponse(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return result(resp.StatusCode(), resp.Body, resp.JSON200)
+}
+
+// SeedDebugOrders calls the debug seeding route; the zero request runs the
+// server's default scenario.
+func (c *Client) SeedDebugOrders(ctx context.Context, in openapi.SeedRequest) ([]openapi.OrderResponse, error) {
+	resp, err := c.api.SeedDebugOrdersWithResponse(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	out, err := result(resp.StatusCode(), resp.Body, resp.JSON201)
+	if err != nil {
+		return nil, err
+	}
+	return *out, nil
+}
+
+// StartReplay republishes snapshots of the orders matching in; the returned
+// job runs in the background and is polled with GetReplay.
+func (c *Client) StartReplay(ctx context.Context, in openapi.ReplayRequest) (*openapi.ReplayJob, error) {
+	resp, err := c.api.StartReplayWithResponse(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return result(resp.StatusCode(), resp.Body, resp.JSON202)
+}
+
+func (c *Client) GetReplay(ctx context.Context, id string) (*openapi.ReplayJob, error) {
+	resp, err := c.api.GetReplayWithResponse(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return result(resp.StatusCode(), resp.Body, resp.JSON200)
+}
+
+// ListReplays returns the recent replays, newest first.
+func (c *Client) ListReplays(ctx context.Context) ([]openapi.ReplayJob, error) {
+	resp, err := c.api.ListReplaysWithResponse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out, err := result(resp.StatusCode(), resp.Body, resp.JSON200)
+	if err != nil {
+		return nil, err
+	}
+	return *out, nil
+}
+
+func (c *Client) CancelReplay(ctx context.Context, id string) (*openapi.ReplayJob, error) {
+	resp, err := c.api.CancelReplayWithResponse(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return result(resp.StatusCode(), resp.Body, resp.JSON200)
+}
+
+// CreateWebhook subscribes a partner endpoint; the secret, generated when
+// in has none, is only returned here.
+func (c *Client) CreateWebhook(ctx context.Context, in openapi.WebhookSubscriptionRequest) (*openapi.WebhookSubscription, error) {
+	resp, err := c.api.CreateWebhookWithResponse(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return result(resp.StatusCode(), resp.Body, resp.JSON201)
+}
+
+// ListWebhooks returns the subscriptions, oldest first, without secrets.
+func (c *Client) ListWebhooks(ctx context.Context) ([]openapi.WebhookSubscription, error) {
+	resp, err := c.api.ListWebhooksWithResponse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out, err := result(resp.StatusCode(), resp.Body, resp.JSON200)
+	if err != nil {
+		return nil, err
+	}
+	return *out, nil
+}
+
+func (c *Client) GetWebhook(ctx context.Context, id string) (*openapi.WebhookSubscription, error) {
+	resp, err := c.api.GetWebhookWithResponse(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return result(resp.StatusCode(), resp.Body, resp.JSON200)
+}
+
+// DeleteWebhook unsubscribes; deliveries still waiting go dead.
+func (c *Client) DeleteWebhook(ctx context.Context, id string) error {
+	resp, err := c.api.DeleteWebhookWithResponse(ctx, id)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() != http.StatusNoContent {
+		return newError(resp.StatusCode(), resp.Body)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns the delivery log of a subscription, newest
+// first.
+func (c *Client) ListWebhookDeliveries(ctx context.Context, id string) ([]openapi.WebhookDelivery, error) {
+	resp, err := c.api.ListWebhookDeliveriesWithResponse(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	out, err := result(resp.StatusCode(), resp.Body, resp.JSON200)
+	if err != nil {
+		return nil, err
+	}
+	return *out, nil
+}
+
+// RedeliverWebhook queues a dead delivery again with a fresh set of
+// attempts; other states answer ErrConflict.
+func (c *Client) RedeliverWebhook(ctx context.Context, id, deliveryID string) (*openapi.WebhookDelivery, error) {
+	resp, err := c.api.RetryWebhookDeliveryWithResponse(ctx, id, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	return result(resp.StatusCode(), resp.Body, resp.JSON200)
+}
+
+// result returns the decoded success body, or an *Error built from the
+// error body when the status is not a success.
+func result[T any](status int, body []byte, ok *T) (*T, error) {
+	if status >= http.StatusBadRequest || ok == nil {
+		return nil, newError(status, body)
+	}
+	return ok, nil
+}
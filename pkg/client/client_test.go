@@ -0,0 +1,234 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikolaev/service-order/internal/clock"
+	"github.com/nikolaev/service-order/internal/domain/entity"
+	"github.com/nikolaev/service-order/internal/gateway/kafka"
+	"github.com/nikolaev/service-order/internal/gateway/webhook"
+	"github.com/nikolaev/service-order/internal/handlers"
+	repo "github.com/nikolaev/service-order/internal/repository/order"
+	seed "github.com/nikolaev/service-order/internal/usecase/debug/seed"
+	uc "github.com/nikolaev/service-order/internal/usecase/order"
+	"github.com/nikolaev/service-order/internal/usecase/replay"
+	"github.com/nikolaev/service-order/pkg/api/openapi"
+	"github.com/nikolaev/service-order/pkg/client"
+)
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv, _ := newServerWithWebhooks(t)
+	return srv
+}
+
+// newServerWithWebhooks also returns the webhook dispatcher; it is not
+// started, so deliveries stay pending.
+func newServerWithWebhooks(t *testing.T) (*httptest.Server, *webhook.Dispatcher) {
+	t.Helper()
+	mem := repo.NewInMemory()
+	rp := replay.NewWithLimits(mem, kafka.NoopProducer{}, clock.System{}, replay.Limits{Rate: 1, MaxRate: 1000})
+	t.Cleanup(func() { _ = rp.Stop(context.Background()) })
+	svc := uc.New(mem, kafka.NoopProducer{})
+	sd, err := seed.New(svc, mem, kafka.NoopProducer{}, clock.System{}, seed.Options{})
+	require.NoError(t, err)
+	wh := webhook.NewDispatcher(clock.System{}, webhook.Options{MaxPending: 1})
+	h := handlers.NewOrderHandler(svc, sd).WithReplay(rp).WithWebhooks(wh).WithAdminToken("admin")
+	r := chi.NewRouter()
+	r.Mount(client.APIPrefix, h.Routes())
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv, wh
+}
+
+func newOrder() openapi.CreateOrderRequest {
+	return openapi.CreateOrderRequest{
+		RestaurantID: "rest-1",
+		Items:        []openapi.Item{{FoodID: "f1", Name: "Pizza", Quantity: 1, Price: 500}},
+		TotalPrice:   500,
+		Address:      openapi.DeliveryAddress{Street: "Main"},
+	}
+}
+
+func TestClient_AllRoutes(t *testing.T) {
+	srv := newServer(t)
+	c, err := client.New(srv.URL, client.WithUserID("u1"), client.WithAdminToken("admin"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	created, err := c.CreateOrder(ctx, newOrder())
+	require.NoError(t, err)
+	assert.Equal(t, "u1", created.UserID)
+
+	got, err := c.GetOrder(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+
+	st, err := c.GetOrderStatus(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, openapi.OrderStatusCreated, st.Status)
+
+	fio := "Petrov P.P."
+	upd, err := c.UpdateOrder(ctx, created.ID, openapi.UpdateOrderRequest{FIO: &fio})
+	require.NoError(t, err)
+	assert.Equal(t, fio, upd.FIO)
+
+	seeded, err := c.SeedDebugOrders(ctx, openapi.SeedRequest{})
+	require.NoError(t, err)
+	assert.Len(t, seeded, 10)
+
+	list, err := c.ListOrders(ctx, time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, list, 11)
+
+	del, err := c.DeleteOrder(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, openapi.OrderStatusDeleted, del.Status)
+
+	_, err = c.GetOrder(ctx, created.ID)
+	assert.True(t, errors.Is(err, client.ErrNotFound))
+	var apiErr *client.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+
+	// At the default rate of 1/s the replay of ten orders is still running.
+	job, err := c.StartReplay(ctx, openapi.ReplayRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 10, job.Total)
+	_, err = c.StartReplay(ctx, openapi.ReplayRequest{})
+	assert.True(t, errors.Is(err, client.ErrConflict))
+
+	job, err = c.CancelReplay(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, openapi.ReplayJobStateCanceled, job.State)
+	got2, err := c.GetReplay(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, job.ID, got2.ID)
+	jobs, err := c.ListReplays(ctx)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 1)
+}
+
+func TestClient_AuthOptions(t *testing.T) {
+	srv := newServer(t)
+	ctx := context.Background()
+
+	anon, err := client.New(srv.URL)
+	require.NoError(t, err)
+	_, err = anon.CreateOrder(ctx, newOrder())
+	assert.True(t, errors.Is(err, client.ErrUnauthorized))
+
+	bypass, err := client.New(srv.URL, client.WithBypassAuth())
+	require.NoError(t, err)
+	o, err := bypass.CreateOrder(ctx, newOrder())
+	require.NoError(t, err)
+	assert.Equal(t, "default-user", o.UserID)
+
+	other, err := client.New(srv.URL, client.WithUserID("u2"))
+	require.NoError(t, err)
+	_, err = other.DeleteOrder(ctx, o.ID)
+	assert.True(t, errors.Is(err, client.ErrBadRequest))
+}
+
+func TestClient_Webhooks(t *testing.T) {
+	srv, wh := newServerWithWebhooks(t)
+	c, err := client.New(srv.URL, client.WithAdminToken("admin"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	events := []openapi.WebhookEvent{"created"}
+	sub, err := c.CreateWebhook(ctx, openapi.WebhookSubscriptionRequest{URL: "https://partner.example/hook", Events: &events})
+	require.NoError(t, err)
+	assert.NotEmpty(t, sub.Secret)
+	_, err = c.CreateWebhook(ctx, openapi.WebhookSubscriptionRequest{URL: "http://127.0.0.1/hook"})
+	assert.True(t, errors.Is(err, client.ErrBadRequest))
+
+	got, err := c.GetWebhook(ctx, sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sub.URL, got.URL)
+	assert.Empty(t, got.Secret)
+	subs, err := c.ListWebhooks(ctx)
+	require.NoError(t, err)
+	assert.Len(t, subs, 1)
+
+	// With MaxPending 1 the second event pushes the first into the dead letter.
+	for _, id := range []string{"o1", "o2"} {
+		require.NoError(t, wh.OrderCreated(ctx, &entity.Order{ID: id, Status: entity.OrderStatusCreated}))
+	}
+	log, err := c.ListWebhookDeliveries(ctx, sub.ID)
+	require.NoError(t, err)
+	require.Len(t, log, 2)
+	assert.Equal(t, openapi.WebhookDeliveryStateDead, log[1].State)
+	_, err = c.RedeliverWebhook(ctx, sub.ID, log[0].ID)
+	assert.True(t, errors.Is(err, client.ErrConflict))
+	dl, err := c.RedeliverWebhook(ctx, sub.ID, log[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, openapi.WebhookDeliveryStatePending, dl.State)
+
+	require.NoError(t, c.DeleteWebhook(ctx, sub.ID))
+	err = c.DeleteWebhook(ctx, sub.ID)
+	assert.True(t, errors.Is(err, client.ErrNotFound))
+	_, err = c.GetWebhook(ctx, sub.ID)
+	assert.True(t, errors.Is(err, client.ErrNotFound))
+
+	anon, err := client.New(srv.URL)
+	require.NoError(t, err)
+	_, err = anon.ListWebhooks(ctx)
+	assert.True(t, errors.Is(err, client.ErrUnauthorized))
+}
+
+func TestClient_RetriesIdempotentCalls(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"order_id":"o1","status":"pending"}`))
+	}))
+	defer srv.Close()
+
+	c, err := client.New(srv.URL, client.WithRetry(client.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+	require.NoError(t, err)
+	st, err := c.GetOrderStatus(context.Background(), "o1")
+	require.NoError(t, err)
+	assert.Equal(t, openapi.OrderStatusPending, st.Status)
+	assert.Equal(t, int32(3), calls.Load())
+
+	calls.Store(0)
+	_, err = c.CreateOrder(context.Background(), newOrder())
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load(), "POST must not be retried")
+}
+
+func TestClient_WatchOrder(t *testing.T) {
+	srv := newServer(t)
+	c, err := client.New(srv.URL, client.WithUserID("u1"), client.WithPollInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	o, err := c.CreateOrder(ctx, newOrder())
+	require.NoError(t, err)
+
+	var seen []openapi.OrderStatus
+	err = c.WatchOrder(ctx, o.ID, func(st openapi.OrderStatusResponse) {
+		seen = append(seen, st.Status)
+		if st.Status == openapi.OrderStatusCreated {
+			_, _ = c.DeleteOrder(ctx, o.ID)
+		}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []openapi.OrderStatus{openapi.OrderStatusCreated, openapi.OrderStatusDeleted}, seen)
+}
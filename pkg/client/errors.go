@@ -0,0 +1,52 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/nikolaev/service-order/pkg/api/openapi"
+)
+
+// Sentinel errors matched by Error.Is, so callers can use errors.Is(err, client.ErrNotFound).
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrBadRequest   = errors.New("bad request")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrInternal     = errors.New("internal error")
+)
+
+var codeToErr = map[string]error{
+	"unauthorized": ErrUnauthorized,
+	"forbidden":    ErrForbidden,
+	"bad_request":  ErrBadRequest,
+	"not_found":    ErrNotFound,
+	"conflict":     ErrConflict,
+	"internal":     ErrInternal,
+}
+
+// Error is a non-success response of the service, decoded from transport.Error.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("service-order: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	return codeToErr[e.Code] == target
+}
+
+func newError(status int, body []byte) error {
+	var er openapi.Error
+	if err := json.Unmarshal(body, &er); err != nil || er.Code == "" {
+		er = openapi.Error{Code: "unexpected_response", Message: http.StatusText(status)}
+	}
+	return &Error{StatusCode: status, Code: er.Code, Message: er.Message}
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nikolaev/service-order/pkg/api/openapi"
+)
+
+// WatchOrder polls the order status and calls fn with the current status and
+// then with every change. It returns nil when the order reaches a terminal
+// status (completed, canceled, deleted) and ctx.Err() when ctx is done.
+// The HTTP API has no push channel; use the gRPC WatchOrder for streaming.
+func (c *Client) WatchOrder(ctx context.Context, id string, fn func(openapi.OrderStatusResponse)) error {
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	var last openapi.OrderStatus
+	for {
+		st, err := c.GetOrderStatus(ctx, id)
+		switch {
+		case errors.Is(err, ErrNotFound) && last != "":
+			// Deleted orders disappear from the API.
+			fn(openapi.OrderStatusResponse{OrderID: id, Status: openapi.OrderStatusDeleted})
+			return nil
+		case err != nil:
+			return err
+		case st.Status != last:
+			last = st.Status
+			fn(*st)
+		}
+		if terminal(last) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func terminal(s openapi.OrderStatus) bool {
+	switch s {
+	case openapi.OrderStatusCompleted, openapi.OrderStatusCanceled, openapi.OrderStatusDeleted:
+		return true
+	default:
+		return false
+	}
+}